@@ -24,6 +24,7 @@ import (
 
 	"github.com/ericlagergren/decimal"
 	"github.com/gorilla/websocket"
+	"golang.org/x/exp/maps"
 )
 
 // We are not using the official alpaca client SDK, because it uses float64.
@@ -227,27 +228,28 @@ const (
 	messageActionAuth = "auth"
 )
 
+// Candle resolutions which are natively supported by the broker.
+var candleResolutionStr = map[candles.CandleResolution]string{
+	candles.CandleOneMinute:      "1Min",
+	candles.CandleFiveMinutes:    "5Min",
+	candles.CandleFifteenMinutes: "15Min",
+	candles.CandleThirtyMinutes:  "30Min",
+	candles.CandleSixtyMinutes:   "1Hour",
+	candles.CandleTwoHours:       "2Hour",
+	candles.CandleFourHours:      "4Hour",
+	candles.CandleOneDay:         "1Day",
+	candles.CandleOneWeek:        "1Week",
+	candles.CandleOneMonth:       "1Month",
+	candles.CandleOneQuarter:     "3Month",
+	candles.CandleOneYear:        "12Month",
+}
+
 func getCandleResolutionStr(r candles.CandleResolution) string {
-	switch r {
-	case candles.CandleOneMinute:
-		return "1Min"
-	case candles.CandleFiveMinutes:
-		return "5Min"
-	case candles.CandleFifteenMinutes:
-		return "15Min"
-	case candles.CandleThirtyMinutes:
-		return "30Min"
-	case candles.CandleSixtyMinutes:
-		return "1Hour"
-	case candles.CandleOneDay:
-		return "1Day"
-	case candles.CandleOneWeek:
-		return "1Week"
-	case candles.CandleOneMonth:
-		return "1Month"
-	default:
+	s, ok := candleResolutionStr[r]
+	if !ok {
 		panic("unsupported candle resolution")
 	}
+	return s
 }
 
 func getSideStr(sell bool) string {
@@ -381,8 +383,10 @@ func GetBrokerId() stockval.BrokerId {
 
 func (rq *alpacaBroker) GetCapabilities() stockapi.Capabilities {
 	return stockapi.Capabilities{
-		RealtimeBidAsk: true,
-		PaperTrading:   true,
+		RealtimeBidAsk:       true,
+		PaperTrading:         true,
+		ExtendedHoursCandles: true,
+		CandleResolutions:    maps.Keys(candleResolutionStr),
 	}
 }
 
@@ -608,6 +612,8 @@ func (rq *alpacaBroker) QueryCandles(ctx context.Context, request <-chan stockap
 		if resp.Error != nil {
 			rq.logger.Print(resp.Error)
 		}
+		resp.FromTime = req.FromTime
+		resp.ToTime = req.ToTime
 		response <- resp
 	}
 	rq.logger.Println("finnhub QueryCandles terminating.")
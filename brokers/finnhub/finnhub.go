@@ -23,6 +23,7 @@ import (
 	"github.com/gorilla/websocket"
 
 	"github.com/ericlagergren/decimal"
+	"golang.org/x/exp/maps"
 )
 
 // We are not using the finnhub apiClient, because it uses float32, which is bad for price calculations.
@@ -93,27 +94,24 @@ type realtimeCommand struct {
 
 const messageTypeTrade = "trade"
 
+// Candle resolutions which are natively supported by the broker.
+var candleResolutionStr = map[candles.CandleResolution]string{
+	candles.CandleOneMinute:      "1",
+	candles.CandleFiveMinutes:    "5",
+	candles.CandleFifteenMinutes: "15",
+	candles.CandleThirtyMinutes:  "30",
+	candles.CandleSixtyMinutes:   "60",
+	candles.CandleOneDay:         "D",
+	candles.CandleOneWeek:        "W",
+	candles.CandleOneMonth:       "M",
+}
+
 func getCandleResolutionStr(r candles.CandleResolution) string {
-	switch r {
-	case candles.CandleOneMinute:
-		return "1"
-	case candles.CandleFiveMinutes:
-		return "5"
-	case candles.CandleFifteenMinutes:
-		return "15"
-	case candles.CandleThirtyMinutes:
-		return "30"
-	case candles.CandleSixtyMinutes:
-		return "60"
-	case candles.CandleOneDay:
-		return "D"
-	case candles.CandleOneWeek:
-		return "W"
-	case candles.CandleOneMonth:
-		return "M"
-	default:
+	s, ok := candleResolutionStr[r]
+	if !ok {
 		panic("unsupported candle resolution")
 	}
+	return s
 }
 
 func getRealtimeDataSubscriptionStr(s stockapi.RealtimeDataSubscription) string {
@@ -190,7 +188,10 @@ func GetBrokerId() stockval.BrokerId {
 }
 
 func (rq *finnhubBroker) GetCapabilities() stockapi.Capabilities {
-	return stockapi.Capabilities{}
+	return stockapi.Capabilities{
+		UtcDateCandles:    true,
+		CandleResolutions: maps.Keys(candleResolutionStr),
+	}
 }
 
 func (rq *finnhubBroker) RemainingApiLimit() int {
@@ -392,6 +393,8 @@ func (rq *finnhubBroker) QueryCandles(ctx context.Context, request <-chan stocka
 		if resp.Error != nil {
 			rq.logger.Print(resp.Error)
 		}
+		resp.FromTime = req.FromTime
+		resp.ToTime = req.ToTime
 		response <- resp
 	}
 	rq.logger.Println("finnhub QueryCandles terminating.")
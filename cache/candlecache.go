@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package cache
+
+import (
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"time"
+)
+
+// Candles within this duration before now may still be changed by the broker, e.g. because of delayed trades.
+// Ranges within this duration are only considered to be covered by the cache if candles were returned.
+const CandleUpdateDelay = time.Hour * 24
+
+// A time range [From, To).
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Persistent storage of consolidated candles, keyed by asset and candle resolution.
+type CandleCache interface {
+	// Returns the cached candles with timestamps within [from, to].
+	GetCandles(figi string, r candles.CandleResolution, from time.Time, to time.Time) []indapi.CandleData
+	// Returns the parts of [from, to) which are not covered by the cache, and need to be queried from the broker.
+	GetMissingRanges(figi string, r candles.CandleResolution, from time.Time, to time.Time) []TimeRange
+	// Stores candles which were queried from the broker for [from, to].
+	// Returns true if cached prices differ from the new prices, e.g. because of a split or a dividend.
+	// In this case, all cached candles of the asset are invalidated before storing the new candles.
+	StoreCandles(figi string, r candles.CandleResolution, from time.Time, to time.Time, data []indapi.CandleData) (adjusted bool)
+	// Removes all cached candles of an asset.
+	InvalidateAsset(figi string)
+}
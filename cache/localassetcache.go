@@ -9,6 +9,7 @@ import (
 	"log"
 	"maystocks/config"
 	"maystocks/stockval"
+	"os"
 	"path/filepath"
 	"sort"
 	"sync"
@@ -19,6 +20,9 @@ import (
 
 const CacheKeyStockSymbols = "stocksymbols"
 
+// Cache stock symbols for some hours.
+const symbolCacheDuration = time.Hour * 12
+
 type localAssetCache struct {
 	broker   stockval.BrokerId
 	data     *localcache.Cache
@@ -38,16 +42,17 @@ func NewLocalAssetCache(broker stockval.BrokerId) AssetCache {
 }
 
 func (c *localAssetCache) GetAssetList(ctx context.Context, req func(ctx context.Context) ([]stockval.AssetData, error)) AssetList {
-	// Cache stock symbols for some hours.
-	err := c.data.PurgeKey(CacheKeyStockSymbols, time.Hour*12)
-	if err != nil {
-		log.Printf("error purging cache %s, symbol data may be outdated", CacheKeyStockSymbols)
-	}
-	symbols := c.readSymbolsFromCache()
+	symbols := c.readSymbolsFromCache(symbolCacheDuration)
 	if symbols == nil {
+		var err error
 		symbols, err = c.initSymbolCache(ctx, req)
 		if err != nil {
 			log.Printf("error requesting stock symbols: %v", err)
+			// Use outdated symbols if the broker is not reachable.
+			symbols = c.readSymbolsFromCache(0)
+			if symbols != nil {
+				log.Printf("using outdated %s stock symbols", c.broker)
+			}
 		}
 	}
 	if symbols == nil {
@@ -57,7 +62,25 @@ func (c *localAssetCache) GetAssetList(ctx context.Context, req func(ctx context
 	return symbols
 }
 
-func (c *localAssetCache) readSymbolsFromCache() []stockval.AssetData {
+// Returns true if the cached symbols are older than maxAge.
+func (c *localAssetCache) isOutdated(maxAge time.Duration) bool {
+	path := c.data.IfExists(CacheKeyStockSymbols)
+	if path == "" {
+		return true
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return true
+	}
+	return time.Since(info.ModTime()) > maxAge
+}
+
+// Reads the cached symbols, ignoring symbols older than maxAge.
+// A maxAge of 0 returns the cached symbols regardless of their age.
+func (c *localAssetCache) readSymbolsFromCache(maxAge time.Duration) []stockval.AssetData {
+	if maxAge > 0 && c.isOutdated(maxAge) {
+		return nil
+	}
 	rawSymbols, err := c.data.ReadFile(CacheKeyStockSymbols)
 	if err == nil {
 		var symbols []stockval.AssetData
@@ -78,7 +101,7 @@ func (c *localAssetCache) initSymbolCache(ctx context.Context, req func(ctx cont
 	c.initLock.Lock()
 	defer c.initLock.Unlock()
 	// retry reading cache within lock, to avoid requesting the data twice.
-	cachedSymbols := c.readSymbolsFromCache()
+	cachedSymbols := c.readSymbolsFromCache(symbolCacheDuration)
 	if cachedSymbols != nil {
 		return cachedSymbols, nil
 	}
@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maystocks/config"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/stockval"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lotodore/localcache"
+)
+
+type localCandleCache struct {
+	broker  stockval.BrokerId
+	data    *localcache.Cache
+	entries map[string]*candleCacheEntry
+	mutex   sync.Mutex
+}
+
+type candleCacheEntry struct {
+	Covered []TimeRange
+	Candles []indapi.CandleData
+}
+
+func NewLocalCandleCache(broker stockval.BrokerId) CandleCache {
+	data, err := localcache.New(filepath.Join(config.AppName, string(broker), "candles"))
+	if err != nil {
+		log.Fatalf("error initializing candle cache: %v", err)
+	}
+	return newLocalCandleCache(broker, data)
+}
+
+func newLocalCandleCache(broker stockval.BrokerId, data *localcache.Cache) *localCandleCache {
+	return &localCandleCache{
+		broker:  broker,
+		data:    data,
+		entries: make(map[string]*candleCacheEntry),
+	}
+}
+
+func getCandleCacheKey(figi string, r candles.CandleResolution) string {
+	return fmt.Sprintf("%s_%d", figi, r)
+}
+
+func (c *localCandleCache) GetCandles(figi string, r candles.CandleResolution, from time.Time, to time.Time) []indapi.CandleData {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e := c.loadEntry(getCandleCacheKey(figi, r))
+	var data []indapi.CandleData
+	for _, candle := range e.Candles {
+		if !candle.Timestamp.Before(from) && !candle.Timestamp.After(to) {
+			data = append(data, candle)
+		}
+	}
+	return data
+}
+
+func (c *localCandleCache) GetMissingRanges(figi string, r candles.CandleResolution, from time.Time, to time.Time) []TimeRange {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e := c.loadEntry(getCandleCacheKey(figi, r))
+	var missing []TimeRange
+	for _, covered := range e.Covered {
+		if !covered.To.After(from) {
+			continue
+		}
+		if !covered.From.Before(to) {
+			break
+		}
+		if covered.From.After(from) {
+			missing = append(missing, TimeRange{From: from, To: covered.From})
+		}
+		from = covered.To
+	}
+	if from.Before(to) {
+		missing = append(missing, TimeRange{From: from, To: to})
+	}
+	return missing
+}
+
+func (c *localCandleCache) StoreCandles(figi string, r candles.CandleResolution, from time.Time, to time.Time, data []indapi.CandleData) (adjusted bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := getCandleCacheKey(figi, r)
+	e := c.loadEntry(key)
+
+	// Brokers return adjusted prices. If historical prices have changed,
+	// the asset had a split or a dividend and all cached candles are outdated.
+	if hasAdjustedPrices(e.Candles, data) {
+		log.Printf("cached candles of %s %s were adjusted, invalidating cache", figi, r.String())
+		c.invalidateAsset(figi)
+		e = c.loadEntry(key)
+		adjusted = true
+	}
+
+	coveredTo := to
+	if time.Since(to) < CandleUpdateDelay {
+		// The last candle may still be updated, do not consider it as covered.
+		if len(data) == 0 {
+			return
+		}
+		coveredTo = data[len(data)-1].Timestamp
+		data = data[:len(data)-1]
+	}
+	e.Candles = mergeCandles(e.Candles, data)
+	if coveredTo.After(from) {
+		e.Covered = mergeTimeRanges(e.Covered, TimeRange{From: from, To: coveredTo})
+	}
+	c.writeEntry(key, e)
+	return
+}
+
+func (c *localCandleCache) InvalidateAsset(figi string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.invalidateAsset(figi)
+}
+
+func (c *localCandleCache) invalidateAsset(figi string) {
+	for r := candles.CandleResolution(0); r < candles.NumCandleResolutions; r++ {
+		key := getCandleCacheKey(figi, r)
+		delete(c.entries, key)
+		if c.data.IfExists(key) != "" {
+			err := c.data.Remove(key)
+			if err != nil {
+				log.Printf("error deleting candle cache %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// Returns the cache entry, reading it from disk if necessary.
+// The caller needs to lock the mutex.
+func (c *localCandleCache) loadEntry(key string) *candleCacheEntry {
+	e, ok := c.entries[key]
+	if ok {
+		return e
+	}
+	e = new(candleCacheEntry)
+	rawEntry, err := c.data.ReadFile(key)
+	if err == nil {
+		err = json.Unmarshal(rawEntry, e)
+		if err != nil {
+			log.Printf("%s candle cache %s contains invalid data", c.broker, key)
+			e = new(candleCacheEntry)
+		}
+	}
+	c.entries[key] = e
+	return e
+}
+
+// The caller needs to lock the mutex.
+func (c *localCandleCache) writeEntry(key string, e *candleCacheEntry) {
+	rawEntry, err := json.Marshal(e)
+	if err == nil {
+		err = c.data.WriteFile(key, rawEntry)
+	}
+	if err != nil {
+		log.Printf("error writing candle cache %s: %v", key, err)
+	}
+}
+
+func hasAdjustedPrices(cached []indapi.CandleData, data []indapi.CandleData) bool {
+	for _, candle := range data {
+		if time.Since(candle.Timestamp) < CandleUpdateDelay {
+			continue // recent candles may still be updated
+		}
+		i := sort.Search(len(cached), func(i int) bool {
+			return !cached[i].Timestamp.Before(candle.Timestamp)
+		})
+		if i < len(cached) && cached[i].Timestamp.Equal(candle.Timestamp) &&
+			(cached[i].ClosePrice.Cmp(candle.ClosePrice) != 0 || cached[i].OpenPrice.Cmp(candle.OpenPrice) != 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// Merges sorted candle lists, new candles replace cached candles with the same timestamp.
+func mergeCandles(cached []indapi.CandleData, data []indapi.CandleData) []indapi.CandleData {
+	merged := append(append([]indapi.CandleData{}, cached...), data...)
+	sort.Stable(indapi.CandleList(merged))
+	k := 0
+	for i := range merged {
+		if k > 0 && merged[k-1].Timestamp.Equal(merged[i].Timestamp) {
+			merged[k-1] = merged[i]
+		} else {
+			merged[k] = merged[i]
+			k++
+		}
+	}
+	return merged[:k]
+}
+
+// Adds a time range to a sorted list of non-overlapping time ranges.
+func mergeTimeRanges(ranges []TimeRange, n TimeRange) []TimeRange {
+	var merged []TimeRange
+	for _, t := range ranges {
+		if t.To.Before(n.From) {
+			merged = append(merged, t)
+		} else if n.To.Before(t.From) {
+			merged = append(merged, n)
+			n = t
+		} else {
+			if t.From.Before(n.From) {
+				n.From = t.From
+			}
+			if t.To.After(n.To) {
+				n.To = t.To
+			}
+		}
+	}
+	return append(merged, n)
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package cache
+
+import (
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"testing"
+	"time"
+
+	"github.com/ericlagergren/decimal"
+	"github.com/lotodore/localcache"
+	"github.com/stretchr/testify/assert"
+)
+
+const testFigi = "BBG000B9XRY4"
+
+func newTestCandles(start time.Time, num int, price int64) []indapi.CandleData {
+	var data []indapi.CandleData
+	for i := range num {
+		data = append(data, indapi.CandleData{
+			Timestamp:  start.AddDate(0, 0, i),
+			OpenPrice:  decimal.New(price, 0),
+			HighPrice:  decimal.New(price+1, 0),
+			LowPrice:   decimal.New(price-1, 0),
+			ClosePrice: decimal.New(price, 0),
+			Volume:     decimal.New(100, 0),
+		})
+	}
+	return data
+}
+
+func TestCandleCacheMissingRanges(t *testing.T) {
+	c := newLocalCandleCache("test", localcache.NewForTesting(t))
+	r := candles.CandleOneDay
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	missing := c.GetMissingRanges(testFigi, r, start, end)
+	assert.Equal(t, []TimeRange{{From: start, To: end}}, missing)
+
+	// Store the middle part of the range.
+	c.StoreCandles(testFigi, r, start.AddDate(0, 0, 10), start.AddDate(0, 0, 20), newTestCandles(start.AddDate(0, 0, 10), 10, 50))
+	missing = c.GetMissingRanges(testFigi, r, start, end)
+	assert.Equal(t, []TimeRange{
+		{From: start, To: start.AddDate(0, 0, 10)},
+		{From: start.AddDate(0, 0, 20), To: end},
+	}, missing)
+	assert.Len(t, c.GetCandles(testFigi, r, start, end), 10)
+	// Other resolutions are not affected.
+	assert.Len(t, c.GetMissingRanges(testFigi, candles.CandleOneWeek, start, end), 1)
+
+	// Adjacent ranges are merged.
+	c.StoreCandles(testFigi, r, start, start.AddDate(0, 0, 10), newTestCandles(start, 10, 50))
+	missing = c.GetMissingRanges(testFigi, r, start, end)
+	assert.Equal(t, []TimeRange{{From: start.AddDate(0, 0, 20), To: end}}, missing)
+	assert.Len(t, c.GetCandles(testFigi, r, start, end), 20)
+}
+
+func TestCandleCachePersistence(t *testing.T) {
+	data := localcache.NewForTesting(t)
+	c := newLocalCandleCache("test", data)
+	r := candles.CandleOneDay
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.StoreCandles(testFigi, r, start, start.AddDate(0, 0, 5), newTestCandles(start, 5, 50))
+
+	c = newLocalCandleCache("test", data)
+	cached := c.GetCandles(testFigi, r, start, start.AddDate(0, 0, 5))
+	assert.Len(t, cached, 5)
+	assert.True(t, cached[0].Timestamp.Equal(start))
+	assert.Equal(t, 0, cached[0].ClosePrice.Cmp(decimal.New(50, 0)))
+	assert.Empty(t, c.GetMissingRanges(testFigi, r, start, start.AddDate(0, 0, 5)))
+}
+
+func TestCandleCacheRecentCandles(t *testing.T) {
+	c := newLocalCandleCache("test", localcache.NewForTesting(t))
+	r := candles.CandleOneMinute
+	start := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	end := start.Add(time.Hour * 2)
+	data := newTestCandles(start, 1, 50)
+	data = append(data, indapi.CandleData{
+		Timestamp:  start.Add(time.Minute),
+		OpenPrice:  decimal.New(50, 0),
+		HighPrice:  decimal.New(50, 0),
+		LowPrice:   decimal.New(50, 0),
+		ClosePrice: decimal.New(50, 0),
+		Volume:     decimal.New(1, 0),
+	})
+	c.StoreCandles(testFigi, r, start, end, data)
+	// The last candle may still change, it is neither stored nor covered.
+	assert.Len(t, c.GetCandles(testFigi, r, start, end), 1)
+	assert.Equal(t, []TimeRange{{From: start.Add(time.Minute), To: end}}, c.GetMissingRanges(testFigi, r, start, end))
+}
+
+func TestCandleCacheAdjustment(t *testing.T) {
+	c := newLocalCandleCache("test", localcache.NewForTesting(t))
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.StoreCandles(testFigi, candles.CandleOneWeek, start, start.AddDate(0, 0, 70), newTestCandles(start, 10, 100))
+	c.StoreCandles(testFigi, candles.CandleOneDay, start, start.AddDate(0, 0, 10), newTestCandles(start, 10, 100))
+	// Same prices do not invalidate the cache.
+	assert.False(t, c.StoreCandles(testFigi, candles.CandleOneDay, start.AddDate(0, 0, 5), start.AddDate(0, 0, 15), newTestCandles(start.AddDate(0, 0, 5), 10, 100)))
+	// After a 2:1 split, prices are halved.
+	assert.True(t, c.StoreCandles(testFigi, candles.CandleOneDay, start.AddDate(0, 0, 5), start.AddDate(0, 0, 15), newTestCandles(start.AddDate(0, 0, 5), 10, 50)))
+	assert.Len(t, c.GetCandles(testFigi, candles.CandleOneDay, start, start.AddDate(0, 0, 15)), 10)
+	assert.Len(t, c.GetMissingRanges(testFigi, candles.CandleOneDay, start, start.AddDate(0, 0, 15)), 1)
+	// All resolutions of the asset are invalidated.
+	assert.Empty(t, c.GetCandles(testFigi, candles.CandleOneWeek, start, start.AddDate(0, 0, 70)))
+}
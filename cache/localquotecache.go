@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package cache
+
+import (
+	"encoding/json"
+	"log"
+	"maystocks/config"
+	"maystocks/stockval"
+	"path/filepath"
+	"time"
+
+	"github.com/ericlagergren/decimal"
+	"github.com/lotodore/localcache"
+)
+
+type localQuoteCache struct {
+	broker stockval.BrokerId
+	data   *localcache.Cache
+}
+
+type cachedQuote struct {
+	Price              *decimal.Big
+	PreviousClosePrice *decimal.Big
+	DeltaPercentage    *decimal.Big
+	Timestamp          time.Time
+}
+
+func NewLocalQuoteCache(broker stockval.BrokerId) QuoteCache {
+	data, err := localcache.New(filepath.Join(config.AppName, string(broker), "quotes"))
+	if err != nil {
+		log.Fatalf("error initializing quote cache: %v", err)
+	}
+	return &localQuoteCache{broker: broker, data: data}
+}
+
+func (c *localQuoteCache) GetQuote(figi string) (stockval.QuoteData, bool) {
+	rawQuote, err := c.data.ReadFile(figi)
+	if err != nil {
+		return stockval.QuoteData{}, false
+	}
+	var q cachedQuote
+	err = json.Unmarshal(rawQuote, &q)
+	if err != nil || q.Price == nil {
+		log.Printf("%s quote cache %s contains invalid data", c.broker, figi)
+		return stockval.QuoteData{}, false
+	}
+	return stockval.QuoteData{
+		CurrentDelayedPrice:   q.Price,
+		CurrentPrice:          q.Price,
+		CurrentPriceTimestamp: q.Timestamp,
+		Type:                  stockval.QuoteTypeDelayed,
+		PreviousClosePrice:    q.PreviousClosePrice,
+		DeltaPercentage:       q.DeltaPercentage,
+		Cached:                true,
+	}, true
+}
+
+func (c *localQuoteCache) StoreQuote(figi string, q stockval.QuoteData) {
+	if q.CurrentPrice == nil {
+		return
+	}
+	rawQuote, err := json.Marshal(cachedQuote{
+		Price:              q.CurrentPrice,
+		PreviousClosePrice: q.PreviousClosePrice,
+		DeltaPercentage:    q.DeltaPercentage,
+		Timestamp:          q.CurrentPriceTimestamp,
+	})
+	if err == nil {
+		err = c.data.WriteFile(figi, rawQuote)
+	}
+	if err != nil {
+		log.Printf("error writing quote cache %s: %v", figi, err)
+	}
+}
@@ -0,0 +1,13 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package cache
+
+import "maystocks/stockval"
+
+// Persistent storage of the last known quote of each asset.
+type QuoteCache interface {
+	// Returns the last known quote, which is marked as cached.
+	GetQuote(figi string) (stockval.QuoteData, bool)
+	StoreQuote(figi string, q stockval.QuoteData)
+}
@@ -4,6 +4,8 @@
 package calendar
 
 import (
+	"maystocks/indapi/candles"
+	"maystocks/stockval"
 	"time"
 
 	"github.com/rickar/cal/v2"
@@ -98,3 +100,48 @@ func (b BankCalendar) GetTradingHours(t time.Time) (trading, partial bool, h Tra
 	h.ExtClose = h.Close.Add(b.extendedHoursAfterClose)
 	return
 }
+
+// Returns whether the exchange is open at time t.
+func (b BankCalendar) IsTradingTime(t time.Time, extendedHours bool) bool {
+	trading, _, h := b.GetTradingHours(t)
+	if !trading {
+		return false
+	}
+	if extendedHours {
+		return !t.Before(h.PreOpen) && t.Before(h.ExtClose)
+	}
+	return !t.Before(h.Open) && t.Before(h.Close)
+}
+
+// Returns the trading session which is used to align candles.
+func (b BankCalendar) GetCandleSession(extendedHours bool) candles.Session {
+	open := time.Duration(b.stdOpenTime.hours)*time.Hour + time.Duration(b.stdOpenTime.minutes)*time.Minute
+	return candles.Session{
+		Location:      b.bankLocation,
+		OpenTime:      open,
+		PreOpenTime:   open - b.extendedHoursBeforeOpen,
+		ExtendedHours: extendedHours,
+	}
+}
+
+// Returns the trading session of the exchange of an asset.
+// Only US exchanges are supported, so the US calendar is used for all assets except crypto.
+func GetCandleSession(asset stockval.AssetData, extendedHours bool) candles.Session {
+	if asset.Class == stockval.AssetClassCrypto {
+		// Crypto is traded around the clock.
+		return candles.NewUtcSession()
+	}
+	return NewUSBankCalendar().GetCandleSession(extendedHours)
+}
+
+// Returns a function which checks whether the exchange of an asset is open.
+// Like GetCandleSession, this only supports US exchanges.
+func GetTradingTimeFunc(asset stockval.AssetData, extendedHours bool) stockval.TradingTimeFunc {
+	if asset.Class == stockval.AssetClassCrypto {
+		return nil // traded around the clock
+	}
+	b := NewUSBankCalendar()
+	return func(t time.Time) bool {
+		return b.IsTradingTime(t, extendedHours)
+	}
+}
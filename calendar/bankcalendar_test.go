@@ -138,3 +138,23 @@ func TestGetTradingHoursPartial(t *testing.T) {
 	assert.True(t, h.PreOpen.Equal(time.Date(2018, 12, 24, 4, 0, 0, 0, c.bankLocation)))
 	assert.True(t, h.ExtClose.Equal(time.Date(2018, 12, 24, 17, 0, 0, 0, c.bankLocation)))
 }
+
+func TestGetCandleSession(t *testing.T) {
+	c := NewUSBankCalendar()
+	s := c.GetCandleSession(false)
+	assert.Equal(t, c.bankLocation, s.Location)
+	assert.Equal(t, time.Hour*9+time.Minute*30, s.OpenTime)
+	assert.Equal(t, time.Hour*4, s.PreOpenTime)
+	assert.False(t, s.ExtendedHours)
+	assert.True(t, s.GetSessionStart(time.Date(2023, 8, 9, 0, 0, 0, 0, c.bankLocation)).Equal(time.Date(2023, 8, 9, 9, 30, 0, 0, c.bankLocation)))
+}
+
+func TestIsTradingTime(t *testing.T) {
+	c := NewUSBankCalendar()
+	assert.True(t, c.IsTradingTime(time.Date(2023, 8, 9, 9, 30, 0, 0, c.bankLocation), false))
+	assert.False(t, c.IsTradingTime(time.Date(2023, 8, 9, 16, 0, 0, 0, c.bankLocation), false))
+	assert.False(t, c.IsTradingTime(time.Date(2023, 8, 9, 5, 0, 0, 0, c.bankLocation), false))
+	assert.True(t, c.IsTradingTime(time.Date(2023, 8, 9, 5, 0, 0, 0, c.bankLocation), true))
+	// Saturday
+	assert.False(t, c.IsTradingTime(time.Date(2023, 8, 12, 10, 0, 0, 0, c.bankLocation), true))
+}
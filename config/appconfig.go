@@ -15,6 +15,7 @@ type AppConfig struct {
 	LightTheme       bool `yaml:",omitempty"`
 	BrokerConfig     map[stockval.BrokerId]BrokerConfig
 	WindowConfig     []WindowConfig
+	CustomIndicators []CustomIndicatorConfig `yaml:",omitempty"`
 }
 
 type BrokerConfig struct {
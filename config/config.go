@@ -11,6 +11,8 @@ type EncryptionSetup interface {
 type Config interface {
 	EncryptionSetup
 	GetAppName() string
+	// Directory of indicator plugins.
+	GetPluginDir() string
 	Lock() (*AppConfig, error)
 	Unlock(c *AppConfig, forceWriting bool) error
 	Copy(forceReading bool) (AppConfig, error)
@@ -22,6 +22,7 @@ import (
 
 const AppName = "maystocks"
 const configFileName = "globalconfig.yaml"
+const pluginDirName = "plugins"
 const configFileVersion = 3
 
 type GlobalConfig struct {
@@ -119,6 +120,10 @@ func (g *GlobalConfig) getAppConfigDir() string {
 	return filepath.Join(userConfigDir, g.GetAppName())
 }
 
+func (g *GlobalConfig) GetPluginDir() string {
+	return filepath.Join(g.getAppConfigDir(), pluginDirName)
+}
+
 func (g *GlobalConfig) createEncryptionKey(salt []byte) ([]byte, error) {
 	g.encryptionPwMutex.Lock()
 	defer g.encryptionPwMutex.Unlock()
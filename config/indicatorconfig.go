@@ -10,6 +10,18 @@ import (
 
 type IndicatorConfig struct {
 	IndicatorId indapi.IndicatorId
-	Properties  map[string]string
-	Colors      []color.NRGBA
+	// Name which is unique within the plot, so that the outputs of the indicator can be used as input.
+	Name       string
+	Properties map[string]string
+	Colors     []color.NRGBA
+	// Output of another indicator which is used as input instead of the candle data, empty if not set.
+	Input indapi.IndicatorInput
+}
+
+// User-defined indicator, which is calculated by an expression, see package indapi/expression.
+type CustomIndicatorConfig struct {
+	Id         indapi.IndicatorId
+	Expression string
+	// Plot the indicator in the price subplot instead of a separate subplot.
+	Overlay bool `yaml:",omitempty"`
 }
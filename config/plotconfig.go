@@ -13,7 +13,11 @@ type PlotConfig struct {
 	Resolution    candles.CandleResolution
 	BrokerId      stockval.BrokerId
 	PlotScalingX  stockval.PlotScaling
+	ChartType     stockval.ChartType `yaml:",omitempty"`
+	BoxSize       float64            `yaml:",omitempty"` // box size of price-based charts, 0 for ATR
 	SubPlotConfig []SubPlotConfig
+	// Assets which are compared to the asset of the plot.
+	CompareAssets []stockval.AssetData `yaml:",omitempty"`
 }
 
 // Returns some valid default plot data. Make sure the broker is available.
@@ -31,6 +35,7 @@ func NewPlotConfig() PlotConfig {
 		},
 		Resolution:    candles.CandleOneDay,
 		BrokerId:      "alpaca",
+		ChartType:     stockval.ChartCandles,
 		SubPlotConfig: NewSubPlotConfig(),
 	}
 }
@@ -38,7 +43,21 @@ func NewPlotConfig() PlotConfig {
 func (p *PlotConfig) sanitize() {
 	// Generate normalized name, this is not stored.
 	p.AssetData.CompanyNameNormalized = stockval.NormalizeAssetName(p.AssetData.CompanyName)
+	for i := range p.CompareAssets {
+		p.CompareAssets[i].CompanyNameNormalized = stockval.NormalizeAssetName(p.CompareAssets[i].CompanyName)
+	}
+	if !p.ChartType.IsValid() {
+		p.ChartType = stockval.ChartCandles
+	}
+	if stockval.IndexOf(stockval.BoxSizeList(), p.BoxSize) < 0 {
+		p.BoxSize = stockval.AtrBoxSize
+	}
 	if len(p.SubPlotConfig) == 0 {
 		p.SubPlotConfig = NewSubPlotConfig()
 	}
+	for i := range p.SubPlotConfig {
+		if !p.SubPlotConfig[i].Scale.IsValid() {
+			p.SubPlotConfig[i].Scale = stockval.ScaleLinear
+		}
+	}
 }
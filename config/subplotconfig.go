@@ -5,10 +5,12 @@ package config
 
 import (
 	"maystocks/indapi"
+	"maystocks/stockval"
 )
 
 type SubPlotConfig struct {
 	Type       indapi.SubPlotType
+	Scale      stockval.ScaleType `yaml:",omitempty"`
 	Indicators []IndicatorConfig
 }
 
@@ -4,6 +4,7 @@ go 1.24.0
 
 require (
 	github.com/inkeliz/giohyperlink v0.0.0-20220903215451-2ac5d54abdce
+	github.com/tetratelabs/wazero v1.10.1
 	golang.org/x/exp v0.0.0-20251009144603-d2f985daa21b
 	golang.org/x/image v0.32.0
 )
@@ -25,7 +26,6 @@ require (
 	gioui.org v0.9.0
 	gioui.org/x v0.9.0
 	github.com/barkimedes/go-deepcopy v0.0.0-20220514131651-17c30cfc62df
-	github.com/cinar/indicator v1.3.0
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/ericlagergren/decimal v0.0.0-20240411145413-00de7ca16731
 	github.com/google/go-cmp v0.7.0
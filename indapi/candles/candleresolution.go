@@ -4,6 +4,7 @@
 package candles
 
 import (
+	"sort"
 	"strconv"
 	"time"
 )
@@ -19,192 +20,353 @@ const (
 	CandleOneDay
 	CandleOneWeek
 	CandleOneMonth
+	// New resolutions are appended, because resolutions are stored as numbers in the configuration.
+	CandleOneSecond
+	CandleFiveSeconds
+	CandleFifteenSeconds
+	CandleTwoHours
+	CandleFourHours
+	CandleOneQuarter
+	CandleOneYear
 )
 
-const NumCandleResolutions = CandleOneMonth + 1
+const NumCandleResolutions = CandleOneYear + 1
+
+type candleUnit int
+
+const (
+	unitSecond candleUnit = iota
+	unitMinute
+	unitDay
+	unitWeek
+	unitMonth
+)
+
+// Describes a candle resolution as a multiple of a calendar unit.
+type resolutionInfo struct {
+	unit     candleUnit
+	count    int
+	uiString string
+}
+
+// Adding a resolution only requires an entry in this table.
+var resolutionInfoList = [NumCandleResolutions]resolutionInfo{
+	CandleOneSecond:      {unitSecond, 1, "1 sec"},
+	CandleFiveSeconds:    {unitSecond, 5, "5 sec"},
+	CandleFifteenSeconds: {unitSecond, 15, "15 sec"},
+	CandleOneMinute:      {unitMinute, 1, "1 min"},
+	CandleFiveMinutes:    {unitMinute, 5, "5 min"},
+	CandleFifteenMinutes: {unitMinute, 15, "15 min"},
+	CandleThirtyMinutes:  {unitMinute, 30, "30 min"},
+	CandleSixtyMinutes:   {unitMinute, 60, "60 min"},
+	CandleTwoHours:       {unitMinute, 120, "2 hours"},
+	CandleFourHours:      {unitMinute, 240, "4 hours"},
+	CandleOneDay:         {unitDay, 1, "1 day"},
+	CandleOneWeek:        {unitWeek, 1, "1 week"},
+	CandleOneMonth:       {unitMonth, 1, "1 month"},
+	CandleOneQuarter:     {unitMonth, 3, "1 quarter"},
+	CandleOneYear:        {unitMonth, 12, "1 year"},
+}
 
 func CandleResolutionFromString(s string) CandleResolution {
 	// Ignore error and return 0 if invalid.
 	r, _ := strconv.ParseInt(s, 10, 32)
+	if !CandleResolution(r).IsValid() {
+		return 0
+	}
 	return CandleResolution(r)
 }
 
+// Returns all candle resolutions, ordered by duration.
+func CandleResolutionList() []CandleResolution {
+	list := make([]CandleResolution, NumCandleResolutions)
+	for i := range list {
+		list[i] = CandleResolution(i)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Less(list[j])
+	})
+	return list
+}
+
+// Returns the ui strings of all candle resolutions, in the order of CandleResolutionList.
 func CandleResolutionUiStringList() []string {
-	return []string{
-		"1 min",
-		"5 min",
-		"15 min",
-		"30 min",
-		"60 min",
-		"1 day",
-		"1 week",
-		"1 month",
+	list := CandleResolutionList()
+	uiStrings := make([]string, len(list))
+	for i, r := range list {
+		uiStrings[i] = r.UiString()
+	}
+	return uiStrings
+}
+
+func (r CandleResolution) IsValid() bool {
+	return r >= 0 && r < NumCandleResolutions
+}
+
+func (r CandleResolution) info() resolutionInfo {
+	if !r.IsValid() {
+		panic("unsupported candle resolution")
+	}
+	return resolutionInfoList[r]
+}
+
+func (r CandleResolution) UiString() string {
+	return r.info().uiString
+}
+
+// Returns whether candles of resolution r are shorter than candles of resolution o.
+func (r CandleResolution) Less(o CandleResolution) bool {
+	ri, oi := r.info(), o.info()
+	if ri.isIntraday() != oi.isIntraday() {
+		return ri.isIntraday()
+	}
+	if ri.isIntraday() {
+		return ri.getIntradayDuration() < oi.getIntradayDuration()
+	}
+	return ri.approxDays() < oi.approxDays()
+}
+
+// Returns whether the candle duration is fixed, i.e. does not depend on the calendar.
+func (r CandleResolution) IsIntraday() bool {
+	return r.info().isIntraday()
+}
+
+// Returns whether candles of resolution r can be built by combining consecutive candles of resolution b.
+// Intraday candles are combined only from intraday candles, so that the amount of data stays reasonable.
+func (r CandleResolution) IsMultipleOf(b CandleResolution) bool {
+	ri, bi := r.info(), b.info()
+	if ri.isIntraday() {
+		// Intraday candles are aligned to the session start, so multiples share their boundaries.
+		return bi.isIntraday() && ri.getIntradayDuration()%bi.getIntradayDuration() == 0
+	}
+	switch bi.unit {
+	case unitDay:
+		return bi.count == 1
+	case unitWeek:
+		return ri.unit == unitWeek && ri.count%bi.count == 0
+	case unitMonth:
+		return ri.unit == unitMonth && ri.count%bi.count == 0
+	default:
+		return false
+	}
+}
+
+// Returns the coarsest of the available resolutions which can be used to build candles of resolution r.
+// Returns false if candles of resolution r cannot be built from any of the available resolutions.
+func (r CandleResolution) FindBaseResolution(available []CandleResolution) (CandleResolution, bool) {
+	base, found := CandleResolution(0), false
+	for _, b := range available {
+		if r.IsMultipleOf(b) && (!found || base.Less(b)) {
+			base, found = b, true
+		}
 	}
+	return base, found
 }
 
 func (r CandleResolution) FormatString() string {
-	switch r {
-	case CandleOneMinute:
+	switch r.info().unit {
+	case unitSecond:
+		return "15:04:05"
+	case unitMinute:
 		return "15:04"
-	case CandleFiveMinutes:
-		return "15:04"
-	case CandleFifteenMinutes:
-		return "15:04"
-	case CandleThirtyMinutes:
-		return "15:04"
-	case CandleSixtyMinutes:
-		return "15:04"
-	case CandleOneDay:
-		return "02 Jan 06"
-	case CandleOneWeek:
-		return "02 Jan 06"
-	case CandleOneMonth:
-		return "02 Jan 06"
 	default:
-		panic("unsupported candle resolution")
+		return "02 Jan 06"
 	}
 }
 
-func (r CandleResolution) GetDuration(context time.Time) time.Duration {
-	switch r {
-	case CandleOneMinute:
-		return time.Minute
-	case CandleFiveMinutes:
-		return time.Minute * 5
-	case CandleFifteenMinutes:
-		return time.Minute * 15
-	case CandleThirtyMinutes:
-		return time.Minute * 30
-	case CandleSixtyMinutes:
-		return time.Hour
-	case CandleOneDay:
-		return getDayDuration(context)
-	case CandleOneWeek:
-		d, _ := getWeekDuration(context)
-		return d
-	case CandleOneMonth:
-		d, _ := getMonthDuration(context)
-		return d
-	default:
-		panic("unsupported candle resolution")
+// Returns the duration of the candle containing context.
+// Durations of day-based candles may vary due to daylight saving time and different month lengths.
+func (r CandleResolution) GetDuration(context time.Time, s Session) time.Duration {
+	info := r.info()
+	if info.isIntraday() {
+		return info.getIntradayDuration()
 	}
+	return r.GetNthCandleTime(context, 1, s).Sub(r.getRecentCandleStartTime(context, s))
 }
 
-func (r CandleResolution) GetDeltaCandleCount(candleTime time.Time, tradeTime time.Time) int {
-	unitCount := -1
+// Returns the number of candles from the candle starting at candleTime to the candle containing tradeTime,
+// or -1 if the trade belongs to an earlier candle.
+// The trade is assigned to a candle using the same rules as GetNthCandleTime, e.g. pre-market trades
+// belong to the daily candle of the same trading day.
+func (r CandleResolution) GetDeltaCandleCount(candleTime time.Time, tradeTime time.Time, s Session) int {
+	tradeCandleTime := r.GetNthCandleTime(tradeTime, 0, s)
+	if tradeCandleTime.Before(candleTime) {
+		return -1
+	}
+	unitCount := 0
 	// Each duration may be different, therefore we loop.
-	// Check whether trade is within or after the current candle.
-	// TcandleStart <= Ttrade < TnextCandleStart, so candleTime==tradeTime means current candle.
-	for candleTime.Before(tradeTime) {
+	for candleTime.Before(tradeCandleTime) {
 		unitCount++
-		candleTime = candleTime.Add(r.GetDuration(candleTime))
+		candleTime = r.GetNthCandleTime(candleTime, 1, s)
 	}
 	return unitCount
 }
 
-func (r CandleResolution) GetNthCandleTime(t time.Time, n int) time.Time {
+// Returns the start time of the nth candle relative to the candle containing t.
+// Candles are aligned to the start of the trading session s.
+func (r CandleResolution) GetNthCandleTime(t time.Time, n int, s Session) time.Time {
 	// Get 0th candle time first, so that n = 0 works.
-	t = r.getRecentCandleStartTime(t)
-	if n < 0 {
-		for i := 0; i > n; i-- {
-			// Go one second back to the previous interval to get the correct duration.
-			t = t.Add(-r.GetDuration(t.Add(-time.Second)))
-		}
+	t = r.getRecentCandleStartTime(t, s)
+	info := r.info()
+	y, m, d := s.getDate(t)
+	switch info.unit {
+	case unitDay:
+		return s.getStartTime(y, m, d+n*info.count)
+	case unitWeek:
+		return s.getStartTime(y, m, d+n*info.count*7)
+	case unitMonth:
+		return s.getStartTime(y, m+time.Month(n*info.count), d)
+	default:
+		// Realign, because the session start may have been shifted by daylight saving time.
+		return r.getRecentCandleStartTime(t.Add(time.Duration(n)*info.getIntradayDuration()), s)
+	}
+}
+
+// Returns the start time of the candle containing t, for brokers which stamp day-based candles
+// with midnight UTC of their trading date. Converting these to the exchange time zone would yield
+// the previous day, so the UTC date is used instead. Intraday candles are handled like GetNthCandleTime.
+func (r CandleResolution) GetCandleTimeOfUtcDate(t time.Time, s Session) time.Time {
+	if !r.info().isIntraday() {
+		t = s.getStartTime(t.UTC().Date())
 	}
+	return r.GetNthCandleTime(t, 0, s)
+}
+
+// Returns the start time of the nth candle after the candle containing t, n >= 0.
+// In contrast to GetNthCandleTime, candles which start while the exchange is closed are skipped,
+// e.g. weekends and holidays. A nil isTradingTime function means that the asset is traded around the clock.
+// Weekly and monthly candles are never skipped.
+func (r CandleResolution) GetNthTradingCandleTime(t time.Time, n int, s Session, isTradingTime func(t time.Time) bool) time.Time {
+	t = r.GetNthCandleTime(t, 0, s)
+	info := r.info()
+	if isTradingTime == nil || info.unit == unitWeek || info.unit == unitMonth {
+		return r.GetNthCandleTime(t, n, s)
+	}
+	// Limit the number of days which are skipped, in case the exchange is closed for a long time.
+	const maxClosedDays = 30
 	for i := 0; i < n; i++ {
-		t = t.Add(r.GetDuration(t))
+		t = r.GetNthCandleTime(t, 1, s)
+		for closedDays := 0; !isTradingTime(t) && closedDays < maxClosedDays; {
+			if info.isIntraday() {
+				// Skip to the start of the next session instead of iterating all candles while the exchange is closed.
+				next := s.GetSessionStart(t)
+				if !next.After(t) {
+					next = CandleOneDay.GetNthCandleTime(t, 1, s)
+					closedDays++
+				}
+				t = r.GetNthCandleTime(next, 0, s)
+			} else {
+				t = r.GetNthCandleTime(t, 1, s)
+				closedDays++
+			}
+		}
 	}
 	return t
 }
 
 func (r CandleResolution) ConvertTimeToCandleUnits(t time.Time) float64 {
-	switch r {
-	case CandleOneMinute:
-		return float64(t.UnixMilli()) / 60000
-	case CandleFiveMinutes:
-		return float64(t.UnixMilli()) / (60000 * 5)
-	case CandleFifteenMinutes:
-		return float64(t.UnixMilli()) / (60000 * 15)
-	case CandleThirtyMinutes:
-		return float64(t.UnixMilli()) / (60000 * 30)
-	case CandleSixtyMinutes:
-		return float64(t.UnixMilli()) / (60000 * 60)
-	case CandleOneDay:
-		return float64(t.Unix()) / (60 * 60 * 24)
-	case CandleOneWeek:
+	info := r.info()
+	switch info.unit {
+	case unitDay:
+		return float64(t.Unix()) / (60 * 60 * 24 * float64(info.count))
+	case unitWeek:
 		// Jan 1 1970 was a Thursday, we need our weeks to start on Monday.
 		// So in this case, we adjust the start and use Monday Jan 5 1970.
 		s := time.Date(1970, 1, 5, 0, 0, 0, 0, time.UTC)
 		numWeeks := int(t.Sub(s).Hours()) / (24 * 7)
 		d, firstDay := getWeekDuration(t)
-		return float64(numWeeks) + (t.Sub(firstDay).Seconds() / d.Seconds())
-	case CandleOneMonth:
+		return (float64(numWeeks) + (t.Sub(firstDay).Seconds() / d.Seconds())) / float64(info.count)
+	case unitMonth:
 		y, m, _ := t.Date()
 		numMonths := (y-1970)*12 + (int(m) - 1)
 		d, firstDay := getMonthDuration(t)
-		return float64(numMonths) + (t.Sub(firstDay).Seconds() / d.Seconds())
+		return (float64(numMonths) + (t.Sub(firstDay).Seconds() / d.Seconds())) / float64(info.count)
 	default:
-		panic("unsupported candle resolution")
+		return float64(t.UnixMilli()) / float64(info.getIntradayDuration().Milliseconds())
 	}
 }
 
 func (r CandleResolution) ConvertCandleUnitsToTime(u float64) time.Time {
-	switch r {
-	case CandleOneMinute:
-		return time.UnixMilli(int64(u * 60000))
-	case CandleFiveMinutes:
-		return time.UnixMilli(int64(u * 60000 * 5))
-	case CandleFifteenMinutes:
-		return time.UnixMilli(int64(u * 60000 * 15))
-	case CandleThirtyMinutes:
-		return time.UnixMilli(int64(u * 60000 * 30))
-	case CandleSixtyMinutes:
-		return time.UnixMilli(int64(u * 60000 * 60))
-	case CandleOneDay:
-		return time.Unix(int64(u*60*60*24), 0)
-	case CandleOneWeek:
+	info := r.info()
+	switch info.unit {
+	case unitDay:
+		return time.Unix(int64(u*60*60*24*float64(info.count)), 0)
+	case unitWeek:
 		// Jan 1 1970 was a Thursday, we need our weeks to start on Monday.
 		// So in this case, we adjust the start and use Monday Jan 5 1970.
+		u *= float64(info.count)
 		firstDay := time.Date(1970, 1, 5+(int(u)*7), 0, 0, 0, 0, time.UTC)
 		d, _ := getWeekDuration(firstDay)
 		return firstDay.Add(time.Duration((u - float64(int(u))) * float64(d))).Local()
-	case CandleOneMonth:
+	case unitMonth:
+		u *= float64(info.count)
 		firstDay := time.Date(1970, time.Month(1+int(u)), 1, 0, 0, 0, 0, time.UTC)
 		d, _ := getMonthDuration(firstDay)
 		return firstDay.Add(time.Duration((u - float64(int(u))) * float64(d))).Local()
+	default:
+		return time.UnixMilli(int64(u * float64(info.getIntradayDuration().Milliseconds())))
+	}
+}
+
+func (info resolutionInfo) isIntraday() bool {
+	return info.unit == unitSecond || info.unit == unitMinute
+}
+
+func (info resolutionInfo) getIntradayDuration() time.Duration {
+	switch info.unit {
+	case unitSecond:
+		return time.Second * time.Duration(info.count)
+	case unitMinute:
+		return time.Minute * time.Duration(info.count)
 	default:
 		panic("unsupported candle resolution")
 	}
 }
 
-func (r CandleResolution) getRecentCandleStartTime(t time.Time) time.Time {
-	switch r {
-	case CandleOneMinute:
-		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
-	case CandleFiveMinutes:
-		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()/5*5, 0, 0, t.Location())
-	case CandleFifteenMinutes:
-		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()/15*15, 0, 0, t.Location())
-	case CandleThirtyMinutes:
-		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()/30*30, 0, 0, t.Location())
-	case CandleSixtyMinutes:
-		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
-	case CandleOneDay:
-		// We use UTC start of day as normalised start of day-based candles.
-		// The broker may use timestamps of closing time, which may even be non-constant.
-		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
-	case CandleOneWeek:
+// Returns the approximate number of days per candle, which is used for ordering only.
+func (info resolutionInfo) approxDays() int {
+	switch info.unit {
+	case unitWeek:
+		return info.count * 7
+	case unitMonth:
+		return info.count * 30
+	default:
+		return info.count
+	}
+}
+
+func (r CandleResolution) getRecentCandleStartTime(t time.Time, s Session) time.Time {
+	info := r.info()
+	y, m, d := s.getDate(t)
+	switch info.unit {
+	case unitDay:
+		// Day-based candles start at the beginning of the trading session.
+		// The broker may use timestamps of closing time, or midnight of the exchange time zone.
+		// Both are normalized using the date of the exchange time zone.
+		return s.getStartTime(y, m, d)
+	case unitWeek:
 		// Candlestick weeks start on Mondays. Golang Weeks start on Sundays.
 		// We need to adjust the difference.
-		weekdayDiff := int(t.Weekday()) - int(time.Monday)
+		weekdayDiff := int(t.In(s.location()).Weekday()) - int(time.Monday)
 		if weekdayDiff < 0 {
 			weekdayDiff = 7 + weekdayDiff
 		}
-		return time.Date(t.Year(), t.Month(), t.Day()-weekdayDiff, 0, 0, 0, 0, time.UTC)
-	case CandleOneMonth:
-		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return s.getStartTime(y, m, d-weekdayDiff)
+	case unitMonth:
+		// Quarters and years start in the first month of the respective period.
+		m -= time.Month((int(m) - 1) % info.count)
+		return s.getStartTime(y, m, 1)
 	default:
-		panic("unsupported candle resolution")
+		// Intraday candles are aligned to the session start, e.g. 60 minute candles
+		// start at 9:30 during regular trading hours at NYSE.
+		// Times before the session start are aligned backwards.
+		sessionStart := s.getStartTime(y, m, d)
+		duration := info.getIntradayDuration()
+		n := t.Sub(sessionStart) / duration
+		if t.Before(sessionStart.Add(n * duration)) {
+			n--
+		}
+		return sessionStart.Add(n * duration)
 	}
 }
 
@@ -16,11 +16,18 @@ func _() {
 	_ = x[CandleOneDay-5]
 	_ = x[CandleOneWeek-6]
 	_ = x[CandleOneMonth-7]
+	_ = x[CandleOneSecond-8]
+	_ = x[CandleFiveSeconds-9]
+	_ = x[CandleFifteenSeconds-10]
+	_ = x[CandleTwoHours-11]
+	_ = x[CandleFourHours-12]
+	_ = x[CandleOneQuarter-13]
+	_ = x[CandleOneYear-14]
 }
 
-const _CandleResolution_name = "CandleOneMinuteCandleFiveMinutesCandleFifteenMinutesCandleThirtyMinutesCandleSixtyMinutesCandleOneDayCandleOneWeekCandleOneMonth"
+const _CandleResolution_name = "CandleOneMinuteCandleFiveMinutesCandleFifteenMinutesCandleThirtyMinutesCandleSixtyMinutesCandleOneDayCandleOneWeekCandleOneMonthCandleOneSecondCandleFiveSecondsCandleFifteenSecondsCandleTwoHoursCandleFourHoursCandleOneQuarterCandleOneYear"
 
-var _CandleResolution_index = [...]uint8{0, 15, 32, 52, 71, 89, 101, 114, 128}
+var _CandleResolution_index = [...]uint8{0, 15, 32, 52, 71, 89, 101, 114, 128, 143, 160, 180, 194, 209, 225, 238}
 
 func (i CandleResolution) String() string {
 	idx := int(i) - 0
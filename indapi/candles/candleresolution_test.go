@@ -47,27 +47,27 @@ func TestGetDayDuration(t *testing.T) {
 func TestGetNthCandleTime(t *testing.T) {
 	r := CandleOneMonth
 	d := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
-	n := r.GetNthCandleTime(d, 1)
+	n := r.GetNthCandleTime(d, 1, NewUtcSession())
 	assert.True(t, n.Equal(time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)))
-	n = r.GetNthCandleTime(d, 12)
+	n = r.GetNthCandleTime(d, 12, NewUtcSession())
 	assert.True(t, n.Equal(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)))
-	n = r.GetNthCandleTime(d, -1)
+	n = r.GetNthCandleTime(d, -1, NewUtcSession())
 	assert.True(t, n.Equal(time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)))
-	n = r.GetNthCandleTime(d, -12)
+	n = r.GetNthCandleTime(d, -12, NewUtcSession())
 	assert.True(t, n.Equal(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)))
 }
 
 func TestGetZerothCandleTime(t *testing.T) {
 	r := CandleOneMonth
 	d := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
-	n := r.GetNthCandleTime(d, 0)
+	n := r.GetNthCandleTime(d, 0, NewUtcSession())
 	assert.True(t, n.Equal(d))
 	r = CandleOneWeek
 	d = time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
-	n = r.GetNthCandleTime(d, 0)
+	n = r.GetNthCandleTime(d, 0, NewUtcSession())
 	assert.True(t, n.Equal(d))
 	d2 := time.Date(2022, 1, 5, 0, 0, 0, 0, time.UTC)
-	n = r.GetNthCandleTime(d2, 0)
+	n = r.GetNthCandleTime(d2, 0, NewUtcSession())
 	assert.True(t, n.Equal(d))
 }
 
@@ -108,3 +108,231 @@ func TestConvertCandleUnitsToTimeMonth(t *testing.T) {
 	n = r.ConvertCandleUnitsToTime(626.5)
 	assert.True(t, n.Equal(time.Date(2022, 3, 16, 12, 0, 0, 0, time.UTC)))
 }
+
+func newTestNyseSession(t *testing.T, extendedHours bool) Session {
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+	return Session{
+		Location:      loc,
+		OpenTime:      time.Hour*9 + time.Minute*30,
+		PreOpenTime:   time.Hour * 4,
+		ExtendedHours: extendedHours,
+	}
+}
+
+func TestGetZerothCandleTimeSessionDay(t *testing.T) {
+	s := newTestNyseSession(t, false)
+	r := CandleOneDay
+	// Alpaca uses midnight in exchange time zone as daily candle timestamp.
+	d := time.Date(2023, 8, 9, 4, 0, 0, 0, time.UTC)
+	n := r.GetNthCandleTime(d, 0, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 9, 30, 0, 0, s.Location)))
+	// Closing time belongs to the same candle.
+	d = time.Date(2023, 8, 9, 16, 0, 0, 0, s.Location)
+	n = r.GetNthCandleTime(d, 0, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 9, 30, 0, 0, s.Location)))
+	// Still the same day in New York, but already the next day in UTC.
+	d = time.Date(2023, 8, 10, 1, 0, 0, 0, time.UTC)
+	n = r.GetNthCandleTime(d, 0, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 9, 30, 0, 0, s.Location)))
+}
+
+func TestGetCandleTimeOfUtcDate(t *testing.T) {
+	s := newTestNyseSession(t, false)
+	// Finnhub uses midnight UTC as daily candle timestamp, which is the previous day in New York.
+	d := time.Date(2023, 8, 9, 0, 0, 0, 0, time.UTC)
+	n := CandleOneDay.GetCandleTimeOfUtcDate(d, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 9, 30, 0, 0, s.Location)))
+	n = CandleOneWeek.GetCandleTimeOfUtcDate(d, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 7, 9, 30, 0, 0, s.Location)))
+	n = CandleOneDay.GetCandleTimeOfUtcDate(d, newTestNyseSession(t, true))
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 4, 0, 0, 0, s.Location)))
+	// Intraday candles are not affected.
+	n = CandleSixtyMinutes.GetCandleTimeOfUtcDate(d, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 8, 19, 30, 0, 0, s.Location)))
+}
+
+func TestGetNthCandleTimeSessionDayDst(t *testing.T) {
+	s := newTestNyseSession(t, false)
+	r := CandleOneDay
+	// Daylight saving time starts on March 12 2023 in New York.
+	d := time.Date(2023, 3, 10, 12, 0, 0, 0, s.Location)
+	n := r.GetNthCandleTime(d, 3, s)
+	assert.True(t, n.Equal(time.Date(2023, 3, 13, 9, 30, 0, 0, s.Location)))
+	n = r.GetNthCandleTime(n, -3, s)
+	assert.True(t, n.Equal(time.Date(2023, 3, 10, 9, 30, 0, 0, s.Location)))
+	assert.Equal(t, float64(23), r.GetDuration(time.Date(2023, 3, 11, 12, 0, 0, 0, s.Location), s).Hours())
+}
+
+func TestGetZerothCandleTimeSessionWeek(t *testing.T) {
+	s := newTestNyseSession(t, false)
+	r := CandleOneWeek
+	d := time.Date(2023, 8, 11, 15, 0, 0, 0, s.Location)
+	n := r.GetNthCandleTime(d, 0, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 7, 9, 30, 0, 0, s.Location)))
+	n = r.GetNthCandleTime(d, 1, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 14, 9, 30, 0, 0, s.Location)))
+}
+
+func TestGetZerothCandleTimeSessionIntraday(t *testing.T) {
+	s := newTestNyseSession(t, false)
+	r := CandleSixtyMinutes
+	d := time.Date(2023, 8, 9, 10, 15, 0, 0, s.Location)
+	n := r.GetNthCandleTime(d, 0, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 9, 30, 0, 0, s.Location)))
+	n = r.GetNthCandleTime(d, 1, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 10, 30, 0, 0, s.Location)))
+	// Pre-market times are aligned backwards from the session start.
+	d = time.Date(2023, 8, 9, 9, 0, 0, 0, s.Location)
+	n = r.GetNthCandleTime(d, 0, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 8, 30, 0, 0, s.Location)))
+
+	// Using extended hours, the session starts at 4:00.
+	s = newTestNyseSession(t, true)
+	d = time.Date(2023, 8, 9, 10, 15, 0, 0, s.Location)
+	n = r.GetNthCandleTime(d, 0, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 10, 0, 0, 0, s.Location)))
+	n = r.GetNthCandleTime(d, -1, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 9, 0, 0, 0, s.Location)))
+}
+
+func TestGetDeltaCandleCountSession(t *testing.T) {
+	s := newTestNyseSession(t, false)
+	r := CandleSixtyMinutes
+	c := time.Date(2023, 8, 9, 9, 30, 0, 0, s.Location)
+	assert.Equal(t, 0, r.GetDeltaCandleCount(c, time.Date(2023, 8, 9, 10, 29, 0, 0, s.Location), s))
+	assert.Equal(t, 1, r.GetDeltaCandleCount(c, time.Date(2023, 8, 9, 10, 31, 0, 0, s.Location), s))
+	r = CandleOneDay
+	assert.Equal(t, 1, r.GetDeltaCandleCount(c, time.Date(2023, 8, 10, 9, 31, 0, 0, s.Location), s))
+	// A pre-market trade belongs to the candle of the same trading day, like in GetNthCandleTime.
+	trade := time.Date(2023, 8, 10, 8, 0, 0, 0, s.Location)
+	assert.Equal(t, 1, r.GetDeltaCandleCount(c, trade, s))
+	assert.True(t, r.GetNthCandleTime(c, 1, s).Equal(r.GetNthCandleTime(trade, 0, s)))
+	// The start of the candle belongs to the candle.
+	assert.Equal(t, 0, r.GetDeltaCandleCount(c, c, s))
+	assert.Equal(t, -1, r.GetDeltaCandleCount(c, time.Date(2023, 8, 8, 12, 0, 0, 0, s.Location), s))
+}
+
+func TestCandleResolutionList(t *testing.T) {
+	list := CandleResolutionList()
+	assert.Len(t, list, int(NumCandleResolutions))
+	assert.Equal(t, CandleOneSecond, list[0])
+	assert.Equal(t, CandleOneYear, list[len(list)-1])
+	for i := 1; i < len(list); i++ {
+		assert.True(t, list[i-1].Less(list[i]))
+	}
+	uiStrings := CandleResolutionUiStringList()
+	assert.Equal(t, "1 sec", uiStrings[0])
+	assert.Equal(t, "1 year", uiStrings[len(uiStrings)-1])
+	// Invalid values fall back to CandleOneMinute (value 0).
+	assert.Equal(t, CandleOneMinute, CandleResolutionFromString("99"))
+	assert.Equal(t, CandleOneQuarter, CandleResolutionFromString("13"))
+}
+
+func TestGetZerothCandleTimeSessionFourHours(t *testing.T) {
+	s := newTestNyseSession(t, false)
+	r := CandleFourHours
+	d := time.Date(2023, 8, 9, 14, 0, 0, 0, s.Location)
+	n := r.GetNthCandleTime(d, 0, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 13, 30, 0, 0, s.Location)))
+	n = r.GetNthCandleTime(d, -1, s)
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 9, 30, 0, 0, s.Location)))
+	assert.Equal(t, float64(4), r.GetDuration(d, s).Hours())
+}
+
+func TestGetNthCandleTimeSeconds(t *testing.T) {
+	r := CandleFifteenSeconds
+	d := time.Date(2023, 8, 9, 14, 0, 20, 0, time.UTC)
+	n := r.GetNthCandleTime(d, 0, NewUtcSession())
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 14, 0, 15, 0, time.UTC)))
+	n = r.GetNthCandleTime(d, 3, NewUtcSession())
+	assert.True(t, n.Equal(time.Date(2023, 8, 9, 14, 1, 0, 0, time.UTC)))
+	assert.Equal(t, "15:04:05", r.FormatString())
+}
+
+func TestGetNthCandleTimeQuarterYear(t *testing.T) {
+	s := newTestNyseSession(t, false)
+	r := CandleOneQuarter
+	d := time.Date(2023, 8, 9, 12, 0, 0, 0, s.Location)
+	n := r.GetNthCandleTime(d, 0, s)
+	assert.True(t, n.Equal(time.Date(2023, 7, 1, 9, 30, 0, 0, s.Location)))
+	n = r.GetNthCandleTime(d, 2, s)
+	assert.True(t, n.Equal(time.Date(2024, 1, 1, 9, 30, 0, 0, s.Location)))
+	r = CandleOneYear
+	n = r.GetNthCandleTime(d, 0, s)
+	assert.True(t, n.Equal(time.Date(2023, 1, 1, 9, 30, 0, 0, s.Location)))
+	n = r.GetNthCandleTime(d, -1, s)
+	assert.True(t, n.Equal(time.Date(2022, 1, 1, 9, 30, 0, 0, s.Location)))
+}
+
+func TestConvertCandleUnitsQuarter(t *testing.T) {
+	r := CandleOneQuarter
+	d := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, float64(208), r.ConvertTimeToCandleUnits(d))
+	n := r.ConvertCandleUnitsToTime(208)
+	assert.True(t, n.Equal(d))
+	n = r.ConvertCandleUnitsToTime(209)
+	assert.True(t, n.Equal(time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestFindBaseResolution(t *testing.T) {
+	available := []CandleResolution{
+		CandleOneMinute, CandleFiveMinutes, CandleFifteenMinutes, CandleThirtyMinutes,
+		CandleSixtyMinutes, CandleOneDay, CandleOneWeek, CandleOneMonth,
+	}
+	b, ok := CandleFourHours.FindBaseResolution(available)
+	assert.True(t, ok)
+	assert.Equal(t, CandleSixtyMinutes, b)
+	b, ok = CandleOneYear.FindBaseResolution(available)
+	assert.True(t, ok)
+	assert.Equal(t, CandleOneMonth, b)
+	b, ok = CandleOneDay.FindBaseResolution(available)
+	assert.True(t, ok)
+	assert.Equal(t, CandleOneDay, b)
+	// Weeks cannot be combined to months.
+	assert.False(t, CandleOneMonth.IsMultipleOf(CandleOneWeek))
+	// Seconds are built from realtime trades only.
+	_, ok = CandleFiveSeconds.FindBaseResolution(available)
+	assert.False(t, ok)
+}
+
+// Trading time of NYSE without holidays, regular hours only.
+func testNyseTradingTime(t time.Time) bool {
+	loc, _ := time.LoadLocation("America/New_York")
+	t = t.In(loc)
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	minutes := t.Hour()*60 + t.Minute()
+	return minutes >= 9*60+30 && minutes < 16*60
+}
+
+func TestGetNthTradingCandleTimeDay(t *testing.T) {
+	s := newTestNyseSession(t, false)
+	r := CandleOneDay
+	// Friday
+	d := time.Date(2023, 8, 11, 9, 30, 0, 0, s.Location)
+	n := r.GetNthTradingCandleTime(d, 0, s, testNyseTradingTime)
+	assert.True(t, n.Equal(d))
+	n = r.GetNthTradingCandleTime(d, 1, s, testNyseTradingTime)
+	assert.True(t, n.Equal(time.Date(2023, 8, 14, 9, 30, 0, 0, s.Location)))
+	n = r.GetNthTradingCandleTime(d, 6, s, testNyseTradingTime)
+	assert.True(t, n.Equal(time.Date(2023, 8, 21, 9, 30, 0, 0, s.Location)))
+	// Around the clock.
+	n = r.GetNthTradingCandleTime(d, 1, s, nil)
+	assert.True(t, n.Equal(time.Date(2023, 8, 12, 9, 30, 0, 0, s.Location)))
+}
+
+func TestGetNthTradingCandleTimeIntraday(t *testing.T) {
+	s := newTestNyseSession(t, false)
+	r := CandleSixtyMinutes
+	// Last candle on Friday
+	d := time.Date(2023, 8, 11, 15, 30, 0, 0, s.Location)
+	n := r.GetNthTradingCandleTime(d, 1, s, testNyseTradingTime)
+	assert.True(t, n.Equal(time.Date(2023, 8, 14, 9, 30, 0, 0, s.Location)))
+	n = r.GetNthTradingCandleTime(d, 3, s, testNyseTradingTime)
+	assert.True(t, n.Equal(time.Date(2023, 8, 14, 11, 30, 0, 0, s.Location)))
+	// Pre-market candle is skipped to the regular open.
+	n = CandleOneMinute.GetNthTradingCandleTime(time.Date(2023, 8, 14, 8, 0, 0, 0, s.Location), 1, s, testNyseTradingTime)
+	assert.True(t, n.Equal(time.Date(2023, 8, 14, 9, 30, 0, 0, s.Location)))
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package candles
+
+import "time"
+
+// Describes the daily trading session of an exchange.
+// Candles are aligned to the start of the session, using the time zone of the exchange.
+type Session struct {
+	Location *time.Location
+	// Time of day of the regular market open, e.g. 9:30 for NYSE.
+	OpenTime time.Duration
+	// Time of day of the extended hours market open, e.g. 4:00 for NYSE.
+	PreOpenTime time.Duration
+	// Align intraday candles to the extended hours market open instead of the regular market open.
+	ExtendedHours bool
+}
+
+// Returns a session which starts at midnight UTC.
+// This is used for assets which are traded around the clock, e.g. crypto.
+func NewUtcSession() Session {
+	return Session{Location: time.UTC}
+}
+
+func (s Session) location() *time.Location {
+	if s.Location == nil {
+		return time.UTC
+	}
+	return s.Location
+}
+
+// Returns the time of day at which candles start.
+func (s Session) startTimeOfDay() time.Duration {
+	if s.ExtendedHours {
+		return s.PreOpenTime
+	}
+	return s.OpenTime
+}
+
+// Returns the start of the session for the given date in the exchange time zone.
+// Date values are normalized, i.e. day 32 of a month is the first day of the next month.
+func (s Session) getStartTime(y int, m time.Month, d int) time.Time {
+	// Use hours and minutes instead of adding a duration to midnight,
+	// so that daylight saving time changes during the night are handled properly.
+	start := s.startTimeOfDay()
+	return time.Date(y, m, d, int(start/time.Hour), int((start%time.Hour)/time.Minute), 0, 0, s.location())
+}
+
+// Returns the date of t in the exchange time zone.
+func (s Session) getDate(t time.Time) (int, time.Month, int) {
+	return t.In(s.location()).Date()
+}
+
+// Returns the session start of the trading day of t.
+// Times before the session start belong to the same trading day (e.g. pre-market trades).
+func (s Session) GetSessionStart(t time.Time) time.Time {
+	return s.getStartTime(s.getDate(t))
+}
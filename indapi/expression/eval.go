@@ -0,0 +1,279 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package expression
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/series"
+	"slices"
+)
+
+// Price series which can be used by name.
+var sources = map[string]struct{}{
+	indapi.SourceOpen:   {},
+	indapi.SourceHigh:   {},
+	indapi.SourceLow:    {},
+	indapi.SourceClose:  {},
+	indapi.SourceVolume: {},
+	indapi.SourceHL2:    {},
+	indapi.SourceHLC3:   {},
+	indapi.SourceOHLC4:  {},
+}
+
+type function struct {
+	numArgs int
+	// The last argument is a constant period.
+	period bool
+	eval   func(args [][]float64, period int) []float64
+}
+
+var functions = map[string]function{
+	"sma":        periodFunction(series.Sma),
+	"ema":        periodFunction(series.Ema),
+	"wma":        periodFunction(series.Wma),
+	"rma":        periodFunction(series.Rma),
+	"sum":        periodFunction(series.Sum),
+	"stddev":     periodFunction(series.StdDev),
+	"highest":    periodFunction(series.Highest),
+	"lowest":     periodFunction(series.Lowest),
+	"crossover":  {numArgs: 2, eval: func(args [][]float64, _ int) []float64 { return cross(args[0], args[1]) }},
+	"crossunder": {numArgs: 2, eval: func(args [][]float64, _ int) []float64 { return cross(args[1], args[0]) }},
+	"if":         {numArgs: 3, eval: func(args [][]float64, _ int) []float64 { return ifElse(args[0], args[1], args[2]) }},
+	"abs":        {numArgs: 1, eval: func(args [][]float64, _ int) []float64 { return apply1(args[0], math.Abs) }},
+	"sqrt":       {numArgs: 1, eval: func(args [][]float64, _ int) []float64 { return apply1(args[0], math.Sqrt) }},
+	"log":        {numArgs: 1, eval: func(args [][]float64, _ int) []float64 { return apply1(args[0], math.Log) }},
+	"min":        {numArgs: 2, eval: func(args [][]float64, _ int) []float64 { return apply2(args[0], args[1], math.Min) }},
+	"max":        {numArgs: 2, eval: func(args [][]float64, _ int) []float64 { return apply2(args[0], args[1], math.Max) }},
+}
+
+func periodFunction(f func(values []float64, period int) []float64) function {
+	return function{
+		numArgs: 2,
+		period:  true,
+		eval: func(args [][]float64, period int) []float64 {
+			return f(args[0], period)
+		},
+	}
+}
+
+// Returns the names of the available functions.
+func GetFunctionNames() []string {
+	return slices.Sorted(maps.Keys(functions))
+}
+
+type context struct {
+	data      *indapi.PlotData
+	n         int
+	variables map[string][]float64
+}
+
+type node interface {
+	eval(ctx *context) []float64
+}
+
+type numberNode struct {
+	value float64
+}
+
+type sourceNode struct {
+	name string
+}
+
+type variableNode struct {
+	name string
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+type shiftNode struct {
+	operand node
+	offset  int
+}
+
+type callNode struct {
+	f      function
+	args   []node
+	period int
+}
+
+func outputName(i int) string {
+	if i == 0 {
+		return indapi.OutputValue
+	}
+	return fmt.Sprintf("%s %d", indapi.OutputValue, i+1)
+}
+
+// Evaluates the program on the candle data, the data needs to be locked by the caller.
+// Returns one series for each output, with the same length as the candle data.
+func (prog *Program) Evaluate(data *indapi.PlotData) [][]float64 {
+	ctx := context{
+		data:      data,
+		n:         len(data.Cache.ClosePrices),
+		variables: make(map[string][]float64),
+	}
+	outputs := make([][]float64, 0, prog.numOutputs)
+	for _, st := range prog.statements {
+		result := st.expr.eval(&ctx)
+		if st.name != "" {
+			ctx.variables[st.name] = result
+		} else {
+			outputs = append(outputs, result)
+		}
+	}
+	return outputs
+}
+
+func (n *numberNode) eval(ctx *context) []float64 {
+	result := make([]float64, ctx.n)
+	for i := range result {
+		result[i] = n.value
+	}
+	return result
+}
+
+func (n *sourceNode) eval(ctx *context) []float64 {
+	values := indapi.GetSourceSeries(n.name, ctx.data)
+	// Copy the series, the cache may not be modified.
+	result := series.NewNaN(ctx.n)
+	copy(result, values)
+	return result
+}
+
+func (n *variableNode) eval(ctx *context) []float64 {
+	return ctx.variables[n.name]
+}
+
+func (n *unaryNode) eval(ctx *context) []float64 {
+	operand := n.operand.eval(ctx)
+	if n.op == "-" {
+		return apply1(operand, func(v float64) float64 { return -v })
+	}
+	return apply1(operand, func(v float64) float64 { return boolValue(v == 0) })
+}
+
+func (n *binaryNode) eval(ctx *context) []float64 {
+	left, right := n.left.eval(ctx), n.right.eval(ctx)
+	switch n.op {
+	case "+":
+		return apply2(left, right, func(a, b float64) float64 { return a + b })
+	case "-":
+		return apply2(left, right, func(a, b float64) float64 { return a - b })
+	case "*":
+		return apply2(left, right, func(a, b float64) float64 { return a * b })
+	case "/":
+		return apply2(left, right, divide)
+	case "<":
+		return compare(left, right, func(a, b float64) bool { return a < b })
+	case ">":
+		return compare(left, right, func(a, b float64) bool { return a > b })
+	case "<=":
+		return compare(left, right, func(a, b float64) bool { return a <= b })
+	case ">=":
+		return compare(left, right, func(a, b float64) bool { return a >= b })
+	case "==":
+		return compare(left, right, func(a, b float64) bool { return a == b })
+	case "!=":
+		return compare(left, right, func(a, b float64) bool { return a != b })
+	case "&&":
+		return compare(left, right, func(a, b float64) bool { return a != 0 && b != 0 })
+	default: // "||"
+		return compare(left, right, func(a, b float64) bool { return a != 0 || b != 0 })
+	}
+}
+
+func (n *shiftNode) eval(ctx *context) []float64 {
+	operand := n.operand.eval(ctx)
+	result := series.NewNaN(len(operand))
+	for i := n.offset; i < len(operand); i++ {
+		result[i] = operand[i-n.offset]
+	}
+	return result
+}
+
+func (n *callNode) eval(ctx *context) []float64 {
+	args := make([][]float64, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(ctx)
+	}
+	return n.f.eval(args, n.period)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Division by zero results in NaN instead of infinity, so that the value is not plotted.
+func divide(a, b float64) float64 {
+	if b == 0 {
+		return math.NaN()
+	}
+	return a / b
+}
+
+func apply1(values []float64, f func(float64) float64) []float64 {
+	result := make([]float64, len(values))
+	for i, v := range values {
+		result[i] = f(v)
+	}
+	return result
+}
+
+func apply2(a []float64, b []float64, f func(float64, float64) float64) []float64 {
+	result := make([]float64, len(a))
+	for i := range result {
+		result[i] = f(a[i], b[i])
+	}
+	return result
+}
+
+// Comparisons result in 1 or 0, or NaN if any of the values is NaN.
+func compare(a []float64, b []float64, f func(float64, float64) bool) []float64 {
+	return apply2(a, b, func(x, y float64) float64 {
+		if math.IsNaN(x) || math.IsNaN(y) {
+			return math.NaN()
+		}
+		return boolValue(f(x, y))
+	})
+}
+
+// Returns 1 where a crosses above b, 0 otherwise.
+func cross(a []float64, b []float64) []float64 {
+	result := series.NewNaN(len(a))
+	for i := 1; i < len(a); i++ {
+		if math.IsNaN(a[i]) || math.IsNaN(b[i]) || math.IsNaN(a[i-1]) || math.IsNaN(b[i-1]) {
+			continue
+		}
+		result[i] = boolValue(a[i] > b[i] && a[i-1] <= b[i-1])
+	}
+	return result
+}
+
+func ifElse(cond []float64, a []float64, b []float64) []float64 {
+	result := series.NewNaN(len(cond))
+	for i, c := range cond {
+		if math.IsNaN(c) {
+			continue
+		}
+		if c != 0 {
+			result[i] = a[i]
+		} else {
+			result[i] = b[i]
+		}
+	}
+	return result
+}
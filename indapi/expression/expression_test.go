@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package expression
+
+import (
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/series"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPlotData(closePrices []float64) *indapi.PlotData {
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	n := len(closePrices)
+	data.Cache.OpenPrices = make([]float64, n)
+	data.Cache.HighPrices = make([]float64, n)
+	data.Cache.LowPrices = make([]float64, n)
+	data.Cache.ClosePrices = closePrices
+	data.Cache.Volumes = make([]float64, n)
+	for i, c := range closePrices {
+		data.Cache.OpenPrices[i] = c - 1
+		data.Cache.HighPrices[i] = c + 2
+		data.Cache.LowPrices[i] = c - 2
+		data.Cache.Volumes[i] = 100
+	}
+	return &data
+}
+
+func evaluate(t *testing.T, source string, data *indapi.PlotData) [][]float64 {
+	prog, err := Compile(source)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	return prog.Evaluate(data)
+}
+
+func assertSeries(t *testing.T, expected []float64, actual []float64) {
+	if assert.Equal(t, len(expected), len(actual)) {
+		for i := range expected {
+			assert.InDelta(t, expected[i], actual[i], 1e-9, "index %d", i) // NaN is equal to NaN within delta
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	data := newTestPlotData([]float64{1, 2, 3})
+	result := evaluate(t, "(high + low) / 2 - -close * 2", data)
+	assertSeries(t, []float64{3, 6, 9}, result[0])
+	result = evaluate(t, "hl2 - close", data)
+	assertSeries(t, []float64{0, 0, 0}, result[0])
+	result = evaluate(t, "close / (close - 2)", data)
+	assertSeries(t, []float64{-1, math.NaN(), 3}, result[0])
+}
+
+func TestFunctions(t *testing.T) {
+	closePrices := []float64{1, 3, 2, 5, 4, 6}
+	data := newTestPlotData(closePrices)
+	result := evaluate(t, "sma(close, 3)\nema(close, 2)\nhighest(high, 2)\nlowest(close, 3)", data)
+	if assert.Len(t, result, 4) {
+		assertSeries(t, series.Sma(closePrices, 3), result[0])
+		assertSeries(t, series.Ema(closePrices, 2), result[1])
+		assertSeries(t, []float64{math.NaN(), 5, 5, 7, 7, 8}, result[2])
+		assertSeries(t, []float64{math.NaN(), math.NaN(), 1, 2, 2, 4}, result[3])
+	}
+	result = evaluate(t, "sma(close - sma(close, 2), 2)", data)
+	assertSeries(t, series.Sma(applySub(closePrices, series.Sma(closePrices, 2)), 2), result[0])
+	result = evaluate(t, "max(close, 3) + abs(-1)", data)
+	assertSeries(t, []float64{4, 4, 4, 6, 5, 7}, result[0])
+}
+
+func applySub(a []float64, b []float64) []float64 {
+	return apply2(a, b, func(x, y float64) float64 { return x - y })
+}
+
+func TestConditionsAndCrossover(t *testing.T) {
+	data := newTestPlotData([]float64{1, 3, 2, 5, 4, 6})
+	result := evaluate(t, "crossover(close, close[1] + 0.5); crossunder(close, close[1])", data)
+	assertSeries(t, []float64{math.NaN(), math.NaN(), 0, 1, 0, 1}, result[0])
+	assertSeries(t, []float64{math.NaN(), math.NaN(), 1, 0, 1, 0}, result[1])
+	result = evaluate(t, "if(close > 2 and not (close == 5), close, -1)", data)
+	assertSeries(t, []float64{-1, 3, -1, -1, 4, 6}, result[0])
+	result = evaluate(t, "if(close[1] >= 3 || close <= 1, 1, 0)", data)
+	assertSeries(t, []float64{math.NaN(), 0, 1, 0, 1, 1}, result[0])
+}
+
+func TestVariables(t *testing.T) {
+	data := newTestPlotData([]float64{1, 2, 3, 4})
+	prog, err := Compile("# moving average difference\nfast = sma(close, 1)\nslow = sma(close, 2)\nfast - slow\nslow")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{indapi.OutputValue, "value 2"}, prog.GetOutputNames())
+	result := prog.Evaluate(data)
+	assertSeries(t, []float64{math.NaN(), 0.5, 0.5, 0.5}, result[0])
+	assertSeries(t, []float64{math.NaN(), 1.5, 2.5, 3.5}, result[1])
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, source := range []string{
+		"",
+		"x = close",
+		"close +",
+		"unknown",
+		"foo(close)",
+		"sma(close)",
+		"sma(close, 2, 3)",
+		"sma(close, close)",
+		"sma(close, 2.5)",
+		"sma(close, 0)",
+		"sma(close, 100000)",
+		"close[-1]",
+		"close = 1; close",
+		"sma = 1; sma",
+		"x = x + 1; x",
+		"(close",
+		"close close",
+		"close $ 1",
+		strings.Repeat("(", 200) + "close" + strings.Repeat(")", 200),
+		strings.Repeat("close+", 2000) + "close",
+	} {
+		_, err := Compile(source)
+		assert.Error(t, err, source)
+	}
+}
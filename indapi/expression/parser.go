@@ -0,0 +1,412 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+// Package expression implements a small expression language to define indicators at runtime.
+//
+// A program consists of statements separated by ";" or new lines. A statement is either an
+// assignment "name = expression", which defines a variable, or an expression, which is an output
+// of the indicator. Expressions operate on whole price series, e.g.
+//
+//	fast = ema(close, 12); slow = ema(close, 26); fast - slow
+//
+// The language is sandboxed: there are no loops, no access to anything but the candle data,
+// and the size of programs is limited.
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	MaxSourceLength = 4096
+	maxNodes        = 1000
+	maxDepth        = 64
+	// Maximum period of functions and maximum offset of the history operator.
+	MaxPeriod = 10000
+)
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+	tokenSeparator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+var operators = []string{"&&", "||", "<=", ">=", "==", "!=", "+", "-", "*", "/", "<", ">", "!", "=", "(", ")", "[", "]", ","}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\n' || r == ';':
+			tokens = append(tokens, token{kind: tokenSeparator, text: ";", pos: i})
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '#':
+			// Comment until the end of the line.
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", text, start+1)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: value, pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			text := strings.ToLower(string(runes[start:i]))
+			// Logical keywords are treated like their operators.
+			switch text {
+			case "and":
+				tokens = append(tokens, token{kind: tokenOperator, text: "&&", pos: start})
+			case "or":
+				tokens = append(tokens, token{kind: tokenOperator, text: "||", pos: start})
+			case "not":
+				tokens = append(tokens, token{kind: tokenOperator, text: "!", pos: start})
+			default:
+				tokens = append(tokens, token{kind: tokenIdent, text: text, pos: start})
+			}
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:min(i+2, len(runes))]), op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i+1)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, pos: len(runes)}), nil
+}
+
+type statement struct {
+	// Name of the variable, empty for outputs.
+	name string
+	expr node
+}
+
+// Compiled program, which can be evaluated on candle data.
+type Program struct {
+	statements []statement
+	numOutputs int
+}
+
+type parser struct {
+	tokens    []token
+	pos       int
+	depth     int
+	numNodes  int
+	variables map[string]bool
+}
+
+// Compiles the source code of a program. At least one output is required.
+func Compile(source string) (*Program, error) {
+	if len(source) > MaxSourceLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", MaxSourceLength)
+	}
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens, variables: make(map[string]bool)}
+	var prog Program
+	for p.peek().kind != tokenEnd {
+		if p.peek().kind == tokenSeparator {
+			p.next()
+			continue
+		}
+		st, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		prog.statements = append(prog.statements, st)
+		if st.name == "" {
+			prog.numOutputs++
+		}
+		if t := p.peek(); t.kind != tokenSeparator && t.kind != tokenEnd {
+			return nil, p.errorAt(t, "expected end of statement")
+		}
+	}
+	if prog.numOutputs == 0 {
+		return nil, errors.New("expression has no output")
+	}
+	return &prog, nil
+}
+
+// Returns the names of the outputs, the first output is named indapi.OutputValue.
+func (prog *Program) GetOutputNames() []string {
+	names := make([]string, prog.numOutputs)
+	for i := range names {
+		names[i] = outputName(i)
+	}
+	return names
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekN(n int) token {
+	return p.tokens[min(p.pos+n, len(p.tokens)-1)]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorAt(t token, msg string) error {
+	if t.kind == tokenEnd {
+		return fmt.Errorf("%s at end of expression", msg)
+	}
+	return fmt.Errorf("%s at position %d", msg, t.pos+1)
+}
+
+func (p *parser) expect(op string) error {
+	if t := p.next(); t.kind != tokenOperator || t.text != op {
+		return p.errorAt(t, fmt.Sprintf("expected %q", op))
+	}
+	return nil
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseStatement() (statement, error) {
+	if p.peek().kind == tokenIdent && p.peekN(1).kind == tokenOperator && p.peekN(1).text == "=" {
+		t := p.next()
+		p.next()
+		if _, ok := sources[t.text]; ok {
+			return statement{}, p.errorAt(t, fmt.Sprintf("%s cannot be assigned", t.text))
+		}
+		if _, ok := functions[t.text]; ok {
+			return statement{}, p.errorAt(t, fmt.Sprintf("%s cannot be assigned", t.text))
+		}
+		expr, err := p.parseExpression()
+		if err != nil {
+			return statement{}, err
+		}
+		// The variable is defined after the expression, so that it cannot reference itself.
+		p.variables[t.text] = true
+		return statement{name: t.text, expr: expr}, nil
+	}
+	expr, err := p.parseExpression()
+	return statement{expr: expr}, err
+}
+
+// Counts nodes and nesting depth, to limit the resources used by a program.
+func (p *parser) enter() error {
+	p.depth++
+	p.numNodes++
+	if p.depth > maxDepth {
+		return p.errorAt(p.peek(), "expression is nested too deeply")
+	}
+	if p.numNodes > maxNodes {
+		return p.errorAt(p.peek(), "expression is too long")
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) parseExpression() (node, error) {
+	return p.parseBinary(0)
+}
+
+// Binary operators by precedence, from lowest to highest.
+var binaryPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"<", ">", "<=", ">=", "==", "!="},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(binaryPrecedence) {
+		return p.parseUnary()
+	}
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(binaryPrecedence[level]...) {
+		op := p.next().text
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	if p.isOperator("-", "!") {
+		op := p.next().text
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := operand.(*numberNode); ok && op == "-" {
+			// Fold negative numbers, so that they can be used as constant arguments.
+			return &numberNode{value: -n.value}, nil
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+// Parses the history operator, e.g. close[1] is the close price of the previous candle.
+func (p *parser) parsePostfix() (node, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("[") {
+		p.next()
+		offset, err := p.parseConstant("offset", 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		expr = &shiftNode{operand: expr, offset: offset}
+	}
+	return expr, nil
+}
+
+// Parses an integer constant within the range of periods.
+func (p *parser) parseConstant(what string, minValue int) (int, error) {
+	t := p.peek()
+	expr, err := p.parseExpression()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := expr.(*numberNode)
+	if !ok || n.value != float64(int(n.value)) {
+		return 0, p.errorAt(t, fmt.Sprintf("%s needs to be an integer number", what))
+	}
+	if int(n.value) < minValue || int(n.value) > MaxPeriod {
+		return 0, p.errorAt(t, fmt.Sprintf("%s needs to be between %d and %d", what, minValue, MaxPeriod))
+	}
+	return int(n.value), nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return &numberNode{value: t.value}, nil
+	case tokenIdent:
+		if p.isOperator("(") {
+			return p.parseCall(t)
+		}
+		if p.variables[t.text] {
+			return &variableNode{name: t.text}, nil
+		}
+		if _, ok := sources[t.text]; ok {
+			return &sourceNode{name: t.text}, nil
+		}
+		return nil, p.errorAt(t, fmt.Sprintf("unknown name %s", t.text))
+	case tokenOperator:
+		if t.text == "(" {
+			expr, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(")")
+		}
+	}
+	return nil, p.errorAt(t, "expected a value")
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	f, ok := functions[name.text]
+	if !ok {
+		return nil, p.errorAt(name, fmt.Sprintf("unknown function %s", name.text))
+	}
+	p.next() // (
+	call := callNode{f: f}
+	numArgs := 0
+	for ; !p.isOperator(")"); numArgs++ {
+		if numArgs > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		if numArgs == f.numArgs-1 && f.period {
+			period, err := p.parseConstant("period", 1)
+			if err != nil {
+				return nil, err
+			}
+			call.period = period
+			continue
+		}
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+	}
+	p.next() // )
+	if numArgs != f.numArgs {
+		return nil, p.errorAt(name, fmt.Sprintf("%s needs %d arguments", name.text, f.numArgs))
+	}
+	return &call, nil
+}
@@ -39,20 +39,84 @@ func (x CandleList) Less(i, j int) bool { return x[i].Timestamp.Before(x[j].Time
 func (x CandleList) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
 
 type PlotData struct {
+	// The trading session is set during initialization and never changed.
+	// Therefore it is safe to be accessed from different goroutines.
+	Session candles.Session
+	// Reports whether the exchange is open, nil if the asset is traded around the clock.
+	// Like the session, this is set during initialization and never changed.
+	IsTradingTime  func(t time.Time) bool
 	Data           []CandleData
 	DataLastChange time.Time
 	DataMutex      *sync.RWMutex
-	Cache          struct {
-		LastUpdate  time.Time
-		Timestamps  []time.Time
-		OpenPrices  []float64
-		HighPrices  []float64
-		LowPrices   []float64
-		ClosePrices []float64
-		Volumes     []float64
+	// Float values of the candles, including live candles which have not been consolidated yet.
+	// Between resets, candles are only appended or the last candle is replaced, see UpdateState.
+	Cache struct {
+		LastUpdate time.Time
+		// Incremented whenever the cached values change.
+		ChangeCount uint64
+		// Incremented whenever cached values are changed other than by appending or replacing the last candle.
+		ResetCount uint64
+		// Number of cached candles which are consolidated, the remaining candles are live candles.
+		NumConsolidated int
+		Timestamps      []time.Time
+		OpenPrices      []float64
+		HighPrices      []float64
+		LowPrices       []float64
+		ClosePrices     []float64
+		Volumes         []float64
 	}
 }
 
+// Returns the cached candle timestamps, followed by the start times of n future candles.
+// Future candles are aligned to the trading session and skip times when the exchange is closed.
+// This is used to plot values which are shifted into the future. The data needs to be locked by the caller.
+func (d *PlotData) GetExtendedTimestamps(r candles.CandleResolution, n int) []time.Time {
+	timestamps := d.Cache.Timestamps
+	extended := make([]time.Time, len(timestamps), len(timestamps)+n)
+	copy(extended, timestamps)
+	if len(timestamps) == 0 {
+		return extended
+	}
+	last := timestamps[len(timestamps)-1]
+	for i := 1; i <= n; i++ {
+		last = r.GetNthTradingCandleTime(last, 1, d.Session, d.IsTradingTime)
+		extended = append(extended, last)
+	}
+	return extended
+}
+
+// Tracks the cached candles which have already been processed by an indicator,
+// so that indicators can be updated incrementally on every tick. Indicators which keep state
+// across the whole history (e.g. anchored or trailing calculations) may ignore start
+// and calculate all values again whenever something changed.
+type UpdateState struct {
+	data        *PlotData
+	changeCount uint64
+	resetCount  uint64
+	count       int
+}
+
+// Returns the index of the first cached candle which needs to be calculated (again), and whether anything
+// changed since the previous call. The last processed candle is always calculated again, because
+// it may have been replaced. If the cache was reset or different data is passed, start is 0. The data needs to be locked by the caller.
+func (s *UpdateState) Next(data *PlotData) (start int, changed bool) {
+	c := &data.Cache
+	n := len(c.Timestamps)
+	sameData := s.data == data && s.resetCount == c.ResetCount
+	if sameData && s.changeCount == c.ChangeCount && s.count == n {
+		return n, false
+	}
+	// Different data is used e.g. if the candle resolution was changed.
+	if sameData && s.count <= n {
+		start = max(s.count-1, 0)
+	}
+	s.data = data
+	s.changeCount = c.ChangeCount
+	s.resetCount = c.ResetCount
+	s.count = n
+	return start, true
+}
+
 type SubPlotType int
 
 const (
@@ -61,21 +125,89 @@ const (
 	SubPlotTypeIndicator
 )
 
-type LinePlotter interface {
+type MarkerShape int
+
+const (
+	MarkerCircle MarkerShape = iota
+	MarkerTriangleUp
+	MarkerTriangleDown
+)
+
+type LabelPosition int
+
+const (
+	LabelAbove LabelPosition = iota
+	LabelBelow
+)
+
+// Plotting functions which are available to indicators.
+// Values which are NaN are not plotted.
+// All functions update maxValue, so that the subplot can be scaled to fit the data.
+type Plotter interface {
 	PlotLine(timestamps []time.Time, data []float64, maxValue *float64, r candles.CandleResolution, c color.NRGBA, gtx layout.Context)
+	// Plots bars from zero to the data values. Empty colors are replaced by the default up and down bar colors.
+	PlotHistogram(timestamps []time.Time, data []float64, maxValue *float64, r candles.CandleResolution, positive color.NRGBA, negative color.NRGBA, gtx layout.Context)
+	// Fills the area between two series.
+	PlotBand(timestamps []time.Time, upper []float64, lower []float64, maxValue *float64, r candles.CandleResolution, c color.NRGBA, gtx layout.Context)
+	// Plots a reference line across the whole plot area.
+	PlotHorizontalLine(value float64, maxValue *float64, c color.NRGBA, gtx layout.Context)
+	// Plots a horizontal line from the candle at start to the candle at end, both inclusive.
+	PlotHorizontalSegment(start time.Time, end time.Time, value float64, maxValue *float64, r candles.CandleResolution, c color.NRGBA, gtx layout.Context)
+	PlotMarkers(timestamps []time.Time, data []float64, maxValue *float64, r candles.CandleResolution, c color.NRGBA, shape MarkerShape, gtx layout.Context)
+	// Plots horizontal bars at the right edge of the plot area, e.g. a volume profile. Bar i covers the values from
+	// bounds[i] to bounds[i+1], its length is relative to the largest value, with maxWidth as fraction of the plot width.
+	// The bars do not affect the scaling of the subplot.
+	PlotHorizontalBars(bounds []float64, values []float64, colors []color.NRGBA, maxWidth float64, gtx layout.Context)
+	// Returns the times at the left and the right edge of the plot area.
+	GetVisibleTimeRange(r candles.CandleResolution) (start time.Time, end time.Time)
+	// Plots text labels above or below the data values. Empty labels are skipped.
+	PlotLabels(timestamps []time.Time, data []float64, labels []string, maxValue *float64, r candles.CandleResolution, c color.NRGBA, position LabelPosition, gtx layout.Context)
+	// Shows a message in the top left corner of the plot area, e.g. if the indicator failed.
+	// Messages are shown below each other and do not affect the scaling of the subplot.
+	PlotMessage(text string, c color.NRGBA, gtx layout.Context)
 }
 
 type IndicatorData interface {
 	Update(r candles.CandleResolution, data *PlotData)
-	Plot(p LinePlotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context)
+	Plot(p Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context)
 	GetId() IndicatorId
+	GetPropertyDescriptors() []PropertyDescriptor
 	GetProperties() map[string]string
-	SetProperties(map[string]string)
+	// Sets the property values. Invalid values are ignored and reported in the returned error.
+	SetProperties(map[string]string) error
 	GetColors() []color.NRGBA
 	SetColors([]color.NRGBA)
 	GetSubPlotType() SubPlotType
 }
 
+// Name of the output series of indicators which have a single output.
+const OutputValue = "value"
+
+// Implemented by indicators which provide output series, which can be used as input of other indicators.
+type OutputProvider interface {
+	GetOutputNames() []string
+	// Returns the output series with the given name, aligned to the cached candle timestamps.
+	// Returns nil if the name is unknown.
+	GetOutput(name string) []float64
+}
+
+// Reference to an output series of another indicator of the same plot.
+type IndicatorInput struct {
+	// Name of the indicator within the plot.
+	Name string
+	// Name of the output series, see OutputProvider.
+	Output string
+}
+
+// Implemented by indicators which use the output of another indicator as input instead of the candle data.
+// The input indicator needs to be updated first.
+type ChainedIndicator interface {
+	IndicatorData
+	GetInput() IndicatorInput
+	// Returns the indicator which provides the input, or nil if it was not found.
+	GetInputIndicator() IndicatorData
+}
+
 func GetMinColors(c []color.NRGBA, numColors int) []color.NRGBA {
 	for len(c) < numColors {
 		c = append(c, color.NRGBA{})
@@ -94,3 +226,15 @@ func GetNormalisedColors(c []color.NRGBA, def color.NRGBA) []color.NRGBA {
 	}
 	return nc
 }
+
+// Color of reference lines like overbought/oversold levels, derived from the indicator color.
+func GetReferenceLineColor(c color.NRGBA) color.NRGBA {
+	c.A /= 3
+	return c
+}
+
+// Color of filled areas like bands, derived from the indicator color.
+func GetFillColor(c color.NRGBA) color.NRGBA {
+	c.A /= 6
+	return c
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package indapi
+
+import (
+	"maystocks/indapi/candles"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetExtendedTimestamps(t *testing.T) {
+	d := PlotData{Session: candles.NewUtcSession()}
+	assert.Empty(t, d.GetExtendedTimestamps(candles.CandleOneDay, 2))
+
+	// Thursday and Friday
+	d.Cache.Timestamps = []time.Time{
+		time.Date(2023, 8, 10, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 8, 11, 0, 0, 0, 0, time.UTC),
+	}
+	d.IsTradingTime = func(t time.Time) bool {
+		return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+	}
+	extended := d.GetExtendedTimestamps(candles.CandleOneDay, 2)
+	assert.Equal(t, 4, len(extended))
+	assert.True(t, extended[1].Equal(d.Cache.Timestamps[1]))
+	assert.True(t, extended[2].Equal(time.Date(2023, 8, 14, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, extended[3].Equal(time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestUpdateState(t *testing.T) {
+	var s UpdateState
+	d := PlotData{}
+	d.Cache.Timestamps = make([]time.Time, 3)
+	d.Cache.ChangeCount = 1
+	start, changed := s.Next(&d)
+	assert.True(t, changed)
+	assert.Equal(t, 0, start)
+	_, changed = s.Next(&d)
+	assert.False(t, changed)
+
+	// Append a candle, the previous last candle is calculated again.
+	d.Cache.Timestamps = append(d.Cache.Timestamps, time.Time{})
+	d.Cache.ChangeCount++
+	start, changed = s.Next(&d)
+	assert.True(t, changed)
+	assert.Equal(t, 2, start)
+
+	// Replace the last candle.
+	d.Cache.ChangeCount++
+	start, _ = s.Next(&d)
+	assert.Equal(t, 3, start)
+
+	// Reset
+	d.Cache.ChangeCount++
+	d.Cache.ResetCount++
+	start, _ = s.Next(&d)
+	assert.Equal(t, 0, start)
+
+	// Different data
+	other := d
+	start, changed = s.Next(&other)
+	assert.True(t, changed)
+	assert.Equal(t, 0, start)
+}
@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package adx
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	adx         []float64
+	plusDi      []float64
+	minusDi     []float64
+	updateState indapi.UpdateState
+	diPeriods   int
+	adxPeriods  int
+	threshold   float64
+	colors      []color.NRGBA
+}
+
+const Id = "adx"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "DI Periods", Label: "DI Period", Description: "Number of candles of the directional indicators.", Type: indapi.PropertyTypeInt, Default: "14", Min: 1, Max: 1000},
+	{Key: "ADX Periods", Label: "ADX Smoothing", Description: "Number of candles of the ADX smoothing.", Type: indapi.PropertyTypeInt, Default: "14", Min: 1, Max: 1000},
+	{Key: "Threshold", Label: "Trend Threshold", Description: "ADX level above which there is a trend.", Type: indapi.PropertyTypeFloat, Default: "25", Min: 0, Max: 100},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{diPeriods: 14, adxPeriods: 14, threshold: 25}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"DI Periods":  strconv.Itoa(d.diPeriods),
+		"ADX Periods": strconv.Itoa(d.adxPeriods),
+		"Threshold":   strconv.FormatFloat(d.threshold, 'f', -1, 64),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "DI Periods":
+			d.diPeriods, _ = strconv.Atoi(value)
+		case "ADX Periods":
+			d.adxPeriods, _ = strconv.Atoi(value)
+		case "Threshold":
+			d.threshold, _ = strconv.ParseFloat(value, 64)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 3)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.adx, d.plusDi, d.minusDi = Calculate(data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices, d.diPeriods, d.adxPeriods)
+	}
+}
+
+// Calculates the average directional index and the directional indicators +DI and -DI.
+func Calculate(high []float64, low []float64, closing []float64, diPeriods int, adxPeriods int) (adx []float64, plusDi []float64, minusDi []float64) {
+	n := len(closing)
+	plusDm := series.NewNaN(n)
+	minusDm := series.NewNaN(n)
+	trueRange := series.NewNaN(n)
+	tr := series.TrueRange(high, low, closing)
+	for i := 1; i < n; i++ {
+		upMove := high[i] - high[i-1]
+		downMove := low[i-1] - low[i]
+		plusDm[i], minusDm[i] = 0, 0
+		if upMove > downMove && upMove > 0 {
+			plusDm[i] = upMove
+		}
+		if downMove > upMove && downMove > 0 {
+			minusDm[i] = downMove
+		}
+		// The first true range has no previous close, it is skipped like the directional movement.
+		trueRange[i] = tr[i]
+	}
+	smoothedTr := series.Rma(trueRange, diPeriods)
+	smoothedPlusDm := series.Rma(plusDm, diPeriods)
+	smoothedMinusDm := series.Rma(minusDm, diPeriods)
+	plusDi = series.NewNaN(n)
+	minusDi = series.NewNaN(n)
+	dx := series.NewNaN(n)
+	for i := range dx {
+		if math.IsNaN(smoothedTr[i]) {
+			continue
+		}
+		if smoothedTr[i] > 0 {
+			plusDi[i] = 100 * smoothedPlusDm[i] / smoothedTr[i]
+			minusDi[i] = 100 * smoothedMinusDm[i] / smoothedTr[i]
+		} else {
+			plusDi[i], minusDi[i] = 0, 0
+		}
+		if sum := plusDi[i] + minusDi[i]; sum > 0 {
+			dx[i] = 100 * math.Abs(plusDi[i]-minusDi[i]) / sum
+		} else {
+			dx[i] = 0
+		}
+	}
+	adx = series.Rma(dx, adxPeriods)
+	return
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotHorizontalLine(d.threshold, maxValue, indapi.GetReferenceLineColor(c[0]), gtx)
+	p.PlotLine(d.timestamps[0:len(d.plusDi)], d.plusDi, maxValue, d.resolution, c[1], gtx)
+	p.PlotLine(d.timestamps[0:len(d.minusDi)], d.minusDi, maxValue, d.resolution, c[2], gtx)
+	p.PlotLine(d.timestamps[0:len(d.adx)], d.adx, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{"adx", "+di", "-di"}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	switch name {
+	case "adx":
+		return d.adx
+	case "+di":
+		return d.plusDi
+	case "-di":
+		return d.minusDi
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
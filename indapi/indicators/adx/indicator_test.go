@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package adx
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	// Steadily rising prices, each candle moves up by 1 with a range of 2.
+	n := 10
+	high := make([]float64, n)
+	low := make([]float64, n)
+	closing := make([]float64, n)
+	for i := range closing {
+		high[i] = float64(i) + 1
+		low[i] = float64(i) - 1
+		closing[i] = float64(i)
+	}
+	adx, plusDi, minusDi := Calculate(high, low, closing, 3, 3)
+	assert.True(t, math.IsNaN(plusDi[2]))
+	// +DM is 1 and the true range is 2 for each candle.
+	assert.InDelta(t, 50, plusDi[3], 1e-9)
+	assert.InDelta(t, 0, minusDi[3], 1e-9)
+	// ADX needs another period of DX values.
+	assert.True(t, math.IsNaN(adx[4]))
+	assert.InDelta(t, 100, adx[5], 1e-9)
+	assert.InDelta(t, 100, adx[n-1], 1e-9)
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package atr
+
+import (
+	"image/color"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	trueRange   []float64
+	result      []float64
+	updateState indapi.UpdateState
+	numPeriods  int
+	colors      []color.NRGBA
+}
+
+const Id = "atr"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Period", Description: "Number of candles of Wilder's smoothing.", Type: indapi.PropertyTypeInt, Default: "14", Min: 1, Max: 1000},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{numPeriods: 14}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods": strconv.Itoa(d.numPeriods),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.trueRange = series.UpdateTrueRange(d.trueRange, data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices, start)
+		d.result = series.UpdateRma(d.result, d.trueRange, d.numPeriods, start)
+	}
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
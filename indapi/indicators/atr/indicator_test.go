@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package atr
+
+import (
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPlotData(high []float64, low []float64, closing []float64) *indapi.PlotData {
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	data.Cache.Timestamps = make([]time.Time, len(closing))
+	data.Cache.HighPrices = high
+	data.Cache.LowPrices = low
+	data.Cache.ClosePrices = closing
+	data.Cache.ChangeCount = 1
+	return &data
+}
+
+func TestUpdate(t *testing.T) {
+	// True ranges are 1, 1.5, 2, 1.5 and 2.5, the latter including the gap to the previous close.
+	data := newTestPlotData(
+		[]float64{10, 11, 12, 11, 13},
+		[]float64{9, 9.5, 10, 10, 11},
+		[]float64{9.5, 10.5, 11.5, 10.5, 12.5},
+	)
+	d := NewIndicator().(*Indicator)
+	assert.NoError(t, d.SetProperties(map[string]string{"Time Periods": "3"}))
+	d.Update(candles.CandleOneDay, data)
+	// The first value is the average of the first period, then Wilder's smoothing is applied.
+	assert.True(t, math.IsNaN(d.result[0]))
+	assert.True(t, math.IsNaN(d.result[1]))
+	assert.InDeltaSlice(t, []float64{1.5, 1.5, 5.5 / 3}, d.result[2:], 1e-9)
+
+	// Append a live candle with a true range of 1.5.
+	data.Cache.Timestamps = append(data.Cache.Timestamps, time.Time{})
+	data.Cache.HighPrices = append(data.Cache.HighPrices, 12)
+	data.Cache.LowPrices = append(data.Cache.LowPrices, 11)
+	data.Cache.ClosePrices = append(data.Cache.ClosePrices, 11.5)
+	data.Cache.ChangeCount++
+	d.Update(candles.CandleOneDay, data)
+	assert.Len(t, d.result, 6)
+	assert.InDelta(t, (5.5/3*2+1.5)/3, d.result[5], 1e-9)
+
+	// Replace the live candle, the true range is 3 now.
+	data.Cache.HighPrices[5] = 14
+	data.Cache.ChangeCount++
+	d.Update(candles.CandleOneDay, data)
+	assert.InDelta(t, (5.5/3*2+3)/3, d.result[5], 1e-9)
+	assert.InDelta(t, 5.5/3, d.result[4], 1e-9)
+}
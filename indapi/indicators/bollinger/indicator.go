@@ -5,15 +5,14 @@ package bollinger
 
 import (
 	"image/color"
-	"log"
+	"math"
 	"maystocks/indapi"
 	"maystocks/indapi/candles"
-	"maystocks/indapi/properties"
+	"maystocks/indapi/series"
 	"strconv"
 	"time"
 
 	"gioui.org/layout"
-	"github.com/cinar/indicator"
 )
 
 type Indicator struct {
@@ -21,37 +20,60 @@ type Indicator struct {
 	timestamps     []time.Time
 	top            []float64
 	mid            []float64
+	stdDev         []float64
 	bottom         []float64
-	dataLastChange time.Time
+	updateState    indapi.UpdateState
 	timeUnits      int
+	multiplier     float64
+	source         string
+	signalsEnabled bool
+	signals        []indapi.Signal
 	colors         []color.NRGBA
 }
 
 const Id = "bollinger"
 
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Units", Label: "Period", Description: "Number of candles of the moving average.", Type: indapi.PropertyTypeInt, Default: "20", Min: 1, Max: 1000},
+	{Key: "Multiplier", Label: "Std. Dev.", Description: "Width of the bands in standard deviations.", Type: indapi.PropertyTypeFloat, Default: "2", Min: 0.1, Max: 10, Step: 0.1},
+	{Key: "Source", Label: "Source", Type: indapi.PropertyTypeSource, Default: indapi.SourceClose},
+	indapi.SignalsProperty,
+}
+
 func NewIndicator() indapi.IndicatorData {
-	return &Indicator{timeUnits: 20}
+	return &Indicator{timeUnits: 20, multiplier: 2, source: indapi.SourceClose}
 }
 
 func (d *Indicator) GetId() indapi.IndicatorId {
 	return Id
 }
 
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
 func (d *Indicator) GetProperties() map[string]string {
 	return map[string]string{
-		"Time Units": strconv.Itoa(d.timeUnits),
+		"Time Units":              strconv.Itoa(d.timeUnits),
+		"Multiplier":              strconv.FormatFloat(d.multiplier, 'f', -1, 64),
+		"Source":                  d.source,
+		indapi.SignalsPropertyKey: strconv.FormatBool(d.signalsEnabled),
 	}
 }
 
-func (d *Indicator) SetProperties(prop map[string]string) {
-	for key, value := range prop {
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
 		switch key {
 		case "Time Units":
-			properties.SetPositiveValue(&d.timeUnits, value)
-		default:
-			log.Printf("Unknown property %s was ignored.", key)
+			d.timeUnits, _ = strconv.Atoi(value)
+		case "Multiplier":
+			d.multiplier, _ = strconv.ParseFloat(value, 64)
+		case "Source":
+			d.source = value
+		case indapi.SignalsPropertyKey:
+			d.signalsEnabled, _ = strconv.ParseBool(value)
 		}
-	}
+	})
 }
 
 func (d *Indicator) GetColors() []color.NRGBA {
@@ -65,22 +87,85 @@ func (d *Indicator) SetColors(c []color.NRGBA) {
 func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
 	data.DataMutex.Lock()
 	defer data.DataMutex.Unlock()
-	if !d.dataLastChange.Equal(data.DataLastChange) { // TODO this should be generic for all indicators
-		d.dataLastChange = data.DataLastChange
+	if start, changed := d.updateState.Next(data); changed {
 		d.resolution = r
-
-		d.mid, d.top, d.bottom = indicator.BollingerBands(data.Cache.ClosePrices)
+		d.mid, d.stdDev, d.top, d.bottom = update(d.mid, d.stdDev, d.top, d.bottom,
+			indapi.GetSourceSeries(d.source, data), d.timeUnits, d.multiplier, start)
 		d.timestamps = data.Cache.Timestamps
+		if d.signalsEnabled {
+			d.signals = detectBandTouches(indapi.TruncateSignals(d.signals, data, start), data, d.top, d.bottom, start)
+		}
+	}
+}
+
+// Calculates the middle, upper and lower Bollinger Bands.
+func Calculate(values []float64, period int, multiplier float64) (mid []float64, top []float64, bottom []float64) {
+	mid, _, top, bottom = update(nil, nil, nil, nil, values, period, multiplier, 0)
+	return
+}
+
+// Updates the bands from index start on, after values were appended or replaced.
+func update(mid []float64, stdDev []float64, top []float64, bottom []float64, values []float64, period int, multiplier float64,
+	start int) ([]float64, []float64, []float64, []float64) {
+
+	mid = series.UpdateSma(mid, values, period, start)
+	stdDev = series.UpdateStdDev(stdDev, values, mid, period, start)
+	start = min(start, len(top), len(bottom))
+	top, bottom = top[:start], bottom[:start]
+	for i := start; i < len(values); i++ {
+		top = append(top, mid[i]+multiplier*stdDev[i])
+		bottom = append(bottom, mid[i]-multiplier*stdDev[i])
 	}
+	return mid, stdDev, top, bottom
 }
 
-func (d *Indicator) Plot(p indapi.LinePlotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+// Detects candles which touch a band, after the previous candle did not.
+// Touching the lower band is a bullish signal, touching the upper band a bearish signal.
+// Detected signals from index start on are appended to the given signals.
+func detectBandTouches(signals []indapi.Signal, data *indapi.PlotData, top []float64, bottom []float64, start int) []indapi.Signal {
+	c := &data.Cache
+	for i := max(start, 1); i < min(len(top), len(bottom), len(c.LowPrices), len(c.HighPrices)); i++ {
+		if math.IsNaN(bottom[i-1]) || math.IsNaN(top[i-1]) {
+			continue
+		}
+		switch {
+		case c.LowPrices[i] <= bottom[i] && c.LowPrices[i-1] > bottom[i-1]:
+			signals = append(signals, indapi.NewSignal(data, i, indapi.SignalBullish, "Price touched lower band"))
+		case c.HighPrices[i] >= top[i] && c.HighPrices[i-1] < top[i-1]:
+			signals = append(signals, indapi.NewSignal(data, i, indapi.SignalBearish, "Price touched upper band"))
+		}
+	}
+	return signals
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
 	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotBand(d.timestamps[0:len(d.top)], d.top, d.bottom, maxValue, d.resolution, indapi.GetFillColor(c[1]), gtx)
 	p.PlotLine(d.timestamps[0:len(d.top)], d.top, maxValue, d.resolution, c[0], gtx)
 	p.PlotLine(d.timestamps[0:len(d.mid)], d.mid, maxValue, d.resolution, c[1], gtx)
 	p.PlotLine(d.timestamps[0:len(d.bottom)], d.bottom, maxValue, d.resolution, c[2], gtx)
 }
 
+func (d *Indicator) GetOutputNames() []string {
+	return []string{"mid", "top", "bottom"}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	switch name {
+	case "mid":
+		return d.mid
+	case "top":
+		return d.top
+	case "bottom":
+		return d.bottom
+	}
+	return nil
+}
+
+func (d *Indicator) GetSignals() []indapi.Signal {
+	return d.signals
+}
+
 func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
 	return indapi.SubPlotTypePrice
 }
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package bollinger
+
+import (
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	mid, top, bottom := Calculate([]float64{1, 3, 3, 7}, 2, 2)
+	assert.True(t, math.IsNaN(mid[0]))
+	assert.True(t, math.IsNaN(top[0]))
+	assert.Equal(t, []float64{2, 3, 5}, mid[1:])
+	assert.Equal(t, []float64{4, 3, 9}, top[1:])
+	assert.Equal(t, []float64{0, 3, 1}, bottom[1:])
+}
+
+func TestProperties(t *testing.T) {
+	d := NewIndicator()
+	assert.NoError(t, d.SetProperties(map[string]string{"Time Units": "10", "Multiplier": "2.5", "Source": "hl2"}))
+	assert.Equal(t, map[string]string{"Time Units": "10", "Multiplier": "2.5", "Source": "hl2", "Signals": "false"}, d.GetProperties())
+	assert.Error(t, d.SetProperties(map[string]string{"Multiplier": "0"}))
+	assert.Equal(t, "2.5", d.GetProperties()["Multiplier"])
+}
+
+func TestUpdateIncremental(t *testing.T) {
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	data.Cache.Timestamps = make([]time.Time, 3)
+	data.Cache.ClosePrices = []float64{1, 3, 3}
+	data.Cache.ChangeCount = 1
+	d := NewIndicator().(*Indicator)
+	assert.NoError(t, d.SetProperties(map[string]string{"Time Units": "2"}))
+	d.Update(candles.CandleOneDay, &data)
+	// Replace the last candle and append a live candle.
+	data.Cache.Timestamps = append(data.Cache.Timestamps, time.Time{})
+	data.Cache.ClosePrices = []float64{1, 3, 5, 7}
+	data.Cache.ChangeCount++
+	d.Update(candles.CandleOneDay, &data)
+	mid, top, bottom := Calculate(data.Cache.ClosePrices, d.timeUnits, d.multiplier)
+	assert.Equal(t, mid[2:], d.mid[2:])
+	assert.Equal(t, top[2:], d.top[2:])
+	assert.Equal(t, bottom[2:], d.bottom[2:])
+}
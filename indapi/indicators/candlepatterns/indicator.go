@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package candlepatterns
+
+import (
+	"image/color"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/patterns"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+// Labels candles which match candlestick patterns.
+type Indicator struct {
+	resolution     candles.CandleResolution
+	timestamps     []time.Time
+	candles        []patterns.Candle
+	matches        []patterns.Match
+	updateState    indapi.UpdateState
+	enabled        map[patterns.Pattern]bool
+	signalsEnabled bool
+	signals        []indapi.Signal
+	// Labels of bullish, bearish and neutral patterns.
+	labels     [numColors][]string
+	highPrices []float64
+	lowPrices  []float64
+	colors     []color.NRGBA
+}
+
+const Id = "candlepatterns"
+
+// Colors of bullish, bearish and neutral patterns.
+const numColors = 3
+
+var propertyDescriptors = newPropertyDescriptors()
+
+func newPropertyDescriptors() []indapi.PropertyDescriptor {
+	var desc []indapi.PropertyDescriptor
+	for _, p := range patterns.Patterns {
+		desc = append(desc, indapi.PropertyDescriptor{
+			Key:         string(p),
+			Label:       string(p),
+			Description: "Label candles which match the pattern with \"" + p.GetAbbreviation() + "\".",
+			Type:        indapi.PropertyTypeBool,
+			Default:     "true",
+		})
+	}
+	return append(desc, indapi.SignalsProperty)
+}
+
+func NewIndicator() indapi.IndicatorData {
+	d := Indicator{enabled: make(map[patterns.Pattern]bool, len(patterns.Patterns))}
+	for _, p := range patterns.Patterns {
+		d.enabled[p] = true
+	}
+	return &d
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	prop := make(map[string]string, len(propertyDescriptors))
+	for _, p := range patterns.Patterns {
+		prop[string(p)] = strconv.FormatBool(d.enabled[p])
+	}
+	prop[indapi.SignalsPropertyKey] = strconv.FormatBool(d.signalsEnabled)
+	return prop
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		if key == indapi.SignalsPropertyKey {
+			d.signalsEnabled, _ = strconv.ParseBool(value)
+		} else {
+			d.enabled[patterns.Pattern(key)], _ = strconv.ParseBool(value)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, numColors)
+}
+
+func (d *Indicator) getEnabledPatterns() []patterns.Pattern {
+	var enabled []patterns.Pattern
+	for _, p := range patterns.Patterns {
+		if d.enabled[p] {
+			enabled = append(enabled, p)
+		}
+	}
+	return enabled
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		c := &data.Cache
+		d.resolution = r
+		d.timestamps = c.Timestamps
+		d.highPrices = c.HighPrices
+		d.lowPrices = c.LowPrices
+		d.candles = d.candles[:min(start, len(d.candles))]
+		for i := len(d.candles); i < len(c.Timestamps); i++ {
+			d.candles = append(d.candles, patterns.Candle{Open: c.OpenPrices[i], High: c.HighPrices[i], Low: c.LowPrices[i], Close: c.ClosePrices[i]})
+		}
+		n := 0
+		for n < len(d.matches) && d.matches[n].Index < start {
+			n++
+		}
+		d.matches = append(d.matches[:n], patterns.Detect(d.candles, d.getEnabledPatterns(), start)...)
+		d.updateLabels(data)
+	}
+}
+
+// Bullish patterns are labelled below the candle, other patterns above the candle.
+// Labels above the candle use the bearish color if they contain a bearish pattern.
+func (d *Indicator) updateLabels(data *indapi.PlotData) {
+	n := len(d.candles)
+	for i := range d.labels {
+		d.labels[i] = make([]string, n)
+	}
+	bullish, bearish, neutral := d.labels[0], d.labels[1], d.labels[2]
+	d.signals = nil
+	for _, m := range d.matches {
+		i := m.Index
+		switch m.Pattern.GetBias() {
+		case patterns.BiasBullish:
+			bullish[i] = appendLabel(bullish[i], m.Pattern)
+			if d.signalsEnabled {
+				d.signals = append(d.signals, indapi.NewSignal(data, i, indapi.SignalBullish, string(m.Pattern)))
+			}
+		case patterns.BiasBearish:
+			bearish[i] = appendLabel(bearish[i]+neutral[i], m.Pattern)
+			neutral[i] = ""
+			if d.signalsEnabled {
+				d.signals = append(d.signals, indapi.NewSignal(data, i, indapi.SignalBearish, string(m.Pattern)))
+			}
+		default:
+			if bearish[i] != "" {
+				bearish[i] = appendLabel(bearish[i], m.Pattern)
+			} else {
+				neutral[i] = appendLabel(neutral[i], m.Pattern)
+			}
+		}
+	}
+}
+
+func appendLabel(label string, p patterns.Pattern) string {
+	if label == "" {
+		return p.GetAbbreviation()
+	}
+	return label + " " + p.GetAbbreviation()
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	n := len(d.labels[0])
+	p.PlotLabels(d.timestamps[0:n], d.lowPrices[0:n], d.labels[0], maxValue, d.resolution, c[0], indapi.LabelBelow, gtx)
+	p.PlotLabels(d.timestamps[0:n], d.highPrices[0:n], d.labels[1], maxValue, d.resolution, c[1], indapi.LabelAbove, gtx)
+	p.PlotLabels(d.timestamps[0:n], d.highPrices[0:n], d.labels[2], maxValue, d.resolution, c[2], indapi.LabelAbove, gtx)
+}
+
+func (d *Indicator) GetSignals() []indapi.Signal {
+	return d.signals
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypePrice
+}
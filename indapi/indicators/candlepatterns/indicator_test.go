@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package candlepatterns
+
+import (
+	"maystocks/indapi"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestData() *indapi.PlotData {
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	c := &data.Cache
+	c.OpenPrices = []float64{10, 10, 8.8}
+	c.HighPrices = []float64{12, 10.5, 10.8}
+	c.LowPrices = []float64{8, 8.5, 8.6}
+	c.ClosePrices = []float64{10.1, 9, 10.5}
+	c.Volumes = make([]float64, 3)
+	for i := range c.ClosePrices {
+		c.Timestamps = append(c.Timestamps, time.Date(2023, 8, 1+i, 0, 0, 0, 0, time.UTC))
+	}
+	c.ChangeCount = 1
+	return &data
+}
+
+func TestUpdate(t *testing.T) {
+	d := NewIndicator().(*Indicator)
+	assert.NoError(t, d.SetProperties(map[string]string{"Inside Bar": "false", indapi.SignalsPropertyKey: "true"}))
+	data := newTestData()
+	d.Update(0, data)
+	assert.Equal(t, []string{"", "", "BullE"}, d.labels[0])
+	assert.Equal(t, []string{"D", "", ""}, d.labels[2])
+	assert.Equal(t, []indapi.Signal{
+		{Timestamp: data.Cache.Timestamps[2], Direction: indapi.SignalBullish, Price: 8.6, Description: "Bullish Engulfing"},
+	}, d.GetSignals())
+
+	// Replace the last candle, it is no longer engulfing.
+	data.Cache.ClosePrices[2] = 9.8
+	data.Cache.ChangeCount++
+	d.Update(0, data)
+	assert.Equal(t, []string{"", "", ""}, d.labels[0])
+	assert.Empty(t, d.GetSignals())
+}
@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package cci
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	result      []float64
+	updateState indapi.UpdateState
+	numPeriods  int
+	overbought  float64
+	oversold    float64
+	source      string
+	colors      []color.NRGBA
+}
+
+const Id = "cci"
+
+// Lambert's constant, so that about 70-80% of the values are within -100 and +100.
+const scale = 0.015
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Period", Description: "Number of candles used for the calculation.", Type: indapi.PropertyTypeInt, Default: "20", Min: 1, Max: 1000},
+	{Key: "Overbought", Label: "Overbought", Type: indapi.PropertyTypeFloat, Default: "100", Min: -1000, Max: 1000},
+	{Key: "Oversold", Label: "Oversold", Type: indapi.PropertyTypeFloat, Default: "-100", Min: -1000, Max: 1000},
+	{Key: "Source", Label: "Source", Type: indapi.PropertyTypeSource, Default: indapi.SourceHLC3},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{numPeriods: 20, overbought: 100, oversold: -100, source: indapi.SourceHLC3}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods": strconv.Itoa(d.numPeriods),
+		"Overbought":   strconv.FormatFloat(d.overbought, 'f', -1, 64),
+		"Oversold":     strconv.FormatFloat(d.oversold, 'f', -1, 64),
+		"Source":       d.source,
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		case "Overbought":
+			d.overbought, _ = strconv.ParseFloat(value, 64)
+		case "Oversold":
+			d.oversold, _ = strconv.ParseFloat(value, 64)
+		case "Source":
+			d.source = value
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.result = Calculate(indapi.GetSourceSeries(d.source, data), d.numPeriods)
+	}
+}
+
+// Calculates the commodity channel index, usually of the typical price (hlc3).
+func Calculate(values []float64, period int) []float64 {
+	mean := series.Sma(values, period)
+	meanDev := series.MeanDeviation(values, period)
+	result := series.NewNaN(len(values))
+	for i := range values {
+		if math.IsNaN(mean[i]) {
+			continue
+		}
+		if meanDev[i] > 0 {
+			result[i] = (values[i] - mean[i]) / (scale * meanDev[i])
+		} else {
+			result[i] = 0
+		}
+	}
+	return result
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	refColor := indapi.GetReferenceLineColor(c[0])
+	p.PlotHorizontalLine(d.overbought, maxValue, refColor, gtx)
+	p.PlotHorizontalLine(d.oversold, maxValue, refColor, gtx)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
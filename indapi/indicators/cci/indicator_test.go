@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package cci
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	// Typical prices 1, 2, 3, 5: mean 10/3, mean deviation 10/9 at index 3.
+	result := Calculate([]float64{1, 2, 3, 5}, 3)
+	assert.True(t, math.IsNaN(result[1]))
+	// (3 - 2) / (0.015 * 2/3)
+	assert.InDelta(t, 100, result[2], 1e-9)
+	// (5 - 10/3) / (0.015 * 10/9)
+	assert.InDelta(t, 100, result[3], 1e-9)
+
+	result = Calculate([]float64{3, 3, 3}, 3)
+	assert.Equal(t, 0.0, result[2])
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package indicators
+
+import (
+	"image/color"
+	"log"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"sort"
+	"sync"
+
+	"gioui.org/layout"
+)
+
+// Wraps an indicator, so that it uses the output of another indicator as input instead of the candle data.
+// The output series is passed as open, high, low and close price, so that any indicator can be chained.
+// If the input is not available, nothing is plotted.
+type Chained struct {
+	indapi.IndicatorData
+	input  indapi.IndicatorInput
+	source indapi.IndicatorData
+	// Candle data which was last used, to detect changes.
+	base            *indapi.PlotData
+	baseChangeCount uint64
+	baseResetCount  uint64
+	data            indapi.PlotData
+	signals         []indapi.Signal
+}
+
+func NewChained(ind indapi.IndicatorData, input indapi.IndicatorInput) *Chained {
+	return &Chained{
+		IndicatorData: ind,
+		input:         input,
+		data:          indapi.PlotData{DataMutex: new(sync.RWMutex)},
+	}
+}
+
+// Connects the indicator which provides the input. This is done after all indicators of a plot
+// have been created, because indicators may be chained in any order.
+func (c *Chained) Connect(source indapi.IndicatorData) {
+	c.source = source
+	if _, ok := source.(indapi.OutputProvider); source != nil && !ok {
+		log.Printf("Input %s of indicator %s has no outputs", c.input.Name, c.GetId())
+	}
+}
+
+// Returns the indicator which provides the input, or false if it is missing or has no outputs.
+func (c *Chained) getInputProvider() (indapi.OutputProvider, bool) {
+	provider, ok := c.source.(indapi.OutputProvider)
+	return provider, ok
+}
+
+func (c *Chained) GetInput() indapi.IndicatorInput {
+	return c.input
+}
+
+func (c *Chained) GetInputIndicator() indapi.IndicatorData {
+	return c.source
+}
+
+func (c *Chained) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	provider, ok := c.getInputProvider()
+	if !ok {
+		return // not plotted, this was logged when connecting
+	}
+	values := provider.GetOutput(c.input.Output)
+	data.DataMutex.RLock()
+	// The input is calculated from the candle data, so it changes in the same way.
+	if c.base != data || c.baseResetCount != data.Cache.ResetCount {
+		c.data.Cache.ResetCount++
+		c.data.Cache.ChangeCount++
+	} else if c.baseChangeCount != data.Cache.ChangeCount {
+		c.data.Cache.ChangeCount++
+	}
+	c.base = data
+	c.baseResetCount = data.Cache.ResetCount
+	c.baseChangeCount = data.Cache.ChangeCount
+	n := min(len(values), len(data.Cache.Timestamps), len(data.Cache.Volumes))
+	c.data.Session = data.Session
+	c.data.IsTradingTime = data.IsTradingTime
+	c.data.Cache.Timestamps = data.Cache.Timestamps[:n]
+	c.data.Cache.Volumes = data.Cache.Volumes[:n]
+	data.DataMutex.RUnlock()
+
+	values = values[:n]
+	c.data.Cache.OpenPrices = values
+	c.data.Cache.HighPrices = values
+	c.data.Cache.LowPrices = values
+	c.data.Cache.ClosePrices = values
+	c.IndicatorData.Update(r, &c.data)
+	c.updateSignals(data)
+}
+
+// Signals are shown on the price chart, so they are placed at the candles instead of the input values.
+func (c *Chained) updateSignals(data *indapi.PlotData) {
+	c.signals = nil
+	provider, ok := c.IndicatorData.(indapi.SignalProvider)
+	if !ok {
+		return
+	}
+	data.DataMutex.RLock()
+	defer data.DataMutex.RUnlock()
+	timestamps := data.Cache.Timestamps
+	for _, s := range provider.GetSignals() {
+		i := sort.Search(len(timestamps), func(i int) bool { return !timestamps[i].Before(s.Timestamp) })
+		if i < len(timestamps) && timestamps[i].Equal(s.Timestamp) {
+			c.signals = append(c.signals, indapi.NewSignal(data, i, s.Direction, s.Description))
+		}
+	}
+}
+
+func (c *Chained) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	if _, ok := c.getInputProvider(); ok {
+		c.IndicatorData.Plot(p, maxValue, defaultColor, gtx)
+	}
+}
+
+func (c *Chained) GetOutputNames() []string {
+	if provider, ok := c.IndicatorData.(indapi.OutputProvider); ok {
+		return provider.GetOutputNames()
+	}
+	return nil
+}
+
+func (c *Chained) GetOutput(name string) []float64 {
+	if _, ok := c.getInputProvider(); !ok {
+		return nil
+	}
+	if provider, ok := c.IndicatorData.(indapi.OutputProvider); ok {
+		return provider.GetOutput(name)
+	}
+	return nil
+}
+
+func (c *Chained) GetSignals() []indapi.Signal {
+	if _, ok := c.getInputProvider(); !ok {
+		return nil
+	}
+	return c.signals
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package indicators
+
+import (
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/indicators/rsi"
+	"maystocks/indapi/indicators/sma"
+	"maystocks/indapi/series"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPlotData(closePrices []float64) *indapi.PlotData {
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	data.Cache.Timestamps = make([]time.Time, len(closePrices))
+	data.Cache.HighPrices = closePrices
+	data.Cache.LowPrices = closePrices
+	data.Cache.ClosePrices = closePrices
+	data.Cache.Volumes = make([]float64, len(closePrices))
+	data.Cache.ChangeCount = 1
+	return &data
+}
+
+func TestChained(t *testing.T) {
+	data := newTestPlotData([]float64{1, 2, 3, 2, 4, 5, 4, 6})
+	source := Create(rsi.Id, map[string]string{"Time Periods": "2"}, nil)
+	chained := NewChained(Create(sma.Id, map[string]string{"Time Periods": "3"}, nil), indapi.IndicatorInput{Name: "rsi 1", Output: indapi.OutputValue})
+	ConnectChained([]indapi.IndicatorData{source, chained}, map[string]indapi.IndicatorData{"rsi 1": source})
+	assert.Equal(t, source, chained.GetInputIndicator())
+
+	source.Update(candles.CandleOneDay, data)
+	chained.Update(candles.CandleOneDay, data)
+	rsiValues := rsi.Calculate(data.Cache.ClosePrices, 2)
+	assert.Equal(t, len(rsiValues), len(chained.GetOutput(indapi.OutputValue)))
+	expected := series.Sma(rsiValues, 3)
+	for i, v := range chained.GetOutput(indapi.OutputValue) {
+		assert.InDelta(t, expected[i], v, 1e-9, "index %d", i) // NaN is equal to NaN within delta
+	}
+
+	// Append a live candle.
+	data.Cache.Timestamps = append(data.Cache.Timestamps, time.Time{})
+	data.Cache.ClosePrices = append(data.Cache.ClosePrices, 3)
+	data.Cache.HighPrices, data.Cache.LowPrices = data.Cache.ClosePrices, data.Cache.ClosePrices
+	data.Cache.Volumes = append(data.Cache.Volumes, 0)
+	data.Cache.ChangeCount++
+	source.Update(candles.CandleOneDay, data)
+	chained.Update(candles.CandleOneDay, data)
+	expected = series.Sma(rsi.Calculate(data.Cache.ClosePrices, 2), 3)
+	actual := chained.GetOutput(indapi.OutputValue)
+	assert.Equal(t, len(expected), len(actual))
+	assert.InDelta(t, expected[len(expected)-1], actual[len(actual)-1], 1e-9)
+}
+
+func TestChainedInputNotFound(t *testing.T) {
+	data := newTestPlotData([]float64{1, 2, 3})
+	chained := NewChained(Create(sma.Id, map[string]string{"Time Periods": "2"}, nil), indapi.IndicatorInput{Name: "missing", Output: indapi.OutputValue})
+	ConnectChained([]indapi.IndicatorData{chained}, map[string]indapi.IndicatorData{})
+	chained.Update(candles.CandleOneDay, data)
+	// Nothing is calculated without input.
+	assert.Nil(t, chained.GetOutput(indapi.OutputValue))
+	assert.Nil(t, chained.GetSignals())
+}
+
+func TestChainedSignals(t *testing.T) {
+	closing := []float64{1, 2, 3, 2, 4, 5, 4, 6, 2, 1, 3, 5}
+	timestamps := make([]time.Time, len(closing))
+	for i := range timestamps {
+		timestamps[i] = time.Date(2023, 8, 1+i, 0, 0, 0, 0, time.UTC)
+	}
+	data := newTestPlotData(closing)
+	data.Cache.Timestamps = timestamps
+	props := map[string]string{"Time Periods": "3", indapi.SignalsPropertyKey: "true"}
+	source := Create(rsi.Id, map[string]string{"Time Periods": "2"}, nil)
+	chained := NewChained(Create(sma.Id, props, nil), indapi.IndicatorInput{Name: "rsi 1", Output: indapi.OutputValue})
+	ConnectChained([]indapi.IndicatorData{source, chained}, map[string]indapi.IndicatorData{"rsi 1": source})
+	source.Update(candles.CandleOneDay, data)
+	chained.Update(candles.CandleOneDay, data)
+
+	// Signals are detected like using the input as candle data, but are placed at the candles.
+	expected := Create(sma.Id, props, nil)
+	inputData := newTestPlotData(rsi.Calculate(closing, 2))
+	inputData.Cache.Timestamps = timestamps
+	expected.Update(candles.CandleOneDay, inputData)
+	expectedSignals := expected.(indapi.SignalProvider).GetSignals()
+	var provider indapi.SignalProvider = chained
+	signals := provider.GetSignals()
+	assert.NotEmpty(t, signals)
+	assert.Equal(t, len(expectedSignals), len(signals))
+	for i, s := range signals {
+		assert.Equal(t, expectedSignals[i].Timestamp, s.Timestamp)
+		assert.Equal(t, expectedSignals[i].Direction, s.Direction)
+		assert.Equal(t, closing[s.Timestamp.Day()-1], s.Price)
+	}
+}
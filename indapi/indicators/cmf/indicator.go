@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package cmf
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	result      []float64
+	updateState indapi.UpdateState
+	numPeriods  int
+	colors      []color.NRGBA
+}
+
+const Id = "cmf"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Period", Type: indapi.PropertyTypeInt, Default: "20", Min: 1, Max: 1000},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{numPeriods: 20}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods": strconv.Itoa(d.numPeriods),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.result = Calculate(data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices, data.Cache.Volumes, d.numPeriods)
+	}
+}
+
+// Calculates the Chaikin money flow, the sum of the money flow volume divided by the sum of the volume.
+func Calculate(highPrices []float64, lowPrices []float64, closePrices []float64, volumes []float64, period int) []float64 {
+	n := min(len(highPrices), len(lowPrices), len(closePrices), len(volumes))
+	flowVolumes := make([]float64, n)
+	for i := range flowVolumes {
+		if r := highPrices[i] - lowPrices[i]; r > 0 {
+			multiplier := ((closePrices[i] - lowPrices[i]) - (highPrices[i] - closePrices[i])) / r
+			flowVolumes[i] = multiplier * volumes[i]
+		}
+	}
+	flowSum := series.Sum(flowVolumes, period)
+	volumeSum := series.Sum(volumes[:n], period)
+	result := series.NewNaN(n)
+	for i := range result {
+		if !math.IsNaN(volumeSum[i]) {
+			if volumeSum[i] > 0 {
+				result[i] = flowSum[i] / volumeSum[i]
+			} else {
+				result[i] = 0
+			}
+		}
+	}
+	return result
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotHorizontalLine(0, maxValue, indapi.GetReferenceLineColor(c[0]), gtx)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
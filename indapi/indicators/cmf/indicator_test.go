@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package cmf
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	high := []float64{12, 12, 12}
+	low := []float64{8, 8, 8}
+	closing := []float64{12, 10, 8}
+	volumes := []float64{100, 100, 200}
+	result := Calculate(high, low, closing, volumes, 2)
+	assert.True(t, math.IsNaN(result[0]))
+	// Multipliers are 1, 0 and -1.
+	assert.InDelta(t, 0.5, result[1], 1e-9)
+	assert.InDelta(t, -200.0/300, result[2], 1e-9)
+}
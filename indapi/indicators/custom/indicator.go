@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+// Package custom implements user-defined indicators, which are calculated by an expression.
+package custom
+
+import (
+	"image/color"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/expression"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	id          indapi.IndicatorId
+	program     *expression.Program
+	overlay     bool
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	results     [][]float64
+	updateState indapi.UpdateState
+	colors      []color.NRGBA
+}
+
+var propertyDescriptors = []indapi.PropertyDescriptor{}
+
+// Returns a function which creates indicators using the compiled expression.
+// Overlay indicators are plotted in the price subplot.
+func NewFactory(id indapi.IndicatorId, program *expression.Program, overlay bool) func() indapi.IndicatorData {
+	return func() indapi.IndicatorData {
+		return &Indicator{id: id, program: program, overlay: overlay}
+	}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return d.id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, len(d.program.GetOutputNames()))
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.results = d.program.Evaluate(data)
+	}
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	for i, result := range d.results {
+		p.PlotLine(d.timestamps[0:len(result)], result, maxValue, d.resolution, c[i], gtx)
+	}
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return d.program.GetOutputNames()
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	for i, outputName := range d.program.GetOutputNames() {
+		if outputName == name && i < len(d.results) {
+			return d.results[i]
+		}
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	if d.overlay {
+		return indapi.SubPlotTypePrice
+	}
+	return indapi.SubPlotTypeIndicator
+}
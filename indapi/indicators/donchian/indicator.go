@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package donchian
+
+import (
+	"image/color"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	top         []float64
+	mid         []float64
+	bottom      []float64
+	updateState indapi.UpdateState
+	numPeriods  int
+	colors      []color.NRGBA
+}
+
+const Id = "donchian"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Period", Description: "Number of candles of the highest high and lowest low.", Type: indapi.PropertyTypeInt, Default: "20", Min: 1, Max: 1000},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{numPeriods: 20}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods": strconv.Itoa(d.numPeriods),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 3)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.update(data.Cache.HighPrices, data.Cache.LowPrices, start)
+	}
+}
+
+// Calculates the middle, upper and lower Donchian Channel lines.
+func Calculate(high []float64, low []float64, period int) (mid []float64, top []float64, bottom []float64) {
+	d := Indicator{numPeriods: period}
+	d.update(high, low, 0)
+	return d.mid, d.top, d.bottom
+}
+
+// Updates the lines from index start on, after candles were appended or replaced.
+func (d *Indicator) update(high []float64, low []float64, start int) {
+	d.top = series.UpdateHighest(d.top, high, d.numPeriods, start)
+	d.bottom = series.UpdateLowest(d.bottom, low, d.numPeriods, start)
+	d.mid = series.Resize(d.mid, len(d.top), start)
+	for i := start; i < len(d.mid); i++ {
+		d.mid[i] = (d.top[i] + d.bottom[i]) / 2
+	}
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotBand(d.timestamps[0:len(d.top)], d.top, d.bottom, maxValue, d.resolution, indapi.GetFillColor(c[1]), gtx)
+	p.PlotLine(d.timestamps[0:len(d.top)], d.top, maxValue, d.resolution, c[0], gtx)
+	p.PlotLine(d.timestamps[0:len(d.mid)], d.mid, maxValue, d.resolution, c[1], gtx)
+	p.PlotLine(d.timestamps[0:len(d.bottom)], d.bottom, maxValue, d.resolution, c[2], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{"mid", "top", "bottom"}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	switch name {
+	case "mid":
+		return d.mid
+	case "top":
+		return d.top
+	case "bottom":
+		return d.bottom
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypePrice
+}
@@ -0,0 +1,19 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package donchian
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	mid, top, bottom := Calculate([]float64{10, 12, 11, 9}, []float64{8, 9, 7, 6}, 3)
+	assert.True(t, math.IsNaN(mid[1]))
+	assert.Equal(t, []float64{12, 12}, top[2:])
+	assert.Equal(t, []float64{7, 6}, bottom[2:])
+	assert.Equal(t, []float64{9.5, 9}, mid[2:])
+}
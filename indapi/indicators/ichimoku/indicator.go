@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package ichimoku
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution   candles.CandleResolution
+	timestamps   []time.Time
+	conversion   []float64
+	base         []float64
+	spanA        []float64
+	spanB        []float64
+	lagging      []float64
+	updateState  indapi.UpdateState
+	conversionP  int
+	baseP        int
+	spanBP       int
+	displacement int
+	colors       []color.NRGBA
+}
+
+const Id = "ichimoku"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Conversion Periods", Label: "Tenkan-sen", Description: "Number of candles of the conversion line.", Type: indapi.PropertyTypeInt, Default: "9", Min: 1, Max: 1000},
+	{Key: "Base Periods", Label: "Kijun-sen", Description: "Number of candles of the base line.", Type: indapi.PropertyTypeInt, Default: "26", Min: 1, Max: 1000},
+	{Key: "Span B Periods", Label: "Senkou Span B", Description: "Number of candles of the leading span B.", Type: indapi.PropertyTypeInt, Default: "52", Min: 1, Max: 1000},
+	{Key: "Displacement", Label: "Displacement", Description: "Number of candles the cloud is shifted forward, and the lagging span backward.", Type: indapi.PropertyTypeInt, Default: "26", Min: 0, Max: 1000},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{conversionP: 9, baseP: 26, spanBP: 52, displacement: 26}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Conversion Periods": strconv.Itoa(d.conversionP),
+		"Base Periods":       strconv.Itoa(d.baseP),
+		"Span B Periods":     strconv.Itoa(d.spanBP),
+		"Displacement":       strconv.Itoa(d.displacement),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Conversion Periods":
+			d.conversionP, _ = strconv.Atoi(value)
+		case "Base Periods":
+			d.baseP, _ = strconv.Atoi(value)
+		case "Span B Periods":
+			d.spanBP, _ = strconv.Atoi(value)
+		case "Displacement":
+			d.displacement, _ = strconv.Atoi(value)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+// Colors of Tenkan-sen, Kijun-sen, Senkou Span A, Senkou Span B and Chikou Span.
+// The cloud is filled using the color of the leading span which is on top.
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 5)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.GetExtendedTimestamps(r, d.displacement)
+		d.conversion, d.base, d.spanA, d.spanB, d.lagging = Calculate(
+			data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices, d.conversionP, d.baseP, d.spanBP, d.displacement)
+	}
+}
+
+// Calculates the lines of Ichimoku Kinko Hyo.
+// The leading spans are shifted forward by displacement, i.e. their length is len(closePrices) + displacement.
+// The lagging span is the close price shifted backward by displacement.
+func Calculate(highPrices []float64, lowPrices []float64, closePrices []float64, conversionPeriod int, basePeriod int, spanBPeriod int,
+	displacement int) (conversion []float64, base []float64, spanA []float64, spanB []float64, lagging []float64) {
+
+	n := min(len(highPrices), len(lowPrices), len(closePrices))
+	if n == 0 {
+		// Future candles are only known if there is data.
+		displacement = 0
+	}
+	highPrices, lowPrices = highPrices[:n], lowPrices[:n]
+	conversion = midpoint(highPrices, lowPrices, conversionPeriod)
+	base = midpoint(highPrices, lowPrices, basePeriod)
+	spanBValues := midpoint(highPrices, lowPrices, spanBPeriod)
+	spanA = series.NewNaN(n + displacement)
+	spanB = series.NewNaN(n + displacement)
+	for i := 0; i < n; i++ {
+		spanA[i+displacement] = (conversion[i] + base[i]) / 2
+		spanB[i+displacement] = spanBValues[i]
+	}
+	lagging = series.NewNaN(n)
+	for i := displacement; i < n; i++ {
+		lagging[i-displacement] = closePrices[i]
+	}
+	return
+}
+
+// Returns the midpoint of the highest high and the lowest low of each period.
+func midpoint(highPrices []float64, lowPrices []float64, period int) []float64 {
+	highest := series.Highest(highPrices, period)
+	lowest := series.Lowest(lowPrices, period)
+	result := make([]float64, len(highest))
+	for i := range result {
+		result[i] = (highest[i] + lowest[i]) / 2
+	}
+	return result
+}
+
+// Returns the parts of the cloud where span A is above span B, and where span B is above span A.
+// Values next to a crossover are included in both, so that the cloud is filled without gaps.
+func splitCloud(spanA []float64, spanB []float64) (upperA []float64, lowerB []float64, upperB []float64, lowerA []float64) {
+	n := len(spanA)
+	upperA, lowerB = series.NewNaN(n), series.NewNaN(n)
+	upperB, lowerA = series.NewNaN(n), series.NewNaN(n)
+	isBullish := func(i int) bool {
+		return i >= 0 && i < n && spanA[i] >= spanB[i]
+	}
+	isBearish := func(i int) bool {
+		return i >= 0 && i < n && spanA[i] < spanB[i]
+	}
+	for i := 0; i < n; i++ {
+		if math.IsNaN(spanA[i]) || math.IsNaN(spanB[i]) {
+			continue
+		}
+		if isBullish(i) || isBullish(i-1) {
+			upperA[i], lowerB[i] = spanA[i], spanB[i]
+		}
+		if isBearish(i) || isBearish(i-1) {
+			upperB[i], lowerA[i] = spanB[i], spanA[i]
+		}
+	}
+	return
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	upperA, lowerB, upperB, lowerA := splitCloud(d.spanA, d.spanB)
+	p.PlotBand(d.timestamps[0:len(upperA)], upperA, lowerB, maxValue, d.resolution, indapi.GetFillColor(c[2]), gtx)
+	p.PlotBand(d.timestamps[0:len(upperB)], upperB, lowerA, maxValue, d.resolution, indapi.GetFillColor(c[3]), gtx)
+	p.PlotLine(d.timestamps[0:len(d.spanA)], d.spanA, maxValue, d.resolution, c[2], gtx)
+	p.PlotLine(d.timestamps[0:len(d.spanB)], d.spanB, maxValue, d.resolution, c[3], gtx)
+	p.PlotLine(d.timestamps[0:len(d.conversion)], d.conversion, maxValue, d.resolution, c[0], gtx)
+	p.PlotLine(d.timestamps[0:len(d.base)], d.base, maxValue, d.resolution, c[1], gtx)
+	p.PlotLine(d.timestamps[0:len(d.lagging)], d.lagging, maxValue, d.resolution, c[4], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{"conversion", "base"}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	switch name {
+	case "conversion":
+		return d.conversion
+	case "base":
+		return d.base
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypePrice
+}
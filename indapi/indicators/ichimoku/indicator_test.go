@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package ichimoku
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	high := []float64{10, 12, 14, 16}
+	low := []float64{8, 10, 12, 14}
+	closing := []float64{9, 11, 13, 15}
+	conversion, base, spanA, spanB, lagging := Calculate(high, low, closing, 1, 2, 3, 2)
+	assert.Equal(t, []float64{9, 11, 13, 15}, conversion)
+	assert.True(t, math.IsNaN(base[0]))
+	assert.InDelta(t, 10.0, base[1], 1e-9)
+	assert.InDelta(t, 14.0, base[3], 1e-9)
+
+	// Leading spans are shifted forward.
+	assert.Equal(t, 6, len(spanA))
+	assert.True(t, math.IsNaN(spanA[2]))
+	assert.InDelta(t, 10.5, spanA[3], 1e-9)
+	assert.InDelta(t, 14.5, spanA[5], 1e-9)
+	assert.True(t, math.IsNaN(spanB[3]))
+	assert.InDelta(t, 11.0, spanB[4], 1e-9)
+	assert.InDelta(t, 13.0, spanB[5], 1e-9)
+
+	// Lagging span is shifted backward.
+	assert.Equal(t, 4, len(lagging))
+	assert.InDelta(t, 13.0, lagging[0], 1e-9)
+	assert.InDelta(t, 15.0, lagging[1], 1e-9)
+	assert.True(t, math.IsNaN(lagging[2]))
+}
+
+func TestCalculateEmpty(t *testing.T) {
+	_, _, spanA, _, _ := Calculate(nil, nil, nil, 9, 26, 52, 26)
+	assert.Empty(t, spanA)
+}
+
+func TestSplitCloud(t *testing.T) {
+	nan := math.NaN()
+	spanA := []float64{nan, 3, 1, 1}
+	spanB := []float64{nan, 2, 2, 2}
+	upperA, lowerB, upperB, lowerA := splitCloud(spanA, spanB)
+	assert.True(t, math.IsNaN(upperA[0]))
+	assert.Equal(t, 3.0, upperA[1])
+	assert.Equal(t, 2.0, lowerB[1])
+	// The crossover is part of both areas.
+	assert.Equal(t, 1.0, upperA[2])
+	assert.Equal(t, 2.0, upperB[2])
+	assert.Equal(t, 1.0, lowerA[2])
+	assert.True(t, math.IsNaN(upperA[3]))
+	assert.True(t, math.IsNaN(upperB[1]))
+}
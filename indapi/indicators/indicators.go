@@ -4,11 +4,37 @@
 package indicators
 
 import (
+	"fmt"
 	"image/color"
+	"log"
 	"maystocks/indapi"
+	"maystocks/indapi/expression"
+	"maystocks/indapi/indicators/adx"
+	"maystocks/indapi/indicators/atr"
 	"maystocks/indapi/indicators/bollinger"
+	"maystocks/indapi/indicators/candlepatterns"
+	"maystocks/indapi/indicators/cci"
+	"maystocks/indapi/indicators/cmf"
+	"maystocks/indapi/indicators/custom"
+	"maystocks/indapi/indicators/donchian"
+	"maystocks/indapi/indicators/ichimoku"
+	"maystocks/indapi/indicators/keltner"
+	"maystocks/indapi/indicators/macd"
+	"maystocks/indapi/indicators/mfi"
+	"maystocks/indapi/indicators/movingaverage"
+	"maystocks/indapi/indicators/obv"
+	"maystocks/indapi/indicators/pivots"
+	"maystocks/indapi/indicators/psar"
+	"maystocks/indapi/indicators/roc"
+	"maystocks/indapi/indicators/rsi"
 	"maystocks/indapi/indicators/sma"
+	"maystocks/indapi/indicators/stddev"
 	"maystocks/indapi/indicators/stochastics"
+	"maystocks/indapi/indicators/supertrend"
+	"maystocks/indapi/indicators/volumeprofile"
+	"maystocks/indapi/indicators/volumesma"
+	"maystocks/indapi/indicators/vwap"
+	"maystocks/indapi/indicators/williamsr"
 	"sort"
 
 	"golang.org/x/exp/maps"
@@ -18,10 +44,38 @@ const DefaultId = "bollinger"
 
 var IndicatorRegistry map[indapi.IndicatorId]func() indapi.IndicatorData = make(map[indapi.IndicatorId]func() indapi.IndicatorData)
 
+// Ids of user-defined indicators in IndicatorRegistry.
+var customIds = make(map[indapi.IndicatorId]struct{})
+
 func init() {
 	IndicatorRegistry[bollinger.Id] = bollinger.NewIndicator
 	IndicatorRegistry[sma.Id] = sma.NewIndicator
 	IndicatorRegistry[stochastics.Id] = stochastics.NewIndicator
+	IndicatorRegistry[rsi.Id] = rsi.NewIndicator
+	IndicatorRegistry[macd.Id] = macd.NewIndicator
+	IndicatorRegistry[cci.Id] = cci.NewIndicator
+	IndicatorRegistry[williamsr.Id] = williamsr.NewIndicator
+	IndicatorRegistry[roc.Id] = roc.NewIndicator
+	IndicatorRegistry[movingaverage.EmaId] = movingaverage.NewEma
+	IndicatorRegistry[movingaverage.WmaId] = movingaverage.NewWma
+	IndicatorRegistry[movingaverage.DemaId] = movingaverage.NewDema
+	IndicatorRegistry[movingaverage.TemaId] = movingaverage.NewTema
+	IndicatorRegistry[psar.Id] = psar.NewIndicator
+	IndicatorRegistry[supertrend.Id] = supertrend.NewIndicator
+	IndicatorRegistry[adx.Id] = adx.NewIndicator
+	IndicatorRegistry[atr.Id] = atr.NewIndicator
+	IndicatorRegistry[keltner.Id] = keltner.NewIndicator
+	IndicatorRegistry[donchian.Id] = donchian.NewIndicator
+	IndicatorRegistry[stddev.Id] = stddev.NewIndicator
+	IndicatorRegistry[vwap.Id] = vwap.NewIndicator
+	IndicatorRegistry[obv.Id] = obv.NewIndicator
+	IndicatorRegistry[mfi.Id] = mfi.NewIndicator
+	IndicatorRegistry[cmf.Id] = cmf.NewIndicator
+	IndicatorRegistry[volumesma.Id] = volumesma.NewIndicator
+	IndicatorRegistry[ichimoku.Id] = ichimoku.NewIndicator
+	IndicatorRegistry[candlepatterns.Id] = candlepatterns.NewIndicator
+	IndicatorRegistry[pivots.Id] = pivots.NewIndicator
+	IndicatorRegistry[volumeprofile.Id] = volumeprofile.NewIndicator
 }
 
 func Create(id indapi.IndicatorId, properties map[string]string, colors []color.NRGBA) indapi.IndicatorData {
@@ -30,11 +84,59 @@ func Create(id indapi.IndicatorId, properties map[string]string, colors []color.
 		panic("invalid indicator name")
 	}
 	ind := d()
-	ind.SetProperties(properties)
+	if err := ind.SetProperties(properties); err != nil {
+		log.Printf("Invalid properties of indicator %s: %v", id, err)
+	}
 	ind.SetColors(colors)
 	return ind
 }
 
+// Registers an additional indicator, e.g. one which is implemented by a plugin.
+// Existing indicators cannot be replaced.
+func Register(id indapi.IndicatorId, newIndicator func() indapi.IndicatorData) error {
+	if _, ok := IndicatorRegistry[id]; ok {
+		return fmt.Errorf("indicator %s is already registered", id)
+	}
+	IndicatorRegistry[id] = newIndicator
+	return nil
+}
+
+// Registers a user-defined indicator, which is calculated by an expression.
+// An existing user-defined indicator with the same id is replaced, built-in indicators cannot be replaced.
+func RegisterCustom(id indapi.IndicatorId, source string, overlay bool) error {
+	if id == "" {
+		return fmt.Errorf("missing name of custom indicator")
+	}
+	if _, ok := IndicatorRegistry[id]; ok && !IsCustom(id) {
+		return fmt.Errorf("%s is the name of a built-in indicator", id)
+	}
+	program, err := expression.Compile(source)
+	if err != nil {
+		return fmt.Errorf("invalid expression of indicator %s: %w", id, err)
+	}
+	IndicatorRegistry[id] = custom.NewFactory(id, program, overlay)
+	customIds[id] = struct{}{}
+	return nil
+}
+
+// Removes all user-defined indicators from the registry.
+func UnregisterCustom() {
+	for id := range customIds {
+		delete(IndicatorRegistry, id)
+	}
+	clear(customIds)
+}
+
+func IsCustom(id indapi.IndicatorId) bool {
+	_, ok := customIds[id]
+	return ok
+}
+
+func IsRegistered(id indapi.IndicatorId) bool {
+	_, ok := IndicatorRegistry[id]
+	return ok
+}
+
 func GetDefaultProperties(id indapi.IndicatorId) map[string]string {
 	d, ok := IndicatorRegistry[id]
 	if !ok {
@@ -43,6 +145,14 @@ func GetDefaultProperties(id indapi.IndicatorId) map[string]string {
 	return d().GetProperties()
 }
 
+func GetPropertyDescriptors(id indapi.IndicatorId) []indapi.PropertyDescriptor {
+	d, ok := IndicatorRegistry[id]
+	if !ok {
+		panic("invalid indicator name")
+	}
+	return d().GetPropertyDescriptors()
+}
+
 func GetSubPlotType(id indapi.IndicatorId) indapi.SubPlotType {
 	d, ok := IndicatorRegistry[id]
 	if !ok {
@@ -51,6 +161,32 @@ func GetSubPlotType(id indapi.IndicatorId) indapi.SubPlotType {
 	return d().GetSubPlotType()
 }
 
+// Returns the names of the output series which can be used as input of other indicators.
+func GetOutputNames(id indapi.IndicatorId) []string {
+	d, ok := IndicatorRegistry[id]
+	if !ok {
+		panic("invalid indicator name")
+	}
+	if provider, ok := d().(indapi.OutputProvider); ok {
+		return provider.GetOutputNames()
+	}
+	return nil
+}
+
+// Connects chained indicators to the indicators which provide their input, using the names of the indicators.
+// If the input is not found, the chained indicator has no input and is not plotted.
+func ConnectChained(list []indapi.IndicatorData, named map[string]indapi.IndicatorData) {
+	for _, ind := range list {
+		if c, ok := ind.(*Chained); ok {
+			source, ok := named[c.input.Name]
+			if !ok {
+				log.Printf("Input %s of indicator %s not found, the indicator is not plotted", c.input.Name, c.GetId())
+			}
+			c.Connect(source)
+		}
+	}
+}
+
 func GetList() indapi.IndicatorList {
 	l := indapi.IndicatorList(maps.Keys(IndicatorRegistry))
 	sort.Sort(l)
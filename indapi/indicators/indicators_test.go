@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package indicators
+
+import (
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/indicators/rsi"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterCustom(t *testing.T) {
+	defer UnregisterCustom()
+	assert.Error(t, RegisterCustom(rsi.Id, "close", false))
+	assert.Error(t, RegisterCustom("", "close", false))
+	assert.Error(t, RegisterCustom("invalid", "close +", false))
+	assert.False(t, IsRegistered("invalid"))
+
+	assert.NoError(t, RegisterCustom("spread", "high - low; close - open", true))
+	assert.True(t, IsCustom("spread"))
+	assert.Contains(t, GetList(), indapi.IndicatorId("spread"))
+	assert.Equal(t, indapi.SubPlotTypePrice, GetSubPlotType("spread"))
+	assert.Equal(t, []string{indapi.OutputValue, "value 2"}, GetOutputNames("spread"))
+
+	ind := Create("spread", nil, nil)
+	assert.Len(t, ind.GetColors(), 2)
+	data := newTestPlotData([]float64{1, 2})
+	data.Cache.OpenPrices = []float64{0, 1}
+	data.Cache.HighPrices = []float64{3, 4}
+	data.Cache.LowPrices = []float64{1, 1}
+	ind.Update(candles.CandleOneDay, data)
+	provider := ind.(indapi.OutputProvider)
+	assert.Equal(t, []float64{2, 3}, provider.GetOutput(indapi.OutputValue))
+	assert.Equal(t, []float64{1, 1}, provider.GetOutput("value 2"))
+
+	// Replace the expression.
+	assert.NoError(t, RegisterCustom("spread", "high - low", false))
+	assert.Equal(t, indapi.SubPlotTypeIndicator, GetSubPlotType("spread"))
+
+	UnregisterCustom()
+	assert.False(t, IsRegistered("spread"))
+	assert.True(t, IsRegistered(rsi.Id))
+}
+
+// Appends a candle to the cache. The close price oscillates, so that signals are detected.
+func appendTestCandle(data *indapi.PlotData, i int, price float64) {
+	c := &data.Cache
+	c.Timestamps = append(c.Timestamps, time.Date(2023, 8, 9, 14, i, 0, 0, time.UTC))
+	c.OpenPrices = append(c.OpenPrices, price-0.5)
+	c.HighPrices = append(c.HighPrices, price+1)
+	c.LowPrices = append(c.LowPrices, price-1)
+	c.ClosePrices = append(c.ClosePrices, price)
+	c.Volumes = append(c.Volumes, float64(100+i%7))
+	c.ChangeCount++
+}
+
+// Updating an indicator on every tick yields the same result as calculating it once.
+func TestIncrementalUpdate(t *testing.T) {
+	prices := make([]float64, 80)
+	for i := range prices {
+		prices[i] = 100 + 10*math.Sin(float64(i)/4) + float64(i%3)
+	}
+	full := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	for i, p := range prices {
+		appendTestCandle(&full, i, p)
+	}
+	for _, id := range GetList() {
+		properties := GetDefaultProperties(id)
+		if _, ok := properties[indapi.SignalsPropertyKey]; ok {
+			properties[indapi.SignalsPropertyKey] = "true"
+		}
+		expected := Create(id, properties, nil)
+		expected.Update(candles.CandleOneMinute, &full)
+		ind := Create(id, properties, nil)
+		data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+		for i, p := range prices {
+			// The live candle changes before the next candle is appended.
+			appendTestCandle(&data, i, p+2)
+			ind.Update(candles.CandleOneMinute, &data)
+			c := &data.Cache
+			c.HighPrices[i], c.LowPrices[i], c.ClosePrices[i] = p+1, p-1, p
+			c.ChangeCount++
+			ind.Update(candles.CandleOneMinute, &data)
+		}
+		if provider, ok := expected.(indapi.OutputProvider); ok {
+			for _, name := range provider.GetOutputNames() {
+				want, got := provider.GetOutput(name), ind.(indapi.OutputProvider).GetOutput(name)
+				assert.Equal(t, len(want), len(got), "%s %s", id, name)
+				for i := range min(len(want), len(got)) {
+					assert.InDelta(t, want[i], got[i], 1e-9, "%s %s index %d", id, name, i) // NaN is equal to NaN within delta
+				}
+			}
+		}
+		if provider, ok := expected.(indapi.SignalProvider); ok {
+			assert.Equal(t, provider.GetSignals(), ind.(indapi.SignalProvider).GetSignals(), id)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package keltner
+
+import (
+	"image/color"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	trueRange   []float64
+	atr         []float64
+	top         []float64
+	mid         []float64
+	bottom      []float64
+	updateState indapi.UpdateState
+	emaPeriods  int
+	atrPeriods  int
+	multiplier  float64
+	colors      []color.NRGBA
+}
+
+const Id = "keltner"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "EMA Periods", Label: "EMA Period", Description: "Number of candles of the middle line.", Type: indapi.PropertyTypeInt, Default: "20", Min: 1, Max: 1000},
+	{Key: "ATR Periods", Label: "ATR Period", Description: "Number of candles of the average true range.", Type: indapi.PropertyTypeInt, Default: "10", Min: 1, Max: 1000},
+	{Key: "Multiplier", Label: "Multiplier", Description: "Width of the channel in average true ranges.", Type: indapi.PropertyTypeFloat, Default: "2", Min: 0.1, Max: 20, Step: 0.5},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{emaPeriods: 20, atrPeriods: 10, multiplier: 2}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"EMA Periods": strconv.Itoa(d.emaPeriods),
+		"ATR Periods": strconv.Itoa(d.atrPeriods),
+		"Multiplier":  strconv.FormatFloat(d.multiplier, 'f', -1, 64),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "EMA Periods":
+			d.emaPeriods, _ = strconv.Atoi(value)
+		case "ATR Periods":
+			d.atrPeriods, _ = strconv.Atoi(value)
+		case "Multiplier":
+			d.multiplier, _ = strconv.ParseFloat(value, 64)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 3)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.update(data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices, start)
+	}
+}
+
+// Calculates the middle, upper and lower Keltner Channel lines.
+func Calculate(high []float64, low []float64, closing []float64, emaPeriods int, atrPeriods int, multiplier float64) (mid []float64, top []float64, bottom []float64) {
+	d := Indicator{emaPeriods: emaPeriods, atrPeriods: atrPeriods, multiplier: multiplier}
+	d.update(high, low, closing, 0)
+	return d.mid, d.top, d.bottom
+}
+
+// Updates the lines from index start on, after candles were appended or replaced.
+func (d *Indicator) update(high []float64, low []float64, closing []float64, start int) {
+	d.mid = series.UpdateEma(d.mid, closing, d.emaPeriods, start)
+	d.trueRange = series.UpdateTrueRange(d.trueRange, high, low, closing, start)
+	d.atr = series.UpdateRma(d.atr, d.trueRange, d.atrPeriods, start)
+	d.top = series.Resize(d.top, len(closing), start)
+	d.bottom = series.Resize(d.bottom, len(closing), start)
+	for i := start; i < len(closing); i++ {
+		d.top[i] = d.mid[i] + d.multiplier*d.atr[i]
+		d.bottom[i] = d.mid[i] - d.multiplier*d.atr[i]
+	}
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotBand(d.timestamps[0:len(d.top)], d.top, d.bottom, maxValue, d.resolution, indapi.GetFillColor(c[1]), gtx)
+	p.PlotLine(d.timestamps[0:len(d.top)], d.top, maxValue, d.resolution, c[0], gtx)
+	p.PlotLine(d.timestamps[0:len(d.mid)], d.mid, maxValue, d.resolution, c[1], gtx)
+	p.PlotLine(d.timestamps[0:len(d.bottom)], d.bottom, maxValue, d.resolution, c[2], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{"mid", "top", "bottom"}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	switch name {
+	case "mid":
+		return d.mid
+	case "top":
+		return d.top
+	case "bottom":
+		return d.bottom
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypePrice
+}
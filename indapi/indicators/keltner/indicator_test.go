@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package keltner
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	high := []float64{11, 12, 13}
+	low := []float64{9, 10, 11}
+	closing := []float64{10, 11, 12}
+	mid, top, bottom := Calculate(high, low, closing, 2, 2, 1.5)
+	assert.True(t, math.IsNaN(top[0]))
+	// EMA seeded with SMA(10, 11), true range is 2 throughout.
+	assert.InDelta(t, 10.5, mid[1], 1e-9)
+	assert.InDelta(t, 13.5, top[1], 1e-9)
+	assert.InDelta(t, 7.5, bottom[1], 1e-9)
+	assert.InDelta(t, 2*12.0/3+10.5/3, mid[2], 1e-9)
+}
@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package macd
+
+import (
+	"image/color"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution     candles.CandleResolution
+	timestamps     []time.Time
+	fast           []float64
+	slow           []float64
+	macd           []float64
+	signal         []float64
+	histogram      []float64
+	updateState    indapi.UpdateState
+	fastPeriods    int
+	slowPeriods    int
+	signalPeriods  int
+	source         string
+	signalsEnabled bool
+	signals        []indapi.Signal
+	colors         []color.NRGBA
+}
+
+const Id = "macd"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Fast Periods", Label: "Fast Period", Description: "Number of candles of the fast EMA.", Type: indapi.PropertyTypeInt, Default: "12", Min: 1, Max: 1000},
+	{Key: "Slow Periods", Label: "Slow Period", Description: "Number of candles of the slow EMA.", Type: indapi.PropertyTypeInt, Default: "26", Min: 1, Max: 1000},
+	{Key: "Signal Periods", Label: "Signal Period", Description: "Number of candles of the signal line EMA.", Type: indapi.PropertyTypeInt, Default: "9", Min: 1, Max: 1000},
+	{Key: "Source", Label: "Source", Type: indapi.PropertyTypeSource, Default: indapi.SourceClose},
+	indapi.SignalsProperty,
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{fastPeriods: 12, slowPeriods: 26, signalPeriods: 9, source: indapi.SourceClose}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Fast Periods":            strconv.Itoa(d.fastPeriods),
+		"Slow Periods":            strconv.Itoa(d.slowPeriods),
+		"Signal Periods":          strconv.Itoa(d.signalPeriods),
+		"Source":                  d.source,
+		indapi.SignalsPropertyKey: strconv.FormatBool(d.signalsEnabled),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Fast Periods":
+			d.fastPeriods, _ = strconv.Atoi(value)
+		case "Slow Periods":
+			d.slowPeriods, _ = strconv.Atoi(value)
+		case "Signal Periods":
+			d.signalPeriods, _ = strconv.Atoi(value)
+		case "Source":
+			d.source = value
+		case indapi.SignalsPropertyKey:
+			d.signalsEnabled, _ = strconv.ParseBool(value)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 4)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.update(indapi.GetSourceSeries(d.source, data), start)
+		if d.signalsEnabled {
+			d.signals = indapi.UpdateCrossovers(d.signals, data, d.macd, d.signal, "MACD crossed above signal line", "MACD crossed below signal line", start)
+		}
+	}
+}
+
+// Calculates the MACD line, the signal line and the histogram.
+func Calculate(values []float64, fastPeriods int, slowPeriods int, signalPeriods int) (macd []float64, signal []float64, histogram []float64) {
+	d := Indicator{fastPeriods: fastPeriods, slowPeriods: slowPeriods, signalPeriods: signalPeriods}
+	d.update(values, 0)
+	return d.macd, d.signal, d.histogram
+}
+
+// Updates the lines from index start on, after values were appended or replaced.
+func (d *Indicator) update(values []float64, start int) {
+	d.fast = series.UpdateEma(d.fast, values, d.fastPeriods, start)
+	d.slow = series.UpdateEma(d.slow, values, d.slowPeriods, start)
+	d.macd = series.Resize(d.macd, len(values), start)
+	for i := start; i < len(values); i++ {
+		d.macd[i] = d.fast[i] - d.slow[i]
+	}
+	d.signal = series.UpdateEma(d.signal, d.macd, d.signalPeriods, start)
+	d.histogram = series.Resize(d.histogram, len(values), start)
+	for i := start; i < len(values); i++ {
+		d.histogram[i] = d.macd[i] - d.signal[i]
+	}
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotHorizontalLine(0, maxValue, indapi.GetReferenceLineColor(c[0]), gtx)
+	// Use the default bar colors of the histogram unless configured.
+	p.PlotHistogram(d.timestamps[0:len(d.histogram)], d.histogram, maxValue, d.resolution, d.colors[2], d.colors[3], gtx)
+	p.PlotLine(d.timestamps[0:len(d.macd)], d.macd, maxValue, d.resolution, c[0], gtx)
+	p.PlotLine(d.timestamps[0:len(d.signal)], d.signal, maxValue, d.resolution, c[1], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{"macd", "signal", "histogram"}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	switch name {
+	case "macd":
+		return d.macd
+	case "signal":
+		return d.signal
+	case "histogram":
+		return d.histogram
+	}
+	return nil
+}
+
+func (d *Indicator) GetSignals() []indapi.Signal {
+	return d.signals
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
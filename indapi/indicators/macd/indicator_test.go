@@ -0,0 +1,35 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package macd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	// An EMA of a linear series lags behind by (period - 1) / 2,
+	// so the MACD of a linear series is (26 - 1) / 2 - (12 - 1) / 2 = 7.
+	values := make([]float64, 60)
+	for i := range values {
+		values[i] = 100 + float64(i)
+	}
+	macd, signal, histogram := Calculate(values, 12, 26, 9)
+	assert.True(t, math.IsNaN(macd[24]))
+	assert.InDelta(t, 7, macd[25], 1e-9)
+	assert.InDelta(t, 7, macd[59], 1e-9)
+	assert.True(t, math.IsNaN(signal[32]))
+	assert.InDelta(t, 7, signal[33], 1e-9)
+	assert.InDelta(t, 0, histogram[59], 1e-9)
+}
+
+func TestCalculateConstant(t *testing.T) {
+	values := []float64{5, 5, 5, 5, 5}
+	macd, signal, histogram := Calculate(values, 2, 3, 2)
+	assert.Equal(t, 0.0, macd[4])
+	assert.Equal(t, 0.0, signal[4])
+	assert.Equal(t, 0.0, histogram[4])
+}
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package mfi
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	result      []float64
+	updateState indapi.UpdateState
+	numPeriods  int
+	overbought  float64
+	oversold    float64
+	colors      []color.NRGBA
+}
+
+const Id = "mfi"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Period", Type: indapi.PropertyTypeInt, Default: "14", Min: 1, Max: 1000},
+	{Key: "Overbought", Label: "Overbought", Type: indapi.PropertyTypeFloat, Default: "80", Min: 0, Max: 100},
+	{Key: "Oversold", Label: "Oversold", Type: indapi.PropertyTypeFloat, Default: "20", Min: 0, Max: 100},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{numPeriods: 14, overbought: 80, oversold: 20}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods": strconv.Itoa(d.numPeriods),
+		"Overbought":   strconv.FormatFloat(d.overbought, 'f', -1, 64),
+		"Oversold":     strconv.FormatFloat(d.oversold, 'f', -1, 64),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		case "Overbought":
+			d.overbought, _ = strconv.ParseFloat(value, 64)
+		case "Oversold":
+			d.oversold, _ = strconv.ParseFloat(value, 64)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.result = Calculate(indapi.GetSourceSeries(indapi.SourceHLC3, data), data.Cache.Volumes, d.numPeriods)
+	}
+}
+
+// Calculates the money flow index, a volume weighted RSI of the typical price.
+func Calculate(typicalPrices []float64, volumes []float64, period int) []float64 {
+	n := min(len(typicalPrices), len(volumes))
+	positiveFlow := series.NewNaN(n)
+	negativeFlow := series.NewNaN(n)
+	for i := 1; i < n; i++ {
+		flow := typicalPrices[i] * volumes[i]
+		positiveFlow[i], negativeFlow[i] = 0, 0
+		if typicalPrices[i] > typicalPrices[i-1] {
+			positiveFlow[i] = flow
+		} else if typicalPrices[i] < typicalPrices[i-1] {
+			negativeFlow[i] = flow
+		}
+	}
+	positiveSum := series.Sum(positiveFlow, period)
+	negativeSum := series.Sum(negativeFlow, period)
+	result := series.NewNaN(n)
+	for i := range result {
+		total := positiveSum[i] + negativeSum[i]
+		switch {
+		case math.IsNaN(total):
+		case total == 0:
+			result[i] = 50
+		default:
+			result[i] = 100 * positiveSum[i] / total
+		}
+	}
+	return result
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	refColor := indapi.GetReferenceLineColor(c[0])
+	p.PlotHorizontalLine(d.overbought, maxValue, refColor, gtx)
+	p.PlotHorizontalLine(d.oversold, maxValue, refColor, gtx)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package mfi
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	prices := []float64{10, 11, 10, 12}
+	volumes := []float64{1, 2, 3, 1}
+	result := Calculate(prices, volumes, 2)
+	assert.True(t, math.IsNaN(result[0]))
+	assert.True(t, math.IsNaN(result[1]))
+	// Positive flow 22, negative flow 30.
+	assert.InDelta(t, 100*22.0/52, result[2], 1e-9)
+	// Negative flow 30, positive flow 12.
+	assert.InDelta(t, 100*12.0/42, result[3], 1e-9)
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+// Package movingaverage contains moving averages which only differ in their calculation.
+package movingaverage
+
+import (
+	"image/color"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	id indapi.IndicatorId
+	// Weights of chained exponential moving averages, or nil for the weighted moving average.
+	emaWeights  []float64
+	emas        [][]float64
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	result      []float64
+	updateState indapi.UpdateState
+	numPeriods  int
+	source      string
+	colors      []color.NRGBA
+}
+
+const (
+	EmaId  = "ema"
+	WmaId  = "wma"
+	DemaId = "dema"
+	TemaId = "tema"
+)
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Period", Description: "Number of candles of the moving average.", Type: indapi.PropertyTypeInt, Default: "20", Min: 1, Max: 1000},
+	{Key: "Source", Label: "Source", Type: indapi.PropertyTypeSource, Default: indapi.SourceClose},
+}
+
+func newIndicator(id indapi.IndicatorId, emaWeights []float64) indapi.IndicatorData {
+	return &Indicator{id: id, emaWeights: emaWeights, emas: make([][]float64, len(emaWeights)), numPeriods: 20, source: indapi.SourceClose}
+}
+
+func NewEma() indapi.IndicatorData {
+	return newIndicator(EmaId, []float64{1})
+}
+
+func NewWma() indapi.IndicatorData {
+	return newIndicator(WmaId, nil)
+}
+
+// The weights match series.Dema.
+func NewDema() indapi.IndicatorData {
+	return newIndicator(DemaId, []float64{2, -1})
+}
+
+// The weights match series.Tema.
+func NewTema() indapi.IndicatorData {
+	return newIndicator(TemaId, []float64{3, -3, 1})
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return d.id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods": strconv.Itoa(d.numPeriods),
+		"Source":       d.source,
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		case "Source":
+			d.source = value
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.update(indapi.GetSourceSeries(d.source, data), start)
+	}
+}
+
+// Updates the moving average from index start on, after values were appended or replaced.
+func (d *Indicator) update(values []float64, start int) {
+	if d.emaWeights == nil {
+		d.result = series.UpdateWma(d.result, values, d.numPeriods, start)
+		return
+	}
+	// Each exponential moving average is calculated from the previous one.
+	input := values
+	for k := range d.emas {
+		d.emas[k] = series.UpdateEma(d.emas[k], input, d.numPeriods, start)
+		input = d.emas[k]
+	}
+	d.result = series.Resize(d.result, len(values), start)
+	for i := start; i < len(values); i++ {
+		d.result[i] = 0
+		for k, w := range d.emaWeights {
+			d.result[i] += w * d.emas[k][i]
+		}
+	}
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypePrice
+}
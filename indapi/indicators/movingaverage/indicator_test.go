@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package movingaverage
+
+import (
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/series"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdate(t *testing.T) {
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex), DataLastChange: time.Now()}
+	data.Cache.ClosePrices = []float64{1, 2, 3, 4}
+	data.Cache.HighPrices = []float64{2, 4, 6, 8}
+	for _, create := range []func() indapi.IndicatorData{NewEma, NewWma, NewDema, NewTema} {
+		ind := create()
+		assert.NoError(t, ind.SetProperties(map[string]string{"Time Periods": "2", "Source": indapi.SourceHigh}))
+		ind.Update(0, &data)
+		result := ind.(*Indicator).result
+		assert.Equal(t, 4, len(result), ind.GetId())
+		// Warm-up values are not available.
+		assert.True(t, math.IsNaN(result[0]), ind.GetId())
+		assert.False(t, math.IsNaN(result[3]), ind.GetId())
+	}
+	assert.Equal(t, indapi.IndicatorId(TemaId), NewTema().GetId())
+}
+
+func TestUpdateIncremental(t *testing.T) {
+	closing := []float64{3, 1, 4, 1, 5, 9, 2, 6, 5, 3}
+	expected := map[indapi.IndicatorId][]float64{
+		EmaId:  series.Ema(closing, 2),
+		WmaId:  series.Wma(closing, 2),
+		DemaId: series.Dema(closing, 2),
+		TemaId: series.Tema(closing, 2),
+	}
+	for _, create := range []func() indapi.IndicatorData{NewEma, NewWma, NewDema, NewTema} {
+		ind := create()
+		assert.NoError(t, ind.SetProperties(map[string]string{"Time Periods": "2"}))
+		data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+		// Candles are appended one by one, and the last candle is replaced.
+		for i := range closing {
+			data.Cache.Timestamps = append(data.Cache.Timestamps, time.Unix(int64(i), 0))
+			data.Cache.ClosePrices = append(data.Cache.ClosePrices, 0)
+			data.Cache.ChangeCount++
+			ind.Update(0, &data)
+			data.Cache.ClosePrices[i] = closing[i]
+			data.Cache.ChangeCount++
+			ind.Update(0, &data)
+		}
+		result := ind.(*Indicator).result
+		assert.Equal(t, len(closing), len(result), ind.GetId())
+		for i := range result {
+			if math.IsNaN(expected[ind.GetId()][i]) {
+				assert.True(t, math.IsNaN(result[i]), ind.GetId())
+			} else {
+				assert.InDelta(t, expected[ind.GetId()][i], result[i], 1e-9, ind.GetId())
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package obv
+
+import (
+	"image/color"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	result      []float64
+	updateState indapi.UpdateState
+	colors      []color.NRGBA
+}
+
+const Id = "obv"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.result = update(d.result, data.Cache.ClosePrices, data.Cache.Volumes, start)
+	}
+}
+
+// Calculates the on-balance volume, which adds the volume on up candles and subtracts it on down candles.
+func Calculate(closePrices []float64, volumes []float64) []float64 {
+	return update(nil, closePrices, volumes, 0)
+}
+
+// Updates the on-balance volume from index start on, after candles were appended or replaced.
+func update(result []float64, closePrices []float64, volumes []float64, start int) []float64 {
+	n := min(len(closePrices), len(volumes))
+	result = series.Resize(result, n, start)
+	for i := start; i < n; i++ {
+		switch {
+		case i == 0:
+			result[i] = 0
+		case closePrices[i] > closePrices[i-1]:
+			result[i] = result[i-1] + volumes[i]
+		case closePrices[i] < closePrices[i-1]:
+			result[i] = result[i-1] - volumes[i]
+		default:
+			result[i] = result[i-1]
+		}
+	}
+	return result
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
@@ -0,0 +1,15 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package obv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	result := Calculate([]float64{10, 11, 11, 9, 12}, []float64{100, 200, 300, 400, 500})
+	assert.Equal(t, []float64{0, 200, 200, -200, 300}, result)
+}
@@ -0,0 +1,352 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package pivots
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"slices"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution     candles.CandleResolution
+	timestamps     []time.Time
+	periods        []Period
+	swingLevels    []SwingLevel
+	outputs        [numLevels][]float64
+	updateState    indapi.UpdateState
+	method         string
+	period         string
+	swingLevelsOn  bool
+	swingStrength  int
+	maxSwingLevels int
+	colors         []color.NRGBA
+}
+
+const Id = "pivots"
+
+const (
+	MethodClassic   = "classic"
+	MethodFibonacci = "fibonacci"
+	MethodCamarilla = "camarilla"
+	MethodWoodie    = "woodie"
+)
+
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// Pivot levels, levels which are not defined by a method are NaN.
+type Levels [numLevels]float64
+
+const (
+	LevelPivot = iota
+	LevelR1
+	LevelS1
+	LevelR2
+	LevelS2
+	LevelR3
+	LevelS3
+	LevelR4
+	LevelS4
+	numLevels
+)
+
+// Output names and labels of the levels.
+var levelNames = [numLevels]string{"pivot", "r1", "s1", "r2", "s2", "r3", "s3", "r4", "s4"}
+var levelLabels = [numLevels]string{"P", "R1", "S1", "R2", "S2", "R3", "S3", "R4", "S4"}
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Method", Label: "Method", Type: indapi.PropertyTypeEnum, Default: MethodClassic, Options: []string{MethodClassic, MethodFibonacci, MethodCamarilla, MethodWoodie}},
+	{Key: "Period", Label: "Period", Description: "Pivot levels are calculated from the high, low and close of the previous period.", Type: indapi.PropertyTypeEnum, Default: PeriodDay, Options: []string{PeriodDay, PeriodWeek, PeriodMonth}},
+	{Key: "Swing Levels", Label: "Support/Resistance", Description: "Show support and resistance levels at swing lows and highs which were not broken yet.", Type: indapi.PropertyTypeBool, Default: "true"},
+	{Key: "Swing Strength", Label: "Swing Strength", Description: "Number of candles on each side of a swing high or low.", Type: indapi.PropertyTypeInt, Default: "5", Min: 1, Max: 100},
+	{Key: "Max Swing Levels", Label: "Max Levels", Description: "Maximum number of support and of resistance levels, the most recent levels are shown.", Type: indapi.PropertyTypeInt, Default: "3", Min: 1, Max: 20},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{method: MethodClassic, period: PeriodDay, swingLevelsOn: true, swingStrength: 5, maxSwingLevels: 3}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Method":           d.method,
+		"Period":           d.period,
+		"Swing Levels":     strconv.FormatBool(d.swingLevelsOn),
+		"Swing Strength":   strconv.Itoa(d.swingStrength),
+		"Max Swing Levels": strconv.Itoa(d.maxSwingLevels),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Method":
+			d.method = value
+		case "Period":
+			d.period = value
+		case "Swing Levels":
+			d.swingLevelsOn, _ = strconv.ParseBool(value)
+		case "Swing Strength":
+			d.swingStrength, _ = strconv.Atoi(value)
+		case "Max Swing Levels":
+			d.maxSwingLevels, _ = strconv.Atoi(value)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+// Colors of the pivot, the resistance levels and the support levels.
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 3)
+}
+
+// All levels are calculated again whenever the candles changed. This cannot be done incrementally,
+// because a swing level of an old candle is removed as soon as a new candle closes beyond it.
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		c := &data.Cache
+		d.resolution = r
+		d.timestamps = c.Timestamps
+		d.periods = CalculatePivots(c.Timestamps, c.HighPrices, c.LowPrices, c.ClosePrices, GetPeriodStartFunc(d.period, data.Session), d.method)
+		d.swingLevels = nil
+		if d.swingLevelsOn {
+			d.swingLevels = CalculateSwingLevels(c.HighPrices, c.LowPrices, c.ClosePrices, d.swingStrength, d.maxSwingLevels)
+		}
+		n := len(c.Timestamps)
+		for l := range d.outputs {
+			d.outputs[l] = series.NewNaN(n)
+			for _, p := range d.periods {
+				for i := p.Start; i < p.End; i++ {
+					d.outputs[l][i] = p.Levels[l]
+				}
+			}
+		}
+	}
+}
+
+// Returns a function which maps a candle time to the start of its pivot period. Periods are based on the
+// trading session, so that e.g. pre-market candles belong to the day of the following session start.
+func GetPeriodStartFunc(period string, session candles.Session) func(t time.Time) time.Time {
+	switch period {
+	case PeriodWeek:
+		return func(t time.Time) time.Time {
+			start := session.GetSessionStart(t)
+			return start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		}
+	case PeriodMonth:
+		return func(t time.Time) time.Time {
+			start := session.GetSessionStart(t)
+			return start.AddDate(0, 0, 1-start.Day())
+		}
+	default:
+		return session.GetSessionStart
+	}
+}
+
+// Candles of a period, with pivot levels based on the previous period.
+type Period struct {
+	// Index of the first candle and index after the last candle.
+	Start  int
+	End    int
+	Levels Levels
+}
+
+// Calculates the pivot levels of each period, using the high, low and close of the previous period.
+// The first period is skipped, because there is no previous period.
+func CalculatePivots(timestamps []time.Time, high []float64, low []float64, closing []float64, getPeriodStart func(t time.Time) time.Time, method string) []Period {
+	n := min(len(timestamps), len(high), len(low), len(closing))
+	var periods []Period
+	prevHigh, prevLow, prevClose := math.NaN(), math.NaN(), math.NaN()
+	periodHigh, periodLow := math.Inf(-1), math.Inf(1)
+	for i := 0; i < n; i++ {
+		if i == 0 || !getPeriodStart(timestamps[i]).Equal(getPeriodStart(timestamps[i-1])) {
+			if i > 0 {
+				prevHigh, prevLow, prevClose = periodHigh, periodLow, closing[i-1]
+				periodHigh, periodLow = math.Inf(-1), math.Inf(1)
+			}
+			if len(periods) > 0 {
+				periods[len(periods)-1].End = i
+			}
+			if !math.IsNaN(prevClose) {
+				periods = append(periods, Period{Start: i, Levels: CalculateLevels(method, prevHigh, prevLow, prevClose)})
+			}
+		}
+		periodHigh = math.Max(periodHigh, high[i])
+		periodLow = math.Min(periodLow, low[i])
+	}
+	if len(periods) > 0 {
+		periods[len(periods)-1].End = n
+	}
+	return periods
+}
+
+// Calculates the pivot levels from the high, low and close of the previous period.
+func CalculateLevels(method string, high float64, low float64, closing float64) Levels {
+	var l Levels
+	for i := range l {
+		l[i] = math.NaN()
+	}
+	r := high - low
+	p := (high + low + closing) / 3
+	switch method {
+	case MethodFibonacci:
+		l[LevelPivot] = p
+		l[LevelR1], l[LevelS1] = p+0.382*r, p-0.382*r
+		l[LevelR2], l[LevelS2] = p+0.618*r, p-0.618*r
+		l[LevelR3], l[LevelS3] = p+r, p-r
+	case MethodCamarilla:
+		l[LevelPivot] = p
+		l[LevelR1], l[LevelS1] = closing+r*1.1/12, closing-r*1.1/12
+		l[LevelR2], l[LevelS2] = closing+r*1.1/6, closing-r*1.1/6
+		l[LevelR3], l[LevelS3] = closing+r*1.1/4, closing-r*1.1/4
+		l[LevelR4], l[LevelS4] = closing+r*1.1/2, closing-r*1.1/2
+	case MethodWoodie:
+		p = (high + low + 2*closing) / 4
+		fallthrough
+	default:
+		l[LevelPivot] = p
+		l[LevelR1], l[LevelS1] = 2*p-low, 2*p-high
+		l[LevelR2], l[LevelS2] = p+r, p-r
+		l[LevelR3], l[LevelS3] = high+2*(p-low), low-2*(high-p)
+	}
+	return l
+}
+
+// Support or resistance level at a swing low or high.
+type SwingLevel struct {
+	// Index of the swing candle.
+	Start      int
+	Value      float64
+	Resistance bool
+}
+
+// Detects support and resistance levels at swing lows and highs. A swing high is a high which is above
+// the highs of strength candles on each side, a swing low vice versa. Levels are removed as soon as a
+// candle closes beyond them. The most recent maxLevels support and resistance levels are returned, ordered by index.
+func CalculateSwingLevels(high []float64, low []float64, closing []float64, strength int, maxLevels int) []SwingLevel {
+	n := min(len(high), len(low), len(closing))
+	var levels []SwingLevel
+	for i := strength; i < n-strength; i++ {
+		swingHigh, swingLow := true, true
+		for j := i - strength; j <= i+strength && (swingHigh || swingLow); j++ {
+			if j == i {
+				continue
+			}
+			// Equal values to the left are allowed, so that flat tops are detected once.
+			if high[j] > high[i] || (j > i && high[j] == high[i]) {
+				swingHigh = false
+			}
+			if low[j] < low[i] || (j > i && low[j] == low[i]) {
+				swingLow = false
+			}
+		}
+		if swingHigh && !isBroken(closing[i+1:], high[i], true) {
+			levels = append(levels, SwingLevel{Start: i, Value: high[i], Resistance: true})
+		}
+		if swingLow && !isBroken(closing[i+1:], low[i], false) {
+			levels = append(levels, SwingLevel{Start: i, Value: low[i], Resistance: false})
+		}
+	}
+	var result []SwingLevel
+	numResistance, numSupport := 0, 0
+	for i := len(levels) - 1; i >= 0; i-- {
+		if levels[i].Resistance && numResistance < maxLevels {
+			numResistance++
+			result = append(result, levels[i])
+		} else if !levels[i].Resistance && numSupport < maxLevels {
+			numSupport++
+			result = append(result, levels[i])
+		}
+	}
+	slices.Reverse(result)
+	return result
+}
+
+func isBroken(closing []float64, value float64, resistance bool) bool {
+	for _, c := range closing {
+		if (resistance && c > value) || (!resistance && c < value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	for _, period := range d.periods {
+		start, end := d.timestamps[period.Start], d.timestamps[period.End-1]
+		for l, value := range period.Levels {
+			p.PlotHorizontalSegment(start, end, value, maxValue, d.resolution, levelColor(c, l), gtx)
+		}
+	}
+	if len(d.periods) > 0 {
+		// Label the levels of the current period.
+		last := d.periods[len(d.periods)-1]
+		timestamps := []time.Time{d.timestamps[last.Start]}
+		for l, value := range last.Levels {
+			p.PlotLabels(timestamps, []float64{value}, []string{levelLabels[l]}, maxValue, d.resolution, levelColor(c, l), indapi.LabelAbove, gtx)
+		}
+	}
+	if len(d.timestamps) > 0 {
+		end := d.timestamps[len(d.timestamps)-1]
+		for _, s := range d.swingLevels {
+			color := c[2]
+			if s.Resistance {
+				color = c[1]
+			}
+			p.PlotHorizontalSegment(d.timestamps[s.Start], end, s.Value, maxValue, d.resolution, indapi.GetReferenceLineColor(color), gtx)
+		}
+	}
+}
+
+func levelColor(c []color.NRGBA, level int) color.NRGBA {
+	switch {
+	case level == LevelPivot:
+		return c[0]
+	case level%2 == 1:
+		return c[1]
+	default:
+		return c[2]
+	}
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return slices.Clone(levelNames[:])
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	for l, levelName := range levelNames {
+		if levelName == name {
+			return d.outputs[l]
+		}
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypePrice
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package pivots
+
+import (
+	"math"
+	"maystocks/indapi/candles"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateLevels(t *testing.T) {
+	l := CalculateLevels(MethodClassic, 12, 8, 11)
+	assert.InDelta(t, 31.0/3, l[LevelPivot], 1e-9)
+	assert.InDelta(t, 2*31.0/3-8, l[LevelR1], 1e-9)
+	assert.InDelta(t, 2*31.0/3-12, l[LevelS1], 1e-9)
+	assert.InDelta(t, 31.0/3+4, l[LevelR2], 1e-9)
+	assert.InDelta(t, 12+2*(31.0/3-8), l[LevelR3], 1e-9)
+	assert.True(t, math.IsNaN(l[LevelR4]))
+
+	l = CalculateLevels(MethodFibonacci, 12, 8, 11)
+	assert.InDelta(t, 31.0/3+0.618*4, l[LevelR2], 1e-9)
+	assert.InDelta(t, 31.0/3-4, l[LevelS3], 1e-9)
+
+	l = CalculateLevels(MethodCamarilla, 12, 8, 11)
+	assert.InDelta(t, 11+4*1.1/12, l[LevelR1], 1e-9)
+	assert.InDelta(t, 11-4*1.1/2, l[LevelS4], 1e-9)
+
+	l = CalculateLevels(MethodWoodie, 12, 8, 11)
+	assert.InDelta(t, 10.5, l[LevelPivot], 1e-9)
+	assert.InDelta(t, 13, l[LevelR1], 1e-9)
+}
+
+// Compares the levels of the classic method, which does not define R4 and S4.
+func assertClassicLevels(t *testing.T, expected Levels, actual Levels) {
+	assert.Equal(t, expected[:LevelR4], actual[:LevelR4])
+	assert.True(t, math.IsNaN(actual[LevelR4]) && math.IsNaN(actual[LevelS4]))
+}
+
+func TestCalculatePivots(t *testing.T) {
+	// Hourly candles in New York, the session starts at 9:30.
+	location, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+	session := candles.Session{Location: location, OpenTime: 9*time.Hour + 30*time.Minute}
+	timestamps := []time.Time{
+		time.Date(2023, 8, 10, 9, 30, 0, 0, location),
+		time.Date(2023, 8, 10, 15, 30, 0, 0, location),
+		// Pre-market belongs to the next session.
+		time.Date(2023, 8, 11, 8, 0, 0, 0, location),
+		time.Date(2023, 8, 11, 9, 30, 0, 0, location),
+		time.Date(2023, 8, 14, 9, 30, 0, 0, location),
+	}
+	high := []float64{12, 11, 13, 14, 10}
+	low := []float64{9, 8, 12, 11, 9}
+	closing := []float64{10, 11, 12, 13, 9.5}
+
+	periods := CalculatePivots(timestamps, high, low, closing, GetPeriodStartFunc(PeriodDay, session), MethodClassic)
+	assert.Equal(t, 2, len(periods))
+	assert.Equal(t, 2, periods[0].Start)
+	assert.Equal(t, 4, periods[0].End)
+	assertClassicLevels(t, CalculateLevels(MethodClassic, 12, 8, 11), periods[0].Levels)
+	assert.Equal(t, 4, periods[1].Start)
+	assert.Equal(t, 5, periods[1].End)
+	assertClassicLevels(t, CalculateLevels(MethodClassic, 14, 11, 13), periods[1].Levels)
+
+	// Weekly periods, Monday starts a new week.
+	periods = CalculatePivots(timestamps, high, low, closing, GetPeriodStartFunc(PeriodWeek, session), MethodClassic)
+	assert.Equal(t, 1, len(periods))
+	assert.Equal(t, 4, periods[0].Start)
+	assertClassicLevels(t, CalculateLevels(MethodClassic, 14, 8, 13), periods[0].Levels)
+}
+
+func TestCalculateSwingLevels(t *testing.T) {
+	high := []float64{5, 6, 8, 6, 5, 7, 9, 7, 6, 5}
+	low := []float64{4, 3, 5, 4, 2, 5, 6, 5, 4, 4.5}
+	closing := []float64{4.5, 5, 7, 5, 3, 6, 7, 6, 5, 4.8}
+	levels := CalculateSwingLevels(high, low, closing, 2, 3)
+	assert.Equal(t, []SwingLevel{
+		{Start: 2, Value: 8, Resistance: true},
+		{Start: 4, Value: 2, Resistance: false},
+		{Start: 6, Value: 9, Resistance: true},
+	}, levels)
+
+	// The swing high at 8 is broken by a close above it.
+	high[7], closing[7] = 8.6, 8.5
+	levels = CalculateSwingLevels(high, low, closing, 2, 3)
+	assert.Equal(t, []SwingLevel{
+		{Start: 4, Value: 2, Resistance: false},
+		{Start: 6, Value: 9, Resistance: true},
+	}, levels)
+
+	// Only the most recent level of each kind
+	levels = CalculateSwingLevels(high, low, closing, 1, 1)
+	assert.Equal(t, []SwingLevel{
+		{Start: 6, Value: 9, Resistance: true},
+		{Start: 8, Value: 4, Resistance: false},
+	}, levels)
+}
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package psar
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	result      []float64
+	updateState indapi.UpdateState
+	step        float64
+	maxStep     float64
+	colors      []color.NRGBA
+}
+
+const Id = "psar"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Step", Label: "Step", Description: "Increment of the acceleration factor.", Type: indapi.PropertyTypeFloat, Default: "0.02", Min: 0.001, Max: 1, Step: 0.01},
+	{Key: "Max Step", Label: "Maximum", Description: "Maximum acceleration factor.", Type: indapi.PropertyTypeFloat, Default: "0.2", Min: 0.001, Max: 1, Step: 0.01},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{step: 0.02, maxStep: 0.2}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Step":     strconv.FormatFloat(d.step, 'f', -1, 64),
+		"Max Step": strconv.FormatFloat(d.maxStep, 'f', -1, 64),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Step":
+			d.step, _ = strconv.ParseFloat(value, 64)
+		case "Max Step":
+			d.maxStep, _ = strconv.ParseFloat(value, 64)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.result = Calculate(data.Cache.HighPrices, data.Cache.LowPrices, d.step, d.maxStep)
+	}
+}
+
+// Calculates Wilder's parabolic stop and reverse.
+// The first value is NaN, because the initial trend is derived from the first two candles.
+func Calculate(high []float64, low []float64, step float64, maxStep float64) []float64 {
+	n := min(len(high), len(low))
+	result := series.NewNaN(n)
+	if n < 2 {
+		return result
+	}
+	up := high[1]+low[1] >= high[0]+low[0]
+	var sar, extreme float64
+	if up {
+		sar, extreme = low[0], high[0]
+	} else {
+		sar, extreme = high[0], low[0]
+	}
+	af := step
+	for i := 1; i < n; i++ {
+		sar += af * (extreme - sar)
+		if up {
+			// The SAR may not be above the lows of the previous two candles.
+			sar = math.Min(sar, low[i-1])
+			if i > 1 {
+				sar = math.Min(sar, low[i-2])
+			}
+			if low[i] < sar {
+				up = false
+				sar, extreme, af = extreme, low[i], step
+			} else if high[i] > extreme {
+				extreme = high[i]
+				af = math.Min(af+step, maxStep)
+			}
+		} else {
+			sar = math.Max(sar, high[i-1])
+			if i > 1 {
+				sar = math.Max(sar, high[i-2])
+			}
+			if high[i] > sar {
+				up = true
+				sar, extreme, af = extreme, high[i], step
+			} else if low[i] < extreme {
+				extreme = low[i]
+				af = math.Min(af+step, maxStep)
+			}
+		}
+		result[i] = sar
+	}
+	return result
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotMarkers(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], indapi.MarkerCircle, gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypePrice
+}
@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package psar
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	high := []float64{10, 11, 12, 13, 12, 9}
+	low := []float64{9, 10, 11, 12, 11, 8}
+	result := Calculate(high, low, 0.02, 0.2)
+	assert.True(t, math.IsNaN(result[0]))
+	// Uptrend, starting at the first low. The SAR may not be above the previous two lows.
+	assert.Equal(t, 9.0, result[1])
+	assert.Equal(t, 9.0, result[2])
+	// New extreme 12 at index 2, acceleration factor 0.06 from now on.
+	assert.InDelta(t, 9+0.06*(12-9), result[3], 1e-9)
+	for i := 1; i < 5; i++ {
+		assert.Less(t, result[i], low[i])
+	}
+	// Reversal to a downtrend, the SAR is set to the previous extreme high.
+	assert.Equal(t, 13.0, result[5])
+}
+
+func TestCalculateShort(t *testing.T) {
+	result := Calculate([]float64{1}, []float64{1}, 0.02, 0.2)
+	assert.True(t, math.IsNaN(result[0]))
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package roc
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	result      []float64
+	updateState indapi.UpdateState
+	numPeriods  int
+	source      string
+	colors      []color.NRGBA
+}
+
+const Id = "roc"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Period", Description: "Number of candles to compare with.", Type: indapi.PropertyTypeInt, Default: "9", Min: 1, Max: 1000},
+	{Key: "Source", Label: "Source", Type: indapi.PropertyTypeSource, Default: indapi.SourceClose},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{numPeriods: 9, source: indapi.SourceClose}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods": strconv.Itoa(d.numPeriods),
+		"Source":       d.source,
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		case "Source":
+			d.source = value
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.result = update(d.result, indapi.GetSourceSeries(d.source, data), d.numPeriods, start)
+	}
+}
+
+// Calculates the rate of change in percent compared to the value period candles ago.
+func Calculate(values []float64, period int) []float64 {
+	return update(nil, values, period, 0)
+}
+
+// Updates the rate of change from index start on, after values were appended or replaced.
+func update(result []float64, values []float64, period int, start int) []float64 {
+	result = series.Resize(result, len(values), start)
+	for i := max(start, series.FirstValid(values)+period); i < len(values); i++ {
+		if prev := values[i-period]; prev != 0 && !math.IsNaN(prev) {
+			result[i] = 100 * (values[i] - prev) / prev
+		}
+	}
+	return result
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotHorizontalLine(0, maxValue, indapi.GetReferenceLineColor(c[0]), gtx)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
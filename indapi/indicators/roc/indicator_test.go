@@ -0,0 +1,18 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package roc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	result := Calculate([]float64{100, 110, 120, 99}, 2)
+	assert.True(t, math.IsNaN(result[1]))
+	assert.InDelta(t, 20, result[2], 1e-9)
+	assert.InDelta(t, -10, result[3], 1e-9)
+}
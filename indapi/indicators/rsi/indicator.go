@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package rsi
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"slices"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution     candles.CandleResolution
+	timestamps     []time.Time
+	gains          []float64
+	losses         []float64
+	avgGain        []float64
+	avgLoss        []float64
+	result         []float64
+	updateState    indapi.UpdateState
+	numPeriods     int
+	overbought     float64
+	oversold       float64
+	source         string
+	signalsEnabled bool
+	signals        []indapi.Signal
+	colors         []color.NRGBA
+}
+
+const Id = "rsi"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Period", Description: "Number of candles of Wilder's smoothing.", Type: indapi.PropertyTypeInt, Default: "14", Min: 1, Max: 1000},
+	{Key: "Overbought", Label: "Overbought", Type: indapi.PropertyTypeFloat, Default: "70", Min: 0, Max: 100},
+	{Key: "Oversold", Label: "Oversold", Type: indapi.PropertyTypeFloat, Default: "30", Min: 0, Max: 100},
+	{Key: "Source", Label: "Source", Type: indapi.PropertyTypeSource, Default: indapi.SourceClose},
+	indapi.SignalsProperty,
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{numPeriods: 14, overbought: 70, oversold: 30, source: indapi.SourceClose}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods":            strconv.Itoa(d.numPeriods),
+		"Overbought":              strconv.FormatFloat(d.overbought, 'f', -1, 64),
+		"Oversold":                strconv.FormatFloat(d.oversold, 'f', -1, 64),
+		"Source":                  d.source,
+		indapi.SignalsPropertyKey: strconv.FormatBool(d.signalsEnabled),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		case "Overbought":
+			d.overbought, _ = strconv.ParseFloat(value, 64)
+		case "Oversold":
+			d.oversold, _ = strconv.ParseFloat(value, 64)
+		case "Source":
+			d.source = value
+		case indapi.SignalsPropertyKey:
+			d.signalsEnabled, _ = strconv.ParseBool(value)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.update(indapi.GetSourceSeries(d.source, data), start)
+		if d.signalsEnabled {
+			d.signals = indapi.TruncateSignals(d.signals, data, start)
+			signals := append(indapi.UpdateLevelCrossovers(nil, data, d.result, d.oversold, "RSI left oversold", "", start),
+				indapi.UpdateLevelCrossovers(nil, data, d.result, d.overbought, "", "RSI left overbought", start)...)
+			slices.SortStableFunc(signals, func(a, b indapi.Signal) int { return a.Timestamp.Compare(b.Timestamp) })
+			d.signals = append(d.signals, signals...)
+		}
+	}
+}
+
+// Calculates the relative strength index using Wilder's smoothing.
+func Calculate(values []float64, period int) []float64 {
+	d := Indicator{numPeriods: period}
+	d.update(values, 0)
+	return d.result
+}
+
+// Updates the relative strength index from index start on, after values were appended or replaced.
+func (d *Indicator) update(values []float64, start int) {
+	d.gains = series.Resize(d.gains, len(values), start)
+	d.losses = series.Resize(d.losses, len(values), start)
+	for i := max(start, series.FirstValid(values)+1); i < len(values); i++ {
+		change := values[i] - values[i-1]
+		d.gains[i] = math.Max(change, 0)
+		d.losses[i] = math.Max(-change, 0)
+	}
+	d.avgGain = series.UpdateRma(d.avgGain, d.gains, d.numPeriods, start)
+	d.avgLoss = series.UpdateRma(d.avgLoss, d.losses, d.numPeriods, start)
+	d.result = series.Resize(d.result, len(values), start)
+	for i := start; i < len(values); i++ {
+		switch {
+		case math.IsNaN(d.avgGain[i]):
+		case d.avgLoss[i] == 0 && d.avgGain[i] == 0:
+			d.result[i] = 50
+		case d.avgLoss[i] == 0:
+			d.result[i] = 100
+		default:
+			d.result[i] = 100 - 100/(1+d.avgGain[i]/d.avgLoss[i])
+		}
+	}
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	refColor := indapi.GetReferenceLineColor(c[0])
+	p.PlotHorizontalLine(d.overbought, maxValue, refColor, gtx)
+	p.PlotHorizontalLine(d.oversold, maxValue, refColor, gtx)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSignals() []indapi.Signal {
+	return d.signals
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package rsi
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	// Reference data and values of Wilder's RSI from StockCharts.
+	closing := []float64{
+		44.3389, 44.0902, 44.1497, 43.6124, 44.3278, 44.8264, 45.0955, 45.4245, 45.8433, 46.0826,
+		45.8931, 46.0328, 45.6140, 46.2820, 46.2820, 46.0028, 46.0328, 46.4116, 46.2222, 45.6439,
+		46.2122, 46.2521, 45.7137, 46.4515, 45.7835, 45.3548, 44.0288, 44.1783, 44.2181, 44.5672,
+		43.4205, 42.6628, 43.1314,
+	}
+	expected := []float64{
+		70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+		54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77,
+	}
+	result := Calculate(closing, 14)
+	for i := 0; i < 14; i++ {
+		assert.True(t, math.IsNaN(result[i]))
+	}
+	assert.InDeltaSlice(t, expected, result[14:], 0.01)
+}
+
+func TestCalculateLimits(t *testing.T) {
+	result := Calculate([]float64{1, 2, 3, 4}, 2)
+	assert.Equal(t, 100.0, result[3])
+	result = Calculate([]float64{4, 3, 2, 1}, 2)
+	assert.Equal(t, 0.0, result[3])
+	result = Calculate([]float64{2, 2, 2, 2}, 2)
+	assert.Equal(t, 50.0, result[3])
+}
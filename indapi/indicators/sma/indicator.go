@@ -7,25 +7,31 @@ import (
 	"image/color"
 	"maystocks/indapi"
 	"maystocks/indapi/candles"
-	"maystocks/indapi/properties"
+	"maystocks/indapi/series"
 	"strconv"
 	"time"
 
 	"gioui.org/layout"
-	"github.com/cinar/indicator"
 )
 
 type Indicator struct {
 	resolution     candles.CandleResolution
 	timestamps     []time.Time
 	result         []float64
-	dataLastChange time.Time
+	updateState    indapi.UpdateState
 	numPeriods     int
+	signalsEnabled bool
+	signals        []indapi.Signal
 	colors         []color.NRGBA
 }
 
 const Id = "sma"
 
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Time Periods", Description: "Number of candles used for the calculation.", Type: indapi.PropertyTypeInt, Default: "9", Min: 1, Max: 1000},
+	indapi.SignalsProperty,
+}
+
 func NewIndicator() indapi.IndicatorData {
 	return &Indicator{numPeriods: 9}
 }
@@ -34,21 +40,26 @@ func (d *Indicator) GetId() indapi.IndicatorId {
 	return Id
 }
 
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
 func (d *Indicator) GetProperties() map[string]string {
 	return map[string]string{
-		"Time Periods": strconv.Itoa(d.numPeriods),
+		"Time Periods":            strconv.Itoa(d.numPeriods),
+		indapi.SignalsPropertyKey: strconv.FormatBool(d.signalsEnabled),
 	}
 }
 
-func (d *Indicator) SetProperties(prop map[string]string) {
-	for key, value := range prop {
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
 		switch key {
 		case "Time Periods":
-			properties.SetPositiveValue(&d.numPeriods, value)
-		default:
-			panic("unknown indicator property")
+			d.numPeriods, _ = strconv.Atoi(value)
+		case indapi.SignalsPropertyKey:
+			d.signalsEnabled, _ = strconv.ParseBool(value)
 		}
-	}
+	})
 }
 
 func (d *Indicator) GetColors() []color.NRGBA {
@@ -62,19 +73,36 @@ func (d *Indicator) SetColors(c []color.NRGBA) {
 func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
 	data.DataMutex.Lock()
 	defer data.DataMutex.Unlock()
-	if !d.dataLastChange.Equal(data.DataLastChange) { // TODO this should be generic for all indicators
-		d.dataLastChange = data.DataLastChange
+	if start, changed := d.updateState.Next(data); changed {
 		d.resolution = r
 		d.timestamps = data.Cache.Timestamps
-		d.result = indicator.Sma(d.numPeriods, data.Cache.ClosePrices)
+		d.result = series.UpdateSma(d.result, data.Cache.ClosePrices, d.numPeriods, start)
+		if d.signalsEnabled {
+			d.signals = indapi.UpdateCrossovers(d.signals, data, data.Cache.ClosePrices, d.result, "Price crossed above SMA", "Price crossed below SMA", start)
+		}
 	}
 }
 
-func (d *Indicator) Plot(p indapi.LinePlotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
 	c := indapi.GetNormalisedColors(d.colors, defaultColor)
 	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
 }
 
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSignals() []indapi.Signal {
+	return d.signals
+}
+
 func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
 	return indapi.SubPlotTypePrice
 }
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stddev
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution     candles.CandleResolution
+	timestamps     []time.Time
+	returns        []float64
+	mean           []float64
+	stdDev         []float64
+	result         []float64
+	updateState    indapi.UpdateState
+	numPeriods     int
+	mode           string
+	periodsPerYear int
+	source         string
+	colors         []color.NRGBA
+}
+
+const Id = "stddev"
+
+const (
+	ModeStdDev     = "standard deviation"
+	ModeVolatility = "historical volatility"
+)
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Period", Description: "Number of candles used for the calculation.", Type: indapi.PropertyTypeInt, Default: "20", Min: 2, Max: 1000},
+	{Key: "Mode", Label: "Mode", Description: "Historical volatility is the annualized deviation of log returns in percent.", Type: indapi.PropertyTypeEnum, Default: ModeStdDev, Options: []string{ModeStdDev, ModeVolatility}},
+	{Key: "Periods Per Year", Label: "Candles per Year", Description: "Used to annualize the volatility, e.g. 252 for daily candles.", Type: indapi.PropertyTypeInt, Default: "252", Min: 1, Max: 1000000},
+	{Key: "Source", Label: "Source", Type: indapi.PropertyTypeSource, Default: indapi.SourceClose},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{numPeriods: 20, mode: ModeStdDev, periodsPerYear: 252, source: indapi.SourceClose}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods":     strconv.Itoa(d.numPeriods),
+		"Mode":             d.mode,
+		"Periods Per Year": strconv.Itoa(d.periodsPerYear),
+		"Source":           d.source,
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		case "Mode":
+			d.mode = value
+		case "Periods Per Year":
+			d.periodsPerYear, _ = strconv.Atoi(value)
+		case "Source":
+			d.source = value
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		values := indapi.GetSourceSeries(d.source, data)
+		if d.mode == ModeVolatility {
+			d.updateVolatility(values, start)
+		} else {
+			d.mean = series.UpdateSma(d.mean, values, d.numPeriods, start)
+			d.result = series.UpdateStdDev(d.result, values, d.mean, d.numPeriods, start)
+		}
+	}
+}
+
+// Calculates the annualized standard deviation of log returns in percent.
+func HistoricalVolatility(values []float64, period int, periodsPerYear int) []float64 {
+	d := Indicator{numPeriods: period, periodsPerYear: periodsPerYear}
+	d.updateVolatility(values, 0)
+	return d.result
+}
+
+// Updates the historical volatility from index start on, after values were appended or replaced.
+func (d *Indicator) updateVolatility(values []float64, start int) {
+	d.returns = series.Resize(d.returns, len(values), start)
+	for i := max(start, series.FirstValid(values)+1); i < len(values); i++ {
+		if values[i-1] > 0 && values[i] > 0 {
+			d.returns[i] = math.Log(values[i] / values[i-1])
+		} else {
+			d.returns[i] = 0
+		}
+	}
+	d.mean = series.UpdateSma(d.mean, d.returns, d.numPeriods, start)
+	d.stdDev = series.UpdateStdDev(d.stdDev, d.returns, d.mean, d.numPeriods, start)
+	d.result = series.Resize(d.result, len(values), start)
+	factor := 100 * math.Sqrt(float64(d.periodsPerYear))
+	for i := start; i < len(values); i++ {
+		d.result[i] = d.stdDev[i] * factor
+	}
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stddev
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoricalVolatility(t *testing.T) {
+	// Alternating returns of +10% and -10% in log space.
+	r := math.Log(1.1)
+	values := []float64{100, 100 * math.Exp(r), 100, 100 * math.Exp(r), 100}
+	result := HistoricalVolatility(values, 2, 252)
+	assert.True(t, math.IsNaN(result[1]))
+	assert.InDelta(t, r*100*math.Sqrt(252), result[2], 1e-9)
+	assert.InDelta(t, r*100*math.Sqrt(252), result[4], 1e-9)
+
+	result = HistoricalVolatility([]float64{5, 5, 5}, 2, 252)
+	assert.Equal(t, 0.0, result[2])
+}
@@ -5,45 +5,76 @@ package stochastics
 
 import (
 	"image/color"
-	"log"
+	"math"
 	"maystocks/indapi"
 	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
 	"time"
 
 	"gioui.org/layout"
-	"github.com/cinar/indicator"
 )
 
 type Indicator struct {
-	resolution     candles.CandleResolution
-	timestamps     []time.Time
-	k              []float64
-	d              []float64
-	dataLastChange time.Time
-	colors         []color.NRGBA
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	highest     []float64
+	lowest      []float64
+	fastK       []float64
+	k           []float64
+	d           []float64
+	updateState indapi.UpdateState
+	kPeriods    int
+	kSmoothing  int
+	dPeriods    int
+	source      string
+	colors      []color.NRGBA
 }
 
 const Id = "stochastics"
 
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "K Periods", Label: "%K Period", Description: "Number of candles of the high/low range.", Type: indapi.PropertyTypeInt, Default: "14", Min: 1, Max: 1000},
+	{Key: "K Smoothing", Label: "%K Smoothing", Description: "1 for the fast, 3 for the slow oscillator.", Type: indapi.PropertyTypeInt, Default: "1", Min: 1, Max: 100},
+	{Key: "D Periods", Label: "%D Period", Description: "Number of candles of the moving average of %K.", Type: indapi.PropertyTypeInt, Default: "3", Min: 1, Max: 100},
+	{Key: "Source", Label: "Source", Description: "Price which is compared to the high/low range.", Type: indapi.PropertyTypeSource, Default: indapi.SourceClose},
+}
+
 func NewIndicator() indapi.IndicatorData {
-	return &Indicator{}
+	return &Indicator{kPeriods: 14, kSmoothing: 1, dPeriods: 3, source: indapi.SourceClose}
 }
 
 func (d *Indicator) GetId() indapi.IndicatorId {
 	return Id
 }
 
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
 func (d *Indicator) GetProperties() map[string]string {
-	return map[string]string{}
+	return map[string]string{
+		"K Periods":   strconv.Itoa(d.kPeriods),
+		"K Smoothing": strconv.Itoa(d.kSmoothing),
+		"D Periods":   strconv.Itoa(d.dPeriods),
+		"Source":      d.source,
+	}
 }
 
-func (d *Indicator) SetProperties(prop map[string]string) {
-	for key := range prop {
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		n, _ := strconv.Atoi(value)
 		switch key {
-		default:
-			log.Printf("Unknown property %s was ignored.", key)
+		case "K Periods":
+			d.kPeriods = n
+		case "K Smoothing":
+			d.kSmoothing = n
+		case "D Periods":
+			d.dPeriods = n
+		case "Source":
+			d.source = value
 		}
-	}
+	})
 }
 
 func (d *Indicator) GetColors() []color.NRGBA {
@@ -57,21 +88,63 @@ func (d *Indicator) SetColors(c []color.NRGBA) {
 func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
 	data.DataMutex.Lock()
 	defer data.DataMutex.Unlock()
-	if !d.dataLastChange.Equal(data.DataLastChange) { // TODO this should be generic for all indicators
-		d.dataLastChange = data.DataLastChange
+	if start, changed := d.updateState.Next(data); changed {
 		d.resolution = r
-
-		d.k, d.d = indicator.StochasticOscillator(data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices)
+		d.update(data.Cache.HighPrices, data.Cache.LowPrices, indapi.GetSourceSeries(d.source, data), start)
 		d.timestamps = data.Cache.Timestamps
 	}
 }
 
-func (d *Indicator) Plot(p indapi.LinePlotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+// Calculates %K and %D of the stochastic oscillator. The closing values are usually the close prices.
+func Calculate(high []float64, low []float64, closing []float64, kPeriods int, kSmoothing int, dPeriods int) (k []float64, d []float64) {
+	ind := Indicator{kPeriods: kPeriods, kSmoothing: kSmoothing, dPeriods: dPeriods}
+	ind.update(high, low, closing, 0)
+	return ind.k, ind.d
+}
+
+// Updates %K and %D from index start on, after candles were appended or replaced.
+func (d *Indicator) update(high []float64, low []float64, closing []float64, start int) {
+	d.highest = series.UpdateHighest(d.highest, high, d.kPeriods, start)
+	d.lowest = series.UpdateLowest(d.lowest, low, d.kPeriods, start)
+	d.fastK = series.Resize(d.fastK, len(closing), start)
+	for i := start; i < len(closing); i++ {
+		if math.IsNaN(d.highest[i]) || math.IsNaN(d.lowest[i]) {
+			continue
+		}
+		if r := d.highest[i] - d.lowest[i]; r > 0 {
+			d.fastK[i] = 100 * (closing[i] - d.lowest[i]) / r
+		} else {
+			d.fastK[i] = 50
+		}
+	}
+	if d.kSmoothing > 1 {
+		d.k = series.UpdateSma(d.k, d.fastK, d.kSmoothing, start)
+	} else {
+		d.k = d.fastK
+	}
+	d.d = series.UpdateSma(d.d, d.k, d.dPeriods, start)
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
 	c := indapi.GetNormalisedColors(d.colors, defaultColor)
 	p.PlotLine(d.timestamps[0:len(d.k)], d.k, maxValue, d.resolution, c[0], gtx)
 	p.PlotLine(d.timestamps[0:len(d.d)], d.d, maxValue, d.resolution, c[1], gtx)
 }
 
+func (d *Indicator) GetOutputNames() []string {
+	return []string{"k", "d"}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	switch name {
+	case "k":
+		return d.k
+	case "d":
+		return d.d
+	}
+	return nil
+}
+
 func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
 	return indapi.SubPlotTypeIndicator
 }
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stochastics
+
+import (
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	high := []float64{10, 12, 11, 14, 13}
+	low := []float64{8, 9, 9, 10, 12}
+	closing := []float64{9, 11, 10, 14, 12}
+	k, d := Calculate(high, low, closing, 2, 1, 2)
+	assert.True(t, math.IsNaN(k[0]))
+	assert.InDeltaSlice(t, []float64{75, 100.0 / 3, 100, 50}, k[1:], 1e-9)
+	assert.True(t, math.IsNaN(d[1]))
+	assert.InDeltaSlice(t, []float64{(75 + 100.0/3) / 2, (100.0/3 + 100) / 2, 75}, d[2:], 1e-9)
+
+	// Slow stochastic smoothes %K.
+	k, _ = Calculate(high, low, closing, 2, 2, 2)
+	assert.True(t, math.IsNaN(k[1]))
+	assert.InDelta(t, (75+100.0/3)/2, k[2], 1e-9)
+}
+
+func TestFlatRange(t *testing.T) {
+	k, _ := Calculate([]float64{5, 5}, []float64{5, 5}, []float64{5, 5}, 2, 1, 1)
+	assert.Equal(t, 50.0, k[1])
+}
+
+func TestUpdateSource(t *testing.T) {
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	data.Cache.Timestamps = make([]time.Time, 2)
+	data.Cache.HighPrices = []float64{10, 12}
+	data.Cache.LowPrices = []float64{8, 8}
+	data.Cache.ClosePrices = []float64{9, 9}
+	data.Cache.OpenPrices = []float64{9, 11}
+	d := NewIndicator().(*Indicator)
+	assert.Equal(t, indapi.SourceClose, d.GetProperties()["Source"])
+	assert.NoError(t, d.SetProperties(map[string]string{"K Periods": "2", "D Periods": "1", "Source": indapi.SourceOpen}))
+	d.Update(candles.CandleOneDay, &data)
+	// The open price is compared to the high/low range instead of the close price.
+	assert.InDelta(t, 75, d.k[1], 1e-9)
+}
+
+func TestUpdateIncremental(t *testing.T) {
+	high := []float64{10, 12, 11, 14, 13, 15}
+	low := []float64{8, 9, 9, 10, 12, 11}
+	closing := []float64{9, 11, 10, 14, 12, 14}
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	d := NewIndicator().(*Indicator)
+	assert.NoError(t, d.SetProperties(map[string]string{"K Periods": "2", "K Smoothing": "2", "D Periods": "2"}))
+	// Candles are appended one by one, and the last candle is replaced.
+	c := &data.Cache
+	for i := range closing {
+		c.Timestamps = append(c.Timestamps, time.Time{})
+		c.HighPrices = append(c.HighPrices, high[i]+1)
+		c.LowPrices = append(c.LowPrices, low[i])
+		c.ClosePrices = append(c.ClosePrices, closing[i]+1)
+		c.ChangeCount++
+		d.Update(candles.CandleOneDay, &data)
+		c.HighPrices[i], c.ClosePrices[i] = high[i], closing[i]
+		c.ChangeCount++
+		d.Update(candles.CandleOneDay, &data)
+	}
+	k, dValues := Calculate(high, low, closing, 2, 2, 2)
+	assert.InDeltaSlice(t, k[2:], d.k[2:], 1e-9)
+	assert.InDeltaSlice(t, dValues[3:], d.d[3:], 1e-9)
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package supertrend
+
+import (
+	"image/color"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	up          []float64
+	down        []float64
+	updateState indapi.UpdateState
+	numPeriods  int
+	multiplier  float64
+	colors      []color.NRGBA
+}
+
+const Id = "supertrend"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "ATR Period", Description: "Number of candles of the average true range.", Type: indapi.PropertyTypeInt, Default: "10", Min: 1, Max: 1000},
+	{Key: "Multiplier", Label: "Multiplier", Description: "Distance of the line in average true ranges.", Type: indapi.PropertyTypeFloat, Default: "3", Min: 0.1, Max: 20, Step: 0.5},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{numPeriods: 10, multiplier: 3}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods": strconv.Itoa(d.numPeriods),
+		"Multiplier":   strconv.FormatFloat(d.multiplier, 'f', -1, 64),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		case "Multiplier":
+			d.multiplier, _ = strconv.ParseFloat(value, 64)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 2)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.up, d.down = Calculate(data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices, d.numPeriods, d.multiplier)
+	}
+}
+
+// Calculates the SuperTrend line. It is returned as two series, up is NaN during a downtrend
+// and down is NaN during an uptrend, so that they can be plotted with different colors.
+func Calculate(high []float64, low []float64, closing []float64, period int, multiplier float64) (up []float64, down []float64) {
+	atr := series.Atr(high, low, closing, period)
+	up = series.NewNaN(len(closing))
+	down = series.NewNaN(len(closing))
+	var upperBand, lowerBand float64
+	uptrend := true
+	start := series.FirstValid(atr)
+	for i := start; i < len(closing); i++ {
+		mid := (high[i] + low[i]) / 2
+		basicUpper := mid + multiplier*atr[i]
+		basicLower := mid - multiplier*atr[i]
+		if i == start {
+			upperBand, lowerBand = basicUpper, basicLower
+			uptrend = closing[i] >= mid
+		} else {
+			// The bands only move towards the price, unless the price crossed them.
+			if basicUpper < upperBand || closing[i-1] > upperBand {
+				upperBand = basicUpper
+			}
+			if basicLower > lowerBand || closing[i-1] < lowerBand {
+				lowerBand = basicLower
+			}
+			if uptrend && closing[i] < lowerBand {
+				uptrend = false
+			} else if !uptrend && closing[i] > upperBand {
+				uptrend = true
+			}
+		}
+		if uptrend {
+			up[i] = lowerBand
+		} else {
+			down[i] = upperBand
+		}
+	}
+	return
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotLine(d.timestamps[0:len(d.up)], d.up, maxValue, d.resolution, c[0], gtx)
+	p.PlotLine(d.timestamps[0:len(d.down)], d.down, maxValue, d.resolution, c[1], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{"up", "down"}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	switch name {
+	case "up":
+		return d.up
+	case "down":
+		return d.down
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypePrice
+}
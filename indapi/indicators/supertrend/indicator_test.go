@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package supertrend
+
+import (
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	// Rising prices followed by a crash.
+	high := []float64{11, 12, 13, 14, 15, 16, 10}
+	low := []float64{9, 10, 11, 12, 13, 14, 6}
+	closing := []float64{10, 11, 12, 13, 14, 15, 7}
+	up, down := Calculate(high, low, closing, 2, 1)
+	// No values during the ATR warm-up.
+	assert.True(t, math.IsNaN(up[0]))
+	assert.True(t, math.IsNaN(down[0]))
+	for i := 1; i < 6; i++ {
+		assert.False(t, math.IsNaN(up[i]), "index %d", i)
+		assert.True(t, math.IsNaN(down[i]), "index %d", i)
+		assert.Less(t, up[i], low[i])
+		if i > 1 {
+			// The line never moves down during an uptrend.
+			assert.GreaterOrEqual(t, up[i], up[i-1])
+		}
+	}
+	assert.True(t, math.IsNaN(up[6]))
+	assert.Greater(t, down[6], closing[6])
+}
+
+func TestGetOutput(t *testing.T) {
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	data.Cache.Timestamps = make([]time.Time, 7)
+	data.Cache.HighPrices = []float64{11, 12, 13, 14, 15, 16, 10}
+	data.Cache.LowPrices = []float64{9, 10, 11, 12, 13, 14, 6}
+	data.Cache.ClosePrices = []float64{10, 11, 12, 13, 14, 15, 7}
+	d := NewIndicator().(*Indicator)
+	assert.NoError(t, d.SetProperties(map[string]string{"Time Periods": "2", "Multiplier": "1"}))
+	d.Update(candles.CandleOneDay, &data)
+	// The outputs can be used as input of chained indicators.
+	up, down := Calculate(data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices, 2, 1)
+	assert.Equal(t, []string{"up", "down"}, d.GetOutputNames())
+	assert.InDeltaSlice(t, up[2:], d.GetOutput("up")[2:], 1e-9)
+	assert.Equal(t, len(down), len(d.GetOutput("down")))
+	assert.Nil(t, d.GetOutput("invalid"))
+}
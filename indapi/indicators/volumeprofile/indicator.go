@@ -0,0 +1,260 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package volumeprofile
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+// Volume by price of the visible candles or of the last session, shown at the right edge of the price subplot.
+type Indicator struct {
+	resolution     candles.CandleResolution
+	session        candles.Session
+	timestamps     []time.Time
+	highPrices     []float64
+	lowPrices      []float64
+	volumes        []float64
+	updateState    indapi.UpdateState
+	rangeMode      string
+	numRows        int
+	valueAreaRatio float64
+	width          float64
+	colors         []color.NRGBA
+}
+
+const Id = "volumeprofile"
+
+const (
+	// The profile is calculated from the candles which are visible in the plot.
+	RangeVisible = "visible"
+	// The profile is calculated from the candles of the last trading session.
+	RangeSession = "session"
+)
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Range", Label: "Range", Description: "Candles which are used for the calculation.", Type: indapi.PropertyTypeEnum, Default: RangeVisible, Options: []string{RangeVisible, RangeSession}},
+	{Key: "Rows", Label: "Rows", Description: "Number of price levels.", Type: indapi.PropertyTypeInt, Default: "24", Min: 4, Max: 200},
+	{Key: "Value Area", Label: "Value Area %", Description: "Percentage of the volume around the point of control which forms the value area.", Type: indapi.PropertyTypeInt, Default: "70", Min: 1, Max: 100},
+	{Key: "Width", Label: "Width %", Description: "Width of the largest row in percent of the plot width.", Type: indapi.PropertyTypeInt, Default: "25", Min: 5, Max: 100},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{rangeMode: RangeVisible, numRows: 24, valueAreaRatio: 0.7, width: 0.25}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Range":      d.rangeMode,
+		"Rows":       strconv.Itoa(d.numRows),
+		"Value Area": strconv.Itoa(int(math.Round(d.valueAreaRatio * 100))),
+		"Width":      strconv.Itoa(int(math.Round(d.width * 100))),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Range":
+			d.rangeMode = value
+		case "Rows":
+			d.numRows, _ = strconv.Atoi(value)
+		case "Value Area":
+			percent, _ := strconv.Atoi(value)
+			d.valueAreaRatio = float64(percent) / 100
+		case "Width":
+			percent, _ := strconv.Atoi(value)
+			d.width = float64(percent) / 100
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+// Colors of the rows, of the value area and of the point of control.
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 3)
+}
+
+// The profile depends on the visible range, it is calculated when plotting.
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.session = data.Session
+		d.timestamps = data.Cache.Timestamps
+		d.highPrices = data.Cache.HighPrices
+		d.lowPrices = data.Cache.LowPrices
+		d.volumes = data.Cache.Volumes
+	}
+}
+
+// Volume by price, rows are ordered by price.
+type Profile struct {
+	// Lower bound of each row, followed by the upper bound of the last row.
+	Bounds  []float64
+	Volumes []float64
+	// Row with the largest volume.
+	PointOfControl int
+	// First and last row of the value area.
+	ValueAreaLow  int
+	ValueAreaHigh int
+}
+
+// Returns whether the candle has finite prices and volume.
+func isValidCandle(high float64, low float64, volume float64) bool {
+	return !math.IsNaN(high) && !math.IsInf(high, 0) && !math.IsNaN(low) && !math.IsInf(low, 0) && !math.IsNaN(volume)
+}
+
+// Calculates the volume profile of the candles from index start to end (exclusive). The volume of a candle
+// is distributed evenly across its price range. Candles with invalid prices are skipped.
+// Returns nil if there are no valid candles.
+func Calculate(high []float64, low []float64, volumes []float64, start int, end int, numRows int, valueAreaRatio float64) *Profile {
+	end = min(end, len(high), len(low), len(volumes))
+	start = max(start, 0)
+	minPrice, maxPrice := math.Inf(1), math.Inf(-1)
+	for i := start; i < end; i++ {
+		if !isValidCandle(high[i], low[i], volumes[i]) {
+			continue
+		}
+		minPrice = math.Min(minPrice, low[i])
+		maxPrice = math.Max(maxPrice, high[i])
+	}
+	if start >= end || numRows <= 0 || math.IsInf(minPrice, 0) || math.IsInf(maxPrice, 0) {
+		return nil
+	}
+	if maxPrice == minPrice {
+		// Single price, use a small range so that there is a row.
+		maxPrice = minPrice + math.Max(math.Abs(minPrice)*1e-6, 1e-9)
+	}
+	rowHeight := (maxPrice - minPrice) / float64(numRows)
+	p := Profile{Bounds: make([]float64, numRows+1), Volumes: make([]float64, numRows)}
+	for i := range p.Bounds {
+		p.Bounds[i] = minPrice + float64(i)*rowHeight
+	}
+	p.Bounds[numRows] = maxPrice
+	for i := start; i < end; i++ {
+		l, h := low[i], high[i]
+		if !isValidCandle(h, l, volumes[i]) {
+			continue
+		}
+		first := min(int((l-minPrice)/rowHeight), numRows-1)
+		last := min(int((h-minPrice)/rowHeight), numRows-1)
+		if h <= l {
+			p.Volumes[first] += volumes[i]
+			continue
+		}
+		for row := first; row <= last; row++ {
+			overlap := math.Min(h, p.Bounds[row+1]) - math.Max(l, p.Bounds[row])
+			if overlap > 0 {
+				p.Volumes[row] += volumes[i] * overlap / (h - l)
+			}
+		}
+	}
+	p.PointOfControl, p.ValueAreaLow, p.ValueAreaHigh = calculateValueArea(p.Volumes, valueAreaRatio)
+	return &p
+}
+
+// Determines the row with the largest volume, and extends the value area from there,
+// always adding the neighbouring row with the larger volume.
+func calculateValueArea(volumes []float64, ratio float64) (poc int, low int, high int) {
+	var total float64
+	for i, v := range volumes {
+		total += v
+		if v > volumes[poc] {
+			poc = i
+		}
+	}
+	low, high = poc, poc
+	sum := volumes[poc]
+	for sum < ratio*total && (low > 0 || high < len(volumes)-1) {
+		below, above := -1.0, -1.0
+		if low > 0 {
+			below = volumes[low-1]
+		}
+		if high < len(volumes)-1 {
+			above = volumes[high+1]
+		}
+		if above >= below {
+			high++
+			sum += above
+		} else {
+			low--
+			sum += below
+		}
+	}
+	return poc, low, high
+}
+
+// Returns the index range of the candles which are used for the profile.
+func (d *Indicator) getCandleRange(p indapi.Plotter) (start int, end int) {
+	n := len(d.timestamps)
+	if n == 0 {
+		return 0, 0
+	}
+	var first, last time.Time
+	if d.rangeMode == RangeSession {
+		first = d.session.GetSessionStart(d.timestamps[n-1])
+		last = d.timestamps[n-1]
+	} else {
+		first, last = p.GetVisibleTimeRange(d.resolution)
+		// Candles which are partly visible are included.
+		first = d.resolution.GetNthCandleTime(first, 0, d.session)
+	}
+	for start < n && d.timestamps[start].Before(first) {
+		start++
+	}
+	end = start
+	for end < n && !d.timestamps[end].After(last) {
+		end++
+	}
+	return start, end
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	start, end := d.getCandleRange(p)
+	profile := Calculate(d.highPrices, d.lowPrices, d.volumes, start, end, d.numRows, d.valueAreaRatio)
+	if profile == nil {
+		return
+	}
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	// Rows are transparent, so that candles remain visible.
+	rowColor, valueAreaColor, pocColor := c[0], c[1], c[2]
+	rowColor.A /= 4
+	valueAreaColor.A /= 2
+	pocColor.A = pocColor.A / 4 * 3
+	colors := make([]color.NRGBA, len(profile.Volumes))
+	for i := range colors {
+		switch {
+		case i == profile.PointOfControl:
+			colors[i] = pocColor
+		case i >= profile.ValueAreaLow && i <= profile.ValueAreaHigh:
+			colors[i] = valueAreaColor
+		default:
+			colors[i] = rowColor
+		}
+	}
+	p.PlotHorizontalBars(profile.Bounds, profile.Volumes, colors, d.width, gtx)
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypePrice
+}
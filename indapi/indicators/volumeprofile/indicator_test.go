@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package volumeprofile
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	high := []float64{14, 12, 11, 20}
+	low := []float64{10, 10, 11, 18}
+	volumes := []float64{40, 60, 10, 1000}
+	// The last candle is not part of the range.
+	p := Calculate(high, low, volumes, 0, 3, 4, 0.7)
+	assert.Equal(t, []float64{10, 11, 12, 13, 14}, p.Bounds)
+	// The volume is distributed across the price range of the candle,
+	// the candle without range is added to the row of its price.
+	assert.Equal(t, []float64{40, 50, 10, 10}, p.Volumes)
+	assert.Equal(t, 1, p.PointOfControl)
+	// 70% of 110: Starting at the point of control, the row below has more volume.
+	assert.Equal(t, 0, p.ValueAreaLow)
+	assert.Equal(t, 1, p.ValueAreaHigh)
+
+	assert.Nil(t, Calculate(high, low, volumes, 2, 2, 4, 0.7))
+}
+
+func TestCalculateNaN(t *testing.T) {
+	// Candles without prices are skipped.
+	p := Calculate([]float64{math.NaN(), 10, 11}, []float64{math.NaN(), 9, 10}, []float64{5, 10, 10}, 0, 3, 2, 0.7)
+	assert.Equal(t, []float64{9, 10, 11}, p.Bounds)
+	assert.Equal(t, []float64{10, 10}, p.Volumes)
+
+	assert.Nil(t, Calculate([]float64{math.NaN()}, []float64{math.NaN()}, []float64{5}, 0, 1, 2, 0.7))
+}
+
+func TestCalculateValueArea(t *testing.T) {
+	poc, low, high := calculateValueArea([]float64{1, 2, 10, 3, 1, 1}, 0.8)
+	assert.Equal(t, 2, poc)
+	assert.Equal(t, 1, low)
+	assert.Equal(t, 3, high)
+
+	// Complete profile
+	poc, low, high = calculateValueArea([]float64{1, 2, 10, 3, 1, 1}, 1)
+	assert.Equal(t, 2, poc)
+	assert.Equal(t, 0, low)
+	assert.Equal(t, 5, high)
+}
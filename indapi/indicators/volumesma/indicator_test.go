@@ -0,0 +1,35 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package volumesma
+
+import (
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdate(t *testing.T) {
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	data.Cache.Timestamps = make([]time.Time, 3)
+	data.Cache.Volumes = []float64{100, 300, 200}
+	data.Cache.ChangeCount = 1
+	d := NewIndicator().(*Indicator)
+	assert.NoError(t, d.SetProperties(map[string]string{"Time Periods": "2"}))
+	d.Update(candles.CandleOneDay, &data)
+	assert.True(t, math.IsNaN(d.result[0]))
+	assert.Equal(t, []float64{200, 250}, d.result[1:])
+
+	// Replace the last candle and append a live candle.
+	data.Cache.Timestamps = append(data.Cache.Timestamps, time.Time{})
+	data.Cache.Volumes = []float64{100, 300, 500, 700}
+	data.Cache.ChangeCount++
+	d.Update(candles.CandleOneDay, &data)
+	assert.Equal(t, []float64{200, 400, 600}, d.result[1:])
+	assert.Equal(t, indapi.SubPlotTypeVolume, d.GetSubPlotType())
+}
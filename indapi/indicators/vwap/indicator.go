@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package vwap
+
+import (
+	"image/color"
+	"log"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	result      []float64
+	updateState indapi.UpdateState
+	mode        string
+	anchor      string
+	colors      []color.NRGBA
+}
+
+const Id = "vwap"
+
+const (
+	// The VWAP is reset at the start of each trading session.
+	ModeSession = "session"
+	// The VWAP is calculated starting at the anchor candle.
+	ModeAnchored = "anchored"
+)
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Mode", Label: "Mode", Type: indapi.PropertyTypeEnum, Default: ModeSession, Options: []string{ModeSession, ModeAnchored}},
+	{Key: "Anchor", Label: "Anchor", Description: "Start of the anchored VWAP in exchange time, " + indapi.TimePropertyFormat +
+		". The anchored VWAP is not plotted if this is empty. It can also be set using the context menu of the plot.", Type: indapi.PropertyTypeTime},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{mode: ModeSession}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Mode":   d.mode,
+		"Anchor": d.anchor,
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	err := indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Mode":
+			d.mode = value
+		case "Anchor":
+			d.anchor = value
+		}
+	})
+	// Calculate again using the new properties.
+	d.updateState = indapi.UpdateState{}
+	return err
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if _, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		typicalPrices := indapi.GetSourceSeries(indapi.SourceHLC3, data)
+		if d.mode == ModeAnchored {
+			anchor, ok := d.getAnchor(r, data.Session)
+			if !ok {
+				d.result = nil // not plotted without anchor
+				return
+			}
+			d.result = CalculateAnchored(data.Cache.Timestamps, typicalPrices, data.Cache.Volumes, anchor)
+		} else {
+			d.result = CalculateSession(data.Cache.Timestamps, typicalPrices, data.Cache.Volumes, data.Session)
+		}
+	}
+}
+
+// Returns the start of the candle containing the anchor, or false if the anchor is not set or invalid.
+func (d *Indicator) getAnchor(r candles.CandleResolution, session candles.Session) (time.Time, bool) {
+	if d.anchor == "" {
+		return time.Time{}, false
+	}
+	location := session.Location
+	if location == nil {
+		location = time.UTC
+	}
+	anchor, err := time.ParseInLocation(indapi.TimePropertyFormat, d.anchor, location)
+	if err != nil {
+		log.Printf("Invalid VWAP anchor: %v", err)
+		return time.Time{}, false
+	}
+	return r.GetNthCandleTime(anchor, 0, session), true
+}
+
+// Calculates the VWAP, which is reset at the start of each trading session.
+func CalculateSession(timestamps []time.Time, typicalPrices []float64, volumes []float64, session candles.Session) []float64 {
+	return calculate(typicalPrices, volumes, func(i int) bool {
+		return i == 0 || !session.GetSessionStart(timestamps[i]).Equal(session.GetSessionStart(timestamps[i-1]))
+	})
+}
+
+// Calculates the VWAP starting at the first candle at or after the anchor time.
+func CalculateAnchored(timestamps []time.Time, typicalPrices []float64, volumes []float64, anchor time.Time) []float64 {
+	started := false
+	return calculate(typicalPrices, volumes, func(i int) bool {
+		if !started && !timestamps[i].Before(anchor) {
+			started = true
+			return true
+		}
+		return false
+	})
+}
+
+// Calculates the cumulative VWAP, reset returns true for candles at which the calculation starts again.
+// Values before the first reset are NaN.
+func calculate(typicalPrices []float64, volumes []float64, reset func(i int) bool) []float64 {
+	n := min(len(typicalPrices), len(volumes))
+	result := series.NewNaN(n)
+	started := false
+	var sumPriceVolume, sumVolume float64
+	for i := 0; i < n; i++ {
+		if reset(i) {
+			started = true
+			sumPriceVolume, sumVolume = 0, 0
+		}
+		if !started {
+			continue
+		}
+		sumPriceVolume += typicalPrices[i] * volumes[i]
+		sumVolume += volumes[i]
+		if sumVolume > 0 {
+			result[i] = sumPriceVolume / sumVolume
+		} else {
+			result[i] = typicalPrices[i]
+		}
+	}
+	return result
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypePrice
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package vwap
+
+import (
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateSession(t *testing.T) {
+	ts := []time.Time{
+		time.Date(2023, 8, 9, 10, 0, 0, 0, time.UTC),
+		time.Date(2023, 8, 9, 11, 0, 0, 0, time.UTC),
+		time.Date(2023, 8, 10, 10, 0, 0, 0, time.UTC),
+		time.Date(2023, 8, 10, 11, 0, 0, 0, time.UTC),
+	}
+	prices := []float64{10, 20, 30, 40}
+	volumes := []float64{1, 3, 2, 2}
+	result := CalculateSession(ts, prices, volumes, candles.NewUtcSession())
+	assert.InDelta(t, 10.0, result[0], 1e-9)
+	assert.InDelta(t, 17.5, result[1], 1e-9)
+	// Reset at the start of the second session.
+	assert.InDelta(t, 30.0, result[2], 1e-9)
+	assert.InDelta(t, 35.0, result[3], 1e-9)
+}
+
+func TestCalculateAnchored(t *testing.T) {
+	ts := []time.Time{
+		time.Date(2023, 8, 9, 10, 0, 0, 0, time.UTC),
+		time.Date(2023, 8, 9, 11, 0, 0, 0, time.UTC),
+		time.Date(2023, 8, 10, 10, 0, 0, 0, time.UTC),
+	}
+	prices := []float64{10, 20, 30}
+	volumes := []float64{1, 1, 3}
+	result := CalculateAnchored(ts, prices, volumes, time.Date(2023, 8, 9, 10, 30, 0, 0, time.UTC))
+	assert.True(t, math.IsNaN(result[0]))
+	assert.InDelta(t, 20.0, result[1], 1e-9)
+	// No reset at session start.
+	assert.InDelta(t, 27.5, result[2], 1e-9)
+
+	result = CalculateAnchored(ts, prices, volumes, time.Time{})
+	assert.InDelta(t, 10.0, result[0], 1e-9)
+}
+
+func TestUpdateAnchored(t *testing.T) {
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex), Session: candles.NewUtcSession()}
+	data.Cache.Timestamps = []time.Time{
+		time.Date(2023, 8, 9, 10, 0, 0, 0, time.UTC),
+		time.Date(2023, 8, 9, 11, 0, 0, 0, time.UTC),
+		time.Date(2023, 8, 9, 12, 0, 0, 0, time.UTC),
+	}
+	data.Cache.HighPrices = []float64{10, 20, 30}
+	data.Cache.LowPrices = []float64{10, 20, 30}
+	data.Cache.ClosePrices = []float64{10, 20, 30}
+	data.Cache.Volumes = []float64{1, 1, 1}
+	data.Cache.ChangeCount = 1
+	d := NewIndicator().(*Indicator)
+
+	// Without anchor, the anchored VWAP is not plotted.
+	assert.NoError(t, d.SetProperties(map[string]string{"Mode": ModeAnchored}))
+	d.Update(candles.CandleSixtyMinutes, &data)
+	assert.Empty(t, d.GetOutput(indapi.OutputValue))
+
+	// The anchor is snapped to the start of the candle containing it.
+	assert.NoError(t, d.SetProperties(map[string]string{"Anchor": "2023-08-09 11:30"}))
+	d.Update(candles.CandleSixtyMinutes, &data)
+	result := d.GetOutput(indapi.OutputValue)
+	assert.True(t, math.IsNaN(result[0]))
+	assert.InDeltaSlice(t, []float64{20, 25}, result[1:], 1e-9)
+
+	assert.Error(t, d.SetProperties(map[string]string{"Anchor": "11:30"}))
+}
@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package williamsr
+
+import (
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+)
+
+type Indicator struct {
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	highest     []float64
+	lowest      []float64
+	result      []float64
+	updateState indapi.UpdateState
+	numPeriods  int
+	overbought  float64
+	oversold    float64
+	colors      []color.NRGBA
+}
+
+const Id = "williamsr"
+
+var propertyDescriptors = []indapi.PropertyDescriptor{
+	{Key: "Time Periods", Label: "Period", Description: "Number of candles of the high/low range.", Type: indapi.PropertyTypeInt, Default: "14", Min: 1, Max: 1000},
+	{Key: "Overbought", Label: "Overbought", Type: indapi.PropertyTypeFloat, Default: "-20", Min: -100, Max: 0},
+	{Key: "Oversold", Label: "Oversold", Type: indapi.PropertyTypeFloat, Default: "-80", Min: -100, Max: 0},
+}
+
+func NewIndicator() indapi.IndicatorData {
+	return &Indicator{numPeriods: 14, overbought: -20, oversold: -80}
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return Id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return map[string]string{
+		"Time Periods": strconv.Itoa(d.numPeriods),
+		"Overbought":   strconv.FormatFloat(d.overbought, 'f', -1, 64),
+		"Oversold":     strconv.FormatFloat(d.oversold, 'f', -1, 64),
+	}
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(propertyDescriptors, prop, func(key string, value string) {
+		switch key {
+		case "Time Periods":
+			d.numPeriods, _ = strconv.Atoi(value)
+		case "Overbought":
+			d.overbought, _ = strconv.ParseFloat(value, 64)
+		case "Oversold":
+			d.oversold, _ = strconv.ParseFloat(value, 64)
+		}
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, 1)
+}
+
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	data.DataMutex.Lock()
+	defer data.DataMutex.Unlock()
+	if start, changed := d.updateState.Next(data); changed {
+		d.resolution = r
+		d.timestamps = data.Cache.Timestamps
+		d.update(data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices, start)
+	}
+}
+
+// Calculates Williams %R, which ranges from -100 (lowest low) to 0 (highest high).
+func Calculate(high []float64, low []float64, closing []float64, period int) []float64 {
+	d := Indicator{numPeriods: period}
+	d.update(high, low, closing, 0)
+	return d.result
+}
+
+// Updates Williams %R from index start on, after candles were appended or replaced.
+func (d *Indicator) update(high []float64, low []float64, closing []float64, start int) {
+	d.highest = series.UpdateHighest(d.highest, high, d.numPeriods, start)
+	d.lowest = series.UpdateLowest(d.lowest, low, d.numPeriods, start)
+	d.result = series.Resize(d.result, len(closing), start)
+	for i := start; i < len(closing); i++ {
+		if math.IsNaN(d.highest[i]) || math.IsNaN(d.lowest[i]) {
+			continue
+		}
+		if r := d.highest[i] - d.lowest[i]; r > 0 {
+			d.result[i] = -100 * (d.highest[i] - closing[i]) / r
+		} else {
+			d.result[i] = -50
+		}
+	}
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	refColor := indapi.GetReferenceLineColor(c[0])
+	p.PlotHorizontalLine(d.overbought, maxValue, refColor, gtx)
+	p.PlotHorizontalLine(d.oversold, maxValue, refColor, gtx)
+	p.PlotLine(d.timestamps[0:len(d.result)], d.result, maxValue, d.resolution, c[0], gtx)
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return []string{indapi.OutputValue}
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	if name == indapi.OutputValue {
+		return d.result
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return indapi.SubPlotTypeIndicator
+}
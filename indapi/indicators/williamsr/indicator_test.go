@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package williamsr
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	high := []float64{10, 12, 11, 14}
+	low := []float64{8, 9, 9, 10}
+	closing := []float64{9, 11, 10, 14}
+	result := Calculate(high, low, closing, 2)
+	assert.True(t, math.IsNaN(result[0]))
+	assert.InDeltaSlice(t, []float64{-25, -200.0 / 3, 0}, result[1:], 1e-9)
+
+	result = Calculate([]float64{5, 5}, []float64{5, 5}, []float64{5, 5}, 2)
+	assert.Equal(t, -50.0, result[1])
+}
@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+// Package patterns detects candlestick patterns.
+package patterns
+
+import (
+	"math"
+	"maystocks/indapi"
+	"slices"
+)
+
+type Pattern string
+
+const (
+	Doji               Pattern = "Doji"
+	Hammer             Pattern = "Hammer"
+	BullishEngulfing   Pattern = "Bullish Engulfing"
+	BearishEngulfing   Pattern = "Bearish Engulfing"
+	MorningStar        Pattern = "Morning Star"
+	EveningStar        Pattern = "Evening Star"
+	ThreeWhiteSoldiers Pattern = "Three White Soldiers"
+	InsideBar          Pattern = "Inside Bar"
+	OutsideBar         Pattern = "Outside Bar"
+)
+
+// All patterns, in the order in which they are checked.
+var Patterns = []Pattern{
+	Doji,
+	Hammer,
+	BullishEngulfing,
+	BearishEngulfing,
+	MorningStar,
+	EveningStar,
+	ThreeWhiteSoldiers,
+	InsideBar,
+	OutsideBar,
+}
+
+type Bias int
+
+const (
+	BiasNeutral Bias = iota
+	BiasBullish
+	BiasBearish
+)
+
+type definition struct {
+	// Number of candles of the pattern, including the last candle.
+	numCandles   int
+	bias         Bias
+	abbreviation string
+	matches      func(c []Candle) bool
+}
+
+var definitions = map[Pattern]definition{
+	Doji:               {1, BiasNeutral, "D", isDoji},
+	Hammer:             {2, BiasBullish, "H", isHammer},
+	BullishEngulfing:   {2, BiasBullish, "BullE", isBullishEngulfing},
+	BearishEngulfing:   {2, BiasBearish, "BearE", isBearishEngulfing},
+	MorningStar:        {3, BiasBullish, "MS", isMorningStar},
+	EveningStar:        {3, BiasBearish, "ES", isEveningStar},
+	ThreeWhiteSoldiers: {3, BiasBullish, "3WS", isThreeWhiteSoldiers},
+	InsideBar:          {2, BiasNeutral, "IB", isInsideBar},
+	OutsideBar:         {2, BiasNeutral, "OB", isOutsideBar},
+}
+
+const (
+	// Maximum body of a doji, relative to the range of the candle.
+	dojiBodyRatio = 0.1
+	// Maximum body of the middle candle of a star, relative to the body of the first candle.
+	starBodyRatio = 0.3
+)
+
+// Returns whether the pattern is bullish, bearish or neutral.
+func (p Pattern) GetBias() Bias {
+	return definitions[p].bias
+}
+
+// Returns a short label of the pattern which is shown in the plot.
+func (p Pattern) GetAbbreviation() string {
+	return definitions[p].abbreviation
+}
+
+type Candle struct {
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+}
+
+func NewCandle(c indapi.CandleData) Candle {
+	o, _ := c.OpenPrice.Float64()
+	h, _ := c.HighPrice.Float64()
+	l, _ := c.LowPrice.Float64()
+	cl, _ := c.ClosePrice.Float64()
+	return Candle{Open: o, High: h, Low: l, Close: cl}
+}
+
+func FromCandleData(data []indapi.CandleData) []Candle {
+	c := make([]Candle, len(data))
+	for i := range data {
+		c[i] = NewCandle(data[i])
+	}
+	return c
+}
+
+func (c Candle) body() float64 {
+	return math.Abs(c.Close - c.Open)
+}
+
+func (c Candle) bodyTop() float64 {
+	return math.Max(c.Open, c.Close)
+}
+
+func (c Candle) bodyBottom() float64 {
+	return math.Min(c.Open, c.Close)
+}
+
+func (c Candle) upperShadow() float64 {
+	return c.High - c.bodyTop()
+}
+
+func (c Candle) lowerShadow() float64 {
+	return c.bodyBottom() - c.Low
+}
+
+func (c Candle) isBullish() bool {
+	return c.Close > c.Open
+}
+
+func (c Candle) isBearish() bool {
+	return c.Close < c.Open
+}
+
+// Pattern which was found, ending at the candle with the given index.
+type Match struct {
+	Index   int
+	Pattern Pattern
+}
+
+// Detects the given patterns in the candles. Only patterns which end at index start or later are returned,
+// ordered by index. Patterns which end at the same candle are ordered like Patterns.
+func Detect(candles []Candle, patterns []Pattern, start int) []Match {
+	var matches []Match
+	for i := max(start, 0); i < len(candles); i++ {
+		for _, p := range Patterns {
+			def := definitions[p]
+			if i+1 < def.numCandles || !slices.Contains(patterns, p) {
+				continue
+			}
+			if def.matches(candles[i+1-def.numCandles : i+1]) {
+				matches = append(matches, Match{Index: i, Pattern: p})
+			}
+		}
+	}
+	return matches
+}
+
+// Open and close are (almost) equal.
+func isDoji(c []Candle) bool {
+	r := c[0].High - c[0].Low
+	return r > 0 && c[0].body() <= dojiBodyRatio*r
+}
+
+// Small body at the top of the candle with a long lower shadow, after a decline.
+func isHammer(c []Candle) bool {
+	prev, cur := c[0], c[1]
+	body := cur.body()
+	return body > 0 && !isDoji(c[1:]) && cur.lowerShadow() >= 2*body && cur.upperShadow() <= body && cur.Low < prev.Low
+}
+
+// Bullish candle whose body engulfs the body of the previous bearish candle.
+func isBullishEngulfing(c []Candle) bool {
+	prev, cur := c[0], c[1]
+	return prev.isBearish() && cur.isBullish() && cur.Open <= prev.Close && cur.Close >= prev.Open && cur.body() > prev.body()
+}
+
+// Bearish candle whose body engulfs the body of the previous bullish candle.
+func isBearishEngulfing(c []Candle) bool {
+	prev, cur := c[0], c[1]
+	return prev.isBullish() && cur.isBearish() && cur.Open >= prev.Close && cur.Close <= prev.Open && cur.body() > prev.body()
+}
+
+// Bearish candle, followed by a small body below its close, followed by a bullish candle which
+// closes above the middle of the first body.
+func isMorningStar(c []Candle) bool {
+	first, star, last := c[0], c[1], c[2]
+	return first.isBearish() && star.body() <= starBodyRatio*first.body() && star.bodyTop() <= first.Close &&
+		last.isBullish() && last.Close > (first.Open+first.Close)/2
+}
+
+// Bullish candle, followed by a small body above its close, followed by a bearish candle which
+// closes below the middle of the first body.
+func isEveningStar(c []Candle) bool {
+	first, star, last := c[0], c[1], c[2]
+	return first.isBullish() && star.body() <= starBodyRatio*first.body() && star.bodyBottom() >= first.Close &&
+		last.isBearish() && last.Close < (first.Open+first.Close)/2
+}
+
+// Three bullish candles with rising closes, each opening within the previous body and closing near its high.
+func isThreeWhiteSoldiers(c []Candle) bool {
+	for i, cur := range c {
+		if !cur.isBullish() || cur.upperShadow() > cur.body()/2 {
+			return false
+		}
+		if i > 0 {
+			prev := c[i-1]
+			if cur.Close <= prev.Close || cur.Open < prev.Open || cur.Open > prev.Close {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Range of the candle is within the range of the previous candle.
+func isInsideBar(c []Candle) bool {
+	return c[1].High < c[0].High && c[1].Low > c[0].Low
+}
+
+// Range of the candle exceeds the range of the previous candle on both sides.
+func isOutsideBar(c []Candle) bool {
+	return c[1].High > c[0].High && c[1].Low < c[0].Low
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package patterns
+
+import (
+	"maystocks/indapi"
+	"testing"
+
+	"github.com/ericlagergren/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// Candle with open, high, low and close.
+func c(o, h, l, cl float64) Candle {
+	return Candle{Open: o, High: h, Low: l, Close: cl}
+}
+
+func detectLast(candles []Candle, p Pattern) bool {
+	matches := Detect(candles, []Pattern{p}, len(candles)-1)
+	return len(matches) == 1 && matches[0].Index == len(candles)-1 && matches[0].Pattern == p
+}
+
+func TestDoji(t *testing.T) {
+	assert.True(t, detectLast([]Candle{c(10, 12, 8, 10.1)}, Doji))
+	assert.False(t, detectLast([]Candle{c(10, 12, 8, 11)}, Doji))
+	// Candles without range are not considered.
+	assert.False(t, detectLast([]Candle{c(10, 10, 10, 10)}, Doji))
+}
+
+func TestHammer(t *testing.T) {
+	prev := c(11, 11.5, 9.5, 10)
+	assert.True(t, detectLast([]Candle{prev, c(9.6, 10.1, 7, 10)}, Hammer))
+	// Long upper shadow
+	assert.False(t, detectLast([]Candle{prev, c(9.6, 11, 7, 10)}, Hammer))
+	// No decline
+	assert.False(t, detectLast([]Candle{c(11, 11.5, 6, 10), c(9.6, 10.1, 7, 10)}, Hammer))
+}
+
+func TestEngulfing(t *testing.T) {
+	bullish := []Candle{c(10, 10.5, 8.5, 9), c(8.8, 10.8, 8.6, 10.5)}
+	assert.True(t, detectLast(bullish, BullishEngulfing))
+	assert.False(t, detectLast(bullish, BearishEngulfing))
+	bearish := []Candle{c(9, 10.5, 8.5, 10), c(10.2, 10.4, 8.6, 8.5)}
+	assert.True(t, detectLast(bearish, BearishEngulfing))
+	assert.False(t, detectLast(bearish, BullishEngulfing))
+	// The body does not engulf the previous body.
+	assert.False(t, detectLast([]Candle{c(10, 10.5, 8.5, 9), c(9.2, 10.8, 8.6, 10.5)}, BullishEngulfing))
+}
+
+func TestStars(t *testing.T) {
+	morning := []Candle{c(12, 12.2, 9.8, 10), c(9.8, 10, 9.3, 9.6), c(9.8, 11.5, 9.7, 11.3)}
+	assert.True(t, detectLast(morning, MorningStar))
+	assert.False(t, detectLast(morning, EveningStar))
+	evening := []Candle{c(10, 12.2, 9.8, 12), c(12.2, 12.7, 12, 12.4), c(12.2, 12.3, 10.5, 10.7)}
+	assert.True(t, detectLast(evening, EveningStar))
+	assert.False(t, detectLast(evening, MorningStar))
+	// The last candle does not recover half of the first body.
+	assert.False(t, detectLast([]Candle{morning[0], morning[1], c(9.8, 10.8, 9.7, 10.6)}, MorningStar))
+}
+
+func TestThreeWhiteSoldiers(t *testing.T) {
+	soldiers := []Candle{c(10, 11.1, 9.9, 11), c(10.5, 12.1, 10.4, 12), c(11.5, 13.1, 11.4, 13)}
+	assert.True(t, detectLast(soldiers, ThreeWhiteSoldiers))
+	// The second candle opens above the previous body.
+	assert.False(t, detectLast([]Candle{soldiers[0], c(11.2, 12.1, 11.1, 12), soldiers[2]}, ThreeWhiteSoldiers))
+	// The last candle has a long upper shadow.
+	assert.False(t, detectLast([]Candle{soldiers[0], soldiers[1], c(11.5, 14, 11.4, 12.5)}, ThreeWhiteSoldiers))
+}
+
+func TestInsideOutsideBar(t *testing.T) {
+	inside := []Candle{c(10, 12, 8, 11), c(10.5, 11.5, 9, 11)}
+	assert.True(t, detectLast(inside, InsideBar))
+	assert.False(t, detectLast(inside, OutsideBar))
+	outside := []Candle{inside[1], inside[0]}
+	assert.True(t, detectLast(outside, OutsideBar))
+	assert.False(t, detectLast(outside, InsideBar))
+}
+
+func TestDetect(t *testing.T) {
+	candles := []Candle{c(10, 12, 8, 11), c(10.5, 11.5, 9, 11), c(11, 13, 8.5, 11)}
+	matches := Detect(candles, Patterns, 0)
+	assert.Equal(t, []Match{{Index: 1, Pattern: InsideBar}, {Index: 2, Pattern: Doji}, {Index: 2, Pattern: OutsideBar}}, matches)
+	// Only patterns ending at start or later
+	assert.Equal(t, matches[1:], Detect(candles, Patterns, 2))
+	// Only selected patterns
+	assert.Equal(t, matches[2:], Detect(candles, []Pattern{OutsideBar}, 0))
+}
+
+func TestFromCandleData(t *testing.T) {
+	data := []indapi.CandleData{{
+		OpenPrice:  decimal.New(100, 1),
+		HighPrice:  decimal.New(120, 1),
+		LowPrice:   decimal.New(80, 1),
+		ClosePrice: decimal.New(101, 1),
+		Volume:     decimal.New(1, 0),
+	}}
+	candles := FromCandleData(data)
+	assert.Equal(t, []Candle{c(10, 12, 8, 10.1)}, candles)
+	assert.True(t, detectLast(candles, Doji))
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"maps"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"slices"
+	"time"
+
+	"gioui.org/layout"
+)
+
+// Indicator which is calculated by a plugin.
+type Indicator struct {
+	plugin      *Plugin
+	properties  map[string]string
+	resolution  candles.CandleResolution
+	timestamps  []time.Time
+	results     [][]float64
+	updateState indapi.UpdateState
+	colors      []color.NRGBA
+	// Error of the last calculation, which is shown in the plot.
+	err error
+}
+
+// Creates an indicator using the plugin, can be registered in the indicator registry.
+func (p *Plugin) NewIndicator() indapi.IndicatorData {
+	d := Indicator{
+		plugin:     p,
+		properties: make(map[string]string, len(p.propertyDescriptors)),
+	}
+	for _, desc := range p.propertyDescriptors {
+		d.properties[desc.Key] = desc.Default
+	}
+	return &d
+}
+
+func (d *Indicator) GetId() indapi.IndicatorId {
+	return d.plugin.id
+}
+
+func (d *Indicator) GetPropertyDescriptors() []indapi.PropertyDescriptor {
+	return d.plugin.propertyDescriptors
+}
+
+func (d *Indicator) GetProperties() map[string]string {
+	return maps.Clone(d.properties)
+}
+
+func (d *Indicator) SetProperties(prop map[string]string) error {
+	return indapi.ApplyProperties(d.plugin.propertyDescriptors, prop, func(key string, value string) {
+		d.properties[key] = value
+	})
+}
+
+func (d *Indicator) GetColors() []color.NRGBA {
+	return d.colors
+}
+
+func (d *Indicator) SetColors(c []color.NRGBA) {
+	d.colors = indapi.GetMinColors(c, len(d.plugin.outputs))
+}
+
+// All values are calculated again whenever the candles changed, because the plugin interface
+// passes the whole candle series and has no incremental calculation.
+// The plugin is called without holding the data lock, because a call can take up to callTimeout.
+func (d *Indicator) Update(r candles.CandleResolution, data *indapi.PlotData) {
+	if errors.Is(d.err, ErrStopped) {
+		return
+	}
+	data.DataMutex.Lock()
+	if _, changed := d.updateState.Next(data); !changed {
+		data.DataMutex.Unlock()
+		return
+	}
+	c := &data.Cache
+	d.resolution = r
+	d.timestamps = c.Timestamps
+	open := slices.Clone(c.OpenPrices)
+	high := slices.Clone(c.HighPrices)
+	low := slices.Clone(c.LowPrices)
+	closing := slices.Clone(c.ClosePrices)
+	volumes := slices.Clone(c.Volumes)
+	data.DataMutex.Unlock()
+	d.results, d.err = d.plugin.Calculate(d.properties, open, high, low, closing, volumes)
+	if d.err != nil {
+		log.Printf("Plugin indicator %s failed: %v", d.plugin.id, d.err)
+	}
+}
+
+func (d *Indicator) Plot(p indapi.Plotter, maxValue *float64, defaultColor color.NRGBA, gtx layout.Context) {
+	c := indapi.GetNormalisedColors(d.colors, defaultColor)
+	if d.err != nil {
+		p.PlotMessage(fmt.Sprintf("%s failed: %v", d.plugin.id, d.err), c[0], gtx)
+	}
+	for i, result := range d.results {
+		p.PlotLine(d.timestamps[0:len(result)], result, maxValue, d.resolution, c[i], gtx)
+	}
+}
+
+func (d *Indicator) GetOutputNames() []string {
+	return d.plugin.outputs
+}
+
+func (d *Indicator) GetOutput(name string) []float64 {
+	for i, output := range d.plugin.outputs {
+		if output == name && i < len(d.results) {
+			return d.results[i]
+		}
+	}
+	return nil
+}
+
+func (d *Indicator) GetSubPlotType() indapi.SubPlotType {
+	return d.plugin.subPlotType
+}
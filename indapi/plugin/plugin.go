@@ -0,0 +1,336 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+// Package plugin loads indicators which are compiled to WebAssembly.
+//
+// Plugins are executed in a sandbox: they have no access to files, network or environment, their
+// memory is limited, and calls which do not finish in time stop the plugin. A plugin is a WASI
+// reactor module, e.g. built using GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared, which
+// exports the following functions:
+//
+//	maystocks_alloc(size uint32) uint32
+//	maystocks_free(ptr uint32)
+//	maystocks_describe() uint64
+//	maystocks_calculate(propertiesPtr uint32, propertiesSize uint32, dataPtr uint32, numCandles uint32) uint64
+//
+// Buffers are passed as pointer and size, results are packed as pointer<<32|size. Buffers which
+// are passed to the plugin are allocated using maystocks_alloc, buffers which are returned by the
+// plugin are released by calling maystocks_free.
+//
+// maystocks_describe returns a JSON Descriptor of the indicator. maystocks_calculate receives the
+// properties as JSON object, and the candle data as little endian float64 values: numCandles open
+// prices, followed by the high prices, low prices, close prices and volumes. It returns the output
+// series in the same format, numCandles values for each output in the order of the descriptor.
+package plugin
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"maystocks/indapi"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	FileExtension = ".wasm"
+	// Memory limit of a plugin in pages of 64 KiB.
+	memoryLimitPages = 4096
+	// Calls which take longer stop the plugin.
+	callTimeout = 10 * time.Second
+	numInputs   = 5
+)
+
+// Returned by Calculate if the plugin was stopped, because a call failed or did not finish in time.
+var ErrStopped = errors.New("plugin stopped")
+
+// Description of the indicator which is implemented by a plugin.
+type Descriptor struct {
+	Id indapi.IndicatorId `json:"id"`
+	// One of "price", "volume" or "indicator".
+	SubPlotType string               `json:"subPlotType"`
+	Outputs     []string             `json:"outputs"`
+	Properties  []PropertyDescriptor `json:"properties"`
+}
+
+// Property of a plugin indicator, see indapi.PropertyDescriptor.
+type PropertyDescriptor struct {
+	Key         string `json:"key"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	// One of "int", "float", "enum", "bool", "color", "source" or "time".
+	Type    string   `json:"type"`
+	Default string   `json:"default"`
+	Min     float64  `json:"min"`
+	Max     float64  `json:"max"`
+	Step    float64  `json:"step"`
+	Options []string `json:"options"`
+}
+
+var propertyTypes = map[string]indapi.PropertyType{
+	"int":    indapi.PropertyTypeInt,
+	"float":  indapi.PropertyTypeFloat,
+	"enum":   indapi.PropertyTypeEnum,
+	"bool":   indapi.PropertyTypeBool,
+	"color":  indapi.PropertyTypeColor,
+	"source": indapi.PropertyTypeSource,
+	"time":   indapi.PropertyTypeTime,
+}
+
+var subPlotTypes = map[string]indapi.SubPlotType{
+	"price":     indapi.SubPlotTypePrice,
+	"volume":    indapi.SubPlotTypeVolume,
+	"indicator": indapi.SubPlotTypeIndicator,
+}
+
+// Loaded plugin. Calls are serialised, because a WebAssembly module is not thread safe.
+type Plugin struct {
+	id                  indapi.IndicatorId
+	subPlotType         indapi.SubPlotType
+	outputs             []string
+	propertyDescriptors []indapi.PropertyDescriptor
+	mutex               sync.Mutex
+	ctx                 context.Context
+	runtime             wazero.Runtime
+	module              api.Module
+	alloc               api.Function
+	free                api.Function
+	calculate           api.Function
+	// Error which stopped the plugin, see ErrStopped.
+	err error
+}
+
+// Loads all plugins of a directory. A missing directory is not an error.
+// Plugins which cannot be loaded are skipped, and their errors are returned together with the other plugins.
+func LoadDir(ctx context.Context, dir string) ([]*Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var plugins []*Plugin
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), FileExtension) {
+			continue
+		}
+		p, err := LoadFile(ctx, filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, errors.Join(errs...)
+}
+
+func LoadFile(ctx context.Context, fileName string) (*Plugin, error) {
+	code, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Load(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", filepath.Base(fileName), err)
+	}
+	return p, nil
+}
+
+// Loads a plugin from WebAssembly code. The context is used for all calls of the plugin.
+func Load(ctx context.Context, code []byte) (*Plugin, error) {
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(memoryLimitPages).
+		WithCloseOnContextDone(true))
+	p := Plugin{ctx: ctx, runtime: r}
+	if err := p.instantiate(code); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	if err := p.describe(); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *Plugin) instantiate(code []byte) error {
+	// WASI is provided without file system, environment and arguments.
+	if _, err := wasi_snapshot_preview1.Instantiate(p.ctx, p.runtime); err != nil {
+		return err
+	}
+	module, err := p.runtime.InstantiateWithConfig(p.ctx, code, wazero.NewModuleConfig().
+		WithStartFunctions("_initialize").
+		WithSysWalltime().
+		WithSysNanotime())
+	if err != nil {
+		return err
+	}
+	p.module = module
+	p.alloc = module.ExportedFunction("maystocks_alloc")
+	p.free = module.ExportedFunction("maystocks_free")
+	p.calculate = module.ExportedFunction("maystocks_calculate")
+	if p.alloc == nil || p.free == nil || p.calculate == nil || module.ExportedFunction("maystocks_describe") == nil {
+		return errors.New("missing exported functions")
+	}
+	return nil
+}
+
+func (p *Plugin) describe() error {
+	ctx, cancel := context.WithTimeout(p.ctx, callTimeout)
+	defer cancel()
+	result, err := p.module.ExportedFunction("maystocks_describe").Call(ctx)
+	if err != nil {
+		return err
+	}
+	buf, err := p.readResult(ctx, result[0])
+	if err != nil {
+		return err
+	}
+	var desc Descriptor
+	if err := json.Unmarshal(buf, &desc); err != nil {
+		return fmt.Errorf("invalid descriptor: %w", err)
+	}
+	return p.setDescriptor(desc)
+}
+
+func (p *Plugin) setDescriptor(desc Descriptor) error {
+	if desc.Id == "" {
+		return errors.New("missing indicator id")
+	}
+	if len(desc.Outputs) == 0 {
+		return errors.New("missing outputs")
+	}
+	subPlotType, ok := subPlotTypes[desc.SubPlotType]
+	if !ok {
+		return fmt.Errorf("invalid subplot type %q", desc.SubPlotType)
+	}
+	p.id = desc.Id
+	p.subPlotType = subPlotType
+	p.outputs = desc.Outputs
+	for _, prop := range desc.Properties {
+		propertyType, ok := propertyTypes[prop.Type]
+		if !ok {
+			return fmt.Errorf("invalid type %q of property %s", prop.Type, prop.Key)
+		}
+		p.propertyDescriptors = append(p.propertyDescriptors, indapi.PropertyDescriptor{
+			Key:         prop.Key,
+			Label:       prop.Label,
+			Description: prop.Description,
+			Type:        propertyType,
+			Default:     prop.Default,
+			Min:         prop.Min,
+			Max:         prop.Max,
+			Step:        prop.Step,
+			Options:     prop.Options,
+		})
+	}
+	return nil
+}
+
+func (p *Plugin) GetId() indapi.IndicatorId {
+	return p.id
+}
+
+// Releases the resources of the plugin, it cannot be used afterwards.
+func (p *Plugin) Close() error {
+	return p.runtime.Close(p.ctx)
+}
+
+// Calculates the output series of the indicator.
+// If the plugin fails, it is stopped and all further calls return an error which wraps ErrStopped.
+func (p *Plugin) Calculate(properties map[string]string, open, high, low, closing, volume []float64) ([][]float64, error) {
+	propertiesJson, err := json.Marshal(properties)
+	if err != nil {
+		return nil, err
+	}
+	n := len(closing)
+	data := make([]byte, 0, numInputs*n*8)
+	for _, values := range [][]float64{open, high, low, closing, volume} {
+		if len(values) != n {
+			return nil, errors.New("inconsistent candle data")
+		}
+		for _, v := range values {
+			data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, callTimeout)
+	defer cancel()
+	propertiesPtr, err := p.write(ctx, propertiesJson)
+	if err != nil {
+		return nil, p.stop(err)
+	}
+	defer p.free.Call(ctx, uint64(propertiesPtr))
+	dataPtr, err := p.write(ctx, data)
+	if err != nil {
+		return nil, p.stop(err)
+	}
+	defer p.free.Call(ctx, uint64(dataPtr))
+	result, err := p.calculate.Call(ctx, uint64(propertiesPtr), uint64(len(propertiesJson)), uint64(dataPtr), uint64(n))
+	if err != nil {
+		return nil, p.stop(err)
+	}
+	buf, err := p.readResult(ctx, result[0])
+	if err != nil {
+		return nil, p.stop(err)
+	}
+	if len(buf) != len(p.outputs)*n*8 {
+		return nil, fmt.Errorf("invalid result size %d", len(buf))
+	}
+	outputs := make([][]float64, len(p.outputs))
+	for i := range outputs {
+		outputs[i] = make([]float64, n)
+		for j := range outputs[i] {
+			outputs[i][j] = math.Float64frombits(binary.LittleEndian.Uint64(buf[(i*n+j)*8:]))
+		}
+	}
+	return outputs, nil
+}
+
+// Stops the plugin after a failed call. The state of the module is unknown afterwards, and a call
+// which did not finish in time has already closed the module.
+func (p *Plugin) stop(err error) error {
+	p.err = fmt.Errorf("%w: %w", ErrStopped, err)
+	return p.err
+}
+
+// Copies data to a buffer which is allocated in the memory of the plugin.
+func (p *Plugin) write(ctx context.Context, data []byte) (uint32, error) {
+	result, err := p.alloc.Call(ctx, uint64(len(data)))
+	if err != nil {
+		return 0, err
+	}
+	ptr := uint32(result[0])
+	if !p.module.Memory().Write(ptr, data) {
+		return 0, errors.New("invalid buffer allocated")
+	}
+	return ptr, nil
+}
+
+// Copies a buffer which was returned by the plugin, and releases it.
+func (p *Plugin) readResult(ctx context.Context, packed uint64) ([]byte, error) {
+	ptr, size := uint32(packed>>32), uint32(packed)
+	defer p.free.Call(ctx, uint64(ptr))
+	buf, ok := p.module.Memory().Read(ptr, size)
+	if !ok {
+		return nil, errors.New("invalid buffer returned")
+	}
+	return append([]byte(nil), buf...), nil
+}
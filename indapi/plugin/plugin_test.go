@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package plugin
+
+import (
+	"context"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds the example plugin in testdata using the Go toolchain.
+func buildTestPlugin(t *testing.T, dir string) string {
+	if testing.Short() {
+		t.Skip("building the test plugin is slow")
+	}
+	fileName := filepath.Join(dir, "momentum"+FileExtension)
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", fileName, ".")
+	cmd.Dir = filepath.Join("testdata", "momentum")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm", "GOFLAGS=")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building test plugin failed: %v\n%s", err, output)
+	}
+	return fileName
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	buildTestPlugin(t, dir)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "invalid"+FileExtension), []byte("invalid"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("ignored"), 0600))
+
+	plugins, err := LoadDir(context.Background(), dir)
+	assert.ErrorContains(t, err, "invalid"+FileExtension)
+	if !assert.Len(t, plugins, 1) {
+		return
+	}
+	p := plugins[0]
+	defer p.Close()
+	assert.Equal(t, indapi.IndicatorId("plugin_momentum"), p.GetId())
+
+	ind := p.NewIndicator()
+	assert.Equal(t, indapi.SubPlotTypeIndicator, ind.GetSubPlotType())
+	assert.Equal(t, map[string]string{"Time Periods": "10"}, ind.GetProperties())
+	assert.NoError(t, ind.SetProperties(map[string]string{"Time Periods": "2"}))
+	assert.Error(t, ind.SetProperties(map[string]string{"Time Periods": "0"}))
+	ind.SetColors(nil)
+	assert.Len(t, ind.GetColors(), 1)
+
+	closePrices := []float64{1, 3, 2, 5, 4}
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	data.Cache.Timestamps = make([]time.Time, len(closePrices))
+	data.Cache.OpenPrices = make([]float64, len(closePrices))
+	data.Cache.HighPrices = make([]float64, len(closePrices))
+	data.Cache.LowPrices = make([]float64, len(closePrices))
+	data.Cache.ClosePrices = closePrices
+	data.Cache.Volumes = make([]float64, len(closePrices))
+	data.Cache.ChangeCount = 1
+	ind.Update(candles.CandleOneDay, &data)
+	expected := []float64{math.NaN(), math.NaN(), 1, 2, 2}
+	result := ind.(indapi.OutputProvider).GetOutput(indapi.OutputValue)
+	if assert.Len(t, result, len(expected)) {
+		for i := range expected {
+			assert.InDelta(t, expected[i], result[i], 1e-9, "index %d", i) // NaN is equal to NaN within delta
+		}
+	}
+}
+
+func TestStopped(t *testing.T) {
+	dir := t.TempDir()
+	p, err := LoadFile(context.Background(), buildTestPlugin(t, dir))
+	if !assert.NoError(t, err) {
+		return
+	}
+	ind := p.NewIndicator()
+	data := indapi.PlotData{DataMutex: new(sync.RWMutex)}
+	data.Cache.Timestamps = make([]time.Time, 3)
+	data.Cache.OpenPrices = make([]float64, 3)
+	data.Cache.HighPrices = make([]float64, 3)
+	data.Cache.LowPrices = make([]float64, 3)
+	data.Cache.ClosePrices = []float64{1, 2, 3}
+	data.Cache.Volumes = make([]float64, 3)
+	data.Cache.ChangeCount = 1
+
+	// A failed call stops the plugin, further calls return the same error.
+	assert.NoError(t, p.Close())
+	_, err = p.Calculate(nil, data.Cache.OpenPrices, data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices, data.Cache.Volumes)
+	assert.ErrorIs(t, err, ErrStopped)
+	_, err2 := p.Calculate(nil, data.Cache.OpenPrices, data.Cache.HighPrices, data.Cache.LowPrices, data.Cache.ClosePrices, data.Cache.Volumes)
+	assert.Equal(t, err, err2)
+
+	ind.Update(candles.CandleOneDay, &data)
+	d := ind.(*Indicator)
+	assert.ErrorIs(t, d.err, ErrStopped)
+	assert.Nil(t, d.GetOutput(indapi.OutputValue))
+	// The indicator is not updated anymore.
+	data.Cache.Timestamps = append(data.Cache.Timestamps, time.Time{})
+	data.Cache.ChangeCount++
+	ind.Update(candles.CandleOneDay, &data)
+	assert.Len(t, d.timestamps, 3)
+}
+
+func TestLoadDirMissing(t *testing.T) {
+	plugins, err := LoadDir(context.Background(), filepath.Join(t.TempDir(), "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, plugins)
+}
+
+func TestSetDescriptor(t *testing.T) {
+	var p Plugin
+	assert.Error(t, p.setDescriptor(Descriptor{SubPlotType: "price", Outputs: []string{"value"}}))
+	assert.Error(t, p.setDescriptor(Descriptor{Id: "test", SubPlotType: "price"}))
+	assert.Error(t, p.setDescriptor(Descriptor{Id: "test", SubPlotType: "unknown", Outputs: []string{"value"}}))
+	assert.Error(t, p.setDescriptor(Descriptor{Id: "test", SubPlotType: "price", Outputs: []string{"value"},
+		Properties: []PropertyDescriptor{{Key: "a", Type: "unknown"}}}))
+	assert.NoError(t, p.setDescriptor(Descriptor{Id: "test", SubPlotType: "volume", Outputs: []string{"value"},
+		Properties: []PropertyDescriptor{{Key: "Source", Type: "source", Default: "close"}}}))
+	assert.Equal(t, indapi.SubPlotTypeVolume, p.subPlotType)
+	assert.Equal(t, indapi.PropertyTypeSource, p.propertyDescriptors[0].Type)
+}
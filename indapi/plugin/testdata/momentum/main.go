@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+//go:build wasip1
+
+// Example of an indicator plugin, which calculates the momentum of the close prices.
+// Build using GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o momentum.wasm
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strconv"
+	"unsafe"
+)
+
+const descriptor = `{
+	"id": "plugin_momentum",
+	"subPlotType": "indicator",
+	"outputs": ["value"],
+	"properties": [{"key": "Time Periods", "label": "Time Periods", "type": "int", "default": "10", "min": 1, "max": 1000}]
+}`
+
+// Buffers which are used by the host, so that they are not garbage collected.
+var buffers = make(map[uint32][]byte)
+
+func main() {}
+
+func newBuffer(size uint32) []byte {
+	buf := make([]byte, max(size, 1))
+	buffers[bufferPtr(buf)] = buf
+	return buf[:size]
+}
+
+func bufferPtr(buf []byte) uint32 {
+	return uint32(uintptr(unsafe.Pointer(unsafe.SliceData(buf))))
+}
+
+func pack(buf []byte) uint64 {
+	return uint64(bufferPtr(buf))<<32 | uint64(len(buf))
+}
+
+//go:wasmexport maystocks_alloc
+func alloc(size uint32) uint32 {
+	return bufferPtr(newBuffer(size))
+}
+
+//go:wasmexport maystocks_free
+func free(ptr uint32) {
+	delete(buffers, ptr)
+}
+
+//go:wasmexport maystocks_describe
+func describe() uint64 {
+	buf := newBuffer(uint32(len(descriptor)))
+	copy(buf, descriptor)
+	return pack(buf)
+}
+
+//go:wasmexport maystocks_calculate
+func calculate(propertiesPtr uint32, propertiesSize uint32, dataPtr uint32, numCandles uint32) uint64 {
+	var properties map[string]string
+	_ = json.Unmarshal(buffers[propertiesPtr][:propertiesSize], &properties)
+	period, err := strconv.Atoi(properties["Time Periods"])
+	if err != nil {
+		period = 10
+	}
+	n := int(numCandles)
+	data := buffers[dataPtr]
+	result := newBuffer(numCandles * 8)
+	for i := range n {
+		value := math.NaN()
+		if i >= period {
+			// Close prices follow the open, high and low prices.
+			closing := math.Float64frombits(binary.LittleEndian.Uint64(data[(3*n+i)*8:]))
+			previous := math.Float64frombits(binary.LittleEndian.Uint64(data[(3*n+i-period)*8:]))
+			value = closing - previous
+		}
+		binary.LittleEndian.PutUint64(result[i*8:], math.Float64bits(value))
+	}
+	return pack(result)
+}
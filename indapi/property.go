@@ -0,0 +1,232 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package indapi
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/colornames"
+)
+
+type PropertyType int
+
+const (
+	PropertyTypeInt PropertyType = iota
+	PropertyTypeFloat
+	PropertyTypeEnum
+	PropertyTypeBool
+	PropertyTypeColor
+	PropertyTypeSource
+	// Date and time in the format TimePropertyFormat, or empty if not set.
+	PropertyTypeTime
+)
+
+const TimePropertyFormat = "2006-01-02 15:04"
+
+// Price series which can be used as input of an indicator.
+const (
+	SourceClose  = "close"
+	SourceOpen   = "open"
+	SourceHigh   = "high"
+	SourceLow    = "low"
+	SourceHL2    = "hl2"
+	SourceHLC3   = "hlc3"
+	SourceOHLC4  = "ohlc4"
+	SourceVolume = "volume"
+)
+
+var sourceSeriesList = []string{SourceClose, SourceOpen, SourceHigh, SourceLow, SourceHL2, SourceHLC3, SourceOHLC4, SourceVolume}
+
+// Describes a property of an indicator.
+// Property values are stored as strings in the configuration, the descriptor defines how they are interpreted.
+type PropertyDescriptor struct {
+	Key         string
+	Label       string
+	Description string
+	Type        PropertyType
+	Default     string
+	// Range of int and float values. There is no limit if Min and Max are both zero.
+	Min float64
+	Max float64
+	// Step size of int and float editors. Defaults to 1 if not set.
+	Step float64
+	// Allowed values of enum properties.
+	Options []string
+}
+
+func SourceSeriesList() []string {
+	return sourceSeriesList
+}
+
+func (p PropertyDescriptor) GetOptions() []string {
+	switch p.Type {
+	case PropertyTypeEnum:
+		return p.Options
+	case PropertyTypeSource:
+		return sourceSeriesList
+	case PropertyTypeBool:
+		return []string{"false", "true"}
+	default:
+		return nil
+	}
+}
+
+func (p PropertyDescriptor) GetStep() float64 {
+	if p.Step > 0 {
+		return p.Step
+	}
+	return 1
+}
+
+func (p PropertyDescriptor) HasRange() bool {
+	return p.Min != 0 || p.Max != 0
+}
+
+// Returns an error if the value is invalid for this property.
+func (p PropertyDescriptor) Validate(value string) error {
+	value = strings.TrimSpace(value)
+	switch p.Type {
+	case PropertyTypeInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", p.Label, value)
+		}
+		return p.validateRange(float64(n))
+	case PropertyTypeFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("%s: %q is not a number", p.Label, value)
+		}
+		return p.validateRange(f)
+	case PropertyTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s: %q is not a boolean", p.Label, value)
+		}
+	case PropertyTypeColor:
+		if _, err := ParseColor(value); err != nil {
+			return fmt.Errorf("%s: %w", p.Label, err)
+		}
+	case PropertyTypeTime:
+		if value == "" {
+			return nil
+		}
+		if _, err := time.Parse(TimePropertyFormat, value); err != nil {
+			return fmt.Errorf("%s: %q is not in the format %s", p.Label, value, TimePropertyFormat)
+		}
+	case PropertyTypeEnum, PropertyTypeSource:
+		for _, o := range p.GetOptions() {
+			if value == o {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: %q is not one of %s", p.Label, value, strings.Join(p.GetOptions(), ", "))
+	default:
+		return fmt.Errorf("%s: unsupported property type", p.Label)
+	}
+	return nil
+}
+
+func (p PropertyDescriptor) validateRange(f float64) error {
+	if p.HasRange() && (f < p.Min || f > p.Max) {
+		return fmt.Errorf("%s: %s is not within %s and %s", p.Label, formatFloat(f), formatFloat(p.Min), formatFloat(p.Max))
+	}
+	return nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Returns the default values of the properties.
+func GetDefaultPropertyValues(desc []PropertyDescriptor) map[string]string {
+	values := make(map[string]string, len(desc))
+	for _, p := range desc {
+		values[p.Key] = p.Default
+	}
+	return values
+}
+
+// Validates the property values and calls set for each valid value.
+// Unknown keys and invalid values are ignored, and reported in the returned error.
+func ApplyProperties(desc []PropertyDescriptor, values map[string]string, set func(key string, value string)) error {
+	var errs []error
+	for key, value := range values {
+		found := false
+		for _, p := range desc {
+			if p.Key == key {
+				found = true
+				if err := p.Validate(value); err != nil {
+					errs = append(errs, err)
+				} else {
+					set(key, strings.TrimSpace(value))
+				}
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("unknown property %s", key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Parses a color name or a hex color value "#rrggbb".
+func ParseColor(value string) (color.NRGBA, error) {
+	name := strings.ToLower(strings.ReplaceAll(value, " ", ""))
+	if c, ok := colornames.Map[name]; ok {
+		// no alpha, directly convert
+		return color.NRGBA(c), nil
+	}
+	var r, g, b uint8
+	if len(name) == 7 {
+		if _, err := fmt.Sscanf(name, "#%02x%02x%02x", &r, &g, &b); err == nil {
+			return color.NRGBA{R: r, G: g, B: b, A: 255}, nil
+		}
+	}
+	return color.NRGBA{}, fmt.Errorf("%q is not a valid color", value)
+}
+
+// Returns the price series of the given source. The data needs to be locked by the caller.
+func GetSourceSeries(source string, data *PlotData) []float64 {
+	c := &data.Cache
+	switch source {
+	case SourceOpen:
+		return c.OpenPrices
+	case SourceHigh:
+		return c.HighPrices
+	case SourceLow:
+		return c.LowPrices
+	case SourceVolume:
+		return c.Volumes
+	case SourceHL2:
+		return averageSeries(c.HighPrices, c.LowPrices)
+	case SourceHLC3:
+		return averageSeries(c.HighPrices, c.LowPrices, c.ClosePrices)
+	case SourceOHLC4:
+		return averageSeries(c.OpenPrices, c.HighPrices, c.LowPrices, c.ClosePrices)
+	default:
+		return c.ClosePrices
+	}
+}
+
+func averageSeries(series ...[]float64) []float64 {
+	n := len(series[0])
+	for _, s := range series[1:] {
+		n = min(n, len(s))
+	}
+	result := make([]float64, n)
+	for i := range result {
+		for _, s := range series {
+			result[i] += s[i]
+		}
+		result[i] /= float64(len(series))
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package indapi
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPropertyValidate(t *testing.T) {
+	intProp := PropertyDescriptor{Key: "n", Label: "N", Type: PropertyTypeInt, Min: 1, Max: 100}
+	assert.NoError(t, intProp.Validate("20"))
+	assert.NoError(t, intProp.Validate(" 1 "))
+	assert.Error(t, intProp.Validate("0"))
+	assert.Error(t, intProp.Validate("101"))
+	assert.Error(t, intProp.Validate("2.5"))
+	assert.Error(t, intProp.Validate(""))
+
+	floatProp := PropertyDescriptor{Key: "f", Label: "F", Type: PropertyTypeFloat}
+	assert.NoError(t, floatProp.Validate("-2.5"))
+	assert.Error(t, floatProp.Validate("NaN"))
+	assert.Error(t, floatProp.Validate("abc"))
+
+	enumProp := PropertyDescriptor{Key: "e", Label: "E", Type: PropertyTypeEnum, Options: []string{"a", "b"}}
+	assert.NoError(t, enumProp.Validate("b"))
+	assert.Error(t, enumProp.Validate("c"))
+
+	sourceProp := PropertyDescriptor{Key: "s", Label: "S", Type: PropertyTypeSource}
+	assert.NoError(t, sourceProp.Validate(SourceHLC3))
+	assert.Error(t, sourceProp.Validate("median"))
+
+	boolProp := PropertyDescriptor{Key: "b", Label: "B", Type: PropertyTypeBool}
+	assert.NoError(t, boolProp.Validate("true"))
+	assert.Error(t, boolProp.Validate("yes please"))
+
+	colorProp := PropertyDescriptor{Key: "c", Label: "C", Type: PropertyTypeColor}
+	assert.NoError(t, colorProp.Validate("Dark Red"))
+	assert.NoError(t, colorProp.Validate("#ff8000"))
+	assert.Error(t, colorProp.Validate("#ff80"))
+}
+
+func TestApplyProperties(t *testing.T) {
+	desc := []PropertyDescriptor{
+		{Key: "n", Label: "N", Type: PropertyTypeInt, Default: "9", Min: 1, Max: 100},
+		{Key: "s", Label: "S", Type: PropertyTypeSource, Default: SourceClose},
+	}
+	assert.Equal(t, map[string]string{"n": "9", "s": SourceClose}, GetDefaultPropertyValues(desc))
+
+	applied := make(map[string]string)
+	set := func(key string, value string) { applied[key] = value }
+	assert.NoError(t, ApplyProperties(desc, map[string]string{"n": " 14 ", "s": SourceHigh}, set))
+	assert.Equal(t, map[string]string{"n": "14", "s": SourceHigh}, applied)
+
+	applied = make(map[string]string)
+	err := ApplyProperties(desc, map[string]string{"n": "-1", "s": SourceLow, "x": "1"}, set)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown property x")
+	// Valid values are still applied.
+	assert.Equal(t, map[string]string{"s": SourceLow}, applied)
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("#0a0B0c")
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 10, G: 11, B: 12, A: 255}, c)
+	c, err = ParseColor("Blue")
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{B: 255, A: 255}, c)
+}
+
+func TestGetSourceSeries(t *testing.T) {
+	var data PlotData
+	data.Cache.OpenPrices = []float64{1, 2}
+	data.Cache.HighPrices = []float64{4, 6}
+	data.Cache.LowPrices = []float64{2, 2}
+	data.Cache.ClosePrices = []float64{3, 2}
+	assert.Equal(t, []float64{3, 2}, GetSourceSeries(SourceClose, &data))
+	assert.Equal(t, []float64{3, 4}, GetSourceSeries(SourceHL2, &data))
+	assert.Equal(t, []float64{3, 10.0 / 3}, GetSourceSeries(SourceHLC3, &data))
+	assert.Equal(t, []float64{2.5, 3}, GetSourceSeries(SourceOHLC4, &data))
+}
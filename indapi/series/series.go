@@ -0,0 +1,269 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+// Package series contains calculations on price series which are used by indicators.
+// All functions return a slice of the same length as the input. Values which cannot
+// be calculated yet (e.g. before a full period is available) are set to NaN.
+// Leading NaN values of the input are skipped, so that results can be chained.
+package series
+
+import "math"
+
+// Returns the index of the first value which is not NaN.
+func FirstValid(values []float64) int {
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			return i
+		}
+	}
+	return len(values)
+}
+
+// Returns a slice of the given length, filled with NaN.
+func NewNaN(n int) []float64 {
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = math.NaN()
+	}
+	return result
+}
+
+// Simple moving average.
+func Sma(values []float64, period int) []float64 {
+	result := NewNaN(len(values))
+	if period < 1 {
+		return result
+	}
+	start := FirstValid(values)
+	var sum float64
+	for i := start; i < len(values); i++ {
+		sum += values[i]
+		if i-start >= period {
+			sum -= values[i-period]
+		}
+		if i-start >= period-1 {
+			result[i] = sum / float64(period)
+		}
+	}
+	return result
+}
+
+// Updates a simple moving average which was calculated by Sma, after values from index start on
+// were appended or replaced. Values of result before start are kept, the updated result is returned.
+func UpdateSma(result []float64, values []float64, period int, start int) []float64 {
+	result = Resize(result, len(values), start)
+	if period < 1 {
+		return result
+	}
+	for i := max(start, FirstValid(values)+period-1); i < len(values); i++ {
+		var sum float64
+		for j := i - period + 1; j <= i; j++ {
+			sum += values[j]
+		}
+		result[i] = sum / float64(period)
+	}
+	return result
+}
+
+// Truncates result to start and fills it up with NaN to length n.
+// Used for incremental updates, before the values from index start on are calculated again.
+func Resize(result []float64, n int, start int) []float64 {
+	start = min(start, len(result), n)
+	result = result[:start]
+	for len(result) < n {
+		result = append(result, math.NaN())
+	}
+	return result
+}
+
+// Population standard deviation over a moving window.
+func StdDev(values []float64, period int) []float64 {
+	return UpdateStdDev(nil, values, Sma(values, period), period, 0)
+}
+
+// Updates a standard deviation which was calculated by StdDev, see UpdateSma.
+// The mean needs to be the simple moving average of the values, which is already up to date.
+func UpdateStdDev(result []float64, values []float64, mean []float64, period int, start int) []float64 {
+	result = Resize(result, len(values), start)
+	for i := start; i < len(values); i++ {
+		if math.IsNaN(mean[i]) {
+			continue
+		}
+		var sum float64
+		for j := i - period + 1; j <= i; j++ {
+			d := values[j] - mean[i]
+			sum += d * d
+		}
+		result[i] = math.Sqrt(sum / float64(period))
+	}
+	return result
+}
+
+// Highest value over a moving window.
+func Highest(values []float64, period int) []float64 {
+	return UpdateHighest(nil, values, period, 0)
+}
+
+// Updates the highest value which was calculated by Highest, see UpdateSma.
+func UpdateHighest(result []float64, values []float64, period int, start int) []float64 {
+	return updateMovingExtreme(result, values, period, start, math.Max)
+}
+
+// Lowest value over a moving window.
+func Lowest(values []float64, period int) []float64 {
+	return UpdateLowest(nil, values, period, 0)
+}
+
+// Updates the lowest value which was calculated by Lowest, see UpdateSma.
+func UpdateLowest(result []float64, values []float64, period int, start int) []float64 {
+	return updateMovingExtreme(result, values, period, start, math.Min)
+}
+
+func updateMovingExtreme(result []float64, values []float64, period int, start int, f func(float64, float64) float64) []float64 {
+	result = Resize(result, len(values), start)
+	if period < 1 {
+		return result
+	}
+	for i := max(start, FirstValid(values)+period-1); i < len(values); i++ {
+		e := values[i]
+		for j := i - period + 1; j < i; j++ {
+			e = f(e, values[j])
+		}
+		result[i] = e
+	}
+	return result
+}
+
+// Exponential moving average, seeded with the simple moving average of the first period.
+func Ema(values []float64, period int) []float64 {
+	return UpdateEma(nil, values, period, 0)
+}
+
+// Updates an exponential moving average which was calculated by Ema, see UpdateSma.
+func UpdateEma(result []float64, values []float64, period int, start int) []float64 {
+	return updateSmoothed(result, values, period, 2/float64(period+1), start)
+}
+
+// Wilder's moving average (RMA), as used by RSI and ATR.
+func Rma(values []float64, period int) []float64 {
+	return UpdateRma(nil, values, period, 0)
+}
+
+// Updates a Wilder's moving average which was calculated by Rma, see UpdateSma.
+func UpdateRma(result []float64, values []float64, period int, start int) []float64 {
+	return updateSmoothed(result, values, period, 1/float64(period), start)
+}
+
+func updateSmoothed(result []float64, values []float64, period int, alpha float64, start int) []float64 {
+	result = Resize(result, len(values), start)
+	if period < 1 {
+		return result
+	}
+	seed := FirstValid(values) + period - 1
+	for i := max(start, seed); i < len(values); i++ {
+		if i > seed {
+			result[i] = alpha*values[i] + (1-alpha)*result[i-1]
+			continue
+		}
+		var sum float64
+		for _, v := range values[i-period+1 : i+1] {
+			sum += v
+		}
+		result[i] = sum / float64(period)
+	}
+	return result
+}
+
+// Mean absolute deviation from the simple moving average over a moving window.
+func MeanDeviation(values []float64, period int) []float64 {
+	result := NewNaN(len(values))
+	mean := Sma(values, period)
+	for i := range values {
+		if math.IsNaN(mean[i]) {
+			continue
+		}
+		var sum float64
+		for j := i - period + 1; j <= i; j++ {
+			sum += math.Abs(values[j] - mean[i])
+		}
+		result[i] = sum / float64(period)
+	}
+	return result
+}
+
+// Linearly weighted moving average, the most recent value has the highest weight.
+func Wma(values []float64, period int) []float64 {
+	return UpdateWma(nil, values, period, 0)
+}
+
+// Updates a weighted moving average which was calculated by Wma, see UpdateSma.
+func UpdateWma(result []float64, values []float64, period int, start int) []float64 {
+	result = Resize(result, len(values), start)
+	if period < 1 {
+		return result
+	}
+	weightSum := float64(period*(period+1)) / 2
+	for i := max(start, FirstValid(values)+period-1); i < len(values); i++ {
+		var sum float64
+		for j := 0; j < period; j++ {
+			sum += values[i-j] * float64(period-j)
+		}
+		result[i] = sum / weightSum
+	}
+	return result
+}
+
+// Double exponential moving average.
+func Dema(values []float64, period int) []float64 {
+	ema1 := Ema(values, period)
+	ema2 := Ema(ema1, period)
+	result := make([]float64, len(values))
+	for i := range result {
+		result[i] = 2*ema1[i] - ema2[i]
+	}
+	return result
+}
+
+// Triple exponential moving average.
+func Tema(values []float64, period int) []float64 {
+	ema1 := Ema(values, period)
+	ema2 := Ema(ema1, period)
+	ema3 := Ema(ema2, period)
+	result := make([]float64, len(values))
+	for i := range result {
+		result[i] = 3*ema1[i] - 3*ema2[i] + ema3[i]
+	}
+	return result
+}
+
+// True range, which is the high/low range including a gap to the previous close.
+// The first value is the high/low range.
+func TrueRange(high []float64, low []float64, closing []float64) []float64 {
+	return UpdateTrueRange(nil, high, low, closing, 0)
+}
+
+// Updates a true range which was calculated by TrueRange, see UpdateSma.
+func UpdateTrueRange(result []float64, high []float64, low []float64, closing []float64, start int) []float64 {
+	result = Resize(result, len(closing), start)
+	for i := start; i < len(result); i++ {
+		result[i] = high[i] - low[i]
+		if i > 0 {
+			result[i] = math.Max(result[i], math.Max(math.Abs(high[i]-closing[i-1]), math.Abs(low[i]-closing[i-1])))
+		}
+	}
+	return result
+}
+
+// Average true range using Wilder's smoothing.
+func Atr(high []float64, low []float64, closing []float64, period int) []float64 {
+	return Rma(TrueRange(high, low, closing), period)
+}
+
+// Sum over a moving window.
+func Sum(values []float64, period int) []float64 {
+	result := Sma(values, period)
+	for i := range result {
+		result[i] *= float64(period)
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package series
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertSeries(t *testing.T, expected []float64, actual []float64) {
+	assert.Equal(t, len(expected), len(actual))
+	for i := range expected {
+		if math.IsNaN(expected[i]) {
+			assert.True(t, math.IsNaN(actual[i]), "index %d: expected NaN, got %f", i, actual[i])
+		} else {
+			assert.InDelta(t, expected[i], actual[i], 1e-9, "index %d", i)
+		}
+	}
+}
+
+func TestSma(t *testing.T) {
+	nan := math.NaN()
+	assertSeries(t, []float64{nan, nan, 2, 3, 4}, Sma([]float64{1, 2, 3, 4, 5}, 3))
+	assertSeries(t, []float64{1, 2, 3}, Sma([]float64{1, 2, 3}, 1))
+	assertSeries(t, []float64{nan, nan}, Sma([]float64{1, 2}, 3))
+	// Leading NaN values are skipped.
+	assertSeries(t, []float64{nan, nan, nan, 2.5, 3.5}, Sma([]float64{nan, nan, 2, 3, 4}, 2))
+}
+
+func TestStdDev(t *testing.T) {
+	nan := math.NaN()
+	assertSeries(t, []float64{nan, 1, 0, 2}, StdDev([]float64{1, 3, 3, 7}, 2))
+}
+
+func TestHighestLowest(t *testing.T) {
+	nan := math.NaN()
+	values := []float64{3, 1, 4, 1, 5, 9, 2}
+	assertSeries(t, []float64{nan, nan, 4, 4, 5, 9, 9}, Highest(values, 3))
+	assertSeries(t, []float64{nan, nan, 1, 1, 1, 1, 2}, Lowest(values, 3))
+}
+
+func TestEma(t *testing.T) {
+	nan := math.NaN()
+	// Seeded with SMA(1, 2, 3) = 2, then alpha = 0.5.
+	assertSeries(t, []float64{nan, nan, 2, 3, 5.5}, Ema([]float64{1, 2, 3, 4, 8}, 3))
+	assertSeries(t, []float64{nan, nan, nan, 3, 4}, Ema([]float64{nan, 2, 3, 4, 5}, 3))
+}
+
+func TestRma(t *testing.T) {
+	nan := math.NaN()
+	// Seeded with SMA(1, 2) = 1.5, then alpha = 0.5.
+	assertSeries(t, []float64{nan, 1.5, 2.25, 3.125}, Rma([]float64{1, 2, 3, 4}, 2))
+}
+
+func TestMeanDeviation(t *testing.T) {
+	nan := math.NaN()
+	assertSeries(t, []float64{nan, nan, 2.0 / 3, 10.0 / 9}, MeanDeviation([]float64{1, 2, 3, 5}, 3))
+}
+
+func TestWma(t *testing.T) {
+	nan := math.NaN()
+	// (1*1 + 2*2 + 3*3) / 6
+	assertSeries(t, []float64{nan, nan, 14.0 / 6, 20.0 / 6}, Wma([]float64{1, 2, 3, 4}, 3))
+}
+
+func TestDemaTema(t *testing.T) {
+	// Unlike the EMA, DEMA and TEMA do not lag behind a linear series.
+	values := make([]float64, 30)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	dema := Dema(values, 4)
+	assert.True(t, math.IsNaN(dema[5]))
+	assert.InDelta(t, 6, dema[6], 1e-9)
+	assert.InDelta(t, 29, dema[29], 1e-9)
+	tema := Tema(values, 4)
+	assert.True(t, math.IsNaN(tema[8]))
+	assert.InDelta(t, 9, tema[9], 1e-9)
+	assert.InDelta(t, 29, tema[29], 1e-9)
+}
+
+func TestTrueRange(t *testing.T) {
+	high := []float64{10, 12, 11}
+	low := []float64{8, 11, 7}
+	closing := []float64{9, 11.5, 8}
+	assertSeries(t, []float64{2, 3, 4.5}, TrueRange(high, low, closing))
+	assertSeries(t, []float64{math.NaN(), 2.5, 3.5}, Atr(high, low, closing, 2))
+}
+
+func TestSum(t *testing.T) {
+	nan := math.NaN()
+	assertSeries(t, []float64{nan, 3, 5, 7}, Sum([]float64{1, 2, 3, 4}, 2))
+}
+
+func TestUpdateSma(t *testing.T) {
+	values := []float64{math.NaN(), 1, 2, 3, 4}
+	result := Sma(values[:4], 2)
+	// Replace the last value and append a value.
+	values[3] = 5
+	result = UpdateSma(result, values, 2, 3)
+	assertSeries(t, Sma(values, 2), result)
+	// Nothing calculated yet.
+	assertSeries(t, Sma(values, 3), UpdateSma(nil, values, 3, 0))
+}
+
+func TestUpdateStdDev(t *testing.T) {
+	values := []float64{1, 2, 3, 4}
+	mean := Sma(values, 2)
+	result := StdDev(values[:3], 2)
+	values = append(values, 8)
+	mean = UpdateSma(mean, values, 2, 4)
+	result = UpdateStdDev(result, values, mean, 2, 2)
+	assertSeries(t, StdDev(values, 2), result)
+}
+
+func TestUpdateIncremental(t *testing.T) {
+	updates := []struct {
+		name   string
+		full   func([]float64, int) []float64
+		update func([]float64, []float64, int, int) []float64
+	}{
+		{"ema", Ema, UpdateEma},
+		{"rma", Rma, UpdateRma},
+		{"wma", Wma, UpdateWma},
+		{"highest", Highest, UpdateHighest},
+		{"lowest", Lowest, UpdateLowest},
+	}
+	for _, u := range updates {
+		values := []float64{math.NaN(), 3, 1, 4, 1, 5}
+		// The period is not complete yet, then the last value is replaced and values are appended.
+		result := u.full(values[:3], 3)
+		result = u.update(result, values[:4], 3, 2)
+		values[3] = 2
+		result = u.update(result, values[:4], 3, 3)
+		result = u.update(result, values, 3, 3)
+		assertSeries(t, u.full(values, 3), result)
+		if t.Failed() {
+			t.Fatalf("%s: the incremental update differs", u.name)
+		}
+	}
+	high := []float64{10, 12, 11}
+	low := []float64{8, 11, 7}
+	closing := []float64{9, 11.5, 8}
+	result := TrueRange(high[:2], low[:2], closing[:2])
+	assertSeries(t, TrueRange(high, low, closing), UpdateTrueRange(result, high, low, closing, 1))
+}
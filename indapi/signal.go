@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package indapi
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+type SignalDirection int
+
+const (
+	SignalBullish SignalDirection = iota
+	SignalBearish
+)
+
+// Discrete event which was detected by an indicator, e.g. the price crossing a moving average.
+type Signal struct {
+	Timestamp time.Time
+	Direction SignalDirection
+	// Price at which the signal is shown, the low price of the candle for bullish signals
+	// and the high price for bearish signals.
+	Price       float64
+	Description string
+}
+
+// Implemented by indicators which can detect signals.
+type SignalProvider interface {
+	// Returns the signals which were detected during the last update, ordered by time.
+	// Returns nil if signals are disabled.
+	GetSignals() []Signal
+}
+
+const SignalsPropertyKey = "Signals"
+
+// Property which enables detection of signals, shared by all indicators which implement SignalProvider.
+var SignalsProperty = PropertyDescriptor{
+	Key:         SignalsPropertyKey,
+	Label:       "Signals",
+	Description: "Show signals on the price chart.",
+	Type:        PropertyTypeBool,
+	Default:     "false",
+}
+
+// Creates a signal for the cached candle with index i. The data needs to be locked by the caller.
+func NewSignal(data *PlotData, i int, direction SignalDirection, description string) Signal {
+	c := &data.Cache
+	price := c.LowPrices[i]
+	if direction == SignalBearish {
+		price = c.HighPrices[i]
+	}
+	return Signal{Timestamp: c.Timestamps[i], Direction: direction, Price: price, Description: description}
+}
+
+// Detects where series a crosses above series b (bullish) or below series b (bearish).
+// If a description is empty, signals of this direction are not detected. The data needs to be locked by the caller.
+func DetectCrossovers(data *PlotData, a []float64, b []float64, bullish string, bearish string) []Signal {
+	return UpdateCrossovers(nil, data, a, b, bullish, bearish, 0)
+}
+
+// Updates signals which were detected by DetectCrossovers, after the series were updated from index start on.
+// Signals before start are kept, the updated signals are returned. The data needs to be locked by the caller.
+func UpdateCrossovers(signals []Signal, data *PlotData, a []float64, b []float64, bullish string, bearish string, start int) []Signal {
+	signals = TruncateSignals(signals, data, start)
+	n := min(len(a), len(b), len(data.Cache.Timestamps))
+	for i := max(start, 1); i < n; i++ {
+		if math.IsNaN(a[i]) || math.IsNaN(b[i]) || math.IsNaN(a[i-1]) || math.IsNaN(b[i-1]) {
+			continue
+		}
+		switch {
+		case bullish != "" && a[i] > b[i] && a[i-1] <= b[i-1]:
+			signals = append(signals, NewSignal(data, i, SignalBullish, bullish))
+		case bearish != "" && a[i] < b[i] && a[i-1] >= b[i-1]:
+			signals = append(signals, NewSignal(data, i, SignalBearish, bearish))
+		}
+	}
+	return signals
+}
+
+// Detects where series a crosses above or below a constant level, see DetectCrossovers.
+func DetectLevelCrossovers(data *PlotData, a []float64, level float64, bullish string, bearish string) []Signal {
+	return UpdateLevelCrossovers(nil, data, a, level, bullish, bearish, 0)
+}
+
+// Updates signals which were detected by DetectLevelCrossovers, see UpdateCrossovers.
+func UpdateLevelCrossovers(signals []Signal, data *PlotData, a []float64, level float64, bullish string, bearish string, start int) []Signal {
+	b := make([]float64, len(a))
+	for i := range b {
+		b[i] = level
+	}
+	return UpdateCrossovers(signals, data, a, b, bullish, bearish, start)
+}
+
+// Removes the signals of the cached candles from index start on, so that they can be detected again.
+// The data needs to be locked by the caller.
+func TruncateSignals(signals []Signal, data *PlotData, start int) []Signal {
+	if start == 0 {
+		return nil
+	}
+	if start >= len(data.Cache.Timestamps) {
+		return signals
+	}
+	t := data.Cache.Timestamps[start]
+	n := sort.Search(len(signals), func(i int) bool {
+		return !signals[i].Timestamp.Before(t)
+	})
+	return signals[:n]
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package indapi
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectCrossovers(t *testing.T) {
+	d := PlotData{}
+	d.Cache.Timestamps = make([]time.Time, 6)
+	for i := range d.Cache.Timestamps {
+		d.Cache.Timestamps[i] = time.Date(2023, 8, 10+i, 0, 0, 0, 0, time.UTC)
+	}
+	d.Cache.LowPrices = []float64{1, 2, 3, 4, 5, 6}
+	d.Cache.HighPrices = []float64{11, 12, 13, 14, 15, 16}
+	a := []float64{1, 3, 3, 1, math.NaN(), 3}
+	b := []float64{2, 2, 2, 2, 2, 2}
+
+	signals := DetectCrossovers(&d, a, b, "up", "down")
+	assert.Equal(t, []Signal{
+		{Timestamp: d.Cache.Timestamps[1], Direction: SignalBullish, Price: 2, Description: "up"},
+		{Timestamp: d.Cache.Timestamps[3], Direction: SignalBearish, Price: 14, Description: "down"},
+	}, signals)
+
+	// Bearish signals are disabled.
+	signals = DetectLevelCrossovers(&d, a, 2, "up", "")
+	assert.Equal(t, 1, len(signals))
+	assert.Equal(t, SignalBullish, signals[0].Direction)
+}
+
+func TestUpdateCrossovers(t *testing.T) {
+	d := PlotData{}
+	d.Cache.Timestamps = make([]time.Time, 5)
+	for i := range d.Cache.Timestamps {
+		d.Cache.Timestamps[i] = time.Date(2023, 8, 10+i, 0, 0, 0, 0, time.UTC)
+	}
+	d.Cache.LowPrices = []float64{1, 2, 3, 4, 5}
+	d.Cache.HighPrices = []float64{11, 12, 13, 14, 15}
+	a := []float64{1, 3, 3, 1, 1}
+	signals := DetectLevelCrossovers(&d, a, 2, "up", "down")
+	assert.Equal(t, 2, len(signals))
+
+	// The values from index 3 on are replaced, so that the bearish signal disappears.
+	a[3], a[4] = 3, 3
+	signals = UpdateLevelCrossovers(signals, &d, a, 2, "up", "down", 3)
+	assert.Equal(t, 1, len(signals))
+	assert.Equal(t, d.Cache.Timestamps[1], signals[0].Timestamp)
+
+	// The signals are detected again after a reset.
+	assert.Equal(t, DetectLevelCrossovers(&d, a, 2, "up", "down"), UpdateLevelCrossovers(signals, &d, a, 2, "up", "down", 0))
+}
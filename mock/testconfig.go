@@ -21,6 +21,11 @@ func (t *TestConfig) GetAppName() string {
 	return "test"
 }
 
+func (t *TestConfig) GetPluginDir() string {
+	// No plugins are loaded in tests.
+	return ""
+}
+
 func (g *TestConfig) SetEncryptionPassword(pw string) {
 	// Not used with test config
 }
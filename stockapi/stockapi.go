@@ -17,6 +17,13 @@ import (
 type Capabilities struct {
 	RealtimeBidAsk bool
 	PaperTrading   bool
+	// Intraday candles include extended hours and are aligned to the extended hours market open.
+	ExtendedHoursCandles bool
+	// Day-based candles are stamped with midnight UTC of their trading date.
+	UtcDateCandles bool
+	// Candle resolutions which can be queried from the broker.
+	// Other resolutions are built locally from finer candles or realtime trades.
+	CandleResolutions []candles.CandleResolution
 }
 
 type SearchRequest struct {
@@ -57,8 +64,11 @@ type QueryQuoteResponse struct {
 type QueryCandlesResponse struct {
 	Figi       string
 	Resolution candles.CandleResolution
-	Error      error
-	Data       []indapi.CandleData
+	// The requested time range.
+	FromTime time.Time
+	ToTime   time.Time
+	Error    error
+	Data     []indapi.CandleData
 }
 
 type RealtimeDataSubscription int32
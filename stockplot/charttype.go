@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockplot
+
+import (
+	"image"
+	"maystocks/indapi/candles"
+	"maystocks/stockval"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/x/stroke"
+)
+
+// Updates the min/max price if the candle is visible considering the X axis only.
+// Returns whether the candle is visible, i.e. whether it needs to be drawn.
+func (sub *SubPlot) updateCandlePriceRange(xPos float64, candleWidth int, l, h float64, minPrice, maxPrice *float64, clipRect image.Rectangle) bool {
+	if int(xPos)+candleWidth/2 < clipRect.Min.X || int(xPos)-candleWidth/2 > clipRect.Max.X {
+		return false
+	}
+	if *minPrice < stockval.NearZero || l < *minPrice {
+		*minPrice = l
+	}
+	if h > *maxPrice {
+		*maxPrice = h
+	}
+	y1Pos := int(sub.frame.projection.getYpos(l))
+	y2Pos := int(sub.frame.projection.getYpos(h))
+	return !((y1Pos < clipRect.Min.Y && y2Pos < clipRect.Min.Y) || (y1Pos > clipRect.Max.Y && y2Pos > clipRect.Max.Y))
+}
+
+// Adds segments which are drawn using the line width and line color of candles.
+func (sub *SubPlot) appendCandleLineSegments(isGreenCandle bool, consolidated bool, seg ...stroke.Segment) {
+	switch {
+	case isGreenCandle && consolidated:
+		sub.frame.greenCandleLineSegments = append(sub.frame.greenCandleLineSegments, seg...)
+	case isGreenCandle:
+		sub.frame.unsureGreenCandleLineSegments = append(sub.frame.unsureGreenCandleLineSegments, seg...)
+	case consolidated:
+		sub.frame.redCandleLineSegments = append(sub.frame.redCandleLineSegments, seg...)
+	default:
+		sub.frame.unsureRedCandleLineSegments = append(sub.frame.unsureRedCandleLineSegments, seg...)
+	}
+}
+
+// Plots a bar from low to high, with the open tick on the left and the close tick on the right.
+func (sub *SubPlot) plotSingleOhlcBar(c stockval.PlotCandle, r candles.CandleResolution,
+	minPrice, maxPrice *float64, clipRect image.Rectangle, gtx layout.Context) {
+	candleWidth, _, _ := getCandleWidth(sub.frame.projection.mX, gtx.Dp(1))
+	xPos := float32(sub.frame.projection.getXpos(c.Timestamp, r))
+	if !sub.updateCandlePriceRange(float64(xPos), candleWidth, c.Low, c.High, minPrice, maxPrice, clipRect) {
+		return
+	}
+	yLow := float32(sub.frame.projection.getYpos(c.Low))
+	yHigh := float32(sub.frame.projection.getYpos(c.High))
+	if yLow == yHigh {
+		yHigh++ // Stroke does not draw zero length lines.
+	}
+	yOpen := float32(sub.frame.projection.getYpos(c.Open))
+	yClose := float32(sub.frame.projection.getYpos(c.Close))
+	tick := float32(candleWidth) / 2
+	sub.appendCandleLineSegments(stockval.IsGreenCandle(c.Open, c.Close), c.Consolidated,
+		stroke.MoveTo(f32.Pt(xPos, yLow)),
+		stroke.LineTo(f32.Pt(xPos, yHigh)),
+		stroke.MoveTo(f32.Pt(xPos-tick, yOpen)),
+		stroke.LineTo(f32.Pt(xPos, yOpen)),
+		stroke.MoveTo(f32.Pt(xPos, yClose)),
+		stroke.LineTo(f32.Pt(xPos+tick, yClose)),
+	)
+}
+
+// Plots the outline of a green candle. The wicks end at the body, so that the body is empty.
+func (sub *SubPlot) plotSingleHollowCandle(c stockval.PlotCandle, r candles.CandleResolution,
+	minPrice, maxPrice *float64, clipRect image.Rectangle, gtx layout.Context) {
+	candleWidth, lineWidth, _ := getCandleWidth(sub.frame.projection.mX, gtx.Dp(1))
+	xPos := float32(sub.frame.projection.getXpos(c.Timestamp, r))
+	if !sub.updateCandlePriceRange(float64(xPos), candleWidth, c.Low, c.High, minPrice, maxPrice, clipRect) {
+		return
+	}
+	yLow := float32(sub.frame.projection.getYpos(c.Low))
+	yHigh := float32(sub.frame.projection.getYpos(c.High))
+	yBodyTop := float32(sub.frame.projection.getYpos(c.Close))
+	yBodyBottom := float32(sub.frame.projection.getYpos(c.Open))
+	if yBodyTop == yBodyBottom {
+		yBodyTop-- // minimum height of 1 px
+	}
+	half := float32(candleWidth-lineWidth) / 2
+	seg := []stroke.Segment{
+		stroke.MoveTo(f32.Pt(xPos-half, yBodyTop)),
+		stroke.LineTo(f32.Pt(xPos+half, yBodyTop)),
+		stroke.MoveTo(f32.Pt(xPos+half, yBodyTop)),
+		stroke.LineTo(f32.Pt(xPos+half, yBodyBottom)),
+		stroke.MoveTo(f32.Pt(xPos+half, yBodyBottom)),
+		stroke.LineTo(f32.Pt(xPos-half, yBodyBottom)),
+		stroke.MoveTo(f32.Pt(xPos-half, yBodyBottom)),
+		stroke.LineTo(f32.Pt(xPos-half, yBodyTop)),
+	}
+	if yHigh < yBodyTop {
+		seg = append(seg, stroke.MoveTo(f32.Pt(xPos, yHigh)), stroke.LineTo(f32.Pt(xPos, yBodyTop)))
+	}
+	if yLow > yBodyBottom {
+		seg = append(seg, stroke.MoveTo(f32.Pt(xPos, yBodyBottom)), stroke.LineTo(f32.Pt(xPos, yLow)))
+	}
+	sub.appendCandleLineSegments(true, c.Consolidated, seg...)
+}
+
+// Plots a line through the close prices, and optionally fills the area below the line.
+// The candles need to be sorted by time.
+func (sub *SubPlot) plotPriceLine(c []stockval.PlotCandle, r candles.CandleResolution, area bool,
+	minPrice, maxPrice *float64, clipRect image.Rectangle, gtx layout.Context) {
+	candleWidth, _, _ := getCandleWidth(sub.frame.projection.mX, gtx.Dp(1))
+	// Only the visible candles and their neighbours are drawn.
+	first, last := -1, -1
+	for i, candle := range c {
+		xPos := sub.frame.projection.getXpos(candle.Timestamp, r)
+		if sub.updateCandlePriceRange(xPos, candleWidth, candle.Close, candle.Close, minPrice, maxPrice, image.Rect(clipRect.Min.X, -1e9, clipRect.Max.X, 1e9)) {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return
+	}
+	first, last = max(first-1, 0), min(last+1, len(c)-1)
+	if first == last {
+		return
+	}
+	points := make([]f32.Point, 0, last-first+1)
+	for _, candle := range c[first : last+1] {
+		points = append(points, f32.Pt(float32(sub.frame.projection.getXpos(candle.Timestamp, r)), float32(sub.frame.projection.getYpos(candle.Close))))
+	}
+	if area {
+		var path clip.Path
+		path.Begin(gtx.Ops)
+		bottom := float32(clipRect.Max.Y)
+		path.MoveTo(f32.Pt(points[0].X, bottom))
+		for _, p := range points {
+			path.LineTo(p)
+		}
+		path.LineTo(f32.Pt(points[len(points)-1].X, bottom))
+		path.Close()
+		paint.FillShape(gtx.Ops, sub.Theme.PriceAreaColor, clip.Outline{Path: path.End()}.Op())
+	}
+	var line stroke.Path
+	line.Segments = sub.frame.lineSegments[:0]
+	for i := 1; i < len(points); i++ {
+		// Always move to the previous position, see PlotLine.
+		line.Segments = append(line.Segments, stroke.MoveTo(points[i-1]), stroke.LineTo(points[i]))
+	}
+	sub.frame.lineSegments = line.Segments
+	paint.FillShape(gtx.Ops, sub.Theme.PriceLineColor, stroke.Stroke{Path: line, Width: float32(gtx.Dp(1))}.Op(gtx.Ops))
+}
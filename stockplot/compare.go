@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockplot
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/stockval"
+
+	"gioui.org/layout"
+)
+
+// Asset which is compared to the asset of the plot.
+type CompareData struct {
+	Symbol string
+	Color  color.NRGBA
+	// Candles need to have the resolution of the plot.
+	Data *stockval.CandlePlotData
+}
+
+// Sets the assets which are compared in the price subplots.
+// Call from same goroutine as Layout.
+func (plot *Plot) SetComparisons(c []CompareData) {
+	for _, s := range plot.Sub {
+		if s.Type == indapi.SubPlotTypePrice {
+			s.comparisons = c
+		}
+	}
+}
+
+// Plots the compared assets as lines which show the same percent change as the reference candle.
+// This way, the lines start at the price of the reference candle, and the percent scale also applies to them.
+// Visible values extend the price range of the candles, so that the initial Y axis includes them.
+// Price-based charts do not have a time axis, so comparisons are not shown.
+func (sub *SubPlot) plotComparisons(r candles.CandleResolution, maxValue *float64, gtx layout.Context) {
+	base := sub.frame.referencePrice
+	if sub.chartType.IsPriceBased() || base <= 0 {
+		return
+	}
+	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
+	for _, c := range sub.comparisons {
+		timestamps, values := stockval.CalcPercentChange(c.Data.GetCandleSeries(), sub.frame.referenceTime)
+		for i, v := range values {
+			values[i] = base * (1 + v/100)
+			if !math.IsNaN(values[i]) {
+				sub.updateCandlePriceRange(sub.frame.projection.getXpos(timestamps[i], r), 0, values[i], values[i], &sub.frame.minPrice, &sub.frame.maxPrice, clipRect)
+			}
+		}
+		sub.PlotLine(timestamps, values, maxValue, r, c.Color, gtx)
+	}
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockplot
+
+import (
+	"image"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"maystocks/stockval"
+	"testing"
+	"time"
+
+	"github.com/ericlagergren/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetComparisons(t *testing.T) {
+	plot := NewTestPlot()
+	data := stockval.NewCandlePlotData(candles.CandleOneMinute, candles.NewUtcSession(), nil)
+	plot.SetComparisons([]CompareData{{Symbol: "SPY", Data: data}})
+
+	// Comparisons are only shown in the price subplot.
+	assert.Len(t, plot.Sub[0].comparisons, 1)
+	assert.Empty(t, plot.Sub[1].comparisons)
+
+	plot.SetComparisons(nil)
+	assert.Empty(t, plot.Sub[0].comparisons)
+}
+
+func TestPlotComparisonsPriceRange(t *testing.T) {
+	plot := NewTestPlot()
+	InitializeTestPlot(plot)
+	start := time.Date(2023, 8, 9, 14, 0, 0, 0, time.UTC)
+	var c []indapi.CandleData
+	for i, closing := range []int64{50, 100, 150} {
+		p := decimal.New(closing, 0)
+		c = append(c, indapi.CandleData{Timestamp: start.Add(time.Duration(i) * time.Minute), OpenPrice: p, HighPrice: p, LowPrice: p, ClosePrice: p, Volume: decimal.New(1, 0)})
+	}
+	data := stockval.NewCandlePlotData(candles.CandleOneMinute, candles.NewUtcSession(), nil)
+	data.UpdateConsolidatedCandles(candles.CandleOneMinute, c)
+	plot.SetComparisons([]CompareData{{Symbol: "SPY", Data: data}})
+
+	sub := plot.Sub[0]
+	sub.frame.referencePrice = 10
+	sub.frame.referenceTime = start
+	// The candles are at X positions 100, 500 and 900, the last one is not visible.
+	sub.frame.minPos, sub.frame.maxPos = image.Pt(0, 0), image.Pt(800, 600)
+	sub.frame.projection.mX = 400
+	sub.frame.projection.bX = 100 - 400*candles.CandleOneMinute.ConvertTimeToCandleUnits(start)
+	sub.frame.minPrice, sub.frame.maxPrice = 0, 0
+	var maxValue float64
+	sub.plotComparisons(candles.CandleOneMinute, &maxValue, newTestContext())
+
+	// The compared values start at the reference price, visible values extend the price range.
+	assert.InDelta(t, 10, sub.frame.minPrice, 1e-9)
+	assert.InDelta(t, 20, sub.frame.maxPrice, 1e-9)
+	assert.InDelta(t, 30, maxValue, 1e-9)
+}
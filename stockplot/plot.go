@@ -27,7 +27,7 @@ import (
 
 // Note that this is, by design, not a generic plotting library.
 // It is specifically for stock market plots.
-// X axis is always "time".
+// X axis is always "time", except for price-based charts, which use an index-based X axis.
 
 // X values are projected to "candle time units"
 // We do not simply use unixtime to represent X values, because this causes
@@ -42,9 +42,25 @@ type Plot struct {
 	pointerPressPos     f32.Point
 	Sub                 []*SubPlot
 	candleResolution    candles.CandleResolution
+	candleSession       candles.Session
 	requestFocus        bool
 	previousPlotScaling stockval.PlotScaling
-	frame               struct {
+	signalHandlers      []SignalHandler
+	notifiedSignals     map[notifiedSignal]bool
+	lastCandleTime      time.Time
+	chartType           stockval.ChartType
+	boxSize             float64
+	bricks              []stockval.PriceBrick
+	// The bricks are only built again if the candles, the chart type or the box size changed.
+	bricksKey     bricksKey
+	bricksBoxSize float64
+	// Index based X value at zero position of plot for price-based charts.
+	// It is relative to the last brick, so that new bricks remain visible.
+	zeroValueIndex float64
+	// Subplot and position at which the context menu was opened.
+	contextSub *SubPlot
+	contextPos f32.Point
+	frame      struct {
 		totalPxSize      image.Point
 		pxGridX          int
 		axesMarginPxMin  image.Point
@@ -66,6 +82,7 @@ type PlotTag struct {
 type SubPlotData struct {
 	Type       indapi.SubPlotType
 	Indicators []indapi.IndicatorData
+	Scale      stockval.ScaleType
 }
 
 var defaultSubPlotTemplates = map[indapi.SubPlotType]SubPlotTemplate{
@@ -99,10 +116,14 @@ var defaultSubPlotTemplates = map[indapi.SubPlotType]SubPlotTemplate{
 
 const MinGridDp = 2
 
-func NewPlot(t *widgets.PlotTheme, r candles.CandleResolution, sx stockval.PlotScaling, s []SubPlotData) *Plot {
+// Space after the last brick of price-based charts, in bricks.
+const defaultZeroValueIndex = 2
+
+func NewPlot(t *widgets.PlotTheme, r candles.CandleResolution, session candles.Session, sx stockval.PlotScaling, s []SubPlotData) *Plot {
 	p := &Plot{
-		Theme: t,
-		Sub:   make([]*SubPlot, len(s)),
+		Theme:         t,
+		Sub:           make([]*SubPlot, len(s)),
+		candleSession: session,
 	}
 	var sumBaseRatio float64
 	for i := range s {
@@ -114,6 +135,7 @@ func NewPlot(t *widgets.PlotTheme, r candles.CandleResolution, sx stockval.PlotS
 			gridY:           t.DefaultPlotGrid.Y,
 			SubPlotTemplate: template,
 		}
+		p.Sub[i].SetScale(s[i].Scale)
 		sumBaseRatio += template.pxBaseRatioY
 	}
 	for i := range s {
@@ -137,10 +159,11 @@ func (plot *Plot) setCandleResolution(r candles.CandleResolution, force bool) bo
 	if plot.candleResolution != r || force {
 		plot.candleResolution = r
 		// We do not need the exact duration here, just use a standard value and multiply to get base plot position.
-		singleCandleDuration := r.GetDuration(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		singleCandleDuration := r.GetDuration(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), plot.candleSession)
 		// TODO value grid should depend on resolution. i.e. daily candles should have a grid of 7 days
 		// TODO Grid should be aligned and start at same interval. i.e. start on monday, or use 10 minutes base, or whatever.
 		plot.zeroValueX = r.ConvertTimeToCandleUnits(time.Now().Add(singleCandleDuration * 2))
+		plot.zeroValueIndex = defaultZeroValueIndex
 		// Regenerate base position and zoom during next rendering
 		for i := range plot.Sub {
 			if plot.Sub[i].Type == indapi.SubPlotTypePrice {
@@ -191,7 +214,7 @@ func (plot *Plot) calcPxPosRatioY(subI int) float64 {
 }
 
 func (plot *Plot) calcFirstGridValueX() time.Time {
-	return plot.candleResolution.GetNthCandleTime(plot.candleResolution.ConvertCandleUnitsToTime(math.Floor(plot.zeroValueX/plot.valueGridX)*plot.valueGridX), 0)
+	return plot.candleResolution.GetNthCandleTime(plot.candleResolution.ConvertCandleUnitsToTime(math.Floor(plot.zeroValueX/plot.valueGridX)*plot.valueGridX), 0, plot.candleSession)
 }
 
 func (plot *Plot) calcProjectionVars(subI int) (proj projection) {
@@ -203,9 +226,24 @@ func (plot *Plot) calcProjectionVars(subI int) (proj projection) {
 	proj.mY = -float64(plot.Sub[subI].frame.pxGridY) / plot.Sub[subI].valueGridY
 	proj.bX = -proj.mX*plot.zeroValueX + float64(maxPos.X)
 	proj.bY = -proj.mY*plot.Sub[subI].zeroValueY + float64(maxPos.Y)
+	proj.bI = -proj.mX*plot.zeroValueIndex + float64(maxPos.X)
+	proj.log = plot.Sub[subI].scale == stockval.ScaleLogarithmic
 	return
 }
 
+// Returns the index of the first grid line of price-based charts, relative to the last brick.
+func (plot *Plot) calcFirstGridIndexX() float64 {
+	return math.Floor(plot.zeroValueIndex/plot.valueGridX) * plot.valueGridX
+}
+
+// Returns the X position of the first grid line. Further grid lines are to the left of it.
+func (plot *Plot) calcFirstGridPosX() int {
+	if plot.chartType.IsPriceBased() {
+		return int(plot.Sub[0].frame.projection.getIndexXpos(plot.calcFirstGridIndexX()))
+	}
+	return int(plot.Sub[0].frame.projection.getXpos(plot.calcFirstGridValueX(), plot.candleResolution))
+}
+
 func (plot *Plot) optimiseGridX() {
 	newGridX := plot.gridX
 	newValueGridX := plot.valueGridX
@@ -231,12 +269,16 @@ func recordAxesLabelText(labelText string, c color.NRGBA, fontSize int, gtx layo
 }
 
 func (plot *Plot) paintXaxesText(gtx layout.Context, th *material.Theme) (maxTextSizeY int) {
+	if plot.chartType.IsPriceBased() {
+		return plot.paintXaxesIndexText(gtx, th)
+	}
 	baseTime := plot.calcFirstGridValueX()
 	posX := int(plot.Sub[0].frame.projection.getXpos(baseTime, plot.candleResolution))
 	segmentsX := stockval.CalcNumSegments(posX, plot.frame.axesMarginPxMin.X, plot.frame.pxGridX)
 	timeFormatStr := plot.candleResolution.FormatString()
 	for i := 0; i < segmentsX; i++ {
-		call, textSize := recordAxesLabelText(baseTime.Format(timeFormatStr), plot.Theme.AxesXtextColor, plot.Theme.AxesXfontSize, gtx, th)
+		// Candle times are in the exchange time zone, but labels are shown in local time.
+		call, textSize := recordAxesLabelText(baseTime.Local().Format(timeFormatStr), plot.Theme.AxesXtextColor, plot.Theme.AxesXfontSize, gtx, th)
 		if textSize.Y > maxTextSizeY {
 			maxTextSizeY = textSize.Y
 		}
@@ -244,7 +286,29 @@ func (plot *Plot) paintXaxesText(gtx layout.Context, th *material.Theme) (maxTex
 		// Run recorded drawing.
 		call.Add(gtx.Ops)
 		stack.Pop()
-		baseTime = plot.candleResolution.GetNthCandleTime(baseTime, -int(plot.valueGridX))
+		baseTime = plot.candleResolution.GetNthCandleTime(baseTime, -int(plot.valueGridX), plot.candleSession)
+	}
+	return
+}
+
+// Labels the grid lines of price-based charts with the start time of the brick.
+func (plot *Plot) paintXaxesIndexText(gtx layout.Context, th *material.Theme) (maxTextSizeY int) {
+	baseIndex := plot.calcFirstGridIndexX()
+	posX := plot.calcFirstGridPosX()
+	segmentsX := stockval.CalcNumSegments(posX, plot.frame.axesMarginPxMin.X, plot.frame.pxGridX)
+	timeFormatStr := plot.candleResolution.FormatString()
+	for i := 0; i < segmentsX; i++ {
+		brickIndex := len(plot.bricks) - 1 + int(baseIndex) - i*int(plot.valueGridX)
+		if brickIndex < 0 || brickIndex >= len(plot.bricks) {
+			continue
+		}
+		call, textSize := recordAxesLabelText(plot.bricks[brickIndex].Start.Local().Format(timeFormatStr), plot.Theme.AxesXtextColor, plot.Theme.AxesXfontSize, gtx, th)
+		if textSize.Y > maxTextSizeY {
+			maxTextSizeY = textSize.Y
+		}
+		stack := op.Offset(image.Point{X: posX - i*plot.frame.pxGridX - textSize.X/2, Y: plot.frame.xAxesTextPosY}).Push(gtx.Ops)
+		call.Add(gtx.Ops)
+		stack.Pop()
 	}
 	return
 }
@@ -293,11 +357,103 @@ func (plot *Plot) InitializeFrame(gtx layout.Context, r candles.CandleResolution
 func (plot *Plot) GetSubPlotData() []SubPlotData {
 	data := make([]SubPlotData, 0, len(plot.Sub))
 	for _, s := range plot.Sub {
-		data = append(data, SubPlotData{Type: s.Type, Indicators: s.Indicators})
+		data = append(data, SubPlotData{Type: s.Type, Indicators: s.Indicators, Scale: s.GetScale()})
 	}
 	return data
 }
 
+// Returns the subplot on which the context menu was opened, or nil if there is none.
+// Call from same goroutine as Layout.
+func (plot *Plot) GetContextSubPlot() *SubPlot {
+	return plot.contextSub
+}
+
+// Returns the start time of the candle or brick at which the context menu was opened.
+// Candles are centered at their start time, so the nearest candle start is used.
+// Call from same goroutine as Layout.
+func (plot *Plot) GetContextCandleTime() (time.Time, bool) {
+	sub := plot.contextSub
+	if sub == nil {
+		return time.Time{}, false
+	}
+	if plot.chartType.IsPriceBased() {
+		i := sub.getBrickIndex(plot.contextPos.X)
+		if i < 0 {
+			return time.Time{}, false
+		}
+		return sub.bricks[i].Start, true
+	}
+	proj := sub.frame.projection
+	if proj.mX == 0 {
+		return time.Time{}, false
+	}
+	u := (float64(plot.contextPos.X)-proj.bX)/proj.mX + 0.5
+	return plot.candleResolution.GetNthCandleTime(plot.candleResolution.ConvertCandleUnitsToTime(u), 0, plot.candleSession), true
+}
+
+// Sets the type of the chart which is shown in the price subplots.
+func (plot *Plot) SetChartType(t stockval.ChartType) {
+	if plot.chartType != t {
+		plot.zeroValueIndex = defaultZeroValueIndex
+	}
+	plot.chartType = t
+	for _, sub := range plot.Sub {
+		sub.chartType = t
+	}
+}
+
+// Identifies the input of the bricks of price-based charts.
+type bricksKey struct {
+	data        *stockval.CandlePlotData
+	changeCount uint64
+	chartType   stockval.ChartType
+	boxSize     float64
+}
+
+// Sets the box size of price-based charts, see stockval.BuildPriceBricks.
+func (plot *Plot) SetBoxSize(boxSize float64) {
+	plot.boxSize = boxSize
+}
+
+// Builds the bricks of price-based charts. Call before Layout, because the X axis depends on the bricks.
+func (plot *Plot) UpdatePriceChart(data *stockval.CandlePlotData) {
+	if !plot.chartType.IsPriceBased() {
+		plot.bricks, plot.bricksBoxSize, plot.bricksKey = nil, 0, bricksKey{}
+	} else if key := (bricksKey{data, data.GetCacheChangeCount(), plot.chartType, plot.boxSize}); key != plot.bricksKey {
+		plot.bricks, plot.bricksBoxSize = data.GetPriceBricks(plot.chartType, plot.boxSize)
+		plot.bricksKey = key
+	}
+	for _, sub := range plot.Sub {
+		sub.bricks, sub.boxSize = plot.bricks, plot.bricksBoxSize
+	}
+}
+
+// Updates the indicators of all subplots. Chained indicators are updated after the indicator
+// which provides their input, which may be part of a different subplot.
+func (plot *Plot) UpdateIndicators(data *stockval.CandlePlotData) {
+	updated := make(map[indapi.IndicatorData]bool)
+	for _, sub := range plot.Sub {
+		for _, ind := range sub.Indicators {
+			updateIndicator(ind, data, updated)
+		}
+	}
+	plot.updateSignals(data)
+}
+
+func updateIndicator(ind indapi.IndicatorData, data *stockval.CandlePlotData, updated map[indapi.IndicatorData]bool) {
+	if updated[ind] {
+		return
+	}
+	// Mark first, so that cyclic dependencies do not cause endless recursion.
+	updated[ind] = true
+	if chained, ok := ind.(indapi.ChainedIndicator); ok {
+		if input := chained.GetInputIndicator(); input != nil {
+			updateIndicator(input, data, updated)
+		}
+	}
+	ind.Update(data.Resolution, &data.PlotData)
+}
+
 func (plot *Plot) handleInput(gtx layout.Context) {
 	xAxisArea := clip.Rect(image.Rectangle{
 		Min: image.Point{X: plot.frame.axesMarginPxMin.X, Y: plot.frame.totalPxSize.Y - plot.frame.axesMarginPxMax.Y - plot.frame.textSizePx.Y},
@@ -347,7 +503,7 @@ func (plot *Plot) handleInput(gtx layout.Context) {
 		for {
 			event, ok := gtx.Event(pointer.Filter{
 				Target:  SubPlotTag{a: EventAreaPlot, s: s},
-				Kinds:   pointer.Press | pointer.Drag | pointer.Scroll,
+				Kinds:   pointer.Press | pointer.Drag | pointer.Scroll | pointer.Move | pointer.Leave,
 				ScrollY: pointer.ScrollRange{Min: math.MinInt, Max: math.MaxInt},
 			})
 			if !ok {
@@ -357,16 +513,31 @@ func (plot *Plot) handleInput(gtx layout.Context) {
 			if !ok {
 				continue
 			}
+			// Remember the pointer position for tooltips.
+			s.hasPointer = ev.Kind != pointer.Leave
+			s.pointerPos = ev.Position
+			if ev.Kind == pointer.Move || ev.Kind == pointer.Leave {
+				continue
+			}
 			plot.requestFocus = true
 			switch ev.Kind {
 			case pointer.Press:
 				plot.pointerPressPos = ev.Position
+				if ev.Buttons.Contain(pointer.ButtonSecondary) {
+					plot.contextSub = s
+					plot.contextPos = ev.Position
+				}
 			case pointer.Drag:
 				posDelta := plot.pointerPressPos.Sub(ev.Position)
-				plot.zeroValueX += plot.valueGridX / float64(plot.frame.pxGridX) * float64(posDelta.X)
+				if plot.chartType.IsPriceBased() {
+					plot.zeroValueIndex += plot.valueGridX / float64(plot.frame.pxGridX) * float64(posDelta.X)
+				} else {
+					plot.zeroValueX += plot.valueGridX / float64(plot.frame.pxGridX) * float64(posDelta.X)
+				}
 				if !s.fixedZeroValueY {
 					s.zeroValueY -= s.valueGridY / float64(s.frame.pxGridY) * float64(posDelta.Y)
-					if s.zeroValueY < 0 {
+					// Values of a logarithmic scale may be negative.
+					if s.zeroValueY < 0 && s.scale != stockval.ScaleLogarithmic {
 						s.zeroValueY = 0
 					}
 				}
@@ -429,9 +600,9 @@ func (plot *Plot) handleInput(gtx layout.Context) {
 
 func (plot *Plot) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
 	for _, s := range plot.Sub {
+		s.calculateGridY()
 		s.paintGrid(
-			plot.candleResolution,
-			plot.calcFirstGridValueX(),
+			plot.calcFirstGridPosX(),
 			plot.frame.pxGridX,
 			gtx,
 		)
@@ -466,6 +637,20 @@ func (plot *Plot) GetCandleRange() (startTime time.Time, endTime time.Time, reso
 	minVal := plot.zeroValueX - plot.calcXvalueRange()
 	startTime = plot.candleResolution.ConvertCandleUnitsToTime(minVal)
 	resolution = plot.candleResolution
+	if n := len(plot.bricks); plot.chartType.IsPriceBased() && n > 0 {
+		// Use the time range of the visible bricks.
+		first := n - 1 + int(math.Floor(plot.zeroValueIndex-plot.calcXvalueRange()))
+		last := n - 1 + int(math.Ceil(plot.zeroValueIndex))
+		if last < n-1 {
+			endTime = plot.bricks[max(last, 0)].End
+		}
+		if first >= 0 {
+			startTime = plot.bricks[min(first, n-1)].Start
+		} else {
+			// The number of candles per brick is unknown, load candles for a full plot width before the first brick.
+			startTime = plot.candleResolution.GetNthCandleTime(plot.bricks[0].Start, -int(plot.calcXvalueRange()), plot.candleSession)
+		}
+	}
 	return
 }
 
@@ -8,12 +8,17 @@ import (
 	"math"
 	"maystocks/indapi"
 	"maystocks/indapi/candles"
+	"maystocks/indapi/indicators"
+	"maystocks/indapi/indicators/rsi"
+	"maystocks/indapi/indicators/sma"
 	"maystocks/stockval"
 	"maystocks/widgets"
 	"testing"
+	"time"
 
 	"gioui.org/layout"
 	"gioui.org/op"
+	"github.com/ericlagergren/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,6 +27,7 @@ func NewTestPlot() *Plot {
 	return NewPlot(
 		theme,
 		candles.CandleOneMinute,
+		candles.NewUtcSession(),
 		stockval.PlotScaling{},
 		[]SubPlotData{
 			{Type: indapi.SubPlotTypePrice},
@@ -66,3 +72,134 @@ func TestGetYpos(t *testing.T) {
 
 	assert.True(t, math.Abs(220.0-posY) < stockval.NearZero)
 }
+
+func TestUpdateIndicatorsChained(t *testing.T) {
+	source := indicators.Create(rsi.Id, map[string]string{"Time Periods": "2"}, nil)
+	chained := indicators.NewChained(indicators.Create(sma.Id, map[string]string{"Time Periods": "2"}, nil),
+		indapi.IndicatorInput{Name: "rsi 1", Output: indapi.OutputValue})
+	// The chained indicator is part of an earlier subplot, and is still updated after its input.
+	indicators.ConnectChained([]indapi.IndicatorData{chained, source}, map[string]indapi.IndicatorData{"rsi 1": source})
+	plot := NewPlot(widgets.NewDarkPlotTheme(), candles.CandleOneDay, candles.NewUtcSession(), stockval.PlotScaling{},
+		[]SubPlotData{
+			{Type: indapi.SubPlotTypePrice, Indicators: []indapi.IndicatorData{chained}},
+			{Type: indapi.SubPlotTypeIndicator, Indicators: []indapi.IndicatorData{source}},
+		})
+	data := stockval.NewCandlePlotData(candles.CandleOneDay, candles.NewUtcSession(), nil)
+	data.Cache.Timestamps = make([]time.Time, 5)
+	data.Cache.ClosePrices = []float64{1, 2, 1, 3, 4}
+	data.Cache.Volumes = make([]float64, 5)
+	data.Cache.ChangeCount = 1
+	plot.UpdateIndicators(data)
+	rsiValues := rsi.Calculate(data.Cache.ClosePrices, 2)
+	assert.InDelta(t, (rsiValues[3]+rsiValues[4])/2, chained.GetOutput(indapi.OutputValue)[4], 1e-9)
+}
+
+func TestSignalNotification(t *testing.T) {
+	ind := indicators.Create(sma.Id, map[string]string{"Time Periods": "2", indapi.SignalsPropertyKey: "true"}, nil)
+	plot := NewPlot(widgets.NewDarkPlotTheme(), candles.CandleOneDay, candles.NewUtcSession(), stockval.PlotScaling{},
+		[]SubPlotData{
+			{Type: indapi.SubPlotTypePrice, Indicators: []indapi.IndicatorData{ind}},
+		})
+	var notified []indapi.Signal
+	plot.SubscribeSignals(func(id indapi.IndicatorId, signal indapi.Signal) {
+		assert.Equal(t, indapi.IndicatorId(sma.Id), id)
+		notified = append(notified, signal)
+	})
+	data := stockval.NewCandlePlotData(candles.CandleOneDay, candles.NewUtcSession(), nil)
+	setCandles := func(closePrices []float64) {
+		n := len(closePrices)
+		data.Cache.Timestamps = make([]time.Time, n)
+		for i := range data.Cache.Timestamps {
+			data.Cache.Timestamps[i] = time.Date(2023, 8, 1+i, 0, 0, 0, 0, time.UTC)
+		}
+		data.Cache.ClosePrices = closePrices
+		data.Cache.LowPrices = closePrices
+		data.Cache.HighPrices = closePrices
+		data.Cache.Volumes = make([]float64, n)
+		data.Cache.ChangeCount++
+		data.Cache.ResetCount++
+	}
+
+	// Signals of the history are shown, but not reported.
+	setCandles([]float64{1, 2, 1, 1})
+	plot.UpdateIndicators(data)
+	assert.Equal(t, 1, len(plot.Sub[0].signals))
+	assert.Empty(t, notified)
+
+	// A new signal is reported once.
+	setCandles([]float64{1, 2, 1, 1, 3})
+	plot.UpdateIndicators(data)
+	plot.UpdateIndicators(data)
+	assert.Equal(t, 2, len(plot.Sub[0].signals))
+	assert.Equal(t, 1, len(notified))
+	assert.Equal(t, indapi.SignalBullish, notified[0].Direction)
+}
+
+func TestPriceChartIndexAxis(t *testing.T) {
+	plot := NewTestPlot()
+	plot.SetChartType(stockval.ChartRenko)
+	plot.SetBoxSize(1)
+	data := stockval.NewCandlePlotData(candles.CandleOneMinute, candles.NewUtcSession(), nil)
+	start := time.Date(2023, 8, 9, 14, 0, 0, 0, time.UTC)
+	var c []indapi.CandleData
+	for i := range 5 {
+		p := decimal.New(int64(10+i), 0)
+		c = append(c, indapi.CandleData{Timestamp: start.Add(time.Duration(i) * time.Minute), OpenPrice: p, HighPrice: p, LowPrice: p, ClosePrice: p, Volume: decimal.New(1, 0)})
+	}
+	data.UpdateConsolidatedCandles(candles.CandleOneMinute, c)
+	data.UpdateCache()
+	InitializeTestPlot(plot)
+	plot.UpdatePriceChart(data)
+	sub := plot.Sub[0]
+	assert.Len(t, sub.bricks, 4)
+	assert.Len(t, plot.Sub[1].bricks, 4)
+
+	// The bricks are only built again if the candles changed.
+	bricks := plot.bricks
+	plot.UpdatePriceChart(data)
+	assert.Same(t, &bricks[0], &plot.bricks[0])
+	plot.SetBoxSize(2)
+	plot.UpdatePriceChart(data)
+	assert.Len(t, sub.bricks, 2)
+	plot.SetBoxSize(1)
+	plot.UpdatePriceChart(data)
+	assert.Len(t, sub.bricks, 4)
+
+	// There is some space after the last brick.
+	assert.InDelta(t, float64(sub.frame.maxPos.X)-defaultZeroValueIndex*sub.frame.projection.mX, sub.getBrickXpos(3), 1e-9)
+	for i := range sub.bricks {
+		assert.Equal(t, i, sub.getBrickIndex(float32(sub.getBrickXpos(i))))
+	}
+	assert.Equal(t, -1, sub.getBrickIndex(float32(sub.frame.maxPos.X)))
+
+	// All bricks are visible, so candles before the first brick are requested.
+	startTime, endTime, _ := plot.GetCandleRange()
+	assert.True(t, startTime.Before(sub.bricks[0].Start))
+	assert.True(t, endTime.After(sub.bricks[3].End))
+
+	// Time-based charts do not have bricks.
+	plot.SetChartType(stockval.ChartCandles)
+	plot.UpdatePriceChart(data)
+	assert.Empty(t, sub.bricks)
+}
+
+func TestGetContextCandleTime(t *testing.T) {
+	plot := NewTestPlot()
+	_, ok := plot.GetContextCandleTime()
+	assert.False(t, ok)
+
+	InitializeTestPlot(plot)
+	sub := plot.Sub[0]
+	candle := time.Date(2023, 8, 9, 14, 30, 0, 0, time.UTC)
+	// The candle is at X position 100, one candle is 10 pixels wide.
+	sub.frame.projection.mX = 10
+	sub.frame.projection.bX = 100 - 10*candles.CandleOneMinute.ConvertTimeToCandleUnits(candle)
+	plot.contextSub = sub
+	// The nearest candle start is used, candles are centered at their start.
+	for _, offset := range []float32{-4, 0, 4} {
+		plot.contextPos.X = float32(sub.frame.projection.getXpos(candle, candles.CandleOneMinute)) + offset
+		contextTime, ok := plot.GetContextCandleTime()
+		assert.True(t, ok)
+		assert.True(t, contextTime.Equal(candle), "offset %v: %v", offset, contextTime)
+	}
+}
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockplot
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"maystocks/indapi/candles"
+	"maystocks/stockval"
+	"maystocks/widgets"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/widget/material"
+	"gioui.org/x/stroke"
+)
+
+// Number of line segments which are used to draw an O of a Point & Figure chart.
+const numCircleSegments = 12
+
+// Returns the X position of a brick. The X axis is index-based, relative to the last brick.
+func (sub *SubPlot) getBrickXpos(i int) float64 {
+	return sub.frame.projection.getIndexXpos(float64(i - len(sub.bricks) + 1))
+}
+
+// Returns the index of the brick at the X position, or -1 if there is none.
+func (sub *SubPlot) getBrickIndex(xPos float32) int {
+	proj := sub.frame.projection
+	if proj.mX == 0 {
+		return -1
+	}
+	i := int(math.Round((float64(xPos)-proj.bI)/proj.mX)) + len(sub.bricks) - 1
+	if i < 0 || i >= len(sub.bricks) {
+		return -1
+	}
+	return i
+}
+
+// Plots the bricks of a price-based chart.
+func (sub *SubPlot) plotPriceBricks(minPrice, maxPrice *float64, clipRect image.Rectangle, gtx layout.Context) {
+	switch sub.chartType {
+	case stockval.ChartRenko:
+		for i, b := range sub.bricks {
+			l, h := math.Min(b.Open, b.Close), math.Max(b.Open, b.Close)
+			sub.plotSingleCandleAt(l, h, b.Open, b.Close, sub.getBrickXpos(i), b.Consolidated, minPrice, maxPrice, clipRect, gtx)
+		}
+	case stockval.ChartKagi:
+		sub.plotKagiLines(minPrice, maxPrice, clipRect, gtx)
+	case stockval.ChartPointFigure:
+		sub.plotPointFigureColumns(minPrice, maxPrice, clipRect, gtx)
+	}
+}
+
+// Plots Kagi lines, thick (yang) lines use the color of green candles, thin (yin) lines the color of red candles.
+// Lines are connected by horizontal lines.
+func (sub *SubPlot) plotKagiLines(minPrice, maxPrice *float64, clipRect image.Rectangle, gtx layout.Context) {
+	candleWidth, lineWidth, _ := getCandleWidth(sub.frame.projection.mX, gtx.Dp(1))
+	thick := sub.frame.thickBrickSegments[:0]
+	thin := sub.frame.thinBrickSegments[:0]
+	appendLine := func(yang bool, p1, p2 f32.Point) {
+		if yang {
+			thick = append(thick, stroke.MoveTo(p1), stroke.LineTo(p2))
+		} else {
+			thin = append(thin, stroke.MoveTo(p1), stroke.LineTo(p2))
+		}
+	}
+	// Lines are connected to their predecessor, so lines which are one brick outside are also drawn.
+	visibleRect := clipRect.Inset(-int(math.Ceil(sub.frame.projection.mX)))
+	for i, b := range sub.bricks {
+		xPos := sub.getBrickXpos(i)
+		visible := sub.updateCandlePriceRange(xPos, candleWidth, math.Min(b.Open, b.Close), math.Max(b.Open, b.Close), minPrice, maxPrice, visibleRect)
+		if !visible {
+			continue
+		}
+		x := float32(xPos)
+		yOpen := float32(sub.frame.projection.getYpos(b.Open))
+		yClose := float32(sub.frame.projection.getYpos(b.Close))
+		yang := b.Yang
+		if i > 0 {
+			prev := sub.bricks[i-1]
+			prevYang := prev.Yang != !math.IsNaN(prev.ChangePrice)
+			appendLine(prevYang, f32.Pt(float32(sub.getBrickXpos(i-1)), yOpen), f32.Pt(x, yOpen))
+		}
+		if !math.IsNaN(b.ChangePrice) {
+			yChange := float32(sub.frame.projection.getYpos(b.ChangePrice))
+			appendLine(yang, f32.Pt(x, yOpen), f32.Pt(x, yChange))
+			yOpen, yang = yChange, !yang
+		}
+		appendLine(yang, f32.Pt(x, yOpen), f32.Pt(x, yClose))
+	}
+	sub.frame.thickBrickSegments = thick
+	sub.frame.thinBrickSegments = thin
+	_, yangColor, _ := sub.Theme.GetCandleColors(true, true)
+	_, yinColor, _ := sub.Theme.GetCandleColors(false, true)
+	sub.strokeBrickSegments(gtx, thin, float32(lineWidth), yinColor)
+	sub.strokeBrickSegments(gtx, thick, float32(lineWidth*3), yangColor)
+}
+
+// Same as strokeCandleSegments, but with a square cap, so that connected lines do not have gaps.
+func (sub *SubPlot) strokeBrickSegments(gtx layout.Context, seg []stroke.Segment, lineWidth float32, lineColor color.NRGBA) {
+	if len(seg) == 0 {
+		return
+	}
+	var path stroke.Path
+	path.Segments = seg
+	paint.FillShape(gtx.Ops, lineColor, stroke.Stroke{Path: path, Width: lineWidth, Cap: stroke.SquareCap}.Op(gtx.Ops))
+}
+
+// Plots Point & Figure columns, an X for each box of a rising column and an O for each box of a falling column.
+func (sub *SubPlot) plotPointFigureColumns(minPrice, maxPrice *float64, clipRect image.Rectangle, gtx layout.Context) {
+	if sub.boxSize <= 0 {
+		return
+	}
+	candleWidth, _, _ := getCandleWidth(sub.frame.projection.mX, gtx.Dp(1))
+	halfWidth := float32(candleWidth) / 2
+	for i, b := range sub.bricks {
+		l, h := math.Min(b.Open, b.Close), math.Max(b.Open, b.Close)
+		xPos := sub.getBrickXpos(i)
+		if !sub.updateCandlePriceRange(xPos, candleWidth, l, h, minPrice, maxPrice, clipRect) {
+			continue
+		}
+		x := float32(xPos)
+		numBoxes := int(math.Round((h - l) / sub.boxSize))
+		for j := range numBoxes {
+			boxLow := l + float64(j)*sub.boxSize
+			y := float32(sub.frame.projection.getYpos(boxLow + 0.5*sub.boxSize))
+			// Boxes have different heights using a logarithmic scale.
+			halfHeight := float32(sub.frame.projection.getYpos(boxLow)-sub.frame.projection.getYpos(boxLow+sub.boxSize)) / 2
+			if int(y+halfHeight) < clipRect.Min.Y || int(y-halfHeight) > clipRect.Max.Y {
+				continue
+			}
+			if b.IsUp() {
+				sub.appendCandleLineSegments(true, b.Consolidated,
+					stroke.MoveTo(f32.Pt(x-halfWidth, y-halfHeight)),
+					stroke.LineTo(f32.Pt(x+halfWidth, y+halfHeight)),
+					stroke.MoveTo(f32.Pt(x-halfWidth, y+halfHeight)),
+					stroke.LineTo(f32.Pt(x+halfWidth, y-halfHeight)),
+				)
+			} else {
+				// Each segment needs its own MoveTo, see PlotLine.
+				var seg [numCircleSegments * 2]stroke.Segment
+				for k := range numCircleSegments {
+					a1 := 2 * math.Pi * float64(k) / numCircleSegments
+					a2 := 2 * math.Pi * float64(k+1) / numCircleSegments
+					seg[2*k] = stroke.MoveTo(f32.Pt(x+halfWidth*float32(math.Cos(a1)), y+halfHeight*float32(math.Sin(a1))))
+					seg[2*k+1] = stroke.LineTo(f32.Pt(x+halfWidth*float32(math.Cos(a2)), y+halfHeight*float32(math.Sin(a2))))
+				}
+				sub.appendCandleLineSegments(false, b.Consolidated, seg[:]...)
+			}
+		}
+	}
+}
+
+// Shows the time range which is covered by the brick below the pointer.
+func (sub *SubPlot) plotBrickTooltip(r candles.CandleResolution, gtx layout.Context) {
+	if !sub.hasPointer || sub.frame.theme == nil {
+		return
+	}
+	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
+	if !image.Pt(int(sub.pointerPos.X), int(sub.pointerPos.Y)).In(clipRect) {
+		return
+	}
+	i := sub.getBrickIndex(sub.pointerPos.X)
+	if i < 0 {
+		return
+	}
+	b := sub.bricks[i]
+	timeFormatStr := r.FormatString()
+	text := b.Start.Local().Format(timeFormatStr) + " - " + b.End.Local().Format(timeFormatStr)
+	defer clip.Rect(clipRect).Push(gtx.Ops).Pop()
+	gtx.Constraints.Min = image.Point{}
+	macro := op.Record(gtx.Ops)
+	dims := widgets.Frame{InnerMargin: 5, BorderWidth: 1, BorderColor: sub.Theme.FrameBgColor, BackgroundColor: sub.Theme.FrameBgColor}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		lbl := material.Label(sub.frame.theme, sub.frame.theme.TextSize, text)
+		lbl.Color = sub.Theme.FrameTextColor
+		return lbl.Layout(gtx)
+	})
+	call := macro.Stop()
+	// Show the tooltip next to the pointer, but within the plot area.
+	offset := gtx.Dp(10)
+	pos := image.Pt(int(sub.pointerPos.X)+offset, int(sub.pointerPos.Y)+offset)
+	pos.X = max(min(pos.X, clipRect.Max.X-dims.Size.X), clipRect.Min.X)
+	pos.Y = max(min(pos.Y, clipRect.Max.Y-dims.Size.Y), clipRect.Min.Y)
+	stack := op.Offset(pos).Push(gtx.Ops)
+	call.Add(gtx.Ops)
+	stack.Pop()
+}
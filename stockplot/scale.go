@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockplot
+
+import (
+	"math"
+	"maystocks/indapi"
+	"maystocks/stockval"
+	"sort"
+	"strconv"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// Smallest value which can be shown using a logarithmic scale.
+const minLogValue = 1e-9
+
+// Number of decimal places of the grid in scale units of a logarithmic scale.
+const logMaxDecimalPlaces = 4
+
+// Returns whether the subplot can use the scale. Non-linear scales are only supported for prices,
+// because other values may be zero or negative.
+func (sub *SubPlot) SupportsScale(s stockval.ScaleType) bool {
+	return s == stockval.ScaleLinear || sub.Type == indapi.SubPlotTypePrice
+}
+
+func (sub *SubPlot) GetScale() stockval.ScaleType {
+	if sub.scale == "" {
+		return stockval.ScaleLinear
+	}
+	return sub.scale
+}
+
+// Sets the scale of the Y axis if it is supported. The subplot is zoomed to the data again.
+func (sub *SubPlot) SetScale(s stockval.ScaleType) {
+	if !sub.SupportsScale(s) || sub.GetScale() == s {
+		return
+	}
+	sub.scale = s
+	sub.hasInitialCandleY = false
+	sub.hasInitialRangeY = false
+	sub.nextBaseValueY = 0
+	sub.nextValueRangeY = 0
+}
+
+// Converts a value to the units of the Y axis, which are the log10 of the value for a logarithmic scale.
+func (sub *SubPlot) toScaleUnits(v float64) float64 {
+	if sub.scale == stockval.ScaleLogarithmic {
+		return math.Log10(math.Max(v, minLogValue))
+	}
+	return v
+}
+
+// Number of decimal places of the grid values. Logarithmic values need more precision.
+func (sub *SubPlot) getMaxDecimalPlaces() int {
+	if sub.scale == stockval.ScaleLogarithmic {
+		return logMaxDecimalPlaces
+	}
+	return sub.maxDecimalPlaces
+}
+
+// Returns the largest step of 1, 2 or 5 times a power of ten which is not larger than v.
+func floorNiceStep(v float64) float64 {
+	base := math.Pow10(int(math.Floor(math.Log10(v))))
+	for _, f := range []float64{5, 2} {
+		if f*base <= v {
+			return f * base
+		}
+	}
+	return base
+}
+
+// Returns the smallest step of 1, 2 or 5 times a power of ten which is not smaller than v.
+func ceilNiceStep(v float64) float64 {
+	base := math.Pow10(int(math.Floor(math.Log10(v))))
+	for _, f := range []float64{1, 2, 5} {
+		if f*base >= v*(1-stockval.NearZero) {
+			return f * base
+		}
+	}
+	return 10 * base
+}
+
+// Calculates values and positions of the horizontal grid lines, from bottom to top.
+// Values are percent changes for the percent scale, and prices otherwise.
+func (sub *SubPlot) calculateGridY() {
+	sub.frame.labelValues = sub.frame.labelValues[:0]
+	sub.frame.labelPositions = sub.frame.labelPositions[:0]
+	if sub.valueGridY <= 0 || sub.frame.pxGridY <= 0 {
+		return
+	}
+	switch {
+	case sub.scale == stockval.ScaleLogarithmic:
+		sub.calculateLogGridY()
+	case sub.scale == stockval.ScalePercent && sub.frame.referencePrice > 0:
+		sub.calculatePercentGridY()
+	default:
+		sub.calculateLinearGridY()
+	}
+}
+
+func (sub *SubPlot) addGridLine(value float64, posY int) {
+	// we do not want negative zero on our label
+	if value < 0 && value > -stockval.NearZero {
+		value = 0
+	}
+	sub.frame.labelValues = append(sub.frame.labelValues, value)
+	sub.frame.labelPositions = append(sub.frame.labelPositions, posY)
+}
+
+func (sub *SubPlot) calculateLinearGridY() {
+	baseValue := sub.calcFirstGridValueY()
+	posY := int(sub.frame.projection.getYpos(baseValue))
+	segmentsY := stockval.CalcNumSegments(posY, sub.frame.minPos.Y, sub.frame.pxGridY)
+	for i := 0; i < segmentsY; i++ {
+		sub.addGridLine(baseValue+float64(i)*sub.valueGridY, posY-i*sub.frame.pxGridY)
+	}
+}
+
+// Grid lines have equal distances in scale units, but their values are rounded to steps which
+// are small compared to the distance to the next grid line, so that labels remain readable.
+func (sub *SubPlot) calculateLogGridY() {
+	baseValue := sub.calcFirstGridValueY()
+	posY := int(sub.frame.projection.getYpos(math.Pow(10, baseValue)))
+	segmentsY := stockval.CalcNumSegments(posY, sub.frame.minPos.Y, sub.frame.pxGridY)
+	minStep := math.Pow10(-sub.maxDecimalPlaces)
+	for i := 0; i < segmentsY; i++ {
+		v := math.Pow(10, baseValue+float64(i)*sub.valueGridY)
+		step := math.Max(floorNiceStep(v*(math.Pow(10, sub.valueGridY)-1)), minStep)
+		v = math.Round(v/step) * step
+		if v <= 0 {
+			continue
+		}
+		if n := len(sub.frame.labelValues); n > 0 && math.Abs(sub.frame.labelValues[n-1]-v) < minStep/2 {
+			continue
+		}
+		sub.addGridLine(v, int(math.Round(sub.frame.projection.getYpos(v))))
+	}
+}
+
+// Grid lines are placed at percent steps relative to the reference price, using about the density of the linear grid.
+func (sub *SubPlot) calculatePercentGridY() {
+	base := sub.frame.referencePrice
+	step := ceilNiceStep(sub.valueGridY / base * 100)
+	p := math.Ceil((sub.zeroValueY/base-1)*100/step) * step
+	for ; ; p += step {
+		posY := int(math.Round(sub.frame.projection.getYpos(base * (1 + p/100))))
+		if posY < sub.frame.minPos.Y {
+			break
+		}
+		sub.addGridLine(p, posY)
+	}
+	sub.frame.percentDecimals = max(0, -int(math.Floor(math.Log10(step)+stockval.NearZero)))
+}
+
+func (sub *SubPlot) formatGridLabel(value float64) string {
+	if sub.scale == stockval.ScalePercent && sub.frame.referencePrice > 0 {
+		return strconv.FormatFloat(value, 'f', sub.frame.percentDecimals, 64) + "%"
+	}
+	return sub.formatYlabel(value)
+}
+
+// Updates the reference candle of the percent scale and of compared assets, which is the first visible candle.
+// The reference price is its close, or the open of the first visible brick. Grid and labels are painted
+// before the candles, so the frame is invalidated if the reference price changes.
+func (sub *SubPlot) updateReferenceCandle(data *stockval.CandlePlotData, gtx layout.Context) {
+	if sub.scale != stockval.ScalePercent && len(sub.comparisons) == 0 {
+		return
+	}
+	var base float64
+	var t time.Time
+	minX := float64(sub.frame.minPos.X)
+	if sub.chartType.IsPriceBased() {
+		i := sort.Search(len(sub.bricks), func(i int) bool { return sub.getBrickXpos(i) >= minX })
+		if i < len(sub.bricks) {
+			base, t = sub.bricks[i].Open, sub.bricks[i].Start
+		}
+	} else {
+		c := data.GetCandleSeries()
+		i := sort.Search(len(c), func(i int) bool { return sub.frame.projection.getXpos(c[i].Timestamp, data.Resolution) >= minX })
+		if i < len(c) {
+			base, t = c[i].Close, c[i].Timestamp
+		}
+	}
+	sub.frame.referenceTime = t
+	if base != sub.frame.referencePrice {
+		sub.frame.referencePrice = base
+		gtx.Execute(op.InvalidateCmd{})
+	}
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockplot
+
+import (
+	"maystocks/stockval"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNiceSteps(t *testing.T) {
+	assert.Equal(t, 2.0, floorNiceStep(4.1))
+	assert.Equal(t, 5.0, floorNiceStep(5.16))
+	assert.InDelta(t, 0.1, floorNiceStep(0.19), 1e-12)
+	assert.Equal(t, 1.0, ceilNiceStep(1))
+	assert.Equal(t, 5.0, ceilNiceStep(2.5))
+	assert.Equal(t, 10.0, ceilNiceStep(5.5))
+}
+
+func TestSetScale(t *testing.T) {
+	plot := NewTestPlot()
+	assert.Equal(t, stockval.ScaleLinear, plot.Sub[0].GetScale())
+	plot.Sub[0].SetScale(stockval.ScaleLogarithmic)
+	assert.Equal(t, stockval.ScaleLogarithmic, plot.Sub[0].GetScale())
+	// Volume cannot be shown using a logarithmic scale.
+	plot.Sub[1].SetScale(stockval.ScaleLogarithmic)
+	assert.Equal(t, stockval.ScaleLinear, plot.Sub[1].GetScale())
+
+	data := plot.GetSubPlotData()
+	assert.Equal(t, stockval.ScaleLogarithmic, data[0].Scale)
+	assert.Equal(t, stockval.ScaleLinear, data[1].Scale)
+}
+
+func TestLinearGridY(t *testing.T) {
+	plot := NewTestPlot()
+	sub := plot.Sub[0]
+	sub.zeroValueY = 1.02
+	InitializeTestPlot(plot)
+
+	sub.calculateGridY()
+
+	assert.NotEmpty(t, sub.frame.labelValues)
+	assert.Equal(t, 1.1, sub.frame.labelValues[0])
+	assert.InDelta(t, 1.2, sub.frame.labelValues[1], stockval.NearZero)
+	assert.Equal(t, sub.frame.pxGridY, sub.frame.labelPositions[0]-sub.frame.labelPositions[1])
+}
+
+func TestLogGridY(t *testing.T) {
+	plot := NewTestPlot()
+	sub := plot.Sub[0]
+	sub.SetScale(stockval.ScaleLogarithmic)
+	sub.zeroValueY = 1 // 10
+	sub.valueGridY = 0.1
+	InitializeTestPlot(plot)
+
+	sub.calculateGridY()
+
+	// Values are rounded depending on the distance to the next grid line.
+	assert.Equal(t, []float64{10, 12, 16, 20}, sub.frame.labelValues[:4])
+	for i := 1; i < len(sub.frame.labelPositions); i++ {
+		assert.Less(t, sub.frame.labelPositions[i], sub.frame.labelPositions[i-1])
+	}
+	// Equal ratios have equal distances.
+	proj := sub.frame.projection
+	assert.InDelta(t, proj.getYpos(1)-proj.getYpos(10), proj.getYpos(10)-proj.getYpos(100), 1e-9)
+}
+
+func TestPercentGridY(t *testing.T) {
+	plot := NewTestPlot()
+	sub := plot.Sub[0]
+	sub.SetScale(stockval.ScalePercent)
+	sub.zeroValueY = 95
+	sub.valueGridY = 0.1
+	InitializeTestPlot(plot)
+
+	// Without reference price, the linear grid is used.
+	sub.calculateGridY()
+	assert.Equal(t, 95.0, sub.frame.labelValues[0])
+
+	sub.frame.referencePrice = 100
+	sub.calculateGridY()
+	assert.InDelta(t, -5, sub.frame.labelValues[0], stockval.NearZero)
+	assert.InDelta(t, -4.9, sub.frame.labelValues[1], stockval.NearZero)
+	assert.Equal(t, "-5.0%", sub.formatGridLabel(sub.frame.labelValues[0]))
+	assert.Equal(t, int(sub.frame.projection.getYpos(95)), sub.frame.labelPositions[0])
+}
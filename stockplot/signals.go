@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockplot
+
+import (
+	"maystocks/indapi"
+	"maystocks/stockval"
+	"time"
+)
+
+// Called for each new signal of an indicator of the plot.
+type SignalHandler func(id indapi.IndicatorId, signal indapi.Signal)
+
+type notifiedSignal struct {
+	indicator   indapi.IndicatorData
+	description string
+}
+
+// Subscribes to new signals of the indicators. Only signals of the most recent candle are reported,
+// each of them once. Signals of the history are shown in the plot, but not reported.
+// The handler is called from the same goroutine as Layout.
+func (plot *Plot) SubscribeSignals(handler SignalHandler) {
+	plot.signalHandlers = append(plot.signalHandlers, handler)
+}
+
+// Collects the signals of all indicators for the price subplots, and notifies subscribers of new signals.
+func (plot *Plot) updateSignals(data *stockval.CandlePlotData) {
+	data.DataMutex.RLock()
+	var lastCandleTime time.Time
+	if n := len(data.Cache.Timestamps); n > 0 {
+		lastCandleTime = data.Cache.Timestamps[n-1]
+	}
+	data.DataMutex.RUnlock()
+	if !lastCandleTime.Equal(plot.lastCandleTime) || plot.notifiedSignals == nil {
+		plot.lastCandleTime = lastCandleTime
+		plot.notifiedSignals = make(map[notifiedSignal]bool)
+	}
+
+	var signals []indapi.Signal
+	for _, sub := range plot.Sub {
+		for _, ind := range sub.Indicators {
+			provider, ok := ind.(indapi.SignalProvider)
+			if !ok {
+				continue
+			}
+			indicatorSignals := provider.GetSignals()
+			signals = append(signals, indicatorSignals...)
+			for _, s := range indicatorSignals {
+				key := notifiedSignal{indicator: ind, description: s.Description}
+				if !s.Timestamp.Equal(lastCandleTime) || plot.notifiedSignals[key] {
+					continue
+				}
+				plot.notifiedSignals[key] = true
+				for _, handler := range plot.signalHandlers {
+					handler(ind.GetId(), s)
+				}
+			}
+		}
+	}
+	for _, sub := range plot.Sub {
+		if sub.Type == indapi.SubPlotTypePrice {
+			sub.signals = signals
+		}
+	}
+}
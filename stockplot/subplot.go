@@ -31,9 +31,24 @@ import (
 
 // All subplots of a plot have the same X values but can have different Y values
 type SubPlot struct {
-	Type              indapi.SubPlotType
-	Theme             *widgets.PlotTheme
-	Indicators        []indapi.IndicatorData
+	Type       indapi.SubPlotType
+	Theme      *widgets.PlotTheme
+	Indicators []indapi.IndicatorData
+	// Scale of the Y axis, the grid and the zoom use scale units, see toScaleUnits.
+	scale stockval.ScaleType
+	// Assets which are drawn as lines relative to the reference candle.
+	comparisons []CompareData
+	// Type of the chart. Price-based charts are shown in the price subplot, the other subplots are empty,
+	// because their data is time-based.
+	chartType stockval.ChartType
+	// Bricks of price-based charts, with the box size which was used.
+	bricks  []stockval.PriceBrick
+	boxSize float64
+	// Position of the pointer, if it is within the plot area.
+	pointerPos f32.Point
+	hasPointer bool
+	// Signals of the indicators of all subplots, only used by the price subplot.
+	signals           []indapi.Signal
 	gridY             unit.Dp
 	zeroValueY        float64 // Y value at zero position of plot
 	hasInitialCandleY bool
@@ -55,8 +70,22 @@ type SubPlot struct {
 		yAxesTextPosX                   int
 		projection                      projection
 		labelValues                     []float64
+		labelPositions                  []int
+		referencePrice                  float64 // close of the first visible candle, see updateReferenceCandle
+		referenceTime                   time.Time
+		percentDecimals                 int
+		minIndicatorValue               float64
+		minPrice                        float64 // price range of the visible candles and comparisons, see updateInitialPriceRange
+		maxPrice                        float64
+		messagePosY                     int // position of the next message, see PlotMessage
+		theme                           *material.Theme
+		candles                         []stockval.PlotCandle
+		thickBrickSegments              []stroke.Segment
+		thinBrickSegments               []stroke.Segment
 		gridSegments                    []stroke.Segment
 		lineSegments                    []stroke.Segment
+		histogramUpSegments             []stroke.Segment
+		histogramDownSegments           []stroke.Segment
 		greenCandleBorderSegments       []stroke.Segment
 		greenCandleLineSegments         []stroke.Segment
 		greenCandleSegments             []stroke.Segment
@@ -105,13 +134,24 @@ type projection struct {
 	mY float64
 	bX float64
 	bY float64
+	bI float64 // used instead of bX for an index-based X axis
+	// Y values are projected logarithmically.
+	log bool
 }
 
 func (proj projection) getXpos(t time.Time, r candles.CandleResolution) float64 {
 	return proj.mX*r.ConvertTimeToCandleUnits(t) + proj.bX
 }
 
+func (proj projection) getIndexXpos(i float64) float64 {
+	return proj.mX*i + proj.bI
+}
+
 func (proj projection) getYpos(v float64) float64 {
+	if proj.log {
+		// Avoid infinite positions for values which cannot be shown.
+		v = math.Log10(math.Max(v, minLogValue))
+	}
 	return proj.mY*v + proj.bY
 }
 
@@ -138,7 +178,7 @@ func (sub *SubPlot) updatePositionAndZoom(gtx layout.Context, yPlotSize float64)
 		} else if sub.nextValueRangeY >= 1 {
 			decimalBase = 10
 		} else {
-			decimalBase = math.Pow10(sub.maxDecimalPlaces)
+			decimalBase = math.Pow10(sub.getMaxDecimalPlaces())
 		}
 		minValueGrid = 1 / decimalBase
 		nextValueGrid := math.Ceil((sub.nextValueRangeY/numSegments)*decimalBase) / decimalBase
@@ -174,7 +214,7 @@ func (sub *SubPlot) updatePositionAndZoom(gtx layout.Context, yPlotSize float64)
 	}
 	if sub.nextBaseValueY > stockval.NearZero {
 		// The zero value is initialized one grid below the base value.
-		sub.zeroValueY = sub.nextBaseValueY - sub.valueGridY
+		sub.zeroValueY = sub.toScaleUnits(sub.nextBaseValueY) - sub.valueGridY
 		log.Printf("Initial value: %f ZeroValue: %f", sub.nextBaseValueY, sub.zeroValueY)
 		sub.nextBaseValueY = 0
 	}
@@ -188,7 +228,7 @@ func (sub *SubPlot) optimiseGridY() {
 	for ; newGridY*2 < sub.Theme.DefaultPlotGrid.Y*1.25*unit.Dp(sub.pxGridRatioY); newGridY, newValueGridY = newGridY*2, newValueGridY*2 {
 	}
 	sub.gridY = unit.Dp(math.Round(float64(newGridY)))
-	decimalBase := math.Pow10(sub.maxDecimalPlaces)
+	decimalBase := math.Pow10(sub.getMaxDecimalPlaces())
 	sub.valueGridY = math.Round(newValueGridY*decimalBase) / decimalBase
 }
 
@@ -206,13 +246,12 @@ func (sub *SubPlot) formatYlabel(value float64) string {
 }
 
 func (sub *SubPlot) paintYaxesText(gtx layout.Context, th *material.Theme) (maxTextSizeX int) {
-	baseValue := sub.calcFirstGridValueY()
-	posY := int(sub.frame.projection.getYpos(baseValue))
-	sub.calculateLabelValues(posY, baseValue)
-	sub.determineLabelPrintFormat()
+	if sub.scale != stockval.ScalePercent {
+		sub.determineLabelPrintFormat()
+	}
 	var labelText string
 	for i, v := range sub.frame.labelValues {
-		newLabelText := sub.formatYlabel(v)
+		newLabelText := sub.formatGridLabel(v)
 		if newLabelText == labelText {
 			continue // do not print text twice if it is unchanged due to precision
 		}
@@ -222,7 +261,7 @@ func (sub *SubPlot) paintYaxesText(gtx layout.Context, th *material.Theme) (maxT
 		if textSize.X > maxTextSizeX {
 			maxTextSizeX = textSize.X
 		}
-		stack := op.Offset(image.Point{X: sub.frame.yAxesTextPosX, Y: posY - i*sub.frame.pxGridY - textSize.Y/2}).Push(gtx.Ops)
+		stack := op.Offset(image.Point{X: sub.frame.yAxesTextPosX, Y: sub.frame.labelPositions[i] - textSize.Y/2}).Push(gtx.Ops)
 		// Run recorded drawing.
 		call.Add(gtx.Ops)
 		stack.Pop()
@@ -230,20 +269,6 @@ func (sub *SubPlot) paintYaxesText(gtx layout.Context, th *material.Theme) (maxT
 	return
 }
 
-func (sub *SubPlot) calculateLabelValues(posY int, baseValue float64) {
-	segmentsY := stockval.CalcNumSegments(posY, sub.frame.minPos.Y, sub.frame.pxGridY)
-	labelValues := sub.frame.labelValues[:0]
-	for i := 0; i < segmentsY; i++ {
-		labelValue := baseValue + float64(i)*sub.valueGridY
-		// we do not want negative zero on our label
-		if labelValue < 0 && labelValue > -stockval.NearZero {
-			labelValue = 0
-		}
-		labelValues = append(labelValues, labelValue)
-	}
-	sub.frame.labelValues = labelValues
-}
-
 func (sub *SubPlot) determineLabelPrintFormat() {
 	printBillions := true
 	printMillions := true
@@ -285,25 +310,20 @@ func (sub *SubPlot) paintAxes(gtx layout.Context) {
 	paint.FillShape(gtx.Ops, sub.Theme.AxesColor, area)
 }
 
-func (sub *SubPlot) paintGrid(r candles.CandleResolution, firstGridValueX time.Time, pxGridX int, gtx layout.Context) {
+func (sub *SubPlot) paintGrid(posX int, pxGridX int, gtx layout.Context) {
 	minPos := sub.frame.minPos
 	maxPos := sub.frame.maxPos
-	baseX := firstGridValueX
-	baseY := sub.calcFirstGridValueY()
-	posX := int(sub.frame.projection.getXpos(baseX, r))
-	posY := int(sub.frame.projection.getYpos(baseY))
 
 	segmentsX := stockval.CalcNumSegments(posX, minPos.X, pxGridX)
-	segmentsY := stockval.CalcNumSegments(posY, minPos.Y, sub.frame.pxGridY)
 	var path stroke.Path
 	path.Segments = sub.frame.gridSegments[:0]
 	for i := 0; i < segmentsX; i++ {
 		path.Segments = append(path.Segments, stroke.MoveTo(f32.Pt(float32(posX-i*pxGridX), float32(minPos.Y))))
 		path.Segments = append(path.Segments, stroke.LineTo(f32.Pt(float32(posX-i*pxGridX), float32(maxPos.Y))))
 	}
-	for i := 0; i < segmentsY; i++ {
-		path.Segments = append(path.Segments, stroke.MoveTo(f32.Pt(float32(minPos.X), float32(posY-i*sub.frame.pxGridY))))
-		path.Segments = append(path.Segments, stroke.LineTo(f32.Pt(float32(maxPos.X), float32(posY-i*sub.frame.pxGridY))))
+	for _, posY := range sub.frame.labelPositions {
+		path.Segments = append(path.Segments, stroke.MoveTo(f32.Pt(float32(minPos.X), float32(posY))))
+		path.Segments = append(path.Segments, stroke.LineTo(f32.Pt(float32(maxPos.X), float32(posY))))
 	}
 	sub.frame.gridSegments = path.Segments
 	area := stroke.Stroke{Path: path, Width: float32(gtx.Dp(1))}.Op(gtx.Ops)
@@ -354,11 +374,16 @@ func (sub *SubPlot) PlotLine(timestamps []time.Time, data []float64, maxValue *f
 	var pxPosI int = -1
 	var pyPos float64 = -1
 	var pyPosI int = -1
+	first := true
 	for i, t := range timestamps {
-		sub.plotLineSegment(t, data[i], r, &pxPos, &pyPos, &pxPosI, &pyPosI, i == 0, clipRect, &path)
-		if data[i] > *maxValue {
-			*maxValue = data[i]
+		// Values which are not available are NaN, the line is interrupted.
+		if math.IsNaN(data[i]) {
+			first = true
+			continue
 		}
+		sub.plotLineSegment(t, data[i], r, &pxPos, &pyPos, &pxPosI, &pyPosI, first, clipRect, &path)
+		first = false
+		sub.updateValueRange(data[i], maxValue)
 	}
 
 	// Only draw within the plot area.
@@ -369,6 +394,272 @@ func (sub *SubPlot) PlotLine(timestamps []time.Time, data []float64, maxValue *f
 	paint.FillShape(gtx.Ops, c, pathArea)
 }
 
+func (sub *SubPlot) updateValueRange(value float64, maxValue *float64) {
+	if value > *maxValue {
+		*maxValue = value
+	}
+	if value < sub.frame.minIndicatorValue {
+		sub.frame.minIndicatorValue = value
+	}
+}
+
+func (sub *SubPlot) PlotHistogram(timestamps []time.Time, data []float64, maxValue *float64, r candles.CandleResolution,
+	positive color.NRGBA, negative color.NRGBA, gtx layout.Context) {
+	if empty := (color.NRGBA{}); positive == empty {
+		positive = sub.Theme.BarUpColor
+	}
+	if empty := (color.NRGBA{}); negative == empty {
+		negative = sub.Theme.BarDownColor
+	}
+	sub.frame.histogramUpSegments = sub.frame.histogramUpSegments[:0]
+	sub.frame.histogramDownSegments = sub.frame.histogramDownSegments[:0]
+	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
+	barWidth, _, _ := getCandleWidth(sub.frame.projection.mX, gtx.Dp(1))
+	zeroPos := sub.frame.projection.getYpos(0)
+	for i, t := range timestamps {
+		if math.IsNaN(data[i]) {
+			continue
+		}
+		sub.updateValueRange(data[i], maxValue)
+		xPos := sub.frame.projection.getXpos(t, r)
+		// Performance: Skip bars outside of the visible plot range.
+		if int(xPos)+barWidth/2 < clipRect.Min.X || int(xPos)-barWidth/2 > clipRect.Max.X {
+			continue
+		}
+		yPos := sub.frame.projection.getYpos(data[i])
+		if math.Round(yPos) == math.Round(zeroPos) {
+			yPos-- // Use a minimum height of 1 px
+		}
+		bar1 := stroke.MoveTo(f32.Pt(float32(xPos), float32(zeroPos)))
+		bar2 := stroke.LineTo(f32.Pt(float32(xPos), float32(yPos)))
+		if data[i] >= 0 {
+			sub.frame.histogramUpSegments = append(sub.frame.histogramUpSegments, bar1, bar2)
+		} else {
+			sub.frame.histogramDownSegments = append(sub.frame.histogramDownSegments, bar1, bar2)
+		}
+	}
+	defer clip.Rect(clipRect).Push(gtx.Ops).Pop()
+	sub.strokeCandleSegments(gtx, sub.frame.histogramUpSegments, float32(barWidth), positive)
+	sub.strokeCandleSegments(gtx, sub.frame.histogramDownSegments, float32(barWidth), negative)
+}
+
+func (sub *SubPlot) PlotBand(timestamps []time.Time, upper []float64, lower []float64, maxValue *float64, r candles.CandleResolution,
+	c color.NRGBA, gtx layout.Context) {
+	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
+	defer clip.Rect(clipRect).Push(gtx.Ops).Pop()
+	// Each range of valid values is filled separately.
+	start := -1
+	for i := 0; i <= len(timestamps); i++ {
+		valid := i < len(timestamps) && !math.IsNaN(upper[i]) && !math.IsNaN(lower[i])
+		if valid {
+			sub.updateValueRange(upper[i], maxValue)
+			sub.updateValueRange(lower[i], maxValue)
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start > 1 {
+			sub.fillBandArea(timestamps[start:i], upper[start:i], lower[start:i], r, c, gtx)
+		}
+		start = -1
+	}
+}
+
+func (sub *SubPlot) fillBandArea(timestamps []time.Time, upper []float64, lower []float64, r candles.CandleResolution, c color.NRGBA, gtx layout.Context) {
+	var path clip.Path
+	path.Begin(gtx.Ops)
+	for i, t := range timestamps {
+		pt := f32.Pt(float32(sub.frame.projection.getXpos(t, r)), float32(sub.frame.projection.getYpos(upper[i])))
+		if i == 0 {
+			path.MoveTo(pt)
+		} else {
+			path.LineTo(pt)
+		}
+	}
+	for i := len(timestamps) - 1; i >= 0; i-- {
+		path.LineTo(f32.Pt(float32(sub.frame.projection.getXpos(timestamps[i], r)), float32(sub.frame.projection.getYpos(lower[i]))))
+	}
+	path.Close()
+	paint.FillShape(gtx.Ops, c, clip.Outline{Path: path.End()}.Op())
+}
+
+func (sub *SubPlot) PlotHorizontalLine(value float64, maxValue *float64, c color.NRGBA, gtx layout.Context) {
+	sub.updateValueRange(value, maxValue)
+	yPos := float32(sub.frame.projection.getYpos(value))
+	if int(yPos) < sub.frame.minPos.Y || int(yPos) > sub.frame.maxPos.Y {
+		return
+	}
+	var path stroke.Path
+	path.Segments = append(path.Segments,
+		stroke.MoveTo(f32.Pt(float32(sub.frame.minPos.X), yPos)),
+		stroke.LineTo(f32.Pt(float32(sub.frame.maxPos.X), yPos)),
+	)
+	paint.FillShape(gtx.Ops, c, stroke.Stroke{Path: path, Width: 1}.Op(gtx.Ops))
+}
+
+func (sub *SubPlot) PlotHorizontalSegment(start time.Time, end time.Time, value float64, maxValue *float64, r candles.CandleResolution,
+	c color.NRGBA, gtx layout.Context) {
+	if math.IsNaN(value) {
+		return
+	}
+	sub.updateValueRange(value, maxValue)
+	yPos := float32(sub.frame.projection.getYpos(value))
+	// Candles are centered at their position, the segment covers the full width of the candles.
+	halfCandle := sub.frame.projection.mX / 2
+	x1Pos := float32(max(sub.frame.projection.getXpos(start, r)-halfCandle, float64(sub.frame.minPos.X)))
+	x2Pos := float32(min(sub.frame.projection.getXpos(end, r)+halfCandle, float64(sub.frame.maxPos.X)))
+	if int(yPos) < sub.frame.minPos.Y || int(yPos) > sub.frame.maxPos.Y || x1Pos >= x2Pos {
+		return
+	}
+	var path stroke.Path
+	path.Segments = append(path.Segments,
+		stroke.MoveTo(f32.Pt(x1Pos, yPos)),
+		stroke.LineTo(f32.Pt(x2Pos, yPos)),
+	)
+	paint.FillShape(gtx.Ops, c, stroke.Stroke{Path: path, Width: 1}.Op(gtx.Ops))
+}
+
+func (sub *SubPlot) PlotHorizontalBars(bounds []float64, values []float64, colors []color.NRGBA, maxWidth float64, gtx layout.Context) {
+	n := min(len(bounds)-1, len(values), len(colors))
+	var maxBarValue float64
+	for i := range n {
+		maxBarValue = math.Max(maxBarValue, values[i])
+	}
+	if maxBarValue <= 0 {
+		return
+	}
+	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
+	defer clip.Rect(clipRect).Push(gtx.Ops).Pop()
+	maxLength := maxWidth * float64(clipRect.Dx())
+	for i := range n {
+		y1 := int(math.Round(sub.frame.projection.getYpos(bounds[i])))
+		y2 := int(math.Round(sub.frame.projection.getYpos(bounds[i+1])))
+		bar := image.Rect(clipRect.Max.X-int(values[i]/maxBarValue*maxLength), y1, clipRect.Max.X, y2).Canon()
+		// Leave a gap between the bars if they are large enough.
+		if bar.Dy() > 2 {
+			bar.Max.Y--
+		}
+		if bar.Empty() || !bar.Overlaps(clipRect) {
+			continue
+		}
+		paint.FillShape(gtx.Ops, colors[i], clip.Rect(bar).Op())
+	}
+}
+
+func (sub *SubPlot) GetVisibleTimeRange(r candles.CandleResolution) (start time.Time, end time.Time) {
+	proj := sub.frame.projection
+	if proj.mX == 0 {
+		return
+	}
+	start = r.ConvertCandleUnitsToTime((float64(sub.frame.minPos.X) - proj.bX) / proj.mX)
+	end = r.ConvertCandleUnitsToTime((float64(sub.frame.maxPos.X) - proj.bX) / proj.mX)
+	return
+}
+
+func (sub *SubPlot) PlotMarkers(timestamps []time.Time, data []float64, maxValue *float64, r candles.CandleResolution,
+	c color.NRGBA, shape indapi.MarkerShape, gtx layout.Context) {
+	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
+	defer clip.Rect(clipRect).Push(gtx.Ops).Pop()
+	size := float32(gtx.Dp(3))
+	for i, t := range timestamps {
+		if math.IsNaN(data[i]) {
+			continue
+		}
+		sub.updateValueRange(data[i], maxValue)
+		x := float32(sub.frame.projection.getXpos(t, r))
+		y := float32(sub.frame.projection.getYpos(data[i]))
+		if !image.Pt(int(x), int(y)).In(clipRect.Inset(-int(size))) {
+			continue
+		}
+		paintMarker(x, y, size, c, shape, gtx)
+	}
+}
+
+func (sub *SubPlot) PlotLabels(timestamps []time.Time, data []float64, labels []string, maxValue *float64, r candles.CandleResolution,
+	c color.NRGBA, position indapi.LabelPosition, gtx layout.Context) {
+	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
+	defer clip.Rect(clipRect).Push(gtx.Ops).Pop()
+	offset := gtx.Dp(4)
+	gtx.Constraints.Min = image.Point{}
+	for i := range min(len(timestamps), len(data), len(labels)) {
+		if labels[i] == "" || math.IsNaN(data[i]) {
+			continue
+		}
+		sub.updateValueRange(data[i], maxValue)
+		if sub.frame.theme == nil {
+			continue
+		}
+		x := int(sub.frame.projection.getXpos(timestamps[i], r))
+		y := int(sub.frame.projection.getYpos(data[i]))
+		if x < clipRect.Min.X || x > clipRect.Max.X {
+			continue
+		}
+		call, textSize := recordAxesLabelText(labels[i], c, sub.Theme.LabelFontSize, gtx, sub.frame.theme)
+		pos := image.Pt(x-textSize.X/2, y+offset)
+		if position == indapi.LabelAbove {
+			pos.Y = y - offset - textSize.Y
+		}
+		stack := op.Offset(pos).Push(gtx.Ops)
+		call.Add(gtx.Ops)
+		stack.Pop()
+	}
+}
+
+func (sub *SubPlot) PlotMessage(text string, c color.NRGBA, gtx layout.Context) {
+	if sub.frame.theme == nil {
+		return
+	}
+	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
+	defer clip.Rect(clipRect).Push(gtx.Ops).Pop()
+	offset := gtx.Dp(4)
+	gtx.Constraints.Min = image.Point{}
+	call, textSize := recordAxesLabelText(text, c, sub.Theme.LabelFontSize, gtx, sub.frame.theme)
+	stack := op.Offset(image.Pt(clipRect.Min.X+offset, sub.frame.messagePosY+offset)).Push(gtx.Ops)
+	call.Add(gtx.Ops)
+	stack.Pop()
+	sub.frame.messagePosY += offset + textSize.Y
+}
+
+func paintMarker(x, y, size float32, c color.NRGBA, shape indapi.MarkerShape, gtx layout.Context) {
+	switch shape {
+	case indapi.MarkerTriangleUp, indapi.MarkerTriangleDown:
+		direction := float32(1)
+		if shape == indapi.MarkerTriangleUp {
+			direction = -1
+		}
+		var path clip.Path
+		path.Begin(gtx.Ops)
+		path.MoveTo(f32.Pt(x, y+direction*size))
+		path.LineTo(f32.Pt(x-size, y-direction*size))
+		path.LineTo(f32.Pt(x+size, y-direction*size))
+		path.Close()
+		paint.FillShape(gtx.Ops, c, clip.Outline{Path: path.End()}.Op())
+	default:
+		bounds := image.Rect(int(x-size), int(y-size), int(x+size), int(y+size))
+		paint.FillShape(gtx.Ops, c, clip.Ellipse(bounds).Op(gtx.Ops))
+	}
+}
+
+// Plots signals of the indicators as arrows below or above the candles.
+func (sub *SubPlot) plotSignals(r candles.CandleResolution, gtx layout.Context) {
+	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
+	defer clip.Rect(clipRect).Push(gtx.Ops).Pop()
+	size := float32(gtx.Dp(5))
+	for _, s := range sub.signals {
+		x := float32(sub.frame.projection.getXpos(s.Timestamp, r))
+		y := float32(sub.frame.projection.getYpos(s.Price))
+		if !image.Pt(int(x), int(y)).In(clipRect.Inset(-int(size * 3))) {
+			continue
+		}
+		if s.Direction == indapi.SignalBullish {
+			paintMarker(x, y+size*2, size, sub.Theme.SignalBullishColor, indapi.MarkerTriangleUp, gtx)
+		} else {
+			paintMarker(x, y-size*2, size, sub.Theme.SignalBearishColor, indapi.MarkerTriangleDown, gtx)
+		}
+	}
+}
+
 func (sub *SubPlot) resetCandleSegments() {
 	sub.frame.greenCandleBorderSegments = sub.frame.greenCandleBorderSegments[:0]
 	sub.frame.greenCandleLineSegments = sub.frame.greenCandleLineSegments[:0]
@@ -397,16 +688,23 @@ func (sub *SubPlot) strokeCandleSegments(gtx layout.Context, seg []stroke.Segmen
 	)
 }
 
-func (sub *SubPlot) UpdateIndicators(data *stockval.CandlePlotData) {
-	for _, ind := range sub.Indicators {
-		ind.Update(data.Resolution, &data.PlotData)
-	}
-}
-
 func (sub *SubPlot) Plot(data *stockval.CandlePlotData, quote stockval.QuoteData, gtx layout.Context, th *material.Theme) {
 	var maxIndicatorValue float64
+	sub.frame.theme = th
+	sub.frame.messagePosY = sub.frame.minPos.Y
+	if sub.chartType.IsPriceBased() {
+		if sub.Type == indapi.SubPlotTypePrice {
+			sub.updateReferenceCandle(data, gtx)
+			sub.plotCandles(data, gtx)
+			sub.updateInitialPriceRange(data, gtx)
+			sub.plotQuoteLine(quote, gtx, th)
+			sub.plotBrickTooltip(data.Resolution, gtx)
+		}
+		return
+	}
 	switch sub.Type {
 	case indapi.SubPlotTypePrice:
+		sub.updateReferenceCandle(data, gtx)
 		sub.plotCandles(
 			data,
 			gtx,
@@ -416,52 +714,62 @@ func (sub *SubPlot) Plot(data *stockval.CandlePlotData, quote stockval.QuoteData
 			gtx,
 			th,
 		)
+		sub.plotComparisons(data.Resolution, &maxIndicatorValue, gtx)
+		sub.updateInitialPriceRange(data, gtx)
 		for _, ind := range sub.Indicators {
 			ind.Plot(sub, &maxIndicatorValue, sub.Theme.DefaultIndicatorColor, gtx)
 		}
+		sub.plotSignals(data.Resolution, gtx)
 	case indapi.SubPlotTypeVolume:
-		sub.plotVolumeBars(
+		maxVolume := sub.plotVolumeBars(
 			data,
 			gtx,
 		)
+		for _, ind := range sub.Indicators {
+			ind.Plot(sub, &maxVolume, sub.Theme.DefaultIndicatorColor, gtx)
+		}
+		sub.autoZoomGenericY(0, maxVolume, gtx)
 	case indapi.SubPlotTypeIndicator:
+		sub.frame.minIndicatorValue = 0
 		for _, ind := range sub.Indicators {
 			ind.Plot(sub, &maxIndicatorValue, sub.Theme.DefaultIndicatorColor, gtx)
 		}
-		sub.autoZoomGenericY(maxIndicatorValue, gtx)
+		sub.autoZoomGenericY(sub.frame.minIndicatorValue, maxIndicatorValue, gtx)
 	}
 }
 
+// Plots the candles and sets the price range of the visible candles.
 func (sub *SubPlot) plotCandles(data *stockval.CandlePlotData, gtx layout.Context) {
-	var minPrice, maxPrice float64
+	sub.frame.minPrice, sub.frame.maxPrice = 0, 0
+	minPrice, maxPrice := &sub.frame.minPrice, &sub.frame.maxPrice
 
 	sub.resetCandleSegments()
 	// Only draw within the plot area.
 	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
 	defer clip.Rect(clipRect).Push(gtx.Ops).Pop()
 
-	data.DataMutex.RLock()
-	hasData := len(data.Data) > 0
-	for _, d := range data.Data {
-		l, _ := d.LowPrice.Float64()
-		h, _ := d.HighPrice.Float64()
-		o, _ := d.OpenPrice.Float64()
-		c, _ := d.ClosePrice.Float64()
-		sub.plotSingleCandle(l, h, o, c, d.Timestamp, data.Resolution, true, &minPrice, &maxPrice, clipRect, gtx)
-	}
-	data.DataMutex.RUnlock()
-
-	data.RealtimeData.DataMutex.RLock()
-	for i, d := range data.RealtimeData.Data {
-		if data.HasValidRealtimePrices(i) {
-			l, _ := d.LowPrice.Float64()
-			h, _ := d.HighPrice.Float64()
-			o, _ := d.OpenPrice.Float64()
-			c, _ := d.ClosePrice.Float64()
-			sub.plotSingleCandle(l, h, o, c, d.Timestamp, data.Resolution, data.RealtimeData.OpenConsolidated[i], &minPrice, &maxPrice, clipRect, gtx)
+	switch sub.chartType {
+	case stockval.ChartLine, stockval.ChartArea:
+		sub.plotPriceLine(data.GetCandleSeries(), data.Resolution, sub.chartType == stockval.ChartArea, minPrice, maxPrice, clipRect, gtx)
+	case stockval.ChartRenko, stockval.ChartKagi, stockval.ChartPointFigure:
+		sub.plotPriceBricks(minPrice, maxPrice, clipRect, gtx)
+	case stockval.ChartHeikinAshi:
+		for _, d := range data.GetHeikinAshiCandles() {
+			sub.plotSingleCandle(d.Low, d.High, d.Open, d.Close, d.Timestamp, data.Resolution, d.Consolidated, minPrice, maxPrice, clipRect, gtx)
+		}
+	default:
+		sub.frame.candles = data.GetPlotCandles(sub.frame.candles)
+		for _, d := range sub.frame.candles {
+			switch {
+			case sub.chartType == stockval.ChartOhlcBars:
+				sub.plotSingleOhlcBar(d, data.Resolution, minPrice, maxPrice, clipRect, gtx)
+			case sub.chartType == stockval.ChartHollowCandles && stockval.IsGreenCandle(d.Open, d.Close):
+				sub.plotSingleHollowCandle(d, data.Resolution, minPrice, maxPrice, clipRect, gtx)
+			default:
+				sub.plotSingleCandle(d.Low, d.High, d.Open, d.Close, d.Timestamp, data.Resolution, d.Consolidated, minPrice, maxPrice, clipRect, gtx)
+			}
 		}
 	}
-	data.RealtimeData.DataMutex.RUnlock()
 	candleWidth, lineWidth, borderWidth := getCandleWidth(sub.frame.projection.mX, gtx.Dp(1))
 	candleColor, lineColor, borderColor := sub.Theme.GetCandleColors(true, true)
 	var actualBorderWidth int
@@ -485,6 +793,15 @@ func (sub *SubPlot) plotCandles(data *stockval.CandlePlotData, gtx layout.Contex
 	sub.strokeCandleSegments(gtx, sub.frame.unsureRedCandleBorderSegments, float32(borderWidth), borderColor)
 	sub.strokeCandleSegments(gtx, sub.frame.unsureRedCandleSegments, float32(candleWidth-actualBorderWidth), candleColor)
 
+}
+
+// Initializes the Y axis to the price range of the visible candles and comparisons,
+// unless it was already initialized.
+func (sub *SubPlot) updateInitialPriceRange(data *stockval.CandlePlotData, gtx layout.Context) {
+	data.DataMutex.RLock()
+	hasData := len(data.Data) > 0 || data.RealtimeOnly
+	data.DataMutex.RUnlock()
+	minPrice, maxPrice := sub.frame.minPrice, sub.frame.maxPrice
 	if hasData && minPrice > stockval.NearZero && maxPrice > stockval.NearZero {
 		var invalidate bool
 		if !sub.hasInitialCandleY {
@@ -493,7 +810,7 @@ func (sub *SubPlot) plotCandles(data *stockval.CandlePlotData, gtx layout.Contex
 			invalidate = true
 		}
 		if !sub.hasInitialRangeY {
-			sub.nextValueRangeY = sub.getValueRange(maxPrice - minPrice)
+			sub.nextValueRangeY = sub.getValueRange(sub.toScaleUnits(maxPrice) - sub.toScaleUnits(minPrice))
 			sub.hasInitialRangeY = true
 			invalidate = true
 		}
@@ -504,9 +821,13 @@ func (sub *SubPlot) plotCandles(data *stockval.CandlePlotData, gtx layout.Contex
 }
 
 func (sub *SubPlot) plotSingleCandle(l, h, o, c float64, t time.Time, r candles.CandleResolution, consolidated bool,
+	minPrice, maxPrice *float64, clipRect image.Rectangle, gtx layout.Context) {
+	sub.plotSingleCandleAt(l, h, o, c, sub.frame.projection.getXpos(t, r), consolidated, minPrice, maxPrice, clipRect, gtx)
+}
+
+func (sub *SubPlot) plotSingleCandleAt(l, h, o, c float64, xPos float64, consolidated bool,
 	minPrice, maxPrice *float64, clipRect image.Rectangle, gtx layout.Context) {
 	candleWidth, _, borderWidth := getCandleWidth(sub.frame.projection.mX, gtx.Dp(1))
-	xPos := sub.frame.projection.getXpos(t, r)
 	y1Pos := sub.frame.projection.getYpos(l)
 	y2Pos := sub.frame.projection.getYpos(h)
 	if math.Round(y1Pos) == math.Round(y2Pos) {
@@ -611,7 +932,7 @@ func (sub *SubPlot) plotSingleCandle(l, h, o, c float64, t time.Time, r candles.
 
 func (sub *SubPlot) getValueRange(maxDiff float64) float64 {
 	newValueRange := math.Pow10(stockval.CountDigits(int64(math.Ceil(maxDiff))))
-	decimalPlaces := sub.maxDecimalPlaces
+	decimalPlaces := sub.getMaxDecimalPlaces()
 	if decimalPlaces > 0 {
 		// use one less decimal place for the total range than what is supported for each value.
 		decimalPlaces--
@@ -641,7 +962,8 @@ func (sub *SubPlot) resetVolumeSegments() {
 	sub.frame.unsureRedVolumeSegments = sub.frame.unsureRedVolumeSegments[:0]
 }
 
-func (sub *SubPlot) plotVolumeBars(data *stockval.CandlePlotData, gtx layout.Context) {
+// Plots the volume bars and returns the maximum volume.
+func (sub *SubPlot) plotVolumeBars(data *stockval.CandlePlotData, gtx layout.Context) float64 {
 	sub.resetVolumeSegments()
 	// Only draw within the plot area.
 	clipRect := image.Rectangle{Min: sub.frame.minPos, Max: sub.frame.maxPos}
@@ -676,19 +998,28 @@ func (sub *SubPlot) plotVolumeBars(data *stockval.CandlePlotData, gtx layout.Con
 	sub.strokeCandleSegments(gtx, sub.frame.redVolumeSegments, float32(barWidth), sub.Theme.BarDownColor)
 	sub.strokeCandleSegments(gtx, sub.frame.unsureGreenVolumeSegments, float32(barWidth), sub.Theme.BarUnknownColor)
 	sub.strokeCandleSegments(gtx, sub.frame.unsureRedVolumeSegments, float32(barWidth), sub.Theme.BarUnknownColor)
-
-	sub.autoZoomGenericY(maxYvalue, gtx)
+	return maxYvalue
 }
 
-func (sub *SubPlot) autoZoomGenericY(maxYvalue float64, gtx layout.Context) {
+func (sub *SubPlot) autoZoomGenericY(minYvalue float64, maxYvalue float64, gtx layout.Context) {
 	maxPlottableYvalue := sub.calcYvalueRange()
+	valueRange := maxYvalue - minYvalue
 	// Auto-Zoom subplot to better fit data.
-	if maxYvalue > stockval.NearZero && (maxYvalue > maxPlottableYvalue || maxYvalue < maxPlottableYvalue/2) {
+	if valueRange > stockval.NearZero && (valueRange > maxPlottableYvalue || valueRange < maxPlottableYvalue/2) {
 		// Use rounded value range because this is generic data.
-		sub.nextValueRangeY = sub.getValueRange(maxYvalue)
+		sub.nextValueRangeY = sub.getValueRange(valueRange)
 		// Redraw this subplot with new value range settings.
 		gtx.Execute(op.InvalidateCmd{})
 	}
+	// Move the zero position below the minimum if there are negative values.
+	var zeroValueY float64
+	if minYvalue < 0 {
+		zeroValueY = math.Floor(minYvalue/sub.valueGridY) * sub.valueGridY
+	}
+	if zeroValueY != sub.zeroValueY {
+		sub.zeroValueY = zeroValueY
+		gtx.Execute(op.InvalidateCmd{})
+	}
 }
 
 func (sub *SubPlot) plotSingleBar(v float64, t time.Time, r candles.CandleResolution, proj projection, y2Pos float64,
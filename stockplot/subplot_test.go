@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockplot
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"testing"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget/material"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestContext() layout.Context {
+	var gtx layout.Context
+	gtx.Constraints.Max = image.Pt(800, 600)
+	gtx.Ops = new(op.Ops)
+	return gtx
+}
+
+func TestPlotterValueRange(t *testing.T) {
+	plot := NewTestPlot()
+	InitializeTestPlot(plot)
+	sub := plot.Sub[1]
+	gtx := newTestContext()
+	start := time.Date(2023, 4, 14, 10, 0, 0, 0, time.UTC)
+	timestamps := []time.Time{start, start.Add(time.Minute), start.Add(2 * time.Minute)}
+	c := color.NRGBA{A: 255}
+	nan := math.NaN()
+
+	var maxValue float64
+	sub.frame.minIndicatorValue = 0
+	sub.PlotHistogram(timestamps, []float64{nan, -2, 1}, &maxValue, candles.CandleOneMinute, color.NRGBA{}, color.NRGBA{}, gtx)
+	assert.Equal(t, 1.0, maxValue)
+	assert.Equal(t, -2.0, sub.frame.minIndicatorValue)
+
+	sub.PlotBand(timestamps, []float64{nan, 3, 4}, []float64{nan, -3, 0}, &maxValue, candles.CandleOneMinute, c, gtx)
+	assert.Equal(t, 4.0, maxValue)
+	assert.Equal(t, -3.0, sub.frame.minIndicatorValue)
+
+	sub.PlotHorizontalLine(-5, &maxValue, c, gtx)
+	assert.Equal(t, -5.0, sub.frame.minIndicatorValue)
+
+	sub.PlotMarkers(timestamps, []float64{6, nan, nan}, &maxValue, candles.CandleOneMinute, c, indapi.MarkerTriangleUp, gtx)
+	assert.Equal(t, 6.0, maxValue)
+}
+
+func TestGetVisibleTimeRange(t *testing.T) {
+	plot := NewTestPlot()
+	InitializeTestPlot(plot)
+	sub := plot.Sub[0]
+	start, end := sub.GetVisibleTimeRange(candles.CandleOneMinute)
+	assert.True(t, start.Before(end))
+	// The times are at the edges of the plot area.
+	assert.InDelta(t, float64(sub.frame.minPos.X), sub.frame.projection.getXpos(start, candles.CandleOneMinute), 1)
+	assert.InDelta(t, float64(sub.frame.maxPos.X), sub.frame.projection.getXpos(end, candles.CandleOneMinute), 1)
+}
+
+func TestPlotMessage(t *testing.T) {
+	plot := NewTestPlot()
+	InitializeTestPlot(plot)
+	sub := plot.Sub[1]
+	gtx := newTestContext()
+	c := color.NRGBA{A: 255}
+
+	// Without theme, nothing is plotted.
+	sub.frame.messagePosY = sub.frame.minPos.Y
+	sub.PlotMessage("first", c, gtx)
+	assert.Equal(t, sub.frame.minPos.Y, sub.frame.messagePosY)
+
+	// Messages are shown below each other.
+	sub.frame.theme = material.NewTheme()
+	sub.PlotMessage("first", c, gtx)
+	first := sub.frame.messagePosY
+	assert.Greater(t, first, sub.frame.minPos.Y)
+	sub.PlotMessage("second", c, gtx)
+	assert.Equal(t, sub.frame.minPos.Y+2*(first-sub.frame.minPos.Y), sub.frame.messagePosY)
+}
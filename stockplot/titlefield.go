@@ -12,10 +12,10 @@ import (
 	"gioui.org/widget/material"
 )
 
-func LayoutTitleField(gtx layout.Context, th *material.Theme, pth *widgets.PlotTheme, entry stockval.AssetData) layout.Dimensions {
+// Shows the name of the asset, followed by a legend of the compared assets.
+func LayoutTitleField(gtx layout.Context, th *material.Theme, pth *widgets.PlotTheme, entry stockval.AssetData, comparisons []CompareData) layout.Dimensions {
 	return widgets.Frame{InnerMargin: 5, BorderWidth: 1, BorderColor: pth.FrameBgColor, BackgroundColor: pth.FrameBgColor}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-		return layout.Flex{}.Layout(
-			gtx,
+		children := []layout.FlexChild{
 			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 				lblName := material.H6(
 					th,
@@ -25,6 +25,19 @@ func LayoutTitleField(gtx layout.Context, th *material.Theme, pth *widgets.PlotT
 				lblName.Alignment = text.Start
 				return lblName.Layout(gtx)
 			}),
+		}
+		for _, c := range comparisons {
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Inset{Left: 10}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					lblSymbol := material.H6(th, c.Symbol)
+					lblSymbol.Color = c.Color
+					return lblSymbol.Layout(gtx)
+				})
+			}))
+		}
+		return layout.Flex{Alignment: layout.Baseline}.Layout(
+			gtx,
+			children...,
 		)
 	})
 }
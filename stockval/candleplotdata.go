@@ -7,6 +7,7 @@ import (
 	"log"
 	"maystocks/indapi"
 	"maystocks/indapi/candles"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -17,6 +18,9 @@ import (
 const PreloadCandlesBefore = 64
 const PreloadCandlesAfter = 64
 
+// Maximum number of candles which are kept if candles are built from realtime trades only.
+const MaxRealtimeOnlyCandles = 4096
+
 type RealtimeData struct {
 	// These are arrays, because some old data may still be received (with lag), and
 	// stockapi may return consolidated candles with delay.
@@ -27,6 +31,30 @@ type RealtimeData struct {
 	HasInitData      bool
 }
 
+// Returns the index of the realtime candle starting at candleTime, or -1 if there is none.
+// Trades usually update the most recent candle, so the search starts with the last candle.
+func (r *RealtimeData) findCandle(candleTime time.Time) int {
+	for i := len(r.Data) - 1; i >= 0; i-- {
+		if candleTime.Equal(r.Data[i].Timestamp) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Removes the oldest candles if there are more than maxCandles. A quarter of maxCandles is removed
+// in addition, so that the cache is not reset for every new candle.
+func (r *RealtimeData) trimCandles(maxCandles int) {
+	if len(r.Data) <= maxCandles {
+		return
+	}
+	n := len(r.Data) - maxCandles*3/4
+	r.Data = slices.Delete(r.Data, 0, n)
+	r.OpenTimestamps = slices.Delete(r.OpenTimestamps, 0, n)
+	r.CloseTimestamps = slices.Delete(r.CloseTimestamps, 0, n)
+	r.OpenConsolidated = slices.Delete(r.OpenConsolidated, 0, n)
+}
+
 type CandlePlotData struct {
 	// The resolution is set during initialization and never changed.
 	// Therefore it is safe to be accessed from different goroutines.
@@ -37,13 +65,18 @@ type CandlePlotData struct {
 	indapi.PlotData
 	// Realtime candle data can be updated. Candles will be appended to "consolidated" candles.
 	RealtimeData RealtimeData
+	// There are no "consolidated" candles, candles are built from realtime trades only.
+	// This is set during initialization before realtime data is added.
+	RealtimeOnly bool
 }
 
-func NewCandlePlotData(resolution candles.CandleResolution) *CandlePlotData {
+func NewCandlePlotData(resolution candles.CandleResolution, session candles.Session, isTradingTime TradingTimeFunc) *CandlePlotData {
 	return &CandlePlotData{
 		Resolution: resolution,
 		PlotData: indapi.PlotData{
-			DataMutex: new(sync.RWMutex),
+			Session:       session,
+			IsTradingTime: isTradingTime,
+			DataMutex:     new(sync.RWMutex),
 		},
 		RealtimeData: RealtimeData{
 			PlotData: indapi.PlotData{
@@ -53,31 +86,112 @@ func NewCandlePlotData(resolution candles.CandleResolution) *CandlePlotData {
 	}
 }
 
+// Float values of a candle, as stored in the cache.
+type cachedCandle struct {
+	timestamp     time.Time
+	o, h, l, c, v float64
+}
+
+func newCachedCandle(candle indapi.CandleData) cachedCandle {
+	o, _ := candle.OpenPrice.Float64()
+	h, _ := candle.HighPrice.Float64()
+	l, _ := candle.LowPrice.Float64()
+	c, _ := candle.ClosePrice.Float64()
+	v, _ := candle.Volume.Float64()
+	return cachedCandle{timestamp: candle.Timestamp, o: o, h: h, l: l, c: c, v: v}
+}
+
+func (c cachedCandle) equals(o cachedCandle) bool {
+	return c.timestamp.Equal(o.timestamp) && c.o == o.o && c.h == o.h && c.l == o.l && c.c == o.c && c.v == o.v
+}
+
+// Updates the float values of the candles which are used by indicators.
+// Live candles are appended to the consolidated candles. If only live candles changed, the cache
+// is updated incrementally, i.e. candles are appended or the last candle is replaced.
 func (d *CandlePlotData) UpdateCache() {
+	live := d.getLiveCandles()
 	d.DataMutex.Lock()
 	defer d.DataMutex.Unlock()
-	if !d.DataLastChange.Equal(d.Cache.LastUpdate) {
-		d.Cache.LastUpdate = d.DataLastChange
-		d.Cache.Timestamps = d.Cache.Timestamps[:0]
-		d.Cache.OpenPrices = d.Cache.OpenPrices[:0]
-		d.Cache.HighPrices = d.Cache.HighPrices[:0]
-		d.Cache.LowPrices = d.Cache.LowPrices[:0]
-		d.Cache.ClosePrices = d.Cache.ClosePrices[:0]
-		d.Cache.Volumes = d.Cache.Volumes[:0]
+	c := &d.Cache
+	if !d.DataLastChange.Equal(c.LastUpdate) {
+		c.LastUpdate = d.DataLastChange
+		d.truncateCache(0)
 		for _, candle := range d.Data {
-			o, _ := candle.OpenPrice.Float64()
-			h, _ := candle.HighPrice.Float64()
-			l, _ := candle.LowPrice.Float64()
-			c, _ := candle.ClosePrice.Float64()
-			v, _ := candle.Volume.Float64()
-			d.Cache.Timestamps = append(d.Cache.Timestamps, candle.Timestamp)
-			d.Cache.OpenPrices = append(d.Cache.OpenPrices, o)
-			d.Cache.HighPrices = append(d.Cache.HighPrices, h)
-			d.Cache.LowPrices = append(d.Cache.LowPrices, l)
-			d.Cache.ClosePrices = append(d.Cache.ClosePrices, c)
-			d.Cache.Volumes = append(d.Cache.Volumes, v)
+			d.appendToCache(newCachedCandle(candle))
+		}
+		c.NumConsolidated = len(d.Data)
+		for _, candle := range live {
+			d.appendToCache(candle)
+		}
+		c.ResetCount++
+		c.ChangeCount++
+		return
+	}
+	// Find the first live candle which changed.
+	numCachedLive := len(c.Timestamps) - c.NumConsolidated
+	k := 0
+	for k < len(live) && k < numCachedLive && d.getCachedCandle(c.NumConsolidated+k).equals(live[k]) {
+		k++
+	}
+	if k == len(live) && k == numCachedLive {
+		return // no change
+	}
+	if k < numCachedLive-1 || len(live) < numCachedLive {
+		// Not only the last candle changed, indicators need to be calculated again.
+		c.ResetCount++
+	}
+	d.truncateCache(c.NumConsolidated + k)
+	for _, candle := range live[k:] {
+		d.appendToCache(candle)
+	}
+	c.ChangeCount++
+}
+
+// Returns the live candles which have valid prices and are newer than the consolidated candles, sorted by time.
+func (d *CandlePlotData) getLiveCandles() []cachedCandle {
+	lastConsolidatedTime, _, hasConsolidated := d.GetLastConsolidatedTimestamp()
+	d.RealtimeData.DataMutex.RLock()
+	defer d.RealtimeData.DataMutex.RUnlock()
+	var live []cachedCandle
+	for i, candle := range d.RealtimeData.Data {
+		if d.HasValidRealtimePrices(i) && (!hasConsolidated || candle.Timestamp.After(lastConsolidatedTime)) {
+			// Convert while locked, because the volume is modified by realtime updates.
+			live = append(live, newCachedCandle(candle))
 		}
 	}
+	sort.SliceStable(live, func(i, j int) bool {
+		return live[i].timestamp.Before(live[j].timestamp)
+	})
+	return live
+}
+
+func (d *CandlePlotData) truncateCache(n int) {
+	d.Cache.Timestamps = d.Cache.Timestamps[:n]
+	d.Cache.OpenPrices = d.Cache.OpenPrices[:n]
+	d.Cache.HighPrices = d.Cache.HighPrices[:n]
+	d.Cache.LowPrices = d.Cache.LowPrices[:n]
+	d.Cache.ClosePrices = d.Cache.ClosePrices[:n]
+	d.Cache.Volumes = d.Cache.Volumes[:n]
+}
+
+func (d *CandlePlotData) appendToCache(candle cachedCandle) {
+	d.Cache.Timestamps = append(d.Cache.Timestamps, candle.timestamp)
+	d.Cache.OpenPrices = append(d.Cache.OpenPrices, candle.o)
+	d.Cache.HighPrices = append(d.Cache.HighPrices, candle.h)
+	d.Cache.LowPrices = append(d.Cache.LowPrices, candle.l)
+	d.Cache.ClosePrices = append(d.Cache.ClosePrices, candle.c)
+	d.Cache.Volumes = append(d.Cache.Volumes, candle.v)
+}
+
+func (d *CandlePlotData) getCachedCandle(i int) cachedCandle {
+	return cachedCandle{
+		timestamp: d.Cache.Timestamps[i],
+		o:         d.Cache.OpenPrices[i],
+		h:         d.Cache.HighPrices[i],
+		l:         d.Cache.LowPrices[i],
+		c:         d.Cache.ClosePrices[i],
+		v:         d.Cache.Volumes[i],
+	}
 }
 
 func (d *CandlePlotData) GetLastConsolidatedTimestamp() (t time.Time, r candles.CandleResolution, ok bool) {
@@ -94,6 +208,16 @@ func (d *CandlePlotData) GetLastConsolidatedTimestamp() (t time.Time, r candles.
 	return
 }
 
+// Returns a copy of the "consolidated" candles, so that they can be used without locking.
+// Prices are shared, because they are never modified.
+func (d *CandlePlotData) GetConsolidatedCandlesCopy() []indapi.CandleData {
+	d.DataMutex.RLock()
+	defer d.DataMutex.RUnlock()
+	data := make([]indapi.CandleData, len(d.Data))
+	copy(data, d.Data)
+	return data
+}
+
 func (d *CandlePlotData) UpdateConsolidatedCandles(candleResolution candles.CandleResolution, data []indapi.CandleData) {
 	// Treat last entry as dynamic data, as it may still be updated by realtime data.
 	// TODO this may depend on stockapi broker. It was originally implemented for finnhub.
@@ -102,10 +226,48 @@ func (d *CandlePlotData) UpdateConsolidatedCandles(candleResolution candles.Cand
 		return // no data available
 	}
 	usableSize := len(data) - 1
+	d.NormalizeTimestamps(candleResolution, data, false)
+	d.mergeConsolidatedCandles(data[:usableSize])
+	d.consolidateRealtimeData(candleResolution, data[usableSize])
+}
 
+// Adds candles which are known to be complete, e.g. cached candles or candles of past time ranges.
+// In contrast to UpdateConsolidatedCandles, the last entry is not treated as dynamic data.
+func (d *CandlePlotData) MergeConsolidatedCandles(candleResolution candles.CandleResolution, data []indapi.CandleData) {
+	if len(data) == 0 {
+		return // no data available
+	}
+	d.NormalizeTimestamps(candleResolution, data, false)
+	d.mergeConsolidatedCandles(data)
+}
+
+// Removes all "consolidated" candles, e.g. because prices have been adjusted.
+func (d *CandlePlotData) ClearConsolidatedCandles() {
 	d.DataMutex.Lock()
+	d.Data = nil
+	d.DataLastChange = time.Now()
+	d.DataMutex.Unlock()
+}
+
+// Modifies the timestamps of candles of the given resolution in place.
+// utcDates is set if day-based candles are stamped with midnight UTC of their trading date.
+func (d *CandlePlotData) NormalizeTimestamps(candleResolution candles.CandleResolution, data []indapi.CandleData, utcDates bool) {
+	// Brokers may use different timestamps, e.g. midnight or closing time for daily candles.
+	// Normalize to the start of the candle within the trading session.
+	for i := range data {
+		if utcDates {
+			data[i].Timestamp = candleResolution.GetCandleTimeOfUtcDate(data[i].Timestamp, d.Session)
+		} else {
+			data[i].Timestamp = candleResolution.GetNthCandleTime(data[i].Timestamp, 0, d.Session)
+		}
+	}
+}
+
+func (d *CandlePlotData) mergeConsolidatedCandles(data []indapi.CandleData) {
+	d.DataMutex.Lock()
+	defer d.DataMutex.Unlock()
 	// Do not delete data, merge old data with new data
-	d.Data = append(d.Data, data[:usableSize]...)
+	d.Data = append(d.Data, data...)
 	sort.Stable(indapi.CandleList(d.Data))
 	// Remove adjacent duplicates.
 	k := 0
@@ -121,63 +283,58 @@ func (d *CandlePlotData) UpdateConsolidatedCandles(candleResolution candles.Cand
 	}
 	d.Data = d.Data[:k]
 	d.DataLastChange = time.Now()
-	d.DataMutex.Unlock()
-
-	d.consolidateRealtimeData(candleResolution, data[usableSize])
 }
 
 func (d *CandlePlotData) AddRealtimeData(timestamp time.Time, price *decimal.Big, volume *decimal.Big, tradeContext TradeContext) {
 	lastConsolidatedCandleTime, candleResolution, ok := d.GetLastConsolidatedTimestamp()
-	// Update only if candle data has already been received.
-	if !ok {
-		return // wait for candle data before updating
-	}
-	candleIndex := candleResolution.GetDeltaCandleCount(lastConsolidatedCandleTime, timestamp)
-	if candleIndex < 0 {
-		log.Println("old candle data received, not updating")
-		return
+	var candleTime time.Time
+	if d.RealtimeOnly {
+		candleTime = candleResolution.GetNthCandleTime(timestamp, 0, d.Session)
+	} else {
+		// Update only if candle data has already been received.
+		if !ok {
+			return // wait for candle data before updating
+		}
+		candleIndex := candleResolution.GetDeltaCandleCount(lastConsolidatedCandleTime, timestamp, d.Session)
+		if candleIndex < 0 {
+			log.Println("old candle data received, not updating")
+			return
+		}
+		candleTime = candleResolution.GetNthCandleTime(lastConsolidatedCandleTime, candleIndex, d.Session)
 	}
 
-	candleTime := candleResolution.GetNthCandleTime(lastConsolidatedCandleTime, candleIndex)
-
 	// Either update existing realtime candle or add a new
-	updated := false
 	d.RealtimeData.DataMutex.Lock()
 	defer d.RealtimeData.DataMutex.Unlock()
-	for i := range d.RealtimeData.Data {
-		if candleTime.Equal(d.RealtimeData.Data[i].Timestamp) {
-			// Prices should never be modified. Therefore, we use the original price object without copying.
-			if tradeContext.UpdateHighLow {
-				if d.RealtimeData.Data[i].HighPrice == nil || price.Cmp(d.RealtimeData.Data[i].HighPrice) > 0 {
-					d.RealtimeData.Data[i].HighPrice = price
-				}
-				if d.RealtimeData.Data[i].LowPrice == nil || price.Cmp(d.RealtimeData.Data[i].LowPrice) < 0 {
-					d.RealtimeData.Data[i].LowPrice = price
-				}
+	if i := d.RealtimeData.findCandle(candleTime); i >= 0 {
+		// Prices should never be modified. Therefore, we use the original price object without copying.
+		if tradeContext.UpdateHighLow {
+			if d.RealtimeData.Data[i].HighPrice == nil || price.Cmp(d.RealtimeData.Data[i].HighPrice) > 0 {
+				d.RealtimeData.Data[i].HighPrice = price
 			}
-			if tradeContext.UpdateLast {
-				if d.RealtimeData.Data[i].OpenPrice == nil || d.RealtimeData.OpenTimestamps[i].IsZero() || timestamp.Before(d.RealtimeData.OpenTimestamps[i]) {
-					d.RealtimeData.OpenTimestamps[i] = timestamp
-					d.RealtimeData.Data[i].OpenPrice = price
-				}
-				if d.RealtimeData.Data[i].ClosePrice == nil || d.RealtimeData.CloseTimestamps[i].IsZero() || timestamp.After(d.RealtimeData.CloseTimestamps[i]) {
-					d.RealtimeData.CloseTimestamps[i] = timestamp
-					d.RealtimeData.Data[i].ClosePrice = price
-				}
+			if d.RealtimeData.Data[i].LowPrice == nil || price.Cmp(d.RealtimeData.Data[i].LowPrice) < 0 {
+				d.RealtimeData.Data[i].LowPrice = price
 			}
-			if tradeContext.UpdateVolume {
-				d.RealtimeData.Data[i].Volume.Add(d.RealtimeData.Data[i].Volume, volume)
+		}
+		if tradeContext.UpdateLast {
+			if d.RealtimeData.Data[i].OpenPrice == nil || d.RealtimeData.OpenTimestamps[i].IsZero() || timestamp.Before(d.RealtimeData.OpenTimestamps[i]) {
+				d.RealtimeData.OpenTimestamps[i] = timestamp
+				d.RealtimeData.Data[i].OpenPrice = price
 			}
-			if d.RealtimeData.OpenConsolidated[i] && !d.RealtimeData.HasInitData {
-				// There may still be some high/low difference, but open price is fine.
-				// We consider this as initialized.
-				d.RealtimeData.HasInitData = true
+			if d.RealtimeData.Data[i].ClosePrice == nil || d.RealtimeData.CloseTimestamps[i].IsZero() || timestamp.After(d.RealtimeData.CloseTimestamps[i]) {
+				d.RealtimeData.CloseTimestamps[i] = timestamp
+				d.RealtimeData.Data[i].ClosePrice = price
 			}
-			updated = true
-			break
 		}
-	}
-	if !updated {
+		if tradeContext.UpdateVolume {
+			d.RealtimeData.Data[i].Volume.Add(d.RealtimeData.Data[i].Volume, volume)
+		}
+		if d.RealtimeData.OpenConsolidated[i] && !d.RealtimeData.HasInitData {
+			// There may still be some high/low difference, but open price is fine.
+			// We consider this as initialized.
+			d.RealtimeData.HasInitData = true
+		}
+	} else {
 		// Add new entries
 		d.RealtimeData.Data = append(d.RealtimeData.Data, indapi.CandleData{
 			Timestamp:  candleTime,
@@ -194,6 +351,10 @@ func (d *CandlePlotData) AddRealtimeData(timestamp time.Time, price *decimal.Big
 		if !d.RealtimeData.HasInitData {
 			d.RealtimeData.HasInitData = true // next candle should be fine
 		}
+		if d.RealtimeOnly {
+			// These candles are never consolidated, so the oldest ones are dropped.
+			d.RealtimeData.trimCandles(MaxRealtimeOnlyCandles)
+		}
 	}
 	d.RealtimeData.DataLastChange = time.Now()
 }
@@ -201,9 +362,8 @@ func (d *CandlePlotData) AddRealtimeData(timestamp time.Time, price *decimal.Big
 func (d *CandlePlotData) consolidateRealtimeData(candleResolution candles.CandleResolution, lastCandleData indapi.CandleData) {
 	realtimeCandleExists := false
 	//realtimeCandleIndex := -1
-	// There may have been a banking holiday on the usual start of the candle,
-	// so use normalised start time when comparing with realtime data.
-	lastCandleUnixTime := candleResolution.GetNthCandleTime(lastCandleData.Timestamp, 0).Unix()
+	// Timestamps have already been normalised to the start of the candle within the session.
+	lastCandleUnixTime := lastCandleData.Timestamp.Unix()
 	lastRealtimeUnixTime := lastCandleUnixTime
 	k := 0
 	d.RealtimeData.DataMutex.Lock()
@@ -286,6 +446,94 @@ func (d *CandlePlotData) consolidateRealtimeData(candleResolution candles.Candle
 	}
 }
 
+// Returns the consolidated candles, followed by the realtime candles which have valid prices.
+// Realtime candles may overlap with consolidated candles. The slice buf is reused if possible.
+func (d *CandlePlotData) GetPlotCandles(buf []PlotCandle) []PlotCandle {
+	c := buf[:0]
+	d.DataMutex.RLock()
+	for _, candle := range d.Data {
+		c = append(c, newPlotCandle(candle, true))
+	}
+	d.DataMutex.RUnlock()
+	d.RealtimeData.DataMutex.RLock()
+	for i, candle := range d.RealtimeData.Data {
+		if d.HasValidRealtimePrices(i) {
+			c = append(c, newPlotCandle(candle, d.RealtimeData.OpenConsolidated[i]))
+		}
+	}
+	d.RealtimeData.DataMutex.RUnlock()
+	return c
+}
+
+// Returns the consolidated candles, followed by the realtime candles which are newer than the consolidated candles.
+// In contrast to GetPlotCandles, the candles do not overlap and are sorted by time.
+func (d *CandlePlotData) GetCandleSeries() []PlotCandle {
+	var c []PlotCandle
+	d.DataMutex.RLock()
+	for _, candle := range d.Data {
+		c = append(c, newPlotCandle(candle, true))
+	}
+	d.DataMutex.RUnlock()
+	numConsolidated := len(c)
+	d.RealtimeData.DataMutex.RLock()
+	for i, candle := range d.RealtimeData.Data {
+		if d.HasValidRealtimePrices(i) && (numConsolidated == 0 || candle.Timestamp.After(c[numConsolidated-1].Timestamp)) {
+			c = append(c, newPlotCandle(candle, d.RealtimeData.OpenConsolidated[i]))
+		}
+	}
+	d.RealtimeData.DataMutex.RUnlock()
+	live := c[numConsolidated:]
+	sort.SliceStable(live, func(i, j int) bool {
+		return live[i].Timestamp.Before(live[j].Timestamp)
+	})
+	return c
+}
+
+// Returns Heikin-Ashi candles, which are calculated from the consolidated and realtime candles, see GetCandleSeries.
+func (d *CandlePlotData) GetHeikinAshiCandles() []PlotCandle {
+	return HeikinAshi(d.GetCandleSeries())
+}
+
+// Returns the bricks of a price-based chart and the box size which was used, see BuildPriceBricks.
+// The bricks are built from the cached prices, see UpdateCache.
+func (d *CandlePlotData) GetPriceBricks(t ChartType, boxSize float64) ([]PriceBrick, float64) {
+	return BuildPriceBricks(t, d.GetCachedCandleSeries(), boxSize)
+}
+
+// Returns the candles of the cache, see UpdateCache. Only the consolidated candles are marked as consolidated.
+func (d *CandlePlotData) GetCachedCandleSeries() []PlotCandle {
+	d.DataMutex.RLock()
+	defer d.DataMutex.RUnlock()
+	cache := &d.Cache
+	c := make([]PlotCandle, len(cache.Timestamps))
+	for i := range c {
+		c[i] = PlotCandle{
+			Timestamp:    cache.Timestamps[i],
+			Open:         cache.OpenPrices[i],
+			High:         cache.HighPrices[i],
+			Low:          cache.LowPrices[i],
+			Close:        cache.ClosePrices[i],
+			Consolidated: i < cache.NumConsolidated,
+		}
+	}
+	return c
+}
+
+// Returns the change count of the cache, which is increased whenever the cached candles change, see UpdateCache.
+func (d *CandlePlotData) GetCacheChangeCount() uint64 {
+	d.DataMutex.RLock()
+	defer d.DataMutex.RUnlock()
+	return d.Cache.ChangeCount
+}
+
+func newPlotCandle(candle indapi.CandleData, consolidated bool) PlotCandle {
+	o, _ := candle.OpenPrice.Float64()
+	h, _ := candle.HighPrice.Float64()
+	l, _ := candle.LowPrice.Float64()
+	c, _ := candle.ClosePrice.Float64()
+	return PlotCandle{Timestamp: candle.Timestamp, Open: o, High: h, Low: l, Close: c, Consolidated: consolidated}
+}
+
 func (d *CandlePlotData) HasValidRealtimePrices(i int) bool {
 	return d.RealtimeData.Data[i].OpenPrice != nil && d.RealtimeData.Data[i].HighPrice != nil && d.RealtimeData.Data[i].LowPrice != nil && d.RealtimeData.Data[i].ClosePrice != nil
 }
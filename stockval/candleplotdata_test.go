@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import (
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"testing"
+	"time"
+
+	"github.com/ericlagergren/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateCacheLiveCandles(t *testing.T) {
+	d := NewCandlePlotData(candles.CandleOneMinute, candles.NewUtcSession(), nil)
+	start := time.Date(2023, 8, 9, 14, 0, 0, 0, time.UTC)
+	d.UpdateConsolidatedCandles(candles.CandleOneMinute, []indapi.CandleData{
+		newTestCandle(start, 100, 100, 100, 100, 10),
+		newTestCandle(start.Add(time.Minute), 101, 101, 101, 101, 10),
+		newTestCandle(start.Add(2*time.Minute), 102, 102, 102, 102, 10),
+	})
+	d.UpdateCache()
+	// The last candle is a live candle.
+	assert.Equal(t, []float64{100, 101, 102}, d.Cache.ClosePrices)
+	assert.Equal(t, 2, d.Cache.NumConsolidated)
+	resetCount, changeCount := d.Cache.ResetCount, d.Cache.ChangeCount
+
+	// Trade within the live candle replaces the last candle.
+	d.AddRealtimeData(start.Add(2*time.Minute+time.Second), decimal.New(105, 0), decimal.New(1, 0), NewTradeContext())
+	d.UpdateCache()
+	assert.Equal(t, []float64{100, 101, 105}, d.Cache.ClosePrices)
+	assert.Equal(t, 11.0, d.Cache.Volumes[2])
+	assert.Equal(t, resetCount, d.Cache.ResetCount)
+	assert.Equal(t, changeCount+1, d.Cache.ChangeCount)
+
+	// Trade of the next candle is appended.
+	d.AddRealtimeData(start.Add(3*time.Minute+time.Second), decimal.New(104, 0), decimal.New(1, 0), NewTradeContext())
+	d.UpdateCache()
+	assert.Equal(t, []float64{100, 101, 105, 104}, d.Cache.ClosePrices)
+	assert.Equal(t, resetCount, d.Cache.ResetCount)
+	assert.Equal(t, changeCount+2, d.Cache.ChangeCount)
+
+	// No change.
+	d.UpdateCache()
+	assert.Equal(t, changeCount+2, d.Cache.ChangeCount)
+
+	// Consolidated candles reset the cache.
+	d.MergeConsolidatedCandles(candles.CandleOneMinute, []indapi.CandleData{newTestCandle(start.Add(2*time.Minute), 105, 105, 105, 105, 10)})
+	d.UpdateCache()
+	assert.Equal(t, []float64{100, 101, 105, 104}, d.Cache.ClosePrices)
+	assert.Equal(t, 3, d.Cache.NumConsolidated)
+	assert.Equal(t, resetCount+1, d.Cache.ResetCount)
+}
+
+func TestRealtimeOnlyCandlesLimit(t *testing.T) {
+	d := NewCandlePlotData(candles.CandleOneSecond, candles.NewUtcSession(), nil)
+	d.RealtimeOnly = true
+	start := time.Date(2023, 8, 9, 14, 0, 0, 0, time.UTC)
+	for i := 0; i <= MaxRealtimeOnlyCandles; i++ {
+		d.AddRealtimeData(start.Add(time.Duration(i)*time.Second), decimal.New(100, 0), decimal.New(1, 0), NewTradeContext())
+	}
+	// The oldest candles are removed.
+	assert.Len(t, d.RealtimeData.Data, MaxRealtimeOnlyCandles*3/4)
+	assert.Len(t, d.RealtimeData.OpenConsolidated, MaxRealtimeOnlyCandles*3/4)
+	last := start.Add(MaxRealtimeOnlyCandles * time.Second)
+	assert.True(t, d.RealtimeData.Data[len(d.RealtimeData.Data)-1].Timestamp.Equal(last))
+
+	// Trades of existing candles update them.
+	d.AddRealtimeData(last, decimal.New(101, 0), decimal.New(1, 0), NewTradeContext())
+	assert.Len(t, d.RealtimeData.Data, MaxRealtimeOnlyCandles*3/4)
+	assert.Equal(t, "2", d.RealtimeData.Data[len(d.RealtimeData.Data)-1].Volume.String())
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// Type of the chart which is shown in the price subplot.
+type ChartType string
+
+const (
+	ChartCandles       ChartType = "candles"
+	ChartHollowCandles ChartType = "hollow"
+	ChartOhlcBars      ChartType = "bars"
+	ChartLine          ChartType = "line"
+	ChartArea          ChartType = "area"
+	ChartHeikinAshi    ChartType = "heikinashi"
+	ChartRenko         ChartType = "renko"
+	ChartKagi          ChartType = "kagi"
+	ChartPointFigure   ChartType = "pointfigure"
+)
+
+var chartTypeUiStrings = map[ChartType]string{
+	ChartCandles:       "Candles",
+	ChartHollowCandles: "Hollow Candles",
+	ChartOhlcBars:      "OHLC Bars",
+	ChartLine:          "Line",
+	ChartArea:          "Area",
+	ChartHeikinAshi:    "Heikin-Ashi",
+	ChartRenko:         "Renko",
+	ChartKagi:          "Kagi",
+	ChartPointFigure:   "Point & Figure",
+}
+
+// Returns all chart types, in the order in which they are shown in the ui.
+func ChartTypeList() []ChartType {
+	return []ChartType{ChartCandles, ChartHollowCandles, ChartOhlcBars, ChartLine, ChartArea, ChartHeikinAshi, ChartRenko, ChartKagi, ChartPointFigure}
+}
+
+// Returns the ui strings of all chart types, in the order of ChartTypeList.
+func ChartTypeUiStringList() []string {
+	list := ChartTypeList()
+	uiStrings := make([]string, len(list))
+	for i, c := range list {
+		uiStrings[i] = c.UiString()
+	}
+	return uiStrings
+}
+
+func (c ChartType) IsValid() bool {
+	return slices.Contains(ChartTypeList(), c)
+}
+
+func (c ChartType) UiString() string {
+	return chartTypeUiStrings[c]
+}
+
+// Price-based charts consist of bricks which are built from price changes, independent of time.
+// They are plotted using an index-based X axis.
+func (c ChartType) IsPriceBased() bool {
+	return c == ChartRenko || c == ChartKagi || c == ChartPointFigure
+}
+
+// Float values of a candle which is plotted.
+type PlotCandle struct {
+	Timestamp    time.Time
+	Open         float64
+	High         float64
+	Low          float64
+	Close        float64
+	Consolidated bool
+}
+
+// Converts candles, which need to be sorted by time, to Heikin-Ashi candles.
+// The close is the average price of a candle, and the open is the middle of the previous Heikin-Ashi body.
+func HeikinAshi(c []PlotCandle) []PlotCandle {
+	ha := make([]PlotCandle, len(c))
+	for i, candle := range c {
+		h := candle
+		h.Close = (candle.Open + candle.High + candle.Low + candle.Close) / 4
+		if i == 0 {
+			h.Open = (candle.Open + candle.Close) / 2
+		} else {
+			h.Open = (ha[i-1].Open + ha[i-1].Close) / 2
+		}
+		h.High = math.Max(candle.High, math.Max(h.Open, h.Close))
+		h.Low = math.Min(candle.Low, math.Min(h.Open, h.Close))
+		ha[i] = h
+	}
+	return ha
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import (
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"testing"
+	"time"
+
+	"github.com/ericlagergren/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeikinAshi(t *testing.T) {
+	start := time.Date(2023, 8, 9, 14, 0, 0, 0, time.UTC)
+	ha := HeikinAshi([]PlotCandle{
+		{Timestamp: start, Open: 10, High: 14, Low: 8, Close: 12},
+		{Timestamp: start.Add(time.Minute), Open: 12, High: 16, Low: 11, Close: 13},
+	})
+	assert.Equal(t, []PlotCandle{
+		{Timestamp: start, Open: 11, High: 14, Low: 8, Close: 11},
+		{Timestamp: start.Add(time.Minute), Open: 11, High: 16, Low: 11, Close: 13},
+	}, ha)
+	assert.Empty(t, HeikinAshi(nil))
+}
+
+func TestGetCandleSeries(t *testing.T) {
+	d := NewCandlePlotData(candles.CandleOneMinute, candles.NewUtcSession(), nil)
+	start := time.Date(2023, 8, 9, 14, 0, 0, 0, time.UTC)
+	d.UpdateConsolidatedCandles(candles.CandleOneMinute, []indapi.CandleData{
+		newTestCandle(start, 100, 100, 100, 100, 10),
+		newTestCandle(start.Add(time.Minute), 101, 101, 101, 101, 10),
+	})
+	d.AddRealtimeData(start.Add(2*time.Minute+time.Second), decimal.New(104, 0), decimal.New(1, 0), NewTradeContext())
+	series := d.GetCandleSeries()
+	var closes []float64
+	for i, c := range series {
+		closes = append(closes, c.Close)
+		if i > 0 {
+			assert.True(t, c.Timestamp.After(series[i-1].Timestamp))
+		}
+	}
+	assert.Equal(t, []float64{100, 101, 104}, closes)
+	assert.Len(t, d.GetHeikinAshiCandles(), len(series))
+}
+
+func TestChartTypeIsValid(t *testing.T) {
+	for _, c := range ChartTypeList() {
+		assert.True(t, c.IsValid())
+		assert.NotEmpty(t, c.UiString())
+	}
+	assert.False(t, ChartType("invalid").IsValid())
+	assert.Len(t, ChartTypeUiStringList(), len(ChartTypeList()))
+}
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Calculates the percent change of the close prices relative to the close of the first candle
+// at or after the start time, so that different assets can be compared. Candles need to be sorted by time.
+// If there is no such candle, the last candle is used as reference. Candles before the start are also included.
+func CalcPercentChange(c []PlotCandle, start time.Time) (timestamps []time.Time, values []float64) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	i := sort.Search(len(c), func(i int) bool { return !c[i].Timestamp.Before(start) })
+	base := c[min(i, len(c)-1)].Close
+	timestamps = make([]time.Time, len(c))
+	values = make([]float64, len(c))
+	for j, candle := range c {
+		timestamps[j] = candle.Timestamp
+		if base > 0 {
+			values[j] = (candle.Close/base - 1) * 100
+		} else {
+			values[j] = math.NaN()
+		}
+	}
+	return
+}
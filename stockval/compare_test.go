@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcPercentChange(t *testing.T) {
+	c := newTestCloseCandles(40, 50, 55, 45)
+	timestamps, values := CalcPercentChange(c, c[1].Timestamp)
+	assert.InDeltaSlice(t, []float64{-20, 0, 10, -10}, values, NearZero)
+	assert.Equal(t, c[3].Timestamp, timestamps[3])
+
+	// The first candle after the start is used as reference.
+	_, values = CalcPercentChange(c, c[1].Timestamp.Add(-1))
+	assert.Equal(t, 0.0, values[1])
+
+	// Without candles after the start, the last candle is the reference.
+	_, values = CalcPercentChange(c, c[3].Timestamp.Add(1))
+	assert.Equal(t, 0.0, values[3])
+
+	_, values = CalcPercentChange(newTestCloseCandles(0, 1), c[0].Timestamp)
+	assert.True(t, math.IsNaN(values[1]))
+
+	timestamps, values = CalcPercentChange(nil, c[0].Timestamp)
+	assert.Empty(t, timestamps)
+	assert.Empty(t, values)
+}
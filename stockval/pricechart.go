@@ -0,0 +1,280 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import (
+	"math"
+	"maystocks/indapi/series"
+	"strconv"
+	"time"
+)
+
+// Box size of price-based charts which is derived from the average true range.
+const AtrBoxSize = 0
+
+// Number of candles of the average true range which determines the box size.
+const BoxSizeAtrPeriods = 14
+
+// Number of boxes which are needed for a reversal in a Point & Figure chart.
+const PointFigureReversal = 3
+
+// Maximum number of bricks of a price-based chart.
+const MaxPriceBricks = 5000
+
+var boxSizeList = []float64{AtrBoxSize, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100}
+
+// Returns the box sizes which can be selected for price-based charts.
+func BoxSizeList() []float64 {
+	return boxSizeList
+}
+
+// Returns the ui strings of all box sizes, in the order of BoxSizeList.
+func BoxSizeUiStringList() []string {
+	uiStrings := make([]string, len(boxSizeList))
+	for i, b := range boxSizeList {
+		if b == AtrBoxSize {
+			uiStrings[i] = "ATR"
+		} else {
+			uiStrings[i] = strconv.FormatFloat(b, 'f', -1, 64)
+		}
+	}
+	return uiStrings
+}
+
+// Brick of a price-based chart: a Renko brick, a Kagi line or a Point & Figure column.
+type PriceBrick struct {
+	// Timestamps of the first and of the last candle which are covered by the brick.
+	Start time.Time
+	End   time.Time
+	// Price at the beginning and at the end of the brick.
+	Open  float64
+	Close float64
+	// Whether all covered candles are consolidated.
+	Consolidated bool
+	// Kagi only: Whether the line is thick (yang) at the open. The thickness changes at ChangePrice,
+	// which is NaN if the thickness does not change.
+	Yang        bool
+	ChangePrice float64
+}
+
+func (b PriceBrick) IsUp() bool {
+	return b.Close > b.Open
+}
+
+func newPriceBrick(c []PlotCandle, first int, last int, open float64, close float64) PriceBrick {
+	b := PriceBrick{Start: c[first].Timestamp, End: c[last].Timestamp, Open: open, Close: close, Consolidated: true, ChangePrice: math.NaN()}
+	for _, candle := range c[first : last+1] {
+		b.Consolidated = b.Consolidated && candle.Consolidated
+	}
+	return b
+}
+
+// Calculates the box size from the average true range of the candles, rounded to two significant digits
+// so that the bricks do not change with every small change of the range. Returns 0 if there are not enough candles.
+func CalcAtrBoxSize(c []PlotCandle, periods int) float64 {
+	high := make([]float64, len(c))
+	low := make([]float64, len(c))
+	closing := make([]float64, len(c))
+	for i, candle := range c {
+		high[i], low[i], closing[i] = candle.High, candle.Low, candle.Close
+	}
+	atr := series.Atr(high, low, closing, periods)
+	if len(atr) == 0 || math.IsNaN(atr[len(atr)-1]) || atr[len(atr)-1] <= 0 {
+		return 0
+	}
+	v := atr[len(atr)-1]
+	base := math.Pow10(int(math.Floor(math.Log10(v))) - 1)
+	return math.Round(v/base) * base
+}
+
+// Builds the bricks of a price-based chart from candles, which need to be sorted by time.
+// Returns the bricks and the box size which was used.
+// Box sizes which would result in more than MaxPriceBricks bricks are replaced by the ATR box size,
+// and only the last MaxPriceBricks bricks are returned.
+func BuildPriceBricks(t ChartType, c []PlotCandle, boxSize float64) ([]PriceBrick, float64) {
+	if boxSize != AtrBoxSize && maxNumBricks(t, c, boxSize) > MaxPriceBricks {
+		boxSize = AtrBoxSize
+	}
+	if boxSize == AtrBoxSize {
+		boxSize = CalcAtrBoxSize(c, BoxSizeAtrPeriods)
+	}
+	if boxSize <= 0 {
+		return nil, boxSize
+	}
+	var bricks []PriceBrick
+	switch t {
+	case ChartRenko:
+		bricks = Renko(c, boxSize)
+	case ChartKagi:
+		bricks = Kagi(c, boxSize)
+	case ChartPointFigure:
+		bricks = PointFigure(c, boxSize, PointFigureReversal)
+	}
+	if len(bricks) > MaxPriceBricks {
+		bricks = bricks[len(bricks)-MaxPriceBricks:]
+	}
+	return bricks, boxSize
+}
+
+// Returns an upper bound of the number of bricks, without building them. Each brick requires
+// a price move of at least the box size, and Kagi lines and point and figure columns
+// can change at most once per candle.
+func maxNumBricks(t ChartType, c []PlotCandle, boxSize float64) int {
+	if boxSize <= 0 {
+		return 0
+	}
+	var path float64
+	for i := 1; i < len(c); i++ {
+		path += math.Abs(c[i].Close - c[i-1].Close)
+	}
+	n := path / boxSize
+	if t == ChartKagi || t == ChartPointFigure {
+		n = min(n, float64(len(c)))
+	}
+	return int(min(n, math.MaxInt32))
+}
+
+// Builds Renko bricks from the close prices. A new brick is added if the price moves by the box size
+// beyond the last brick, a reversal requires a move of two box sizes.
+func Renko(c []PlotCandle, boxSize float64) []PriceBrick {
+	if len(c) == 0 || boxSize <= 0 {
+		return nil
+	}
+	var bricks []PriceBrick
+	ref := c[0].Close
+	first := 0
+	for i, candle := range c {
+		p := candle.Close
+		added := false
+		for {
+			// A brick is added above the top or below the bottom of the last brick.
+			top, bottom := ref, ref
+			if len(bricks) > 0 {
+				last := bricks[len(bricks)-1]
+				top, bottom = math.Max(last.Open, last.Close), math.Min(last.Open, last.Close)
+			}
+			var b PriceBrick
+			if p >= top+boxSize {
+				b = newPriceBrick(c, first, i, top, top+boxSize)
+			} else if p <= bottom-boxSize {
+				b = newPriceBrick(c, first, i, bottom, bottom-boxSize)
+			} else {
+				break
+			}
+			bricks = append(bricks, b)
+			// Further bricks of the same candle start at this candle.
+			first = i
+			added = true
+		}
+		if added {
+			first = i + 1
+		}
+	}
+	return bricks
+}
+
+// Builds Kagi lines from the close prices. The direction changes if the price reverses by the given amount.
+// Lines become thick (yang) if they rise above the previous high, and thin (yin) if they fall below the previous low.
+func Kagi(c []PlotCandle, reversal float64) []PriceBrick {
+	if len(c) == 0 || reversal <= 0 {
+		return nil
+	}
+	var bricks []PriceBrick
+	open := c[0].Close
+	extreme := open
+	var hasDirection, up bool
+	first := 0
+	for i, candle := range c {
+		p := candle.Close
+		switch {
+		case !hasDirection:
+			if math.Abs(p-open) >= reversal {
+				hasDirection, up, extreme = true, p > open, p
+			}
+		case (up && p > extreme) || (!up && p < extreme):
+			extreme = p
+		case (up && p <= extreme-reversal) || (!up && p >= extreme+reversal):
+			bricks = append(bricks, newPriceBrick(c, first, max(i-1, first), open, extreme))
+			open, extreme, up, first = extreme, p, !up, i
+		}
+	}
+	if hasDirection {
+		bricks = append(bricks, newPriceBrick(c, first, len(c)-1, open, extreme))
+	}
+	setKagiThickness(bricks)
+	return bricks
+}
+
+func setKagiThickness(bricks []PriceBrick) {
+	if len(bricks) == 0 {
+		return
+	}
+	yang := bricks[0].IsUp()
+	// Top of the previous rising line and bottom of the previous falling line.
+	shoulder, waist := math.NaN(), math.NaN()
+	for i := range bricks {
+		b := &bricks[i]
+		b.Yang = yang
+		if b.IsUp() {
+			if !yang && b.Close > shoulder {
+				b.ChangePrice, yang = shoulder, true
+			}
+			shoulder = b.Close
+		} else {
+			if yang && b.Close < waist {
+				b.ChangePrice, yang = waist, false
+			}
+			waist = b.Close
+		}
+	}
+}
+
+// Returns the index of the box which contains the price.
+func boxIndex(p float64, boxSize float64) int {
+	// Avoid rounding issues if the price is a multiple of the box size.
+	return int(math.Floor(p/boxSize + 1e-9))
+}
+
+// Builds Point & Figure columns from the close prices. Columns of X (rising) are extended as long as the price
+// rises by at least one box, the direction changes if the price reverses by the given number of boxes.
+// Open and close of a column are box boundaries, the column consists of the boxes in between.
+func PointFigure(c []PlotCandle, boxSize float64, reversal int) []PriceBrick {
+	if len(c) == 0 || boxSize <= 0 || reversal <= 0 {
+		return nil
+	}
+	var bricks []PriceBrick
+	newColumn := func(first int, last int, low int, high int, up bool) PriceBrick {
+		if up {
+			return newPriceBrick(c, first, last, float64(low)*boxSize, float64(high+1)*boxSize)
+		}
+		return newPriceBrick(c, first, last, float64(high+1)*boxSize, float64(low)*boxSize)
+	}
+	ref := boxIndex(c[0].Close, boxSize)
+	var low, high int
+	var hasDirection, up bool
+	first := 0
+	for i, candle := range c {
+		k := boxIndex(candle.Close, boxSize)
+		switch {
+		case !hasDirection:
+			if k != ref {
+				hasDirection, up, low, high = true, k > ref, min(k, ref), max(k, ref)
+			}
+		case up && k > high:
+			high = k
+		case !up && k < low:
+			low = k
+		case up && k <= high-reversal:
+			bricks = append(bricks, newColumn(first, max(i-1, first), low, high, up))
+			low, high, up, first = k, high-1, false, i
+		case !up && k >= low+reversal:
+			bricks = append(bricks, newColumn(first, max(i-1, first), low, high, up))
+			low, high, up, first = low+1, k, true, i
+		}
+	}
+	if hasDirection {
+		bricks = append(bricks, newColumn(first, len(c)-1, low, high, up))
+	}
+	return bricks
+}
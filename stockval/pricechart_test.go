@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testPriceChartStart = time.Date(2023, 8, 9, 14, 0, 0, 0, time.UTC)
+
+func newTestCloseCandles(closing ...float64) []PlotCandle {
+	c := make([]PlotCandle, len(closing))
+	for i, v := range closing {
+		c[i] = PlotCandle{Timestamp: testPriceChartStart.Add(time.Duration(i) * time.Minute), Open: v, High: v, Low: v, Close: v, Consolidated: true}
+	}
+	return c
+}
+
+func getBrickPrices(bricks []PriceBrick) [][2]float64 {
+	prices := make([][2]float64, len(bricks))
+	for i, b := range bricks {
+		prices[i] = [2]float64{b.Open, b.Close}
+	}
+	return prices
+}
+
+func TestRenko(t *testing.T) {
+	c := newTestCloseCandles(10, 10.5, 11, 12.2, 11.5, 10.4, 9.9, 8.9)
+	c[7].Consolidated = false
+	bricks := Renko(c, 1)
+	// A reversal needs two boxes.
+	assert.Equal(t, [][2]float64{{10, 11}, {11, 12}, {11, 10}, {10, 9}}, getBrickPrices(bricks))
+	assert.Equal(t, c[0].Timestamp, bricks[0].Start)
+	assert.Equal(t, c[2].Timestamp, bricks[0].End)
+	assert.Equal(t, c[3].Timestamp, bricks[1].Start)
+	assert.Equal(t, c[4].Timestamp, bricks[2].Start)
+	assert.Equal(t, c[6].Timestamp, bricks[2].End)
+	assert.True(t, bricks[2].Consolidated)
+	assert.False(t, bricks[3].Consolidated)
+
+	// A large move creates several bricks, which start at the same candle.
+	bricks = Renko(newTestCloseCandles(10, 13.5), 1)
+	assert.Equal(t, [][2]float64{{10, 11}, {11, 12}, {12, 13}}, getBrickPrices(bricks))
+	assert.Equal(t, bricks[1].End, bricks[2].Start)
+
+	assert.Empty(t, Renko(newTestCloseCandles(10, 10.5), 1))
+	assert.Empty(t, Renko(nil, 1))
+}
+
+func TestKagi(t *testing.T) {
+	c := newTestCloseCandles(10, 10.5, 11.2, 12, 11.5, 10.8, 10, 12.5, 9, 13)
+	lines := Kagi(c, 1)
+	assert.Equal(t, [][2]float64{{10, 12}, {12, 10}, {10, 12.5}, {12.5, 9}, {9, 13}}, getBrickPrices(lines))
+	assert.Equal(t, c[4].Timestamp, lines[0].End)
+	assert.Equal(t, c[5].Timestamp, lines[1].Start)
+	assert.Equal(t, c[9].Timestamp, lines[4].End)
+	// The line becomes thin below the previous low, and thick above the previous high.
+	for i := range 3 {
+		assert.True(t, lines[i].Yang)
+		assert.True(t, math.IsNaN(lines[i].ChangePrice))
+	}
+	assert.True(t, lines[3].Yang)
+	assert.Equal(t, 10.0, lines[3].ChangePrice)
+	assert.False(t, lines[4].Yang)
+	assert.Equal(t, 12.5, lines[4].ChangePrice)
+
+	assert.Empty(t, Kagi(newTestCloseCandles(10, 10.5), 1))
+}
+
+func TestPointFigure(t *testing.T) {
+	c := newTestCloseCandles(10.2, 11.5, 13.1, 12.4, 10.9, 9.5, 10.1, 13.2)
+	columns := PointFigure(c, 1, 3)
+	assert.Equal(t, [][2]float64{{10, 14}, {13, 9}, {10, 14}}, getBrickPrices(columns))
+	assert.True(t, columns[0].IsUp())
+	assert.False(t, columns[1].IsUp())
+	assert.Equal(t, c[3].Timestamp, columns[0].End)
+	assert.Equal(t, c[4].Timestamp, columns[1].Start)
+	assert.Equal(t, c[6].Timestamp, columns[1].End)
+	assert.Equal(t, c[7].Timestamp, columns[2].Start)
+
+	assert.Empty(t, PointFigure(newTestCloseCandles(10.2, 10.8), 1, 3))
+}
+
+func TestCalcAtrBoxSize(t *testing.T) {
+	c := newTestCloseCandles(make([]float64, BoxSizeAtrPeriods+1)...)
+	for i := range c {
+		c[i].Close = 10
+		c[i].High, c[i].Low = 10.617, 9.383
+	}
+	// Rounded to two significant digits.
+	assert.InDelta(t, 1.2, CalcAtrBoxSize(c, 3), 1e-9)
+	assert.Equal(t, 0.0, CalcAtrBoxSize(c[:2], 3))
+
+	bricks, boxSize := BuildPriceBricks(ChartRenko, c, AtrBoxSize)
+	assert.InDelta(t, 1.2, boxSize, 1e-9)
+	assert.Empty(t, bricks)
+	bricks, _ = BuildPriceBricks(ChartCandles, c, 1)
+	assert.Nil(t, bricks)
+}
+
+func TestMaxPriceBricks(t *testing.T) {
+	// A box size which would result in too many bricks is replaced by the ATR box size.
+	closing := make([]float64, 2*BoxSizeAtrPeriods)
+	for i := range closing {
+		closing[i] = float64(100 + 100*(i%2))
+	}
+	c := newTestCloseCandles(closing...)
+	bricks, boxSize := BuildPriceBricks(ChartRenko, c, 0.01)
+	assert.Equal(t, CalcAtrBoxSize(c, BoxSizeAtrPeriods), boxSize)
+	assert.LessOrEqual(t, len(bricks), len(c))
+
+	// Kagi lines change at most once per candle, so the box size is kept.
+	bricks, boxSize = BuildPriceBricks(ChartKagi, c, 0.01)
+	assert.InDelta(t, 0.01, boxSize, 1e-9)
+	assert.Len(t, bricks, len(c)-1)
+
+	// Only the most recent bricks are kept.
+	closing = make([]float64, MaxPriceBricks+10)
+	for i := range closing {
+		closing[i] = float64(i)
+	}
+	c = newTestCloseCandles(closing...)
+	bricks, boxSize = BuildPriceBricks(ChartRenko, c, AtrBoxSize)
+	assert.InDelta(t, 1, boxSize, 1e-9)
+	assert.Len(t, bricks, MaxPriceBricks)
+	assert.Equal(t, c[len(c)-1].Timestamp, bricks[len(bricks)-1].Start)
+}
+
+func TestBoxSizeUiStringList(t *testing.T) {
+	uiStrings := BoxSizeUiStringList()
+	assert.Len(t, uiStrings, len(BoxSizeList()))
+	assert.Equal(t, "ATR", uiStrings[0])
+	assert.Equal(t, "0.25", uiStrings[4])
+}
@@ -25,6 +25,15 @@ type QuoteData struct {
 	PreviousDelayedClosePrice *decimal.Big
 	PreviousClosePrice        *decimal.Big
 	DeltaPercentage           *decimal.Big
+	// The quote was loaded from the cache, and has not yet been updated by the broker.
+	Cached bool
+	// The app is offline, so the quote is not updated by the broker.
+	Offline bool
+}
+
+// Returns whether the quote may be outdated, and should be displayed along with its timestamp.
+func (q QuoteData) IsStale() bool {
+	return q.Cached || q.Offline
 }
 
 type RealtimeTickData struct {
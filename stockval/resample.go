@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import (
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"sort"
+	"time"
+
+	"github.com/ericlagergren/decimal"
+)
+
+// Reports whether the exchange is open at time t.
+// A nil function means that the asset is traded around the clock.
+type TradingTimeFunc func(t time.Time) bool
+
+func (f TradingTimeFunc) isTradingTime(t time.Time) bool {
+	return f == nil || f(t)
+}
+
+// Combines candles of resolution source to candles of the coarser resolution r.
+// Candles are grouped by the start time of the candle of resolution r which contains them,
+// i.e. bucket boundaries follow the trading session s.
+// A resampled candle is marked as partial if source candles are missing at the start or at the end of
+// the bucket while the exchange is open, e.g. because the bucket is still in progress.
+// The input data is not modified.
+func ResampleCandles(data []indapi.CandleData, source candles.CandleResolution, r candles.CandleResolution,
+	s candles.Session, isTradingTime TradingTimeFunc) (resampled []indapi.CandleData, partial []bool) {
+
+	sorted := make([]indapi.CandleData, len(data))
+	copy(sorted, data)
+	sort.Stable(indapi.CandleList(sorted))
+
+	var firstSourceTime, lastSourceTime []time.Time
+	for _, c := range sorted {
+		sourceTime := source.GetNthCandleTime(c.Timestamp, 0, s)
+		candleTime := r.GetNthCandleTime(sourceTime, 0, s)
+		n := len(resampled)
+		if n == 0 || !resampled[n-1].Timestamp.Equal(candleTime) {
+			resampled = append(resampled, indapi.CandleData{
+				Timestamp:  candleTime,
+				OpenPrice:  c.OpenPrice,
+				HighPrice:  c.HighPrice,
+				LowPrice:   c.LowPrice,
+				ClosePrice: c.ClosePrice,
+				Volume:     new(decimal.Big).Copy(c.Volume), // will be modified
+			})
+			firstSourceTime = append(firstSourceTime, sourceTime)
+			lastSourceTime = append(lastSourceTime, sourceTime)
+			continue
+		}
+		// Prices are never modified, so they can be shared.
+		last := &resampled[n-1]
+		if c.HighPrice.Cmp(last.HighPrice) > 0 {
+			last.HighPrice = c.HighPrice
+		}
+		if c.LowPrice.Cmp(last.LowPrice) < 0 {
+			last.LowPrice = c.LowPrice
+		}
+		last.ClosePrice = c.ClosePrice
+		last.Volume.Add(last.Volume, c.Volume)
+		lastSourceTime[n-1] = sourceTime
+	}
+
+	partial = make([]bool, len(resampled))
+	for i := range resampled {
+		bucketEnd := r.GetNthCandleTime(resampled[i].Timestamp, 1, s)
+		sourceEnd := source.GetNthCandleTime(lastSourceTime[i], 1, s)
+		partial[i] = hasTradingGap(resampled[i].Timestamp, firstSourceTime[i], source, s, isTradingTime) ||
+			hasTradingGap(sourceEnd, bucketEnd, source, s, isTradingTime)
+	}
+	return
+}
+
+// Returns whether the exchange is open at any time in [from, to).
+// Intraday gaps are checked per source candle, other gaps per trading day.
+func hasTradingGap(from time.Time, to time.Time, source candles.CandleResolution, s candles.Session, isTradingTime TradingTimeFunc) bool {
+	step := source
+	if !source.IsIntraday() {
+		step = candles.CandleOneDay
+	}
+	for t := step.GetNthCandleTime(from, 0, s); t.Before(to); t = step.GetNthCandleTime(t, 1, s) {
+		if !t.Before(from) && isTradingTime.isTradingTime(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes partial candles.
+// If keepLast is set, the last candle is kept, because it is considered to be still in progress,
+// similar to the last candle returned by a broker.
+func RemovePartialCandles(data []indapi.CandleData, partial []bool, keepLast bool) []indapi.CandleData {
+	var complete []indapi.CandleData
+	for i := range data {
+		if !partial[i] || (keepLast && i == len(data)-1) {
+			complete = append(complete, data[i])
+		}
+	}
+	return complete
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import (
+	"maystocks/indapi"
+	"maystocks/indapi/candles"
+	"testing"
+	"time"
+
+	"github.com/ericlagergren/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCandle(t time.Time, o, h, l, c, v int64) indapi.CandleData {
+	return indapi.CandleData{
+		Timestamp:  t,
+		OpenPrice:  decimal.New(o, 0),
+		HighPrice:  decimal.New(h, 0),
+		LowPrice:   decimal.New(l, 0),
+		ClosePrice: decimal.New(c, 0),
+		Volume:     decimal.New(v, 0),
+	}
+}
+
+// Weekdays only, similar to a stock exchange without holidays.
+func isTestTradingTime(t time.Time) bool {
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+func TestResampleCandlesMinutes(t *testing.T) {
+	s := candles.NewUtcSession()
+	start := time.Date(2023, 8, 9, 10, 0, 0, 0, time.UTC)
+	var data []indapi.CandleData
+	for i := range 20 {
+		data = append(data, newTestCandle(start.Add(time.Duration(i)*time.Minute), int64(100+i), int64(110+i), int64(90+i), int64(101+i), 10))
+	}
+	resampled, partial := ResampleCandles(data, candles.CandleOneMinute, candles.CandleFifteenMinutes, s, nil)
+	assert.Len(t, resampled, 2)
+	assert.True(t, resampled[0].Timestamp.Equal(start))
+	assert.Equal(t, 0, resampled[0].OpenPrice.Cmp(decimal.New(100, 0)))
+	assert.Equal(t, 0, resampled[0].HighPrice.Cmp(decimal.New(124, 0)))
+	assert.Equal(t, 0, resampled[0].LowPrice.Cmp(decimal.New(90, 0)))
+	assert.Equal(t, 0, resampled[0].ClosePrice.Cmp(decimal.New(115, 0)))
+	assert.Equal(t, 0, resampled[0].Volume.Cmp(decimal.New(150, 0)))
+	assert.False(t, partial[0])
+	// The second bucket only contains 5 minutes.
+	assert.True(t, resampled[1].Timestamp.Equal(start.Add(time.Minute*15)))
+	assert.Equal(t, 0, resampled[1].Volume.Cmp(decimal.New(50, 0)))
+	assert.True(t, partial[1])
+	// The input data is not modified.
+	assert.Equal(t, 0, data[0].Volume.Cmp(decimal.New(10, 0)))
+
+	complete := RemovePartialCandles(resampled[:1], partial[:1], true)
+	assert.Len(t, complete, 1)
+}
+
+func TestResampleCandlesWeek(t *testing.T) {
+	s := candles.NewUtcSession()
+	// Wednesday to Friday of the first week, then the full next week.
+	var data []indapi.CandleData
+	for d := 9; d <= 18; d++ {
+		ts := time.Date(2023, 8, d, 0, 0, 0, 0, time.UTC)
+		if isTestTradingTime(ts) {
+			data = append(data, newTestCandle(ts, 100, 110, 90, 105, 1))
+		}
+	}
+	resampled, partial := ResampleCandles(data, candles.CandleOneDay, candles.CandleOneWeek, s, isTestTradingTime)
+	assert.Len(t, resampled, 2)
+	assert.True(t, resampled[0].Timestamp.Equal(time.Date(2023, 8, 7, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 0, resampled[0].Volume.Cmp(decimal.New(3, 0)))
+	// Monday and Tuesday are missing.
+	assert.True(t, partial[0])
+	// The weekend does not count as missing data.
+	assert.True(t, resampled[1].Timestamp.Equal(time.Date(2023, 8, 14, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 0, resampled[1].Volume.Cmp(decimal.New(5, 0)))
+	assert.False(t, partial[1])
+
+	// Without a trading calendar, the weekend is considered missing.
+	_, partial = ResampleCandles(data, candles.CandleOneDay, candles.CandleOneWeek, s, nil)
+	assert.True(t, partial[1])
+
+	complete := RemovePartialCandles(resampled, []bool{true, true}, true)
+	assert.Len(t, complete, 1)
+	assert.True(t, complete[0].Timestamp.Equal(resampled[1].Timestamp))
+	complete = RemovePartialCandles(resampled, []bool{true, true}, false)
+	assert.Empty(t, complete)
+}
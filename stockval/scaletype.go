@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import "slices"
+
+// Scale of the Y axis of a subplot.
+type ScaleType string
+
+const (
+	ScaleLinear ScaleType = "linear"
+	// Values are mapped logarithmically, equal distances represent equal ratios.
+	ScaleLogarithmic ScaleType = "log"
+	// Values are mapped linearly, but labelled with the percent change from the first visible candle.
+	ScalePercent ScaleType = "percent"
+)
+
+var scaleTypeUiStrings = map[ScaleType]string{
+	ScaleLinear:      "Linear Scale",
+	ScaleLogarithmic: "Logarithmic Scale",
+	ScalePercent:     "Percent Scale",
+}
+
+// Returns all scale types, in the order in which they are shown in the ui.
+func ScaleTypeList() []ScaleType {
+	return []ScaleType{ScaleLinear, ScaleLogarithmic, ScalePercent}
+}
+
+func (s ScaleType) IsValid() bool {
+	return slices.Contains(ScaleTypeList(), s)
+}
+
+func (s ScaleType) UiString() string {
+	return scaleTypeUiStrings[s]
+}
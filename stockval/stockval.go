@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"gioui.org/unit"
@@ -96,3 +97,22 @@ func ClassToString(c AssetClass) string {
 		return "Stocks"
 	}
 }
+
+// Returns a human readable description of the age of data with timestamp t, e.g. "as of 16:00 yesterday".
+func FormatDataAge(t time.Time, now time.Time) string {
+	t = t.Local()
+	now = now.Local()
+	y, m, d := t.Date()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch {
+	case !day.Before(today):
+		return "as of " + t.Format("15:04")
+	case day.Equal(today.AddDate(0, 0, -1)):
+		return "as of " + t.Format("15:04") + " yesterday"
+	case day.After(today.AddDate(0, 0, -7)):
+		return "as of " + t.Format("Mon 15:04")
+	default:
+		return "as of " + t.Format("02 Jan 06 15:04")
+	}
+}
@@ -0,0 +1,19 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatDataAge(t *testing.T) {
+	now := time.Date(2023, 4, 14, 10, 30, 0, 0, time.Local)
+	assert.Equal(t, "as of 09:15", FormatDataAge(time.Date(2023, 4, 14, 9, 15, 0, 0, time.Local), now))
+	assert.Equal(t, "as of 16:00 yesterday", FormatDataAge(time.Date(2023, 4, 13, 16, 0, 0, 0, time.Local), now))
+	assert.Equal(t, "as of Mon 16:00", FormatDataAge(time.Date(2023, 4, 10, 16, 0, 0, 0, time.Local), now))
+	assert.Equal(t, "as of 31 Mar 23 16:00", FormatDataAge(time.Date(2023, 3, 31, 16, 0, 0, 0, time.Local), now))
+}
@@ -6,6 +6,8 @@ package stockviz
 import (
 	"context"
 	"log"
+	"maystocks/cache"
+	"maystocks/indapi"
 	"maystocks/indapi/candles"
 	"maystocks/stockapi"
 	"maystocks/stockval"
@@ -20,6 +22,17 @@ type CandleUpdater struct {
 	candleTimeMap       *skipmap.Int32Map[candleTime]
 	candlesRequestChan  chan stockapi.CandlesRequest
 	candlesResponseChan chan stockapi.QueryCandlesResponse
+	// The resolution which is queried from the broker.
+	// Candles are resampled locally if it differs from the resolution of the candle data.
+	queryResolution candles.CandleResolution
+	// Already loaded candles of a finer resolution, which are resampled instead of querying the broker.
+	// This is nil if there is no suitable source.
+	source        *stockval.CandlePlotData
+	isTradingTime stockval.TradingTimeFunc
+	// The broker stamps day-based candles with midnight UTC of their trading date.
+	utcDateCandles bool
+	candleCache    cache.CandleCache
+	uiUpdater      StockUiUpdater
 }
 
 type candleTime struct {
@@ -27,23 +40,48 @@ type candleTime struct {
 	lastCandleTime  time.Time
 }
 
-func NewCandleUpdater(entry stockval.AssetData, resolution candles.CandleResolution) CandleUpdater {
+func NewCandleUpdater(entry stockval.AssetData, resolution candles.CandleResolution, session candles.Session,
+	isTradingTime stockval.TradingTimeFunc, source *stockval.CandlePlotData) CandleUpdater {
 	return CandleUpdater{
 		Entry:         entry,
-		CandleData:    stockval.NewCandlePlotData(resolution),
+		CandleData:    stockval.NewCandlePlotData(resolution, session, isTradingTime),
 		candleTimeMap: skipmap.NewInt32[candleTime](),
+		source:        source,
+		isTradingTime: isTradingTime,
 	}
 }
 
-func (d *CandleUpdater) Initialize(ctx context.Context, broker stockapi.Broker, uiUpdater StockUiUpdater) {
+func (d *CandleUpdater) Initialize(ctx context.Context, broker stockapi.Broker, candleCache cache.CandleCache, uiUpdater StockUiUpdater) {
+	d.candleCache = candleCache
+	d.uiUpdater = uiUpdater
+	capabilities := broker.GetCapabilities()
+	queryResolution, ok := d.CandleData.Resolution.FindBaseResolution(capabilities.CandleResolutions)
+	if !ok {
+		// The broker cannot provide these candles, e.g. for resolutions of seconds.
+		log.Printf("Building candles %s %s from realtime trades.", d.Entry.Figi, d.CandleData.Resolution.String())
+		d.CandleData.RealtimeOnly = true
+		return
+	}
+	d.queryResolution = queryResolution
+	d.utcDateCandles = capabilities.UtcDateCandles
 	// TODO size of buffered channels?
 	d.candlesRequestChan = make(chan stockapi.CandlesRequest, 128)
 	d.candlesResponseChan = make(chan stockapi.QueryCandlesResponse, 128)
 	go func() {
 		for candlesResponseData := range d.candlesResponseChan {
 			log.Printf("Updating candle data %s %s.", candlesResponseData.Figi, candlesResponseData.Resolution.String())
-			d.CandleData.UpdateConsolidatedCandles(candlesResponseData.Resolution, candlesResponseData.Data)
-			uiUpdater.Invalidate()
+			d.uiUpdater.ReportConnectivity(candlesResponseData.Error)
+			if candlesResponseData.Error != nil {
+				continue
+			}
+			data := candlesResponseData.Data
+			d.CandleData.NormalizeTimestamps(candlesResponseData.Resolution, data, d.utcDateCandles)
+			if d.candleCache.StoreCandles(d.Entry.Figi, candlesResponseData.Resolution, candlesResponseData.FromTime, candlesResponseData.ToTime, data) {
+				// Prices have been adjusted, previous candles are outdated.
+				d.CandleData.ClearConsolidatedCandles()
+			}
+			complete := time.Since(candlesResponseData.ToTime) >= cache.CandleUpdateDelay
+			d.updateCandles(candlesResponseData.Resolution, data, complete)
 		}
 		log.Printf("Terminating candle update handler %s.", d.Entry.Figi)
 	}()
@@ -51,26 +89,104 @@ func (d *CandleUpdater) Initialize(ctx context.Context, broker stockapi.Broker,
 }
 
 func (d *CandleUpdater) Refresh() {
+	if d.CandleData.RealtimeOnly {
+		return
+	}
 	d.candleTimeMap.Range(
 		func(uiIndex int32, w candleTime) bool {
-			log.Printf("Requesting candle data %s %s.", d.Entry.Figi, d.CandleData.Resolution.String())
-			candlesRequestData := stockapi.CandlesRequest{
-				Asset:      d.Entry,
-				Resolution: d.CandleData.Resolution,
-				FromTime:   w.firstCandleTime,
-				ToTime:     w.lastCandleTime,
+			if d.source != nil {
+				sourceData, ok := d.getSourceCandles(w)
+				if ok {
+					log.Printf("Resampling candle data %s %s from %s.", d.Entry.Figi, d.CandleData.Resolution.String(), d.source.Resolution.String())
+					go d.updateCandles(d.source.Resolution, sourceData, false)
+					return true
+				}
+			}
+			d.loadCachedCandles(w)
+			if d.uiUpdater.IsOffline() {
+				return true // only cached candles are shown
+			}
+			for _, m := range d.candleCache.GetMissingRanges(d.Entry.Figi, d.queryResolution, w.firstCandleTime, w.lastCandleTime) {
+				// Request whole candles, so that resampled candles are complete.
+				fromTime := d.CandleData.Resolution.GetNthCandleTime(m.From, 0, d.CandleData.Session)
+				log.Printf("Requesting candle data %s %s.", d.Entry.Figi, d.queryResolution.String())
+				candlesRequestData := stockapi.CandlesRequest{
+					Asset:      d.Entry,
+					Resolution: d.queryResolution,
+					FromTime:   fromTime,
+					ToTime:     m.To,
+				}
+				// TODO may send on closed chan?
+				d.candlesRequestChan <- candlesRequestData
 			}
-			// TODO may send on closed chan?
-			d.candlesRequestChan <- candlesRequestData
 			return true
 		},
 	)
 }
 
 func (d *CandleUpdater) Cleanup() {
+	if d.candlesRequestChan == nil {
+		return // realtime only
+	}
 	close(d.candlesRequestChan)
 }
 
+// Updates the candle data using candles of resolution r, which are resampled if necessary.
+// If complete is set, none of the candles is still in progress.
+func (d *CandleUpdater) updateCandles(r candles.CandleResolution, data []indapi.CandleData, complete bool) {
+	if r != d.CandleData.Resolution {
+		resampled, partial := stockval.ResampleCandles(data, r, d.CandleData.Resolution, d.CandleData.Session, d.isTradingTime)
+		data = stockval.RemovePartialCandles(resampled, partial, !complete)
+	}
+	if complete {
+		d.CandleData.MergeConsolidatedCandles(d.CandleData.Resolution, data)
+	} else {
+		d.CandleData.UpdateConsolidatedCandles(d.CandleData.Resolution, data)
+	}
+	d.uiUpdater.Invalidate()
+}
+
+// Adds cached candles of the candle time window which have not been loaded yet.
+func (d *CandleUpdater) loadCachedCandles(w candleTime) {
+	loaded := d.CandleData.GetConsolidatedCandlesCopy()
+	var ranges []cache.TimeRange
+	if len(loaded) == 0 {
+		ranges = append(ranges, cache.TimeRange{From: w.firstCandleTime, To: w.lastCandleTime})
+	} else {
+		if w.firstCandleTime.Before(loaded[0].Timestamp) {
+			ranges = append(ranges, cache.TimeRange{From: w.firstCandleTime, To: loaded[0].Timestamp})
+		}
+		if w.lastCandleTime.After(loaded[len(loaded)-1].Timestamp) {
+			ranges = append(ranges, cache.TimeRange{From: loaded[len(loaded)-1].Timestamp, To: w.lastCandleTime})
+		}
+	}
+	for _, t := range ranges {
+		data := d.candleCache.GetCandles(d.Entry.Figi, d.queryResolution, t.From, t.To)
+		if len(data) > 0 {
+			log.Printf("Loading cached candle data %s %s.", d.Entry.Figi, d.queryResolution.String())
+			d.updateCandles(d.queryResolution, data, true)
+		}
+	}
+}
+
+// Returns the candles of the source if they cover the candle time window up to the present.
+func (d *CandleUpdater) getSourceCandles(w candleTime) ([]indapi.CandleData, bool) {
+	data := d.source.GetConsolidatedCandlesCopy()
+	if len(data) == 0 {
+		return nil, false
+	}
+	sourceStart := data[0].Timestamp
+	sourceEnd := d.source.Resolution.GetNthCandleTime(data[len(data)-1].Timestamp, 1, d.source.Session)
+	requiredEnd := d.CandleData.Resolution.GetNthCandleTime(time.Now(), 0, d.CandleData.Session)
+	if w.lastCandleTime.Before(requiredEnd) {
+		requiredEnd = w.lastCandleTime
+	}
+	if sourceStart.After(w.firstCandleTime) || sourceEnd.Before(requiredEnd) {
+		return nil, false
+	}
+	return data, true
+}
+
 func (d *CandleUpdater) SetCandleTime(uiIndex int32, first time.Time, last time.Time) {
 	c := candleTime{firstCandleTime: first, lastCandleTime: last}
 	d.candleTimeMap.Store(uiIndex, c)
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package stockviz
+
+import (
+	"context"
+	"log"
+	"maystocks/indapi/candles"
+	"maystocks/stockplot"
+	"maystocks/stockval"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Assets which are compared to the asset of a plot. Their candles are queried from the broker of the plot,
+// but they do not use quotes or realtime data.
+type comparisons struct {
+	mutex   sync.Mutex
+	entries []comparison
+}
+
+type comparison struct {
+	priceData PriceData
+	// Candles of this resolution were added, but not requested yet.
+	pendingResolution candles.CandleResolution
+	pendingRefresh    bool
+}
+
+// Adds a compared asset if it is neither the asset of the plot nor already compared.
+// It is safe to call this from any goroutine.
+func (v *PlotView) addComparison(entry stockval.AssetData) bool {
+	if entry.Figi == v.AssetData.Figi {
+		return false
+	}
+	v.comparisons.mutex.Lock()
+	defer v.comparisons.mutex.Unlock()
+	if slices.ContainsFunc(v.comparisons.entries, func(c comparison) bool { return c.priceData.Entry.Figi == entry.Figi }) {
+		return false
+	}
+	log.Printf("Adding comparison %s to plot %d.", entry.Figi, v.UiIndex)
+	priceData := NewPriceData(entry)
+	priceData.InitializeCandles(v.broker, v.candleCache, v.uiUpdater)
+	v.comparisons.entries = append(v.comparisons.entries, comparison{priceData: priceData})
+	return true
+}
+
+// Removes all compared assets. It is safe to call this from any goroutine.
+func (v *PlotView) clearComparisons() {
+	v.comparisons.mutex.Lock()
+	defer v.comparisons.mutex.Unlock()
+	for _, c := range v.comparisons.entries {
+		c.priceData.CleanupCandles()
+	}
+	v.comparisons.entries = nil
+}
+
+func (v *PlotView) hasComparisons() bool {
+	v.comparisons.mutex.Lock()
+	defer v.comparisons.mutex.Unlock()
+	return len(v.comparisons.entries) > 0
+}
+
+func (v *PlotView) GetComparedAssets() []stockval.AssetData {
+	v.comparisons.mutex.Lock()
+	defer v.comparisons.mutex.Unlock()
+	assets := make([]stockval.AssetData, len(v.comparisons.entries))
+	for i, c := range v.comparisons.entries {
+		assets[i] = c.priceData.Entry
+	}
+	return assets
+}
+
+// Returns the candles of the compared assets, candles of a new resolution are added.
+// Colors of the theme are used in turn.
+func (v *PlotView) getComparisonData(ctx context.Context, r candles.CandleResolution) []stockplot.CompareData {
+	v.comparisons.mutex.Lock()
+	defer v.comparisons.mutex.Unlock()
+	data := make([]stockplot.CompareData, len(v.comparisons.entries))
+	for i := range v.comparisons.entries {
+		c := &v.comparisons.entries[i]
+		candleUpdater, loaded := c.priceData.LoadOrAddCandleResolution(ctx, r)
+		if !loaded {
+			c.pendingResolution, c.pendingRefresh = r, true
+		}
+		data[i] = stockplot.CompareData{
+			Symbol: c.priceData.Entry.Symbol,
+			Data:   candleUpdater.CandleData,
+		}
+		if len(v.PlotTheme.CompareColors) > 0 {
+			data[i].Color = v.PlotTheme.CompareColors[i%len(v.PlotTheme.CompareColors)]
+		}
+	}
+	return data
+}
+
+// Requests the candles of the compared assets for the time range of the plot.
+// Only new candle resolutions are requested, unless refreshPlot is set.
+// Call in same thread as Layout()
+func (v *PlotView) updateComparisonRange(startTime time.Time, endTime time.Time, refreshPlot bool) {
+	r := v.GetLastCandleResolution()
+	v.comparisons.mutex.Lock()
+	defer v.comparisons.mutex.Unlock()
+	for i := range v.comparisons.entries {
+		c := &v.comparisons.entries[i]
+		if !refreshPlot && !(c.pendingRefresh && c.pendingResolution == r) {
+			continue
+		}
+		c.pendingRefresh = false
+		c.priceData.candlesMutex.Lock()
+		candleUpdater, ok := c.priceData.candles[r]
+		c.priceData.candlesMutex.Unlock()
+		if ok {
+			candleUpdater.SetCandleTime(v.UiIndex, startTime, endTime)
+			c.priceData.RefreshCandles(r)
+		}
+	}
+}
+
+// Periodically refreshes the candles of the compared assets. It is safe to call this from any goroutine.
+func (v *PlotView) refreshComparisons(r candles.CandleResolution) {
+	v.comparisons.mutex.Lock()
+	defer v.comparisons.mutex.Unlock()
+	for _, c := range v.comparisons.entries {
+		c.priceData.RefreshCandles(r)
+	}
+}
@@ -8,13 +8,18 @@ import (
 	"fmt"
 	"image"
 	"log"
+	"maystocks/cache"
+	"maystocks/calendar"
 	"maystocks/config"
+	"maystocks/indapi"
 	"maystocks/indapi/candles"
+	"maystocks/indapi/indicators/vwap"
 	"maystocks/stockapi"
 	"maystocks/stockplot"
 	"maystocks/stockval"
 	"maystocks/widgets"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -32,13 +37,27 @@ type PlotView struct {
 	indicatorsButton     *widget.Clickable
 	brokerDropdown       *widgets.DropDown
 	resolutionDropDown   *widgets.DropDown
+	chartTypeDropDown    *widgets.DropDown
+	boxSizeDropDown      *widgets.DropDown
 	contextMenuArea      *component.ContextArea
 	contextMenu          *component.MenuState
 	settingsMenuItem     *widget.Clickable
+	scaleMenuItems       []*widget.Clickable // in the order of ScaleTypeList
+	compareMenuItem      *widget.Clickable
+	clearCompareMenuItem *widget.Clickable
+	offlineMenuItem      *widget.Clickable
+	anchorVwapMenuItem   *widget.Clickable
+	compareSearch        *int32 // set if the next submitted search adds a comparison, use atomic accessor
+	comparisons          *comparisons
+	broker               stockapi.Broker
+	candleCache          cache.CandleCache
 	brokerList           stockval.BrokerList
 	lastBroker           *int32
 	lastCandleResolution *candles.CandleResolution // use atomic accessor
+	lastChartType        *int32                    // index in ChartTypeList, use atomic accessor
+	lastBoxSize          *int32                    // index in BoxSizeList, use atomic accessor
 	lastPlotTimeRange    *PlotTimeRange
+	candleSession        candles.Session
 	Plot                 *stockplot.Plot
 	QuoteField           *widgets.QuoteField
 	UiIndex              int32
@@ -57,6 +76,9 @@ type PlotTimeRange struct {
 
 const maxLookupResults = 32
 
+// Suffix of the request id of searches for compared assets.
+const compareRequestSuffix = "-compare"
+
 func NewPlotView(brokerList stockval.BrokerList, theme *widgets.PlotTheme) PlotView {
 	return PlotView{
 		PlotTheme:            theme,
@@ -65,16 +87,36 @@ func NewPlotView(brokerList stockval.BrokerList, theme *widgets.PlotTheme) PlotV
 		contextMenuArea:      new(component.ContextArea),
 		contextMenu:          new(component.MenuState),
 		settingsMenuItem:     new(widget.Clickable),
+		scaleMenuItems:       newClickables(len(stockval.ScaleTypeList())),
+		compareMenuItem:      new(widget.Clickable),
+		clearCompareMenuItem: new(widget.Clickable),
+		offlineMenuItem:      new(widget.Clickable),
+		anchorVwapMenuItem:   new(widget.Clickable),
+		compareSearch:        new(int32),
+		comparisons:          new(comparisons),
 		lastBroker:           new(int32),
 		lastCandleResolution: new(candles.CandleResolution),
+		lastChartType:        new(int32),
+		lastBoxSize:          new(int32),
 		lastPlotTimeRange:    new(PlotTimeRange),
 		scalingX:             new(stockval.PlotScaling),
 		scalingXmutex:        new(sync.Mutex),
 	}
 }
 
-func (v *PlotView) Initialize(ctx context.Context, plotData plotData, symbolSearchTool stockapi.SymbolSearchTool, uiUpdater StockUiUpdater, appTradingUrl string) {
+func newClickables(n int) []*widget.Clickable {
+	c := make([]*widget.Clickable, n)
+	for i := range c {
+		c[i] = new(widget.Clickable)
+	}
+	return c
+}
+
+func (v *PlotView) Initialize(ctx context.Context, plotData plotData, broker stockapi.Broker, candleCache cache.CandleCache,
+	uiUpdater StockUiUpdater, appTradingUrl string) {
 	v.AssetData = plotData.Entry
+	v.broker = broker
+	v.candleCache = candleCache
 	v.searchField = widgets.NewSearchField(plotData.Entry.Symbol)
 	brokerList := make([]string, len(v.brokerList))
 	for i, v := range v.brokerList {
@@ -84,40 +126,66 @@ func (v *PlotView) Initialize(ctx context.Context, plotData plotData, symbolSear
 	if brokerIndex < 0 {
 		panic("unknown data broker")
 	}
-	resolutionList := candles.CandleResolutionUiStringList()
-	if int(plotData.CandleResolution) >= len(resolutionList) {
+	resolutionIndex := stockval.IndexOf(candles.CandleResolutionList(), plotData.CandleResolution)
+	if resolutionIndex < 0 {
 		panic("unknown candle resolution")
 	}
+	chartTypeIndex := stockval.IndexOf(stockval.ChartTypeList(), plotData.ChartType)
+	if chartTypeIndex < 0 {
+		chartTypeIndex = 0
+	}
+	boxSizeIndex := stockval.IndexOf(stockval.BoxSizeList(), plotData.BoxSize)
+	if boxSizeIndex < 0 {
+		boxSizeIndex = 0
+	}
 	if len(plotData.SubPlots) == 0 {
 		panic("missing subplots")
 	}
 
+	v.candleSession = calendar.GetCandleSession(plotData.Entry, broker.GetCapabilities().ExtendedHoursCandles)
 	v.brokerDropdown = widgets.NewDropDown(brokerList, brokerIndex)
-	v.resolutionDropDown = widgets.NewDropDown(resolutionList, int(plotData.CandleResolution))
-	v.Plot = stockplot.NewPlot(v.PlotTheme, plotData.CandleResolution, plotData.ScalingX, plotData.SubPlots)
+	v.resolutionDropDown = widgets.NewDropDown(candles.CandleResolutionUiStringList(), resolutionIndex)
+	v.chartTypeDropDown = widgets.NewDropDown(stockval.ChartTypeUiStringList(), chartTypeIndex)
+	v.boxSizeDropDown = widgets.NewDropDown(stockval.BoxSizeUiStringList(), boxSizeIndex)
+	v.Plot = stockplot.NewPlot(v.PlotTheme, plotData.CandleResolution, v.candleSession, plotData.ScalingX, plotData.SubPlots)
+	v.subscribeSignals()
 	fullAppTradingUrl := fmt.Sprintf(appTradingUrl, plotData.Entry.Symbol)
 	v.QuoteField = widgets.NewQuoteField(string(plotData.BrokerName), fullAppTradingUrl)
 	v.UiIndex = plotData.UiIndex
 	v.uiUpdater = uiUpdater
 	v.appTradingUrl = appTradingUrl
+	for _, entry := range plotData.CompareAssets {
+		v.addComparison(entry)
+	}
 
 	atomic.StoreInt32(v.lastBroker, int32(brokerIndex))
 	atomic.StoreInt32((*int32)(v.lastCandleResolution), int32(plotData.CandleResolution))
+	atomic.StoreInt32(v.lastChartType, int32(chartTypeIndex))
+	atomic.StoreInt32(v.lastBoxSize, int32(boxSizeIndex))
 
 	// TODO size of buffered channels?
 	v.SearchRequestChan = make(chan stockapi.SearchRequest, 10)
 	v.SearchResponseChan = make(chan stockapi.SearchResponse, 10)
 
 	go v.handleSearchResult(ctx)
-	go symbolSearchTool.FindAsset(ctx, v.SearchRequestChan, v.SearchResponseChan)
+	go broker.FindAsset(ctx, v.SearchRequestChan, v.SearchResponseChan)
 }
 
 func (v *PlotView) UpdateSubPlots(subPlots []stockplot.SubPlotData) {
-	v.Plot = stockplot.NewPlot(v.PlotTheme, v.GetLastCandleResolution(), v.GetLastPlotScalingX(), subPlots)
+	v.Plot = stockplot.NewPlot(v.PlotTheme, v.GetLastCandleResolution(), v.candleSession, v.GetLastPlotScalingX(), subPlots)
+	v.subscribeSignals()
+}
+
+func (v *PlotView) subscribeSignals() {
+	symbol := v.AssetData.Symbol
+	v.Plot.SubscribeSignals(func(id indapi.IndicatorId, signal indapi.Signal) {
+		log.Printf("Signal %s %s: %s at %s.", symbol, id, signal.Description, signal.Timestamp.Format(time.DateTime))
+	})
 }
 
 func (v *PlotView) Cleanup() {
 	close(v.SearchRequestChan)
+	v.clearComparisons()
 }
 
 func (v *PlotView) saveConfiguration(plotConfig *config.PlotConfig) {
@@ -125,6 +193,14 @@ func (v *PlotView) saveConfiguration(plotConfig *config.PlotConfig) {
 	plotConfig.BrokerId = v.GetLastBrokerName()
 	plotConfig.Resolution = v.GetLastCandleResolution()
 	plotConfig.PlotScalingX = v.GetLastPlotScalingX()
+	plotConfig.ChartType = v.GetLastChartType()
+	plotConfig.BoxSize = v.GetLastBoxSize()
+	plotConfig.CompareAssets = v.GetComparedAssets()
+	for i, s := range v.Plot.GetSubPlotData() {
+		if i < len(plotConfig.SubPlotConfig) && plotConfig.SubPlotConfig[i].Type == s.Type {
+			plotConfig.SubPlotConfig[i].Scale = s.Scale
+		}
+	}
 }
 
 func (v *PlotView) GetLastBrokerName() stockval.BrokerId {
@@ -135,6 +211,14 @@ func (v *PlotView) GetLastCandleResolution() candles.CandleResolution {
 	return candles.CandleResolution(atomic.LoadInt32((*int32)(v.lastCandleResolution)))
 }
 
+func (v *PlotView) GetLastChartType() stockval.ChartType {
+	return stockval.ChartTypeList()[atomic.LoadInt32(v.lastChartType)]
+}
+
+func (v *PlotView) GetLastBoxSize() float64 {
+	return stockval.BoxSizeList()[atomic.LoadInt32(v.lastBoxSize)]
+}
+
 func (v *PlotView) GetLastPlotScalingX() stockval.PlotScaling {
 	v.scalingXmutex.Lock()
 	defer v.scalingXmutex.Unlock()
@@ -153,7 +237,11 @@ func (v *PlotView) handleSearchResult(ctx context.Context) {
 			log.Printf("Asset search error: %v", searchResponse.Error)
 			continue
 		}
-		if searchResponse.UnambiguousLookup {
+		if searchResponse.UnambiguousLookup && strings.HasSuffix(searchResponse.RequestId, compareRequestSuffix) {
+			if len(searchResponse.Result) > 0 && v.addComparison(searchResponse.Result[0]) {
+				v.uiUpdater.Invalidate()
+			}
+		} else if searchResponse.UnambiguousLookup {
 			if len(searchResponse.Result) > 0 {
 				if v.AssetData.Figi == searchResponse.Result[0].Figi {
 					// Same Figi as already shown. Just update asset data (especially tradable flag).
@@ -162,6 +250,8 @@ func (v *PlotView) handleSearchResult(ctx context.Context) {
 					newPlotView.AssetData = searchResponse.Result[0]
 					v.uiUpdater.UpdatePlot(v.UiIndex, newPlotView)
 				} else {
+					// Comparisons are removed together with the plot.
+					compareAssets := v.GetComparedAssets()
 					v.uiUpdater.RemovePlot(v.AssetData, v.UiIndex)
 					v.uiUpdater.AddPlot(
 						ctx,
@@ -171,7 +261,10 @@ func (v *PlotView) handleSearchResult(ctx context.Context) {
 							v.GetLastBrokerName(),
 							v.UiIndex,
 							v.GetLastPlotScalingX(),
+							v.GetLastChartType(),
+							v.GetLastBoxSize(),
 							v.Plot.GetSubPlotData(),
+							compareAssets,
 						},
 						v.appTradingUrl,
 					)
@@ -202,19 +295,38 @@ func (v *PlotView) handleInput(ctx context.Context, gtx layout.Context) {
 
 	t, ok = v.searchField.SubmittedSearchText()
 	if ok && t != "" {
-		v.SearchRequestChan <- stockapi.SearchRequest{RequestId: strconv.Itoa(int(v.UiIndex)), Text: t, MaxNumResults: maxLookupResults, UnambiguousLookup: true}
+		requestId := strconv.Itoa(int(v.UiIndex))
+		if atomic.CompareAndSwapInt32(v.compareSearch, 1, 0) {
+			requestId += compareRequestSuffix
+			// The search field keeps showing the asset of the plot.
+			v.searchField.SetText(v.AssetData.Symbol)
+		}
+		v.SearchRequestChan <- stockapi.SearchRequest{RequestId: requestId, Text: t, MaxNumResults: maxLookupResults, UnambiguousLookup: true}
 	}
 
 	resolutionIndex := v.resolutionDropDown.ClickedIndex()
 	if resolutionIndex >= 0 {
 		v.resolutionDropDown.SetSelectedIndex(resolutionIndex)
-		atomic.StoreInt32((*int32)(v.lastCandleResolution), int32(resolutionIndex))
+		atomic.StoreInt32((*int32)(v.lastCandleResolution), int32(candles.CandleResolutionList()[resolutionIndex]))
+	}
+
+	chartTypeIndex := v.chartTypeDropDown.ClickedIndex()
+	if chartTypeIndex >= 0 {
+		v.chartTypeDropDown.SetSelectedIndex(chartTypeIndex)
+		atomic.StoreInt32(v.lastChartType, int32(chartTypeIndex))
+	}
+
+	boxSizeIndex := v.boxSizeDropDown.ClickedIndex()
+	if boxSizeIndex >= 0 {
+		v.boxSizeDropDown.SetSelectedIndex(boxSizeIndex)
+		atomic.StoreInt32(v.lastBoxSize, int32(boxSizeIndex))
 	}
 
 	brokerIndex := int32(v.brokerDropdown.ClickedIndex())
 	if brokerIndex >= 0 {
 		if atomic.LoadInt32(v.lastBroker) != brokerIndex {
 			// It is safe to do this asynchronously.
+			compareAssets := v.GetComparedAssets()
 			v.uiUpdater.RemovePlot(v.AssetData, v.UiIndex)
 			v.uiUpdater.AddPlot(
 				ctx,
@@ -224,7 +336,10 @@ func (v *PlotView) handleInput(ctx context.Context, gtx layout.Context) {
 					v.brokerList[brokerIndex],
 					v.UiIndex,
 					v.GetLastPlotScalingX(),
+					v.GetLastChartType(),
+					v.GetLastBoxSize(),
 					v.Plot.GetSubPlotData(),
+					compareAssets,
 				},
 				v.appTradingUrl)
 			v.uiUpdater.Invalidate()
@@ -236,6 +351,47 @@ func (v *PlotView) handleInput(ctx context.Context, gtx layout.Context) {
 	if v.settingsMenuItem.Clicked(gtx) {
 		v.uiUpdater.ShowSettings()
 	}
+	if v.compareMenuItem.Clicked(gtx) {
+		// The next symbol which is entered is compared.
+		atomic.StoreInt32(v.compareSearch, 1)
+		v.searchField.Focus(gtx)
+	}
+	if v.clearCompareMenuItem.Clicked(gtx) {
+		v.clearComparisons()
+	}
+	if v.anchorVwapMenuItem.Clicked(gtx) {
+		if t, ok := v.Plot.GetContextCandleTime(); ok {
+			location := v.candleSession.Location
+			if location == nil {
+				location = time.UTC
+			}
+			v.uiUpdater.SetIndicatorProperties(ctx, v.UiIndex, vwap.Id, map[string]string{
+				"Mode":   vwap.ModeAnchored,
+				"Anchor": t.In(location).Format(indapi.TimePropertyFormat),
+			})
+		}
+	}
+	if v.offlineMenuItem.Clicked(gtx) {
+		v.uiUpdater.SetOffline(!v.uiUpdater.IsOffline())
+	}
+	for i, s := range stockval.ScaleTypeList() {
+		if v.scaleMenuItems[i].Clicked(gtx) {
+			if sub := v.Plot.GetContextSubPlot(); sub != nil {
+				sub.SetScale(s)
+			}
+		}
+	}
+}
+
+func (v *PlotView) hasIndicator(id indapi.IndicatorId) bool {
+	for _, s := range v.Plot.Sub {
+		for _, ind := range s.Indicators {
+			if ind.GetId() == id {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (v *PlotView) Layout(ctx context.Context, gtx layout.Context, th *material.Theme, priceData *PriceData) (layout.Dimensions, bool) {
@@ -247,9 +403,30 @@ func (v *PlotView) Layout(ctx context.Context, gtx layout.Context, th *material.
 
 	v.contextMenu.Options = []func(gtx layout.Context) layout.Dimensions{
 		component.MenuItem(th, v.settingsMenuItem, "Settings").Layout,
+		component.MenuItem(th, v.compareMenuItem, "Compare with...").Layout,
+	}
+	if v.hasComparisons() {
+		v.contextMenu.Options = append(v.contextMenu.Options, component.MenuItem(th, v.clearCompareMenuItem, "Clear Comparisons").Layout)
+	}
+	if _, ok := v.Plot.GetContextCandleTime(); ok && v.hasIndicator(vwap.Id) {
+		v.contextMenu.Options = append(v.contextMenu.Options, component.MenuItem(th, v.anchorVwapMenuItem, "Anchor VWAP Here").Layout)
+	}
+	if v.uiUpdater.IsOffline() {
+		v.contextMenu.Options = append(v.contextMenu.Options, component.MenuItem(th, v.offlineMenuItem, "Go Online").Layout)
+	} else {
+		v.contextMenu.Options = append(v.contextMenu.Options, component.MenuItem(th, v.offlineMenuItem, "Go Offline").Layout)
+	}
+	// Scales can only be selected for subplots which support them.
+	if sub := v.Plot.GetContextSubPlot(); sub != nil {
+		for i, s := range stockval.ScaleTypeList() {
+			if s != sub.GetScale() && sub.SupportsScale(s) {
+				v.contextMenu.Options = append(v.contextMenu.Options, component.MenuItem(th, v.scaleMenuItems[i], s.UiString()).Layout)
+			}
+		}
 	}
 	quote := priceData.GetQuoteCopy()
 	bidAsk := priceData.GetBidAskCopy()
+	comparisonData := v.getComparisonData(ctx, v.GetLastCandleResolution())
 
 	layout.Stack{}.Layout(gtx,
 		layout.Stacked(func(gtx layout.Context) layout.Dimensions {
@@ -281,6 +458,34 @@ func (v *PlotView) Layout(ctx context.Context, gtx layout.Context, th *material.
 								}),
 							)
 						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.Inset{Top: 10, Right: 0, Bottom: 0, Left: 10}.Layout(gtx, material.Body1(th, "Chart:").Layout)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.Inset{Top: 0, Right: 10, Bottom: 0, Left: 10}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+										return v.chartTypeDropDown.Layout(th, gtx)
+									})
+								}),
+							)
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							// The box size is only used by price-based charts.
+							if !v.GetLastChartType().IsPriceBased() {
+								return layout.Dimensions{}
+							}
+							return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.Inset{Top: 10, Right: 0, Bottom: 0, Left: 10}.Layout(gtx, material.Body1(th, "Box:").Layout)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.Inset{Top: 0, Right: 10, Bottom: 0, Left: 10}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+										return v.boxSizeDropDown.Layout(th, gtx)
+									})
+								}),
+							)
+						}),
 						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 							return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
 								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
@@ -300,8 +505,8 @@ func (v *PlotView) Layout(ctx context.Context, gtx layout.Context, th *material.
 						layout.Stacked(func(gtx layout.Context) layout.Dimensions {
 							resolution := v.GetLastCandleResolution()
 							candleResolutionChanged := v.Plot.InitializeFrame(gtx, resolution)
-							d := v.Plot.Layout(gtx, th)
-							// TODO allow displaying lines instead of candles
+							v.Plot.SetChartType(v.GetLastChartType())
+							v.Plot.SetBoxSize(v.GetLastBoxSize())
 							candleUpdater, loaded := priceData.LoadOrAddCandleResolution(ctx, resolution)
 							if candleResolutionChanged {
 								v.lastPlotTimeRange.lastPlotStartTime = time.Time{}
@@ -310,8 +515,12 @@ func (v *PlotView) Layout(ctx context.Context, gtx layout.Context, th *material.
 							if !loaded {
 								refreshQuote = true
 							}
+							v.Plot.SetComparisons(comparisonData)
+							v.Plot.UpdateIndicators(candleUpdater.CandleData)
+							// The X axis of price-based charts depends on the bricks, build them before the layout.
+							v.Plot.UpdatePriceChart(candleUpdater.CandleData)
+							d := v.Plot.Layout(gtx, th)
 							for _, s := range v.Plot.Sub {
-								s.UpdateIndicators(candleUpdater.CandleData)
 								s.Plot(candleUpdater.CandleData, quote, gtx, th)
 							}
 							newScalingX, scalingChanged := v.Plot.GetPlotScalingX()
@@ -328,7 +537,7 @@ func (v *PlotView) Layout(ctx context.Context, gtx layout.Context, th *material.
 								}.Layout(
 									gtx,
 									layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-										return stockplot.LayoutTitleField(gtx, th, v.PlotTheme, v.AssetData)
+										return stockplot.LayoutTitleField(gtx, th, v.PlotTheme, v.AssetData, comparisonData)
 									}),
 									layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 										return layout.Inset{Left: 30}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
@@ -365,8 +574,8 @@ func (v *PlotView) Layout(ctx context.Context, gtx layout.Context, th *material.
 func (v *PlotView) UpdatePlotRange() (startTime time.Time, endTime time.Time, refreshPlot bool) {
 	plotStartTime, plotEndTime, r := v.Plot.GetCandleRange()
 	// For now, we do not filter requesting future data. Brokers need to do that if required.
-	startTime = r.GetNthCandleTime(plotStartTime, -stockval.PreloadCandlesBefore)
-	endTime = r.GetNthCandleTime(plotEndTime, stockval.PreloadCandlesAfter)
+	startTime = r.GetNthCandleTime(plotStartTime, -stockval.PreloadCandlesBefore, v.candleSession)
+	endTime = r.GetNthCandleTime(plotEndTime, stockval.PreloadCandlesAfter, v.candleSession)
 
 	// Start refreshing after passing half the refresh interval.
 	startRefreshDiff := plotStartTime.Sub(startTime) / 2
@@ -6,6 +6,8 @@ package stockviz
 import (
 	"context"
 	"log"
+	"maystocks/cache"
+	"maystocks/calendar"
 	"maystocks/indapi/candles"
 	"maystocks/stockapi"
 	"maystocks/stockval"
@@ -21,11 +23,15 @@ type PriceData struct {
 	RealtimeData      *skipmap.Int64Map[*decimal.Big]
 	candles           map[candles.CandleResolution]CandleUpdater
 	candlesMutex      *sync.Mutex
+	candleSession     candles.Session
+	isTradingTime     stockval.TradingTimeFunc
 	quote             *stockval.QuoteData
 	quoteMutex        *sync.Mutex
 	bidAsk            *stockval.RealtimeBidAskData
 	bidAskMutex       *sync.Mutex
 	broker            stockapi.Broker
+	candleCache       cache.CandleCache
+	quoteCache        cache.QuoteCache
 	uiUpdater         StockUiUpdater
 	quoteRequestChan  chan stockval.AssetData
 	quoteResponseChan chan stockapi.QueryQuoteResponse
@@ -44,18 +50,29 @@ func NewPriceData(entry stockval.AssetData) PriceData {
 	}
 }
 
-func (p *PriceData) Initialize(ctx context.Context, broker stockapi.Broker, uiUpdater StockUiUpdater) {
-	p.broker = broker
-	p.uiUpdater = uiUpdater
+func (p *PriceData) Initialize(ctx context.Context, broker stockapi.Broker, candleCache cache.CandleCache, quoteCache cache.QuoteCache,
+	uiUpdater StockUiUpdater) {
+	p.InitializeCandles(broker, candleCache, uiUpdater)
+	p.quoteCache = quoteCache
+	// Show the last known quote until the broker responds.
+	if cachedQuote, ok := quoteCache.GetQuote(p.Entry.Figi); ok {
+		*p.quote = cachedQuote
+	}
 	// TODO size of buffered channels?
 	p.quoteRequestChan = make(chan stockval.AssetData, 128)
 	p.quoteResponseChan = make(chan stockapi.QueryQuoteResponse, 128)
 	go func() {
 		for quoteResponseData := range p.quoteResponseChan {
 			log.Printf("Updating quote data %s.", quoteResponseData.Figi)
+			uiUpdater.ReportConnectivity(quoteResponseData.Error)
+			if quoteResponseData.Error != nil {
+				// Keep the last known quote.
+				uiUpdater.Invalidate()
+				continue
+			}
 			p.quoteMutex.Lock()
 			p.quote.PreviousDelayedClosePrice = quoteResponseData.PreviousClosePrice
-			if p.quote.PreviousClosePrice == nil {
+			if p.quote.PreviousClosePrice == nil || p.quote.Cached {
 				p.quote.PreviousClosePrice = quoteResponseData.PreviousClosePrice
 			}
 			p.quote.CurrentDelayedPrice = quoteResponseData.CurrentPrice
@@ -64,7 +81,10 @@ func (p *PriceData) Initialize(ctx context.Context, broker stockapi.Broker, uiUp
 				p.quote.CurrentPriceTimestamp = time.Now()
 				p.quote.DeltaPercentage = quoteResponseData.DeltaPercentage
 			}
+			p.quote.Cached = false
+			quote := *p.quote
 			p.quoteMutex.Unlock()
+			p.quoteCache.StoreQuote(p.Entry.Figi, quote)
 			uiUpdater.Invalidate()
 		}
 		log.Printf("Terminating quote update handler %s.", p.Entry.Figi)
@@ -72,7 +92,22 @@ func (p *PriceData) Initialize(ctx context.Context, broker stockapi.Broker, uiUp
 	go broker.QueryQuote(ctx, p.quoteRequestChan, p.quoteResponseChan)
 }
 
+// Prepares querying candles. This is sufficient if neither quotes nor realtime data are needed.
+func (p *PriceData) InitializeCandles(broker stockapi.Broker, candleCache cache.CandleCache, uiUpdater StockUiUpdater) {
+	p.broker = broker
+	p.candleCache = candleCache
+	p.uiUpdater = uiUpdater
+	extendedHours := broker.GetCapabilities().ExtendedHoursCandles
+	p.candleSession = calendar.GetCandleSession(p.Entry, extendedHours)
+	p.isTradingTime = calendar.GetTradingTimeFunc(p.Entry, extendedHours)
+}
+
 func (p *PriceData) Cleanup() {
+	p.SaveQuote()
+	p.CleanupCandles()
+}
+
+func (p *PriceData) CleanupCandles() {
 	p.candlesMutex.Lock()
 	defer p.candlesMutex.Unlock()
 	for _, c := range p.candles {
@@ -82,8 +117,10 @@ func (p *PriceData) Cleanup() {
 
 func (p *PriceData) GetQuoteCopy() stockval.QuoteData {
 	p.quoteMutex.Lock()
-	defer p.quoteMutex.Unlock()
-	return *p.quote
+	quote := *p.quote
+	p.quoteMutex.Unlock()
+	quote.Offline = p.uiUpdater.IsOffline()
+	return quote
 }
 
 func (p *PriceData) GetBidAskCopy() stockval.RealtimeBidAskData {
@@ -92,7 +129,19 @@ func (p *PriceData) GetBidAskCopy() stockval.RealtimeBidAskData {
 	return *p.bidAsk
 }
 
+// Stores the current quote, so that it can be displayed when the broker is not reachable.
+func (p *PriceData) SaveQuote() {
+	quote := p.GetQuoteCopy()
+	if !quote.IsStale() {
+		p.quoteCache.StoreQuote(p.Entry.Figi, quote)
+	}
+}
+
+// Requests the quote from the broker, unless the app is offline.
 func (p *PriceData) RefreshQuote() {
+	if p.uiUpdater.IsOffline() {
+		return
+	}
 	p.quoteRequestChan <- p.Entry
 }
 
@@ -113,14 +162,30 @@ func (p *PriceData) LoadOrAddCandleResolution(ctx context.Context, candleResolut
 	defer p.candlesMutex.Unlock()
 	c, ok := p.candles[candleResolution]
 	if !ok {
-		c = NewCandleUpdater(p.Entry, candleResolution)
-		c.Initialize(ctx, p.broker, p.uiUpdater)
+		c = NewCandleUpdater(p.Entry, candleResolution, p.candleSession, p.isTradingTime, p.findCandleSource(candleResolution))
+		c.Initialize(ctx, p.broker, p.candleCache, p.uiUpdater)
 		p.candles[candleResolution] = c
 	}
 	c.CandleData.UpdateCache()
 	return c, ok
 }
 
+// Returns already loaded candle data which can be resampled to candles of resolution r.
+// The caller needs to lock the candles mutex.
+func (p *PriceData) findCandleSource(r candles.CandleResolution) *stockval.CandlePlotData {
+	var loaded []candles.CandleResolution
+	for resolution, c := range p.candles {
+		if resolution != r && !c.CandleData.RealtimeOnly {
+			loaded = append(loaded, resolution)
+		}
+	}
+	base, ok := r.FindBaseResolution(loaded)
+	if !ok {
+		return nil
+	}
+	return p.candles[base].CandleData
+}
+
 func (p *PriceData) SetRealtimeTradesChan(realtimeChan chan stockval.RealtimeTickData, uiUpdater StockUiUpdater) {
 	go func() {
 		for data := range realtimeChan {
@@ -157,6 +222,7 @@ func (p *PriceData) AddRealtimePriceData(data stockval.RealtimeTickData) {
 			p.quote.PreviousClosePrice = p.quote.CurrentDelayedPrice
 		}
 
+		p.quote.Cached = false
 		if p.quote.Type == stockval.QuoteTypeDelayed {
 			p.quote.Type = stockval.QuoteTypeRealtime
 			p.quote.CurrentPriceTimestamp = time.Time{}
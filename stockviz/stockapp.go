@@ -7,13 +7,17 @@ import (
 	"context"
 	"image"
 	"log"
+	"maps"
+	"maystocks/cache"
 	"maystocks/config"
 	"maystocks/indapi"
 	"maystocks/indapi/candles"
 	"maystocks/indapi/indicators"
+	"maystocks/indapi/plugin"
 	"maystocks/stockapi"
 	"maystocks/stockplot"
 	"maystocks/stockval"
+	"maystocks/webclient"
 	"maystocks/widgets"
 	"reflect"
 	"sort"
@@ -63,11 +67,17 @@ type StockApp struct {
 	widgetStack        []layout.StackChild
 	configView         *widgets.ConfigView
 	indicatorsView     *widgets.IndicatorsView
+	customIndicators   []config.CustomIndicatorConfig
 	messageField       *widgets.MessageField
 	plotTheme          *widgets.PlotTheme
 	matTheme           *material.Theme
 	broker             map[stockval.BrokerId]stockapi.Broker
 	defaultBroker      stockval.BrokerId
+	title              string
+	// No quotes and candles are requested from brokers while offline, use atomic accessor.
+	offline *atomic.Bool
+	// Set after the first successful broker request, use atomic accessor.
+	connected *atomic.Bool
 }
 
 type BrokerData struct {
@@ -76,6 +86,8 @@ type BrokerData struct {
 	tradeRequestChan   chan stockapi.TradeRequest
 	tradeResponseChan  chan stockapi.TradeResponse
 	stockMap           *skipmap.StringMap[PriceData]
+	candleCache        cache.CandleCache
+	quoteCache         cache.QuoteCache
 	refreshTimeSeconds int
 }
 
@@ -85,7 +97,10 @@ type plotData struct {
 	BrokerName       stockval.BrokerId
 	UiIndex          int32
 	ScalingX         stockval.PlotScaling
+	ChartType        stockval.ChartType
+	BoxSize          float64
 	SubPlots         []stockplot.SubPlotData
+	CompareAssets    []stockval.AssetData
 }
 
 type StockUiUpdater interface {
@@ -95,6 +110,10 @@ type StockUiUpdater interface {
 	UpdatePlot(uiIndex int32, v PlotView)
 	ShowSettings()
 	ShowIndicators(uiIndex int32)
+	SetIndicatorProperties(ctx context.Context, uiIndex int32, id indapi.IndicatorId, prop map[string]string)
+	IsOffline() bool
+	SetOffline(offline bool)
+	ReportConnectivity(err error)
 }
 
 func NewStockApp(c config.Config) *StockApp {
@@ -109,6 +128,8 @@ func NewStockApp(c config.Config) *StockApp {
 		configView:         widgets.NewConfigView(config.NewBrokerConfigMap(), c),
 		indicatorsView:     widgets.NewIndicatorsView(),
 		messageField:       widgets.NewMessageField(),
+		offline:            new(atomic.Bool),
+		connected:          new(atomic.Bool),
 	}
 }
 
@@ -127,6 +148,8 @@ func (a *StockApp) Initialize(ctx context.Context, svr map[stockval.BrokerId]sto
 			tradeRequestChan:  make(chan stockapi.TradeRequest, 8),
 			tradeResponseChan: make(chan stockapi.TradeResponse, 8),
 			stockMap:          skipmap.NewString[PriceData](),
+			candleCache:       cache.NewLocalCandleCache(name),
+			quoteCache:        cache.NewLocalQuoteCache(name),
 		}
 		a.brokerData[name] = p
 
@@ -139,6 +162,8 @@ func (a *StockApp) Initialize(ctx context.Context, svr map[stockval.BrokerId]sto
 		go a.handleTradeResponseChan(p.tradeResponseChan)
 	}
 
+	// Plugins need to be registered before the indicators are created.
+	a.loadPlugins(ctx)
 	err := a.reloadConfiguration(ctx)
 	if err != nil {
 		return err
@@ -166,6 +191,13 @@ func (a *StockApp) reloadConfiguration(ctx context.Context) error {
 		a.plotTheme = widgets.NewDarkPlotTheme()
 	}
 
+	// Custom indicators need to be registered before creating the indicators.
+	customChanged := !reflect.DeepEqual(a.customIndicators, appConfig.CustomIndicators)
+	if customChanged {
+		registerCustomIndicators(appConfig.CustomIndicators)
+		a.customIndicators = appConfig.CustomIndicators
+	}
+
 	if a.numUiPlots != appConfig.WindowConfig[0].NumPlots {
 		a.numUiPlots = appConfig.WindowConfig[0].NumPlots
 		// Clear and recreate all plots.
@@ -182,14 +214,7 @@ func (a *StockApp) reloadConfiguration(ctx context.Context) error {
 			if !exists {
 				broker = a.defaultBroker
 			}
-			subPlots := make([]stockplot.SubPlotData, 0, len(plotConfig.SubPlotConfig))
-			for _, s := range plotConfig.SubPlotConfig {
-				indicatorData := make([]indapi.IndicatorData, 0, len(s.Indicators))
-				for _, c := range s.Indicators {
-					indicatorData = append(indicatorData, indicators.Create(c.IndicatorId, c.Properties, c.Colors))
-				}
-				subPlots = append(subPlots, stockplot.SubPlotData{Type: s.Type, Indicators: indicatorData})
-			}
+			subPlots := createSubPlots(plotConfig.SubPlotConfig)
 			a.AddPlot(
 				ctx,
 				plotData{
@@ -198,7 +223,10 @@ func (a *StockApp) reloadConfiguration(ctx context.Context) error {
 					broker,
 					0,
 					plotConfig.PlotScalingX,
+					plotConfig.ChartType,
+					plotConfig.BoxSize,
 					subPlots,
+					plotConfig.CompareAssets,
 				},
 				appConfig.BrokerConfig[broker].AppTradingUrl)
 		}
@@ -211,7 +239,8 @@ func (a *StockApp) reloadConfiguration(ctx context.Context) error {
 			}
 			plotConfig := appConfig.WindowConfig[0].PlotConfig[configIndex]
 			subPlotData := w.Plot.GetSubPlotData()
-			changed := len(plotConfig.SubPlotConfig) != len(subPlotData)
+			// Indicators are recreated if the expression of a custom indicator changed.
+			changed := customChanged || len(plotConfig.SubPlotConfig) != len(subPlotData)
 			if !changed {
 				for i, s := range subPlotData {
 					if plotConfig.SubPlotConfig[i].Type != s.Type {
@@ -235,19 +264,20 @@ func (a *StockApp) reloadConfiguration(ctx context.Context) error {
 							changed = true
 							break
 						}
+						var input indapi.IndicatorInput
+						if chained, ok := c.(indapi.ChainedIndicator); ok {
+							input = chained.GetInput()
+						}
+						if plotConfig.SubPlotConfig[i].Indicators[j].Input != input {
+							changed = true
+							break
+						}
 					}
 				}
 			}
 			if changed {
 				// Update subplots
-				subPlots := make([]stockplot.SubPlotData, 0, len(plotConfig.SubPlotConfig))
-				for _, s := range plotConfig.SubPlotConfig {
-					indicatorData := make([]indapi.IndicatorData, 0, len(s.Indicators))
-					for _, c := range s.Indicators {
-						indicatorData = append(indicatorData, indicators.Create(c.IndicatorId, c.Properties, c.Colors))
-					}
-					subPlots = append(subPlots, stockplot.SubPlotData{Type: s.Type, Indicators: indicatorData})
-				}
+				subPlots := createSubPlots(plotConfig.SubPlotConfig)
 				newPlotView := w
 				newPlotView.UpdateSubPlots(subPlots)
 				a.UpdatePlot(w.UiIndex, newPlotView)
@@ -267,6 +297,61 @@ func (a *StockApp) reloadConfiguration(ctx context.Context) error {
 	return nil
 }
 
+// Creates the indicators of the subplots of a plot.
+// Chained indicators are connected after all indicators have been created, because they may use
+// the output of an indicator which is configured later, or which is part of a different subplot.
+func createSubPlots(subPlotConfig []config.SubPlotConfig) []stockplot.SubPlotData {
+	subPlots := make([]stockplot.SubPlotData, 0, len(subPlotConfig))
+	var all []indapi.IndicatorData
+	named := make(map[string]indapi.IndicatorData)
+	for _, s := range subPlotConfig {
+		indicatorData := make([]indapi.IndicatorData, 0, len(s.Indicators))
+		for _, c := range s.Indicators {
+			if !indicators.IsRegistered(c.IndicatorId) {
+				// A custom indicator may have been removed.
+				log.Printf("unknown indicator %s", c.IndicatorId)
+				continue
+			}
+			var ind indapi.IndicatorData = indicators.Create(c.IndicatorId, c.Properties, c.Colors)
+			if c.Input.Name != "" {
+				ind = indicators.NewChained(ind, c.Input)
+			}
+			if c.Name != "" {
+				named[c.Name] = ind
+			}
+			indicatorData = append(indicatorData, ind)
+		}
+		all = append(all, indicatorData...)
+		subPlots = append(subPlots, stockplot.SubPlotData{Type: s.Type, Indicators: indicatorData, Scale: s.Scale})
+	}
+	indicators.ConnectChained(all, named)
+	return subPlots
+}
+
+// Loads the indicator plugins and registers them. Plugins which cannot be loaded are skipped.
+func (a *StockApp) loadPlugins(ctx context.Context) {
+	plugins, err := plugin.LoadDir(ctx, a.config.GetPluginDir())
+	if err != nil {
+		log.Printf("error loading plugins: %v", err)
+	}
+	for _, p := range plugins {
+		if err := indicators.Register(p.GetId(), p.NewIndicator); err != nil {
+			log.Printf("error registering plugin: %v", err)
+			p.Close()
+		}
+	}
+}
+
+// Replaces the registered custom indicators. Invalid custom indicators are skipped.
+func registerCustomIndicators(customConfig []config.CustomIndicatorConfig) {
+	indicators.UnregisterCustom()
+	for _, c := range customConfig {
+		if err := indicators.RegisterCustom(c.Id, c.Expression, c.Overlay); err != nil {
+			log.Printf("error registering custom indicator: %v", err)
+		}
+	}
+}
+
 func (a *StockApp) saveConfiguration() error {
 	appConfig, err := a.config.Lock()
 	if err != nil {
@@ -359,8 +444,9 @@ func (a *StockApp) handlePeriodicUpdate(brokerName stockval.BrokerId, refreshTim
 					if w.GetLastBrokerName() != brokerName {
 						return true
 					}
-					// Avoid duplicate queries here, this can add up pretty much.
 					r := w.GetLastCandleResolution()
+					w.refreshComparisons(r)
+					// Avoid duplicate queries here, this can add up pretty much.
 					for _, refreshed := range refreshedCandles {
 						if refreshed.figi == w.AssetData.Figi && refreshed.resolution == r {
 							// this is a duplicate, do not request twice.
@@ -423,6 +509,7 @@ func (a *StockApp) handleEvents(ctx context.Context) error {
 		giohyperlink.ListenEvents(event)
 		switch e := event.(type) {
 		case app.FrameEvent:
+			a.updateTitle()
 			gtx := app.NewContext(&ops, e)
 			paint.Fill(gtx.Ops, a.matTheme.Bg)
 			switch a.uiState {
@@ -453,6 +540,86 @@ func (a *StockApp) handleEvents(ctx context.Context) error {
 	}
 }
 
+// Returns whether the app is offline. Cached data is shown instead of requesting quotes and candles.
+// It is safe to call this from any goroutine.
+func (a *StockApp) IsOffline() bool {
+	return a.offline.Load()
+}
+
+// Switches the offline mode. When going online, quotes and candles of all plots are requested again.
+// It is safe to call this from any goroutine.
+func (a *StockApp) SetOffline(offline bool) {
+	if a.offline.Swap(offline) == offline {
+		return
+	}
+	log.Printf("Offline mode: %t", offline)
+	if !offline {
+		a.vizMap.Range(
+			func(uiIndex int32, w PlotView) bool {
+				brokerData, ok := a.brokerData[w.GetLastBrokerName()]
+				if !ok {
+					return true
+				}
+				if priceData, ok := brokerData.stockMap.Load(w.AssetData.Figi); ok {
+					priceData.RefreshQuote()
+					priceData.RefreshCandles(w.GetLastCandleResolution())
+				}
+				w.refreshComparisons(w.GetLastCandleResolution())
+				return true
+			},
+		)
+	}
+	a.Invalidate()
+}
+
+// Is called with the result of each broker request. The app goes offline if brokers are not reachable
+// at startup, i.e. if there is a network error before any request succeeded.
+// It is safe to call this from any goroutine.
+func (a *StockApp) ReportConnectivity(err error) {
+	if err == nil {
+		a.connected.Store(true)
+	} else if !a.connected.Load() && webclient.IsNetworkError(err) {
+		log.Printf("Brokers are not reachable: %v", err)
+		a.SetOffline(true)
+	}
+}
+
+// Shows in the window title whether the app is offline, and the age of the displayed data.
+func (a *StockApp) updateTitle() {
+	offline := a.IsOffline()
+	var lastUpdate time.Time
+	if offline {
+		a.vizMap.Range(
+			func(uiIndex int32, w PlotView) bool {
+				brokerData, ok := a.brokerData[w.GetLastBrokerName()]
+				if !ok {
+					return true
+				}
+				priceData, ok := brokerData.stockMap.Load(w.AssetData.Figi)
+				if !ok {
+					return true
+				}
+				if t := priceData.GetQuoteCopy().CurrentPriceTimestamp; t.After(lastUpdate) {
+					lastUpdate = t
+				}
+				return true
+			},
+		)
+	}
+	title := a.config.GetAppName()
+	if offline {
+		title += " (offline"
+		if !lastUpdate.IsZero() {
+			title += ", data " + stockval.FormatDataAge(lastUpdate, time.Now())
+		}
+		title += ")"
+	}
+	if title != a.title {
+		a.title = title
+		a.windows[0].win.Option(app.Title(title))
+	}
+}
+
 func (a *StockApp) layoutPlots(ctx context.Context, gtx layout.Context) {
 	a.plotLayouts = a.plotLayouts[:0]
 	if a.numUiPlots.X*a.numUiPlots.Y > 0 { // do not divide by zero even if "kind of" a race condition occurs
@@ -471,6 +638,7 @@ func (a *StockApp) layoutPlots(ctx context.Context, gtx layout.Context) {
 					func(gtx layout.Context) layout.Dimensions {
 						d, refreshQuote := w.Layout(ctx, gtx, a.matTheme, &priceData)
 						startTime, endTime, refreshPlot := w.UpdatePlotRange()
+						w.updateComparisonRange(startTime, endTime, refreshPlot)
 						if refreshQuote {
 							priceData.RefreshQuote()
 						}
@@ -549,6 +717,10 @@ func (a *StockApp) terminate() {
 	for _, p := range a.brokerData {
 		close(p.dataRequestChan)
 		close(p.tradeRequestChan)
+		p.stockMap.Range(func(figi string, priceData PriceData) bool {
+			priceData.SaveQuote()
+			return true
+		})
 	}
 	a.terminateWg.Wait()
 }
@@ -580,12 +752,12 @@ func (a *StockApp) AddPlot(ctx context.Context, plotData plotData, appTradingUrl
 	if plotData.UiIndex == 0 {
 		plotData.UiIndex = atomic.AddInt32(a.lastUiIndex, 1)
 	}
-	w.Initialize(ctx, plotData, broker, a, appTradingUrl)
+	w.Initialize(ctx, plotData, broker, brokerData.candleCache, a, appTradingUrl)
 	a.vizMap.Store(w.UiIndex, w)
 
 	_, loaded := brokerData.stockMap.LoadOrStoreLazy(plotData.Entry.Figi, func() PriceData {
 		priceData := NewPriceData(plotData.Entry)
-		priceData.Initialize(ctx, broker, a)
+		priceData.Initialize(ctx, broker, brokerData.candleCache, brokerData.quoteCache, a)
 		return priceData
 	})
 	if !loaded {
@@ -674,3 +846,41 @@ func (a *StockApp) ShowIndicators(uiIndex int32) {
 	a.uiState = StateIndicators
 	a.indicatorsIndex = max(0, int(uiIndex)-1)
 }
+
+// Sets properties of all indicators with the given id in a plot, e.g. the anchor of anchored VWAPs.
+// The properties are stored in the configuration, which is reloaded so that the indicators are recreated.
+// Call from same goroutine as Layout.
+func (a *StockApp) SetIndicatorProperties(ctx context.Context, uiIndex int32, id indapi.IndicatorId, prop map[string]string) {
+	if err := a.saveConfiguration(); err != nil {
+		log.Printf("error saving configuration: %v", err)
+		return
+	}
+	appConfig, err := a.config.Lock()
+	if err != nil {
+		log.Printf("error updating indicator properties: %v", err)
+		return
+	}
+	configIndex := int(uiIndex - 1)
+	if configIndex >= 0 && configIndex < len(appConfig.WindowConfig[0].PlotConfig) {
+		subPlotConfig := appConfig.WindowConfig[0].PlotConfig[configIndex].SubPlotConfig
+		for i := range subPlotConfig {
+			for j := range subPlotConfig[i].Indicators {
+				c := &subPlotConfig[i].Indicators[j]
+				if c.IndicatorId != id {
+					continue
+				}
+				if c.Properties == nil {
+					c.Properties = make(map[string]string)
+				}
+				maps.Copy(c.Properties, prop)
+			}
+		}
+	}
+	if err := a.config.Unlock(appConfig, false); err != nil {
+		log.Printf("error updating indicator properties: %v", err)
+		return
+	}
+	if err := a.reloadConfiguration(ctx); err != nil {
+		log.Printf("error reloading configuration: %v", err)
+	}
+}
@@ -5,10 +5,13 @@ package webclient
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
+	"net"
 	"net/http"
+	"net/url"
 )
 
 func ParseJsonResponse(resp *http.Response, v any) error {
@@ -30,3 +33,11 @@ func ParseJsonResponse(resp *http.Response, v any) error {
 	}
 	return nil
 }
+
+// Returns whether err was caused by the network, e.g. because there is no connection or the server is unreachable.
+// Errors reported by the server are not considered to be network errors.
+func IsNetworkError(err error) bool {
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package widgets
+
+import (
+	"fmt"
+	"maystocks/config"
+	"maystocks/indapi"
+	"maystocks/indapi/expression"
+	"maystocks/indapi/indicators"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+// Editor for a user-defined indicator, which is calculated by an expression.
+type CustomIndicatorView struct {
+	config.CustomIndicatorConfig
+	nameField       component.TextField
+	expressionField component.TextField
+	checkBoxOverlay widget.Bool
+	buttonRemove    widget.Clickable
+}
+
+func NewCustomIndicatorView(c config.CustomIndicatorConfig) *CustomIndicatorView {
+	v := CustomIndicatorView{
+		CustomIndicatorConfig: c,
+		nameField:             component.TextField{Editor: widget.Editor{SingleLine: true, MaxLen: 64}},
+		expressionField:       component.TextField{Editor: widget.Editor{MaxLen: expression.MaxSourceLength}},
+	}
+	v.nameField.SetText(string(c.Id))
+	v.expressionField.SetText(c.Expression)
+	v.checkBoxOverlay.Value = c.Overlay
+	return &v
+}
+
+// Validates the name and the expression, and displays errors. Names which are already used are passed in used.
+func (v *CustomIndicatorView) Validate(used map[indapi.IndicatorId]bool) bool {
+	valid := true
+	id := indapi.IndicatorId(strings.TrimSpace(v.nameField.Text()))
+	switch {
+	case id == "":
+		v.nameField.SetError("Name is required")
+		valid = false
+	case indicators.IsRegistered(id) && !indicators.IsCustom(id):
+		v.nameField.SetError("Name of a built-in indicator")
+		valid = false
+	case used[id]:
+		v.nameField.SetError("Name is already used")
+		valid = false
+	default:
+		v.nameField.ClearError()
+	}
+	used[id] = true
+	if _, err := expression.Compile(v.expressionField.Text()); err != nil {
+		v.expressionField.SetError(err.Error())
+		valid = false
+	} else {
+		v.expressionField.ClearError()
+	}
+	return valid
+}
+
+// Applies the values of the editor to the configuration.
+func (v *CustomIndicatorView) Apply() {
+	v.Id = indapi.IndicatorId(strings.TrimSpace(v.nameField.Text()))
+	v.Expression = v.expressionField.Text()
+	v.Overlay = v.checkBoxOverlay.Value
+}
+
+func (v *CustomIndicatorView) Layout(th *material.Theme, margin unit.Dp, gtx layout.Context) layout.Dimensions {
+	return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+		layout.Flexed(0.3, func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(margin).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return v.nameField.Layout(gtx, th, "Name")
+			})
+		}),
+		layout.Flexed(0.7, func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(margin).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return v.expressionField.Layout(gtx, th, "Expression")
+			})
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(margin).Layout(gtx, material.CheckBox(th, &v.checkBoxOverlay, "Overlay").Layout)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(margin).Layout(gtx, material.Button(th, &v.buttonRemove, "Remove").Layout)
+		}),
+	)
+}
+
+// Help text which lists the available functions.
+func customIndicatorHelp() string {
+	return fmt.Sprintf("Series: open, high, low, close, volume, hl2, hlc3, ohlc4. Functions: %s. "+
+		"Operators: + - * / < > <= >= == != and or not, close[1] is the previous close. "+
+		"Statements are separated by new lines, \"name = expression\" defines a variable, every other statement is plotted.",
+		strings.Join(expression.GetFunctionNames(), ", "))
+}
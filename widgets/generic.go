@@ -14,6 +14,9 @@ import (
 	"gioui.org/x/component"
 )
 
+// TODO use theme
+var errorNoteColor = color.NRGBA{R: 255, A: 255}
+
 func heading(th *material.Theme, t string) material.LabelStyle {
 	l := material.H5(th, t)
 	l.Alignment = text.Middle
@@ -120,8 +123,7 @@ func layoutConfirmationFrame(th *material.Theme, margin unit.Dp, gtx layout.Cont
 func layoutTextFieldWithNote(th *material.Theme, gtx layout.Context, field *component.TextField, hint string, note string, highlightNote bool) layout.Dimensions {
 	noteLabel := material.Body2(th, note)
 	if highlightNote {
-		// TODO use theme
-		noteLabel.Color = color.NRGBA{R: 255, A: 255}
+		noteLabel.Color = errorNoteColor
 	}
 	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
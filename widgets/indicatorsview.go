@@ -4,13 +4,12 @@
 package widgets
 
 import (
+	"fmt"
 	"image/color"
 	"maystocks/config"
 	"maystocks/indapi"
 	"maystocks/indapi/indicators"
 	"maystocks/stockval"
-	"sort"
-	"strings"
 
 	"gioui.org/layout"
 	"gioui.org/op"
@@ -23,26 +22,30 @@ import (
 
 type IndicatorView struct {
 	config.IndicatorConfig
-	dropDownIndicator  *DropDown
-	buttonRemove       widget.Clickable
-	colorTextFields    []component.TextField
-	propertyKeys       []string
-	propertyChildren   []layout.FlexChild
-	propertyTextFields map[string]*component.TextField
+	dropDownIndicator *DropDown
+	dropDownInput     *DropDown
+	inputOptions      []indapi.IndicatorInput
+	buttonRemove      widget.Clickable
+	colorTextFields   []component.TextField
+	propertyKeys      []string
+	propertyChildren  []layout.FlexChild
+	propertyEditors   map[string]*PropertyEditor
 }
 
 type IndicatorsView struct {
-	configList      widget.List
-	buttonContinue  widget.Clickable
-	buttonAdd       widget.Clickable
-	buttonClose     widget.Clickable
-	confirmed       bool
-	Margin          unit.Dp
-	TextMargin      unit.Dp
-	ItemMargin      unit.Dp
-	configChildren  []layout.FlexChild
-	indicatorConfig [][]IndicatorView
-	indicatorsList  []string
+	configList       widget.List
+	buttonContinue   widget.Clickable
+	buttonAdd        widget.Clickable
+	buttonAddCustom  widget.Clickable
+	buttonClose      widget.Clickable
+	confirmed        bool
+	Margin           unit.Dp
+	TextMargin       unit.Dp
+	ItemMargin       unit.Dp
+	configChildren   []layout.FlexChild
+	indicatorConfig  [][]IndicatorView
+	customIndicators []*CustomIndicatorView
+	indicatorsList   []string
 }
 
 func NewIndicatorsView() *IndicatorsView {
@@ -56,15 +59,27 @@ func NewIndicatorsView() *IndicatorsView {
 		TextMargin: DefaultMargin * 2,
 		ItemMargin: 50,
 	}
+	v.updateIndicatorsList()
+	return &v
+}
+
+// Updates the list of indicators which can be selected, custom indicators may have changed.
+func (v *IndicatorsView) updateIndicatorsList() {
 	indList := indicators.GetList()
 	v.indicatorsList = make([]string, len(indList))
 	for i, ind := range indList {
 		v.indicatorsList[i] = string(ind)
 	}
-	return &v
 }
 
 func (v *IndicatorsView) GetIndicatorConfig(appConfig *config.AppConfig) {
+	appConfig.CustomIndicators = appConfig.CustomIndicators[:0]
+	for _, c := range v.customIndicators {
+		// New custom indicators are stored after they were confirmed.
+		if c.Id != "" {
+			appConfig.CustomIndicators = append(appConfig.CustomIndicators, c.CustomIndicatorConfig)
+		}
+	}
 	for i := range v.indicatorConfig {
 		// Clear existing config.
 		for s := range appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig {
@@ -80,37 +95,42 @@ func (v *IndicatorsView) GetIndicatorConfig(appConfig *config.AppConfig) {
 		}
 		appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig = appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig[:s]
 		// Add configuration according to ui.
-		for j := range v.indicatorConfig[i] {
-			subPlotFound := false
-			for s := range appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig {
-				subPlotType := appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig[s].Type
-				if subPlotType != indapi.SubPlotTypeIndicator && subPlotType == indicators.GetSubPlotType(v.indicatorConfig[i][j].IndicatorId) {
-					appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig[s].Indicators =
-						append(
-							appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig[s].Indicators,
-							v.indicatorConfig[i][j].IndicatorConfig,
-						)
-					subPlotFound = true
+		// Chained indicators are added to the subplot of the indicator which provides their input.
+		plotConfig := &appConfig.WindowConfig[0].PlotConfig[i]
+		subPlotIndex := make(map[string]int)
+		var pending []IndicatorView
+		for _, ind := range v.indicatorConfig[i] {
+			if ind.Input.Name == "" {
+				subPlotIndex[ind.Name] = addToSubPlot(plotConfig, ind.IndicatorConfig, -1)
+			} else {
+				pending = append(pending, ind)
+			}
+		}
+		for len(pending) > 0 {
+			var remaining []IndicatorView
+			for _, ind := range pending {
+				if s, ok := subPlotIndex[ind.Input.Name]; ok {
+					subPlotIndex[ind.Name] = addToSubPlot(plotConfig, ind.IndicatorConfig, s)
+				} else {
+					remaining = append(remaining, ind)
 				}
 			}
-			// Add custom indicator sub plots if required.
-			if !subPlotFound {
-				appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig =
-					append(
-						appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig,
-						config.NewSubPlotIndicatorConfig(),
-					)
-				lastIndex := len(appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig) - 1
-				appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig[lastIndex].Indicators =
-					append(
-						appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig[lastIndex].Indicators,
-						v.indicatorConfig[i][j].IndicatorConfig,
-					)
+			if len(remaining) == len(pending) {
+				// The inputs are missing, use the default subplots.
+				for _, ind := range remaining {
+					subPlotIndex[ind.Name] = addToSubPlot(plotConfig, ind.IndicatorConfig, -1)
+				}
+				remaining = nil
 			}
+			pending = remaining
 		}
 		// There may be additional or removed properties for indicators, we need to merge the maps.
 		for s := range appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig {
 			for k := range appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig[s].Indicators {
+				if !indicators.IsRegistered(appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig[s].Indicators[k].IndicatorId) {
+					// Custom indicators are registered when reloading the configuration.
+					continue
+				}
 				configProperties := appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig[s].Indicators[k].Properties
 				// Use default properties as starting point, assign values only for these default properties.
 				appConfig.WindowConfig[0].PlotConfig[i].SubPlotConfig[s].Indicators[k].Properties =
@@ -125,17 +145,112 @@ func (v *IndicatorsView) GetIndicatorConfig(appConfig *config.AppConfig) {
 	}
 }
 
+// Adds the indicator to the subplot with index s. If s is negative, the indicator is added to the
+// subplot of its type, or to a new indicator subplot. Returns the index of the subplot.
+func addToSubPlot(plotConfig *config.PlotConfig, ind config.IndicatorConfig, s int) int {
+	if s < 0 {
+		for i, c := range plotConfig.SubPlotConfig {
+			if c.Type != indapi.SubPlotTypeIndicator && c.Type == indicators.GetSubPlotType(ind.IndicatorId) {
+				s = i
+				break
+			}
+		}
+	}
+	// Add custom indicator sub plots if required.
+	if s < 0 {
+		plotConfig.SubPlotConfig = append(plotConfig.SubPlotConfig, config.NewSubPlotIndicatorConfig())
+		s = len(plotConfig.SubPlotConfig) - 1
+	}
+	plotConfig.SubPlotConfig[s].Indicators = append(plotConfig.SubPlotConfig[s].Indicators, ind)
+	return s
+}
+
 func (v *IndicatorsView) SetIndicatorConfig(appConfig *config.AppConfig) {
+	v.updateIndicatorsList()
+	v.customIndicators = v.customIndicators[:0]
+	for _, c := range appConfig.CustomIndicators {
+		v.customIndicators = append(v.customIndicators, NewCustomIndicatorView(c))
+	}
 	v.indicatorConfig = v.indicatorConfig[:0]
 	for i, p := range appConfig.WindowConfig[0].PlotConfig {
 		v.indicatorConfig = append(v.indicatorConfig, make([]IndicatorView, 0, 8))
 		for s := range p.SubPlotConfig {
 			for _, ind := range p.SubPlotConfig[s].Indicators {
+				if !indicators.IsRegistered(ind.IndicatorId) {
+					// The custom indicator was removed or is invalid.
+					continue
+				}
 				newView := v.createIndicator(ind)
 				v.indicatorConfig[i] = append(v.indicatorConfig[i], newView)
 			}
 		}
+		v.updateInputOptions(i)
+	}
+}
+
+// Assigns unique names to indicators which do not have a name yet, so that they can be used as input.
+func (v *IndicatorsView) assignNames(plotIndex int) {
+	used := make(map[string]bool)
+	for _, ind := range v.indicatorConfig[plotIndex] {
+		used[ind.Name] = true
+	}
+	for i := range v.indicatorConfig[plotIndex] {
+		ind := &v.indicatorConfig[plotIndex][i]
+		for n := 1; ind.Name == ""; n++ {
+			if name := fmt.Sprintf("%s %d", ind.IndicatorId, n); !used[name] {
+				ind.Name = name
+				used[name] = true
+			}
+		}
+	}
+}
+
+// Updates the inputs which can be selected, i.e. the outputs of the other indicators of the plot.
+// Outputs of indicators which directly or indirectly use an indicator as input are not offered, to avoid cycles.
+func (v *IndicatorsView) updateInputOptions(plotIndex int) {
+	v.assignNames(plotIndex)
+	views := v.indicatorConfig[plotIndex]
+	byName := make(map[string]*IndicatorView, len(views))
+	for i := range views {
+		byName[views[i].Name] = &views[i]
+	}
+	for i := range views {
+		ind := &views[i]
+		ind.inputOptions = []indapi.IndicatorInput{{}}
+		labels := []string{"Candles"}
+		for j := range views {
+			if i == j || dependsOn(&views[j], ind.Name, byName) {
+				continue
+			}
+			for _, output := range indicators.GetOutputNames(views[j].IndicatorId) {
+				ind.inputOptions = append(ind.inputOptions, indapi.IndicatorInput{Name: views[j].Name, Output: output})
+				if output == indapi.OutputValue {
+					labels = append(labels, views[j].Name)
+				} else {
+					labels = append(labels, views[j].Name+": "+output)
+				}
+			}
+		}
+		selectedIndex := stockval.IndexOf(ind.inputOptions, ind.Input)
+		if selectedIndex < 0 {
+			// The input was removed.
+			ind.Input = indapi.IndicatorInput{}
+			selectedIndex = 0
+		}
+		ind.dropDownInput = NewDropDown(labels, selectedIndex)
+	}
+}
+
+// Returns whether the indicator uses the output of the named indicator, directly or indirectly.
+func dependsOn(ind *IndicatorView, name string, byName map[string]*IndicatorView) bool {
+	// Limit the depth in case the configuration contains a cycle.
+	for depth := 0; ind != nil && ind.Input.Name != "" && depth < len(byName); depth++ {
+		if ind.Input.Name == name {
+			return true
+		}
+		ind = byName[ind.Input.Name]
 	}
+	return false
 }
 
 func (v *IndicatorsView) createIndicator(ind config.IndicatorConfig) IndicatorView {
@@ -144,9 +259,9 @@ func (v *IndicatorsView) createIndicator(ind config.IndicatorConfig) IndicatorVi
 		panic("unknown data broker")
 	}
 	newView := IndicatorView{
-		IndicatorConfig:    ind,
-		dropDownIndicator:  NewDropDown(v.indicatorsList, indicatorIndex),
-		propertyTextFields: make(map[string]*component.TextField),
+		IndicatorConfig:   ind,
+		dropDownIndicator: NewDropDown(v.indicatorsList, indicatorIndex),
+		propertyEditors:   make(map[string]*PropertyEditor),
 	}
 	for i := range ind.Colors {
 		var colorName string
@@ -161,13 +276,14 @@ func (v *IndicatorsView) createIndicator(ind config.IndicatorConfig) IndicatorVi
 		textField.SetText(colorName)
 		newView.colorTextFields = append(newView.colorTextFields, textField)
 	}
-	for key, value := range ind.Properties {
-		t := component.TextField{Editor: widget.Editor{Submit: true, SingleLine: true, MaxLen: 128}}
-		t.SetText(value)
-		newView.propertyTextFields[key] = &t
-		newView.propertyKeys = append(newView.propertyKeys, key)
+	for _, desc := range indicators.GetPropertyDescriptors(ind.IndicatorId) {
+		value, ok := ind.Properties[desc.Key]
+		if !ok {
+			value = desc.Default
+		}
+		newView.propertyEditors[desc.Key] = NewPropertyEditor(desc, value)
+		newView.propertyKeys = append(newView.propertyKeys, desc.Key)
 	}
-	sort.Strings(newView.propertyKeys)
 	return newView
 }
 
@@ -202,6 +318,32 @@ func (v *IndicatorsView) Layout(th *material.Theme, gtx layout.Context, plotInde
 					)
 				}),
 				v.configChildren)
+			v.configChildren = append(v.configChildren,
+				layout.Rigid(heading(th, "Custom Indicators").Layout),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return layout.Inset{Left: v.ItemMargin, Bottom: v.TextMargin}.Layout(gtx, material.Body2(th, customIndicatorHelp()).Layout)
+				}),
+			)
+			for _, c := range v.customIndicators {
+				v.configChildren = v.appendIndicatorLayout(
+					th,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return layout.Inset{Left: v.ItemMargin}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							return c.Layout(th, v.Margin, gtx)
+						})
+					}),
+					v.configChildren)
+			}
+			v.configChildren = v.appendIndicatorLayout(
+				th,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return layout.Flex{}.Layout(gtx,
+						layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+							return layout.Inset{Top: v.ItemMargin, Left: v.ItemMargin}.Layout(gtx, material.Button(th, &v.buttonAddCustom, "Add Custom Indicator").Layout)
+						}),
+					)
+				}),
+				v.configChildren)
 			return layout.Flex{Axis: layout.Vertical}.Layout(gtx, v.configChildren...)
 		},
 		)
@@ -213,19 +355,20 @@ func (v *IndicatorsView) handleInput(gtx layout.Context, plotIndex int) {
 	if v.buttonContinue.Clicked(gtx) || v.buttonClose.Clicked(gtx) {
 		if v.validate(plotIndex) {
 			for i := range v.indicatorConfig[plotIndex] {
+				properties := make(map[string]string, len(v.indicatorConfig[plotIndex][i].propertyKeys))
 				for _, key := range v.indicatorConfig[plotIndex][i].propertyKeys {
-					v.indicatorConfig[plotIndex][i].IndicatorConfig.Properties[key] = strings.TrimSpace(v.indicatorConfig[plotIndex][i].propertyTextFields[key].Text())
+					properties[key] = v.indicatorConfig[plotIndex][i].propertyEditors[key].Value()
 				}
+				v.indicatorConfig[plotIndex][i].IndicatorConfig.Properties = properties
 				for j := range v.indicatorConfig[plotIndex][i].IndicatorConfig.Colors {
-					var nrgba color.NRGBA
-					c, ok := colornames.Map[strings.ToLower(strings.Replace(v.indicatorConfig[plotIndex][i].colorTextFields[j].Text(), " ", "", -1))]
-					if ok {
-						// no alpha, simply assign.
-						nrgba = color.NRGBA(c)
-					}
+					// Invalid colors are reset to the default color.
+					nrgba, _ := indapi.ParseColor(v.indicatorConfig[plotIndex][i].colorTextFields[j].Text())
 					v.indicatorConfig[plotIndex][i].IndicatorConfig.Colors[j] = nrgba
 				}
 			}
+			for _, c := range v.customIndicators {
+				c.Apply()
+			}
 			v.confirmed = true
 			invalidate = true
 		}
@@ -234,21 +377,45 @@ func (v *IndicatorsView) handleInput(gtx layout.Context, plotIndex int) {
 		defaultData := indicators.Create(indicators.DefaultId, nil, []color.NRGBA{})
 		newView := v.createIndicator(config.IndicatorConfig{IndicatorId: defaultData.GetId(), Properties: defaultData.GetProperties(), Colors: defaultData.GetColors()})
 		v.indicatorConfig[plotIndex] = append(v.indicatorConfig[plotIndex], newView)
+		v.updateInputOptions(plotIndex)
+		invalidate = true
+	}
+	if v.buttonAddCustom.Clicked(gtx) {
+		v.customIndicators = append(v.customIndicators, NewCustomIndicatorView(config.CustomIndicatorConfig{}))
 		invalidate = true
 	}
+	for i, c := range v.customIndicators {
+		if c.buttonRemove.Clicked(gtx) {
+			v.customIndicators = append(v.customIndicators[:i], v.customIndicators[i+1:]...)
+			invalidate = true
+			break
+		}
+	}
 	for i := range v.indicatorConfig[plotIndex] {
 		if v.indicatorConfig[plotIndex][i].buttonRemove.Clicked(gtx) {
 			// Remove indicator.
 			v.indicatorConfig[plotIndex] = append(v.indicatorConfig[plotIndex][:i], v.indicatorConfig[plotIndex][i+1:]...)
+			v.updateInputOptions(plotIndex)
 			invalidate = true
 			break // we changed the list, ignore further input for this frame
 		}
 		clickedIndicator := v.indicatorConfig[plotIndex][i].dropDownIndicator.ClickedIndex()
 		if clickedIndicator >= 0 {
 			newData := indicators.Create(indapi.IndicatorId(v.indicatorsList[clickedIndicator]), nil, []color.NRGBA{})
+			// Keep the input, a new name is assigned because the outputs change.
 			v.indicatorConfig[plotIndex][i] =
-				v.createIndicator(config.IndicatorConfig{IndicatorId: newData.GetId(), Properties: newData.GetProperties(), Colors: newData.GetColors()})
+				v.createIndicator(config.IndicatorConfig{IndicatorId: newData.GetId(), Properties: newData.GetProperties(), Colors: newData.GetColors(),
+					Input: v.indicatorConfig[plotIndex][i].Input})
+			v.updateInputOptions(plotIndex)
+			invalidate = true
+			break
+		}
+		clickedInput := v.indicatorConfig[plotIndex][i].dropDownInput.ClickedIndex()
+		if clickedInput >= 0 {
+			v.indicatorConfig[plotIndex][i].Input = v.indicatorConfig[plotIndex][i].inputOptions[clickedInput]
+			v.updateInputOptions(plotIndex)
 			invalidate = true
+			break
 		}
 	}
 	if invalidate {
@@ -257,12 +424,20 @@ func (v *IndicatorsView) handleInput(gtx layout.Context, plotIndex int) {
 }
 
 func (v *IndicatorsView) validate(plotIndex int) bool {
+	valid := true
 	for i := range v.indicatorConfig[plotIndex] {
+		// Validate all indicators, so that all errors are shown.
 		if !v.indicatorConfig[plotIndex][i].IsValid() {
-			return false
+			valid = false
+		}
+	}
+	used := make(map[indapi.IndicatorId]bool)
+	for _, c := range v.customIndicators {
+		if !c.Validate(used) {
+			valid = false
 		}
 	}
-	return true
+	return valid
 }
 
 func (v *IndicatorsView) layoutConfigEntry(th *material.Theme, gtx layout.Context, ind *IndicatorView, w layout.Widget) layout.Dimensions {
@@ -310,6 +485,7 @@ func (v *IndicatorsView) indicatorConfigChild(th *material.Theme, ind *Indicator
 			return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
 				layout.Flexed(0.5, func(gtx layout.Context) layout.Dimensions {
 					ind.propertyChildren = ind.propertyChildren[:0]
+					ind.propertyChildren = append(ind.propertyChildren, v.inputConfigChild(th, ind))
 					for _, key := range ind.propertyKeys {
 						ind.propertyChildren = append(ind.propertyChildren, v.propertyConfigChild(th, ind, key))
 					}
@@ -324,15 +500,37 @@ func (v *IndicatorsView) indicatorConfigChild(th *material.Theme, ind *Indicator
 	})
 }
 
+// Layout of the name of the indicator, which is used to select it as input, and the input of the indicator.
+func (v *IndicatorsView) inputConfigChild(th *material.Theme, ind *IndicatorView) layout.FlexChild {
+	return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(material.Body2(th, ind.Name).Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return material.Body1(th, "Input:").Layout(gtx)
+					}),
+					layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+						return layout.UniformInset(v.Margin).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							return ind.dropDownInput.Layout(th, gtx)
+						})
+					}),
+				)
+			}),
+		)
+	})
+}
+
 func (v *IndicatorsView) propertyConfigChild(th *material.Theme, ind *IndicatorView, key string) layout.FlexChild {
+	editor := ind.propertyEditors[key]
 	return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 		return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
 			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-				return material.Body1(th, key+":").Layout(gtx)
+				return material.Body1(th, editor.Desc.Label+":").Layout(gtx)
 			}),
-			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
 				return layout.UniformInset(v.Margin).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-					return ind.propertyTextFields[key].Layout(gtx, th, "Value")
+					return editor.Layout(th, v.Margin, gtx)
 				})
 			}),
 		)
@@ -340,5 +538,11 @@ func (v *IndicatorsView) propertyConfigChild(th *material.Theme, ind *IndicatorV
 }
 
 func (b *IndicatorView) IsValid() bool {
-	return true
+	valid := true
+	for _, key := range b.propertyKeys {
+		if !b.propertyEditors[key].Validate() {
+			valid = false
+		}
+	}
+	return valid
 }
@@ -30,6 +30,7 @@ type PlotTheme struct {
 	TextMargin                   DpPoint
 	AxesXfontSize                int
 	AxesYfontSize                int
+	LabelFontSize                int
 	DefaultPlotGrid              DpPoint
 	DefaultTimeUnitGrid          float64
 	AxesColor                    color.NRGBA
@@ -57,6 +58,12 @@ type PlotTheme struct {
 	FrameBgColor                 color.NRGBA
 	FrameTextColor               color.NRGBA
 	DefaultIndicatorColor        color.NRGBA
+	PriceLineColor               color.NRGBA
+	PriceAreaColor               color.NRGBA
+	SignalBullishColor           color.NRGBA
+	SignalBearishColor           color.NRGBA
+	// Line colors of compared assets, used in turn.
+	CompareColors []color.NRGBA
 }
 
 func NewDarkPlotTheme() *PlotTheme {
@@ -67,6 +74,7 @@ func NewDarkPlotTheme() *PlotTheme {
 		TextMargin:                   DpPoint{X: 7, Y: 7},
 		AxesXfontSize:                17,
 		AxesYfontSize:                17,
+		LabelFontSize:                12,
 		DefaultPlotGrid:              DpPoint{X: 150, Y: 100},
 		DefaultTimeUnitGrid:          4,
 		AxesColor:                    color.NRGBA{R: 255, G: 255, B: 255, A: 255},
@@ -94,6 +102,16 @@ func NewDarkPlotTheme() *PlotTheme {
 		FrameBgColor:                 color.NRGBA{R: 50, G: 50, B: 50, A: 200},
 		FrameTextColor:               color.NRGBA{R: 255, G: 255, B: 255, A: 255},
 		DefaultIndicatorColor:        color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+		PriceLineColor:               color.NRGBA{R: 100, G: 180, B: 255, A: 255},
+		PriceAreaColor:               color.NRGBA{R: 100, G: 180, B: 255, A: 60},
+		SignalBullishColor:           color.NRGBA{R: 0, G: 200, B: 255, A: 255},
+		SignalBearishColor:           color.NRGBA{R: 255, G: 140, B: 0, A: 255},
+		CompareColors: []color.NRGBA{
+			{R: 255, G: 215, B: 0, A: 255},
+			{R: 255, G: 105, B: 180, A: 255},
+			{R: 0, G: 230, B: 200, A: 255},
+			{R: 180, G: 130, B: 255, A: 255},
+		},
 	}
 }
 
@@ -105,6 +123,7 @@ func NewLightPlotTheme() *PlotTheme {
 		TextMargin:                   DpPoint{X: 7, Y: 7},
 		AxesXfontSize:                17,
 		AxesYfontSize:                17,
+		LabelFontSize:                12,
 		DefaultPlotGrid:              DpPoint{X: 150, Y: 100},
 		DefaultTimeUnitGrid:          4,
 		AxesColor:                    color.NRGBA{R: 0, G: 0, B: 0, A: 255},
@@ -129,6 +148,16 @@ func NewLightPlotTheme() *PlotTheme {
 		QuoteTextColor:               color.NRGBA{R: 0, G: 0, B: 0, A: 255},
 		HoverTextColor:               color.NRGBA{R: 100, G: 255, B: 100, A: 255},
 		HoverBgColor:                 color.NRGBA{R: 174, G: 174, B: 207, A: 255},
+		PriceLineColor:               color.NRGBA{R: 0, G: 90, B: 200, A: 255},
+		PriceAreaColor:               color.NRGBA{R: 0, G: 90, B: 200, A: 50},
+		SignalBullishColor:           color.NRGBA{R: 0, G: 90, B: 200, A: 255},
+		SignalBearishColor:           color.NRGBA{R: 220, G: 100, B: 0, A: 255},
+		CompareColors: []color.NRGBA{
+			{R: 200, G: 150, B: 0, A: 255},
+			{R: 200, G: 0, B: 120, A: 255},
+			{R: 0, G: 150, B: 130, A: 255},
+			{R: 110, G: 60, B: 200, A: 255},
+		},
 	}
 }
 
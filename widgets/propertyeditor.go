@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: AGPL-3.0-or-later
+// Copyright (c) Lothar May
+
+package widgets
+
+import (
+	"maystocks/indapi"
+	"maystocks/stockval"
+	"strconv"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+// Editor for a single indicator property, the kind of editor depends on the property type.
+type PropertyEditor struct {
+	Desc          indapi.PropertyDescriptor
+	textField     component.TextField
+	dropDown      *DropDown
+	selectedIndex int
+	checkBox      widget.Bool
+	buttonDec     widget.Clickable
+	buttonInc     widget.Clickable
+	note          string
+	highlightNote bool
+}
+
+func NewPropertyEditor(desc indapi.PropertyDescriptor, value string) *PropertyEditor {
+	e := PropertyEditor{
+		Desc: desc,
+		note: desc.Description,
+	}
+	switch desc.Type {
+	case indapi.PropertyTypeEnum, indapi.PropertyTypeSource:
+		e.selectedIndex = stockval.IndexOf(desc.GetOptions(), value)
+		if e.selectedIndex < 0 {
+			e.selectedIndex = stockval.IndexOf(desc.GetOptions(), desc.Default)
+		}
+		e.dropDown = NewDropDown(desc.GetOptions(), e.selectedIndex)
+	case indapi.PropertyTypeBool:
+		e.checkBox.Value, _ = strconv.ParseBool(value)
+	default:
+		e.textField = component.TextField{Editor: widget.Editor{Submit: true, SingleLine: true, MaxLen: 128}}
+		e.textField.SetText(value)
+	}
+	return &e
+}
+
+// Returns the current value in the format used by the indicator properties.
+func (e *PropertyEditor) Value() string {
+	switch e.Desc.Type {
+	case indapi.PropertyTypeEnum, indapi.PropertyTypeSource:
+		options := e.Desc.GetOptions()
+		if e.selectedIndex < 0 || e.selectedIndex >= len(options) {
+			return ""
+		}
+		return options[e.selectedIndex]
+	case indapi.PropertyTypeBool:
+		return strconv.FormatBool(e.checkBox.Value)
+	default:
+		return strings.TrimSpace(e.textField.Text())
+	}
+}
+
+// Validates the current value, and displays an error note if it is invalid.
+func (e *PropertyEditor) Validate() bool {
+	err := e.Desc.Validate(e.Value())
+	if err != nil {
+		e.note = err.Error()
+		e.highlightNote = true
+		return false
+	}
+	e.note = e.Desc.Description
+	e.highlightNote = false
+	return true
+}
+
+func (e *PropertyEditor) isSpinner() bool {
+	return e.Desc.Type == indapi.PropertyTypeInt || e.Desc.Type == indapi.PropertyTypeFloat
+}
+
+// Increments or decrements the numeric value by the step size, limited to the allowed range.
+func (e *PropertyEditor) step(direction float64) {
+	f, err := strconv.ParseFloat(e.Value(), 64)
+	if err != nil {
+		f, _ = strconv.ParseFloat(e.Desc.Default, 64)
+	}
+	f += direction * e.Desc.GetStep()
+	if e.Desc.HasRange() {
+		f = min(max(f, e.Desc.Min), e.Desc.Max)
+	}
+	if e.Desc.Type == indapi.PropertyTypeInt {
+		e.textField.SetText(strconv.Itoa(int(f)))
+	} else {
+		e.textField.SetText(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	e.Validate()
+}
+
+func (e *PropertyEditor) handleInput(gtx layout.Context) {
+	if e.dropDown != nil {
+		if i := e.dropDown.ClickedIndex(); i >= 0 {
+			e.selectedIndex = i
+			e.dropDown.SetSelectedIndex(i)
+		}
+	}
+	if e.isSpinner() {
+		if e.buttonDec.Clicked(gtx) {
+			e.step(-1)
+		}
+		if e.buttonInc.Clicked(gtx) {
+			e.step(1)
+		}
+	}
+}
+
+func (e *PropertyEditor) Layout(th *material.Theme, margin unit.Dp, gtx layout.Context) layout.Dimensions {
+	e.handleInput(gtx)
+	var editor layout.Widget
+	switch e.Desc.Type {
+	case indapi.PropertyTypeEnum, indapi.PropertyTypeSource:
+		editor = func(gtx layout.Context) layout.Dimensions {
+			return e.dropDown.Layout(th, gtx)
+		}
+	case indapi.PropertyTypeBool:
+		editor = material.CheckBox(th, &e.checkBox, "").Layout
+	case indapi.PropertyTypeInt, indapi.PropertyTypeFloat:
+		editor = func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+					return e.textField.Layout(gtx, th, "Value")
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return layout.Inset{Left: margin}.Layout(gtx, material.Button(th, &e.buttonDec, "-").Layout)
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return layout.Inset{Left: margin}.Layout(gtx, material.Button(th, &e.buttonInc, "+").Layout)
+				}),
+			)
+		}
+	default:
+		editor = func(gtx layout.Context) layout.Dimensions {
+			return e.textField.Layout(gtx, th, "Value")
+		}
+	}
+	noteLabel := material.Body2(th, e.note)
+	if e.highlightNote {
+		noteLabel.Color = errorNoteColor
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(editor),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if len(e.note) == 0 {
+				return layout.Dimensions{}
+			}
+			return noteLabel.Layout(gtx)
+		}),
+	)
+}
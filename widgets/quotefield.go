@@ -122,7 +122,15 @@ func (q *QuoteField) Layout(gtx layout.Context, th *material.Theme, pth *PlotThe
 				var hintText string
 				tradingTime := time.Now()
 				isHoliday, holidayName := q.calendar.IsBankHoliday(tradingTime)
-				if isHoliday {
+				if quote.IsStale() && !quote.CurrentPriceTimestamp.IsZero() {
+					// Do not silently display old prices as current prices.
+					hintText = stockval.FormatDataAge(quote.CurrentPriceTimestamp, tradingTime)
+					if quote.Offline {
+						hintText = "Offline, " + hintText
+					}
+				} else if quote.Offline {
+					hintText = "Offline"
+				} else if isHoliday {
 					hintText = holidayName
 				} else {
 					trading, _, h := q.calendar.GetTradingHours(tradingTime)
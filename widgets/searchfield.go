@@ -159,6 +159,20 @@ func (f *SearchField) updateTextFromSelection() {
 	f.textField.SetCaret(0, len(t))
 }
 
+// Replaces the text without searching for it. Call from same goroutine as Layout.
+func (f *SearchField) SetText(t string) {
+	f.resetItems()
+	if t != f.textField.Text() {
+		f.textField.SetText(t)
+		f.ignoreChangeText = t
+	}
+}
+
+// Moves the focus to the search field, the text is selected. Call from same goroutine as Layout.
+func (f *SearchField) Focus(gtx layout.Context) {
+	gtx.Execute(key.FocusCmd{Tag: &f.textField.Editor})
+}
+
 // Call from same goroutine as Layout.
 func (f *SearchField) HandleInput(gtx layout.Context) {
 	for {